@@ -0,0 +1,87 @@
+// Command renumber-tracking rewrites the use count of every tracked slot so
+// that each series is numbered by the date and start time the slots are
+// scheduled on. Run it once after deploying schedule-ordered numbering.
+package main
+
+import (
+	"colortime-service/config"
+	"colortime-service/internal/colortime"
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the slots that would be renumbered without writing")
+	organizationID := flag.String("org", "", "only renumber series of this organization")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := config.LoadConfig()
+
+	mongoClient, err := connectToMongoDB(cfg.MongoURI)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	repo := colortime.NewColorTimeRepository(mongoClient.Database(cfg.MongoDB).Collection("colortime"))
+
+	ctx := context.Background()
+
+	keys, err := repo.GetTrackingKeys(ctx, *organizationID)
+	if err != nil {
+		log.Fatalf("Failed to list tracking series: %v", err)
+	}
+
+	series, slots, failed := 0, 0, 0
+	for _, key := range keys {
+		changed, err := colortime.RenumberTrackingSeries(ctx, repo, key, *dryRun)
+		if err != nil {
+			failed++
+			log.Printf("Failed to renumber %s (org %s, owner %s/%s): %v", key.Tracking, key.OrganizationID, key.OwnerRole, key.OwnerID, err)
+			continue
+		}
+
+		if changed > 0 {
+			series++
+			slots += changed
+			log.Printf("%s (org %s, owner %s/%s): %d slots renumbered", key.Tracking, key.OrganizationID, key.OwnerRole, key.OwnerID, changed)
+		}
+	}
+
+	mode := "renumbered"
+	if *dryRun {
+		mode = "would be renumbered"
+	}
+	log.Printf("%d of %d series, %d slots %s, %d failed", series, len(keys), slots, mode, failed)
+
+	if failed > 0 {
+		log.Fatal("Renumbering finished with errors")
+	}
+}
+
+func connectToMongoDB(uri string) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"colortime-service/config"
+	"colortime-service/internal/closure"
 	"colortime-service/internal/colortime"
 	"colortime-service/internal/default_colortime"
 	"colortime-service/internal/language"
+	"colortime-service/internal/organization_setting"
 	"colortime-service/internal/product"
 	templatecolortime "colortime-service/internal/template_colortime"
 	"colortime-service/internal/term"
@@ -54,27 +56,58 @@ func main() {
 		}
 	}()
 
-	productService := product.NewUserService(consulClient)
-	languageService := language.NewLanguageService(consulClient)
-	userService := user.NewUserService(consulClient)
-	topicService := topic.NewTopicService(consulClient)
-	termService := term.NewTermService(consulClient)
+	gatewayOptions := consul.NewGatewayOptions(cfg)
+	productService := product.NewUserService(consulClient, gatewayOptions, time.Duration(cfg.ProductCacheTTLSeconds)*time.Second, cfg.ProductCacheSize)
+	languageService := language.NewLanguageService(consulClient, gatewayOptions)
+	userService := user.NewUserService(consulClient, gatewayOptions)
+	topicService := topic.NewTopicService(consulClient, gatewayOptions, time.Duration(cfg.TopicCacheTTLSeconds)*time.Second, cfg.TopicCacheSize)
+	topicHandler := topic.NewTopicHandler(topicService)
+	termService := term.NewTermService(consulClient, gatewayOptions)
 
 	colorTimeCollection := mongoClient.Database(cfg.MongoDB).Collection("colortime")
 	defaultColorTimeCollection := mongoClient.Database(cfg.MongoDB).Collection("default_colortime")
+	defaultSlotSeriesCollection := mongoClient.Database(cfg.MongoDB).Collection("default_colortime_series")
 	colorTimeTemplateCollection := mongoClient.Database(cfg.MongoDB).Collection("colortime_template")
+	colorTimeSyncJobCollection := mongoClient.Database(cfg.MongoDB).Collection("colortime_sync_job")
+	templateApplyJobCollection := mongoClient.Database(cfg.MongoDB).Collection("colortime_template_apply_job")
+	templateLibraryCollection := mongoClient.Database(cfg.MongoDB).Collection("colortime_template_library")
+	templateLibraryVersionCollection := mongoClient.Database(cfg.MongoDB).Collection("colortime_template_library_version")
+	organizationSettingCollection := mongoClient.Database(cfg.MongoDB).Collection("organization_setting")
+	closureCollection := mongoClient.Database(cfg.MongoDB).Collection("organization_closure")
 
 	colorTimeRepository := colortime.NewColorTimeRepository(colorTimeCollection)
 	templateColorTimeRepository := templatecolortime.NewTemplateColorTimeRepository(colorTimeTemplateCollection)
-	defaultColorTimeRepository := default_colortime.NewDefaultColorTimeRepository(defaultColorTimeCollection)
+	closureRepository := closure.NewClosureRepository(closureCollection)
+	defaultColorTimeRepository := default_colortime.NewDefaultColorTimeRepository(defaultColorTimeCollection, defaultSlotSeriesCollection, closureRepository)
+	colorTimeSyncJobRepository := colortime.NewSyncJobRepository(colorTimeSyncJobCollection)
+	templateApplyJobRepository := templatecolortime.NewApplyJobRepository(templateApplyJobCollection)
+	templateLibraryRepository := templatecolortime.NewLibraryTemplateRepository(templateLibraryCollection, templateLibraryVersionCollection)
+	organizationSettingRepository := organization_setting.NewOrganizationSettingRepository(organizationSettingCollection)
+
+	indexCtx, cancelIndex := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := colorTimeRepository.EnsureIndexes(indexCtx); err != nil {
+		logger.Errorf("Failed to create colortime indexes: %v", err)
+	}
+	cancelIndex()
 
-	defaultColorTimeService := default_colortime.NewDefaultColorTimeService(defaultColorTimeRepository, productService, topicService)
-	defaultColorTimeHandler := default_colortime.NewDefaultColorTimeHandler(defaultColorTimeService)
+	organizationSettingService := organization_setting.NewOrganizationSettingService(organizationSettingRepository)
+	organizationSettingHandler := organization_setting.NewOrganizationSettingHandler(organizationSettingService)
 
-	colorTimeService := colortime.NewColorTimeService(colorTimeRepository, defaultColorTimeRepository, productService, languageService, termService, userService, topicService)
+	colorTimeService := colortime.NewColorTimeService(colorTimeRepository, defaultColorTimeRepository, productService, languageService, termService, userService, topicService, organizationSettingService, colorTimeSyncJobRepository)
 	colorTimeHandler := colortime.NewColorTimeHandler(colorTimeService)
 
-	templateColorTimeService := templatecolortime.NewTemplateColorTimeService(templateColorTimeRepository, termService, defaultColorTimeRepository)
+	defaultColorTimeService := default_colortime.NewDefaultColorTimeService(defaultColorTimeRepository, productService, topicService, organizationSettingService, colorTimeService)
+	defaultColorTimeHandler := default_colortime.NewDefaultColorTimeHandler(defaultColorTimeService)
+
+	closureService := closure.NewClosureService(closureRepository, organizationSettingService, colorTimeService)
+	closureHandler := closure.NewClosureHandler(closureService)
+
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+	go colorTimeService.RunSyncWorker(syncCtx)
+
+	templateColorTimeService := templatecolortime.NewTemplateColorTimeService(templateColorTimeRepository, termService, defaultColorTimeRepository, closureService, colorTimeService, templateApplyJobRepository, templateLibraryRepository, cfg.TemplateApplyMaxDays)
+	go templateColorTimeService.RunApplyWorker(syncCtx)
 	templateColorTimeHandler := templatecolortime.NewTemplateColorTimeHandler(templateColorTimeService)
 
 	router := gin.Default()
@@ -82,6 +115,9 @@ func main() {
 	colortime.RegisterRoutes(router, colorTimeHandler)
 	default_colortime.RegisterRoutes(router, defaultColorTimeHandler)
 	templatecolortime.RegisterRoutes(router, templateColorTimeHandler)
+	organization_setting.RegisterRoutes(router, organizationSettingHandler)
+	closure.RegisterRoutes(router, closureHandler)
+	topic.RegisterRoutes(router, topicHandler)
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -1,6 +1,9 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 type Consul struct {
 	Host string `mapstructure:"host" validate:"required"`
@@ -55,6 +58,32 @@ type Config struct {
 	Registry Registry         `mapstructure:"registry" validate:"required"`
 	App      AppConfiguration `mapstructure:"app"`
 	Zap      ZapConfig        `mapstructure:"zap"`
+
+	// Longest date range a template is applied to at once, in days
+	TemplateApplyMaxDays int
+
+	// How long product lookups are cached, in seconds, and how many products
+	// are kept
+	ProductCacheTTLSeconds int
+	ProductCacheSize       int
+	TopicCacheTTLSeconds   int
+	TopicCacheSize         int
+
+	// Calls to other services: timeout of one attempt, attempts of idempotent
+	// requests and the backoff between them, and the consecutive failures that
+	// stop calls to a service for GatewayOpenSeconds
+	GatewayTimeoutMs        int
+	GatewayMaxAttempts      int
+	GatewayBackoffMs        int
+	GatewayMaxBackoffMs     int
+	GatewayFailureThreshold int
+	GatewayOpenSeconds      int
+
+	// Instance selection (round_robin, random or least_outstanding) and the
+	// consecutive failed calls that eject an instance for DiscoveryEjectSeconds
+	DiscoveryBalancer      string
+	DiscoveryEjectFailures int
+	DiscoveryEjectSeconds  int
 }
 
 func LoadConfig() *Config {
@@ -98,6 +127,23 @@ func LoadConfig() *Config {
 				},
 			},
 		},
+		TemplateApplyMaxDays: getEnvInt("TEMPLATE_APPLY_MAX_DAYS", 366),
+
+		ProductCacheTTLSeconds: getEnvInt("PRODUCT_CACHE_TTL_SECONDS", 300),
+		ProductCacheSize:       getEnvInt("PRODUCT_CACHE_SIZE", 1000),
+		TopicCacheTTLSeconds:   getEnvInt("TOPIC_CACHE_TTL_SECONDS", 300),
+		TopicCacheSize:         getEnvInt("TOPIC_CACHE_SIZE", 1000),
+
+		GatewayTimeoutMs:        getEnvInt("GATEWAY_TIMEOUT_MS", 5000),
+		GatewayMaxAttempts:      getEnvInt("GATEWAY_MAX_ATTEMPTS", 3),
+		GatewayBackoffMs:        getEnvInt("GATEWAY_BACKOFF_MS", 100),
+		GatewayMaxBackoffMs:     getEnvInt("GATEWAY_MAX_BACKOFF_MS", 2000),
+		GatewayFailureThreshold: getEnvInt("GATEWAY_FAILURE_THRESHOLD", 5),
+		GatewayOpenSeconds:      getEnvInt("GATEWAY_OPEN_SECONDS", 30),
+
+		DiscoveryBalancer:      getEnv("DISCOVERY_BALANCER", "round_robin"),
+		DiscoveryEjectFailures: getEnvInt("DISCOVERY_EJECT_FAILURES", 3),
+		DiscoveryEjectSeconds:  getEnvInt("DISCOVERY_EJECT_SECONDS", 30),
 	}
 	return config
 }
@@ -108,3 +154,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
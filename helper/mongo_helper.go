@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AnyVersion makes PatchDocument write whatever version the document is at,
+// for background writes that do not act on a copy read by a client.
+const AnyVersion int64 = -1
+
+// PatchDocument applies update to the single document matched by filter while
+// it is still at version. It stamps updated_at and bumps version, so a caller
+// still holding an older full copy gets a version conflict on its next write.
+// It returns a VersionConflictError when the document moved past version and
+// mongo.ErrNoDocuments when filter matched nothing.
+func PatchDocument(ctx context.Context, collection *mongo.Collection, filter bson.M, version int64, update bson.M, arrayFilters ...interface{}) error {
+
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+	}
+	set["updated_at"] = time.Now()
+	update["$set"] = set
+	update["$inc"] = bson.M{"version": 1}
+
+	opts := options.Update()
+	if len(arrayFilters) > 0 {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+	}
+
+	versioned := filter
+	if version != AnyVersion {
+		versioned = VersionFilter(filter["_id"], version)
+		for key, value := range filter {
+			versioned[key] = value
+		}
+	}
+
+	result, err := collection.UpdateOne(ctx, versioned, update, opts)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	if version == AnyVersion {
+		return mongo.ErrNoDocuments
+	}
+
+	// Tell a concurrent write apart from a missing document or element
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	if err := collection.FindOne(ctx, filter).Decode(&current); err != nil {
+		return err
+	}
+
+	return &VersionConflictError{CurrentVersion: current.Version}
+}
+
+// PrefixFields returns fields with every key prefixed by path, for setting
+// fields of an array element addressed by a positional operator.
+func PrefixFields(path string, fields bson.M) bson.M {
+	prefixed := make(bson.M, len(fields))
+	for key, value := range fields {
+		prefixed[path+key] = value
+	}
+	return prefixed
+}
+
+// errCodeIllegalOperation is what a standalone server answers to a command
+// that carries a transaction.
+const errCodeIllegalOperation = 20
+
+// ErrTransactionsUnsupported is returned by RunInRequiredTransaction when the
+// server does not support transactions.
+var ErrTransactionsUnsupported = errors.New("mongodb server does not support transactions")
+
+// RunInTransaction runs fn in a transaction on client; every operation of fn
+// that uses the context it is given takes part in it. fn may be called more
+// than once when the transaction is retried. Standalone servers do not support
+// transactions, there fn runs without one.
+func RunInTransaction(ctx context.Context, client *mongo.Client, fn func(ctx context.Context) error) error {
+
+	err := RunInRequiredTransaction(ctx, client, fn)
+	if errors.Is(err, ErrTransactionsUnsupported) {
+		return fn(ctx)
+	}
+
+	return err
+}
+
+// RunInRequiredTransaction is RunInTransaction for writes that must not be
+// partly applied: on a standalone server fn is not run and
+// ErrTransactionsUnsupported is returned.
+func RunInRequiredTransaction(ctx context.Context, client *mongo.Client, fn func(ctx context.Context) error) error {
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(errCodeIllegalOperation) {
+		return ErrTransactionsUnsupported
+	}
+
+	return err
+}
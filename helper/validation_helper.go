@@ -0,0 +1,53 @@
+package helper
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ErrValidation = "ERR_VALIDATION"
+
+// FieldError is one problem with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects the problems of a request so the client gets all of
+// them at once, each tied to its field.
+type ValidationError struct {
+	Errors []*FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Add(field, code, message string) {
+	e.Errors = append(e.Errors, &FieldError{Field: field, Code: code, Message: message})
+}
+
+// Err returns e when it holds any problem and nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func sendValidationError(c *gin.Context, validation *ValidationError) {
+	errorCode := ErrValidation
+	c.JSON(http.StatusBadRequest, APIResponse{
+		StatusCode: http.StatusBadRequest,
+		Data:       validation,
+		Error:      validation.Error(),
+		ErrorCode:  &errorCode,
+	})
+}
@@ -0,0 +1,104 @@
+package helper
+
+import (
+	"colortime-service/pkg/constants"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const ErrVersionConflict = "ERR_VERSION_CONFLICT"
+
+// VersionConflictError is returned when a document changed since the caller
+// read it. CurrentVersion is the version stored now.
+type VersionConflictError struct {
+	CurrentVersion int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("document was modified concurrently, current version is %d", e.CurrentVersion)
+}
+
+type VersionConflictData struct {
+	CurrentVersion int64 `json:"current_version"`
+}
+
+// VersionFilter matches the document only while it is still at version.
+// Documents written before versioning have no version field and count as 0.
+func VersionFilter(id interface{}, version int64) bson.M {
+	if version == 0 {
+		return bson.M{
+			"_id": id,
+			"$or": []bson.M{
+				{"version": 0},
+				{"version": bson.M{"$exists": false}},
+			},
+		}
+	}
+
+	return bson.M{"_id": id, "version": version}
+}
+
+// WithIfMatch stores the version sent in the If-Match header on the context.
+// Requests without the header are returned unchanged.
+func WithIfMatch(ctx context.Context, c *gin.Context) (context.Context, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return ctx, nil
+	}
+
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return ctx, errors.New("invalid If-Match header")
+	}
+
+	return context.WithValue(ctx, constants.IfMatchVersionKey, version), nil
+}
+
+// CheckIfMatch fails with a VersionConflictError when the request carried an
+// If-Match version that differs from currentVersion.
+func CheckIfMatch(ctx context.Context, currentVersion int64) error {
+	expected, ok := ctx.Value(constants.IfMatchVersionKey).(int64)
+	if !ok || expected == currentVersion {
+		return nil
+	}
+
+	return &VersionConflictError{CurrentVersion: currentVersion}
+}
+
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// SendServiceError answers 409 with the current version for version conflicts,
+// 400 with the field errors for validation errors and falls back to SendError
+// with statusCode otherwise.
+func SendServiceError(c *gin.Context, statusCode int, err error) {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		sendValidationError(c, validation)
+		return
+	}
+
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) {
+		SendError(c, statusCode, err, nil)
+		return
+	}
+
+	errorCode := ErrVersionConflict
+	SetETag(c, conflict.CurrentVersion)
+	c.JSON(http.StatusConflict, APIResponse{
+		StatusCode: http.StatusConflict,
+		Data:       VersionConflictData{CurrentVersion: conflict.CurrentVersion},
+		Error:      conflict.Error(),
+		ErrorCode:  &errorCode,
+	})
+}
@@ -0,0 +1,76 @@
+package closure
+
+import (
+	"time"
+)
+
+// Calendar answers which days of a range are closed or make-up days. A nil
+// Calendar has neither.
+type Calendar struct {
+	days map[string]*DayClosure
+}
+
+// NewCalendar indexes closures by day. A make-up day wins over a closure
+// covering the same day, so a break can be reopened for a single day.
+func NewCalendar(closures []*Closure) *Calendar {
+	calendar := &Calendar{days: make(map[string]*DayClosure)}
+
+	for _, kind := range []string{KindClosed, KindMakeUp} {
+		for _, closure := range closures {
+			if closure.Kind != kind {
+				continue
+			}
+
+			day := &DayClosure{
+				ClosureID:     closure.ID,
+				Kind:          closure.Kind,
+				Name:          closure.Name,
+				FollowWeekday: closure.FollowWeekday,
+				UpdatedAt:     closure.UpdatedAt,
+			}
+			for date := closure.StartDate; !date.After(closure.EndDate); date = date.AddDate(0, 0, 1) {
+				calendar.days[date.Format("2006-01-02")] = day
+			}
+		}
+	}
+
+	return calendar
+}
+
+// Day returns the closure in effect on date, nil for a regular day.
+func (c *Calendar) Day(date time.Time) *DayClosure {
+	if c == nil {
+		return nil
+	}
+	return c.days[date.Format("2006-01-02")]
+}
+
+func (c *Calendar) IsClosed(date time.Time) bool {
+	day := c.Day(date)
+	return day != nil && day.Kind == KindClosed
+}
+
+// Weekday returns the weekday whose timetable date follows: its own, or the
+// followed weekday on a make-up day.
+func (c *Calendar) Weekday(date time.Time) time.Weekday {
+	day := c.Day(date)
+	if day != nil && day.Kind == KindMakeUp && day.FollowWeekday != nil {
+		return time.Weekday(*day.FollowWeekday)
+	}
+	return date.Weekday()
+}
+
+// MakeUpReference returns the day a make-up day stands in for: the latest day
+// before date with the followed weekday. ok is false on other days.
+func (c *Calendar) MakeUpReference(date time.Time) (reference time.Time, ok bool) {
+	day := c.Day(date)
+	if day == nil || day.Kind != KindMakeUp || day.FollowWeekday == nil {
+		return time.Time{}, false
+	}
+
+	offset := (int(date.Weekday()) - *day.FollowWeekday + 7) % 7
+	if offset == 0 {
+		offset = 7
+	}
+	return date.AddDate(0, 0, -offset), true
+}
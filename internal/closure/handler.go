@@ -0,0 +1,210 @@
+package closure
+
+import (
+	"colortime-service/helper"
+	"colortime-service/pkg/constants"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClosureHandler struct {
+	ClosureService ClosureService
+}
+
+func NewClosureHandler(closureService ClosureService) *ClosureHandler {
+	return &ClosureHandler{
+		ClosureService: closureService,
+	}
+}
+
+func (h *ClosureHandler) CreateClosure(c *gin.Context) {
+	var req CreateClosureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	closure, err := h.ClosureService.CreateClosure(ctx, &req, userID.(string))
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "closure created successfully", closure)
+}
+
+func (h *ClosureHandler) GetClosures(c *gin.Context) {
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	closures, err := h.ClosureService.GetClosures(ctx, orgID, c.Query("start_date"), c.Query("end_date"))
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "closures retrieved successfully", closures)
+}
+
+func (h *ClosureHandler) GetClosure(c *gin.Context) {
+	closureID := c.Param("id")
+	if closureID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("closure id is required"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	closure, err := h.ClosureService.GetClosure(ctx, closureID)
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SetETag(c, closure.Version)
+	helper.SendSuccess(c, http.StatusOK, "closure retrieved successfully", closure)
+}
+
+func (h *ClosureHandler) UpdateClosure(c *gin.Context) {
+	closureID := c.Param("id")
+	if closureID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("closure id is required"), nil)
+		return
+	}
+
+	var req UpdateClosureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	ctx, err := helper.WithIfMatch(ctx, c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	closure, err := h.ClosureService.UpdateClosure(ctx, closureID, &req)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "closure updated successfully", closure)
+}
+
+func (h *ClosureHandler) DeleteClosure(c *gin.Context) {
+	closureID := c.Param("id")
+	if closureID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("closure id is required"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	ctx, err := helper.WithIfMatch(ctx, c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := h.ClosureService.DeleteClosure(ctx, closureID); err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "closure deleted successfully", nil)
+}
+
+// ImportICal takes the calendar as a multipart "file" field or as the raw
+// request body (text/calendar).
+func (h *ClosureHandler) ImportICal(c *gin.Context) {
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	var body io.Reader = c.Request.Body
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			helper.SendError(c, http.StatusBadRequest, err, nil)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.ClosureService.ImportICal(ctx, orgID, body, userID.(string))
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "calendar imported successfully", result)
+}
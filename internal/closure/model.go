@@ -0,0 +1,57 @@
+package closure
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Closure kinds.
+const (
+	// KindClosed marks days the school is closed: public holidays, breaks and
+	// one-off closures. No colortime is generated for them.
+	KindClosed = "closed"
+	// KindMakeUp marks a day off the school opens on to make up for a closed
+	// day. It follows the timetable of FollowWeekday.
+	KindMakeUp = "make_up"
+)
+
+const (
+	SourceManual = "manual"
+	SourceICal   = "ical"
+)
+
+type Closure struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	Kind           string             `bson:"kind" json:"kind"`
+	Name           string             `bson:"name" json:"name"`
+	StartDate      time.Time          `bson:"start_date" json:"start_date"`                             // first day
+	EndDate        time.Time          `bson:"end_date" json:"end_date"`                                 // last day, inclusive
+	FollowWeekday  *int               `bson:"follow_weekday,omitempty" json:"follow_weekday,omitempty"` // make-up days: 0=Sun,1=Mon,...,6=Sat
+	Source         string             `bson:"source" json:"source"`
+	UID            string             `bson:"uid,omitempty" json:"uid,omitempty"` // iCalendar UID of imported entries
+	CreatedBy      string             `bson:"created_by" json:"created_by"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	Version        int64              `bson:"version" json:"version"`
+}
+
+// DayClosure is the closure in effect on one day, as attached to default days
+// and to the days of synced weeks.
+type DayClosure struct {
+	ClosureID     primitive.ObjectID `bson:"closure_id" json:"closure_id"`
+	Kind          string             `bson:"kind" json:"kind"`
+	Name          string             `bson:"name" json:"name"`
+	FollowWeekday *int               `bson:"follow_weekday,omitempty" json:"follow_weekday,omitempty"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Same reports whether a and b describe the same closure at the same
+// revision. Either may be nil.
+func (a *DayClosure) Same(b *DayClosure) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ClosureID == b.ClosureID && a.UpdatedAt.Equal(b.UpdatedAt)
+}
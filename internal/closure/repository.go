@@ -0,0 +1,122 @@
+package closure
+
+import (
+	"colortime-service/helper"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ClosureRepository interface {
+	CreateClosure(ctx context.Context, closure *Closure) error
+	GetClosureByID(ctx context.Context, id primitive.ObjectID) (*Closure, error)
+	GetClosureByUID(ctx context.Context, organizationID, uid string) (*Closure, error)
+	GetClosuresInRange(ctx context.Context, startDate, endDate time.Time, organizationID string) ([]*Closure, error)
+	GetClosuresByOrganization(ctx context.Context, organizationID string) ([]*Closure, error)
+	UpdateClosure(ctx context.Context, closure *Closure) error
+	DeleteClosure(ctx context.Context, id primitive.ObjectID) error
+}
+
+type closureRepository struct {
+	ClosureCollection *mongo.Collection
+}
+
+func NewClosureRepository(closureCollection *mongo.Collection) ClosureRepository {
+	return &closureRepository{
+		ClosureCollection: closureCollection,
+	}
+}
+
+func (r *closureRepository) CreateClosure(ctx context.Context, closure *Closure) error {
+	_, err := r.ClosureCollection.InsertOne(ctx, closure)
+	return err
+}
+
+func (r *closureRepository) GetClosureByID(ctx context.Context, id primitive.ObjectID) (*Closure, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *closureRepository) GetClosureByUID(ctx context.Context, organizationID, uid string) (*Closure, error) {
+	return r.findOne(ctx, bson.M{"organization_id": organizationID, "uid": uid})
+}
+
+func (r *closureRepository) findOne(ctx context.Context, filter bson.M) (*Closure, error) {
+	var closure Closure
+
+	if err := r.ClosureCollection.FindOne(ctx, filter).Decode(&closure); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &closure, nil
+}
+
+// GetClosuresInRange returns the closures overlapping the days from startDate
+// to endDate.
+func (r *closureRepository) GetClosuresInRange(ctx context.Context, startDate, endDate time.Time, organizationID string) ([]*Closure, error) {
+	return r.find(ctx, bson.M{
+		"organization_id": organizationID,
+		"start_date":      bson.M{"$lte": endDate},
+		"end_date":        bson.M{"$gte": startDate},
+	})
+}
+
+func (r *closureRepository) GetClosuresByOrganization(ctx context.Context, organizationID string) ([]*Closure, error) {
+	return r.find(ctx, bson.M{"organization_id": organizationID})
+}
+
+func (r *closureRepository) find(ctx context.Context, filter bson.M) ([]*Closure, error) {
+	var closures []*Closure
+
+	cursor, err := r.ClosureCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "start_date", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &closures); err != nil {
+		return nil, err
+	}
+
+	return closures, nil
+}
+
+// UpdateClosure only writes when the stored closure is still at the version
+// that was read, and bumps the version on success.
+func (r *closureRepository) UpdateClosure(ctx context.Context, closure *Closure) error {
+	readVersion := closure.Version
+	closure.Version = readVersion + 1
+
+	result, err := r.ClosureCollection.UpdateOne(ctx, helper.VersionFilter(closure.ID, readVersion), bson.M{"$set": closure})
+	if err != nil {
+		closure.Version = readVersion
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		closure.Version = readVersion
+
+		current, err := r.GetClosureByID(ctx, closure.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return errors.New("document not found")
+		}
+		return &helper.VersionConflictError{CurrentVersion: current.Version}
+	}
+
+	return nil
+}
+
+func (r *closureRepository) DeleteClosure(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.ClosureCollection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
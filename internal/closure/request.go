@@ -0,0 +1,17 @@
+package closure
+
+type CreateClosureRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+	Kind           string `json:"kind" binding:"required"` // "closed" or "make_up"
+	Name           string `json:"name" binding:"required"`
+	StartDate      string `json:"start_date" binding:"required"`
+	EndDate        string `json:"end_date"` // defaults to start_date
+	FollowWeekday  *int   `json:"follow_weekday"`
+}
+
+type UpdateClosureRequest struct {
+	Name          string `json:"name"`
+	StartDate     string `json:"start_date"`
+	EndDate       string `json:"end_date"`
+	FollowWeekday *int   `json:"follow_weekday"`
+}
@@ -0,0 +1,16 @@
+package closure
+
+// ImportICalResponse reports what an iCalendar import did. Events already
+// imported before (same UID) are updated in place.
+type ImportICalResponse struct {
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Skipped   []*SkippedICalItem `json:"skipped"`
+}
+
+type SkippedICalItem struct {
+	UID     string `json:"uid"`
+	Summary string `json:"summary"`
+	Reason  string `json:"reason"`
+}
@@ -0,0 +1,19 @@
+package closure
+
+import (
+	"colortime-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, closureHandler *ClosureHandler) {
+	closure := r.Group("api/v1/closure").Use(middleware.Secured())
+	{
+		closure.GET("", closureHandler.GetClosures)
+		closure.POST("", closureHandler.CreateClosure)
+		closure.POST("/import", closureHandler.ImportICal)
+		closure.GET("/:id", closureHandler.GetClosure)
+		closure.PUT("/:id", closureHandler.UpdateClosure)
+		closure.DELETE("/:id", closureHandler.DeleteClosure)
+	}
+}
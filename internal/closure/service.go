@@ -0,0 +1,435 @@
+package closure
+
+import (
+	"colortime-service/helper"
+	"colortime-service/internal/organization_setting"
+	"colortime-service/pkg/ical"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxClosureDays bounds a single closure, which keeps the weeks refreshed on
+// every change to a school year.
+const maxClosureDays = 366
+
+type ClosureService interface {
+	CreateClosure(ctx context.Context, req *CreateClosureRequest, userID string) (*Closure, error)
+	GetClosure(ctx context.Context, id string) (*Closure, error)
+	GetClosures(ctx context.Context, orgID, startDate, endDate string) ([]*Closure, error)
+	UpdateClosure(ctx context.Context, id string, req *UpdateClosureRequest) (*Closure, error)
+	DeleteClosure(ctx context.Context, id string) error
+	ImportICal(ctx context.Context, orgID string, r io.Reader, userID string) (*ImportICalResponse, error)
+	GetCalendar(ctx context.Context, organizationID string, startDate, endDate time.Time) (*Calendar, error)
+}
+
+// ChangeNotifier is told about the days a closure change affects, so the
+// weeks synced from them pick it up. The colortime service implements it.
+type ChangeNotifier interface {
+	NotifyDefaultDayChanged(ctx context.Context, organizationID string, dates []time.Time)
+}
+
+type closureService struct {
+	ClosureRepository          ClosureRepository
+	OrganizationSettingService organization_setting.OrganizationSettingService
+	ChangeNotifier             ChangeNotifier
+}
+
+func NewClosureService(
+	closureRepository ClosureRepository,
+	organizationSettingService organization_setting.OrganizationSettingService,
+	changeNotifier ChangeNotifier,
+) ClosureService {
+	return &closureService{
+		ClosureRepository:          closureRepository,
+		OrganizationSettingService: organizationSettingService,
+		ChangeNotifier:             changeNotifier,
+	}
+}
+
+func (s *closureService) notifyChanged(ctx context.Context, organizationID string, closures ...*Closure) {
+	if s.ChangeNotifier == nil {
+		return
+	}
+
+	seen := make(map[time.Time]bool)
+	var dates []time.Time
+	for _, closure := range closures {
+		for date := closure.StartDate; !date.After(closure.EndDate); date = date.AddDate(0, 0, 1) {
+			if !seen[date] {
+				seen[date] = true
+				dates = append(dates, date)
+			}
+		}
+	}
+
+	s.ChangeNotifier.NotifyDefaultDayChanged(ctx, organizationID, dates)
+}
+
+// validateClosure checks the kind specific fields and the date range.
+func validateClosure(closure *Closure) error {
+	switch closure.Kind {
+	case KindClosed:
+		if closure.FollowWeekday != nil {
+			return errors.New("follow_weekday is only allowed on make-up days")
+		}
+	case KindMakeUp:
+		if closure.FollowWeekday == nil || *closure.FollowWeekday < 0 || *closure.FollowWeekday > 6 {
+			return errors.New("make-up days need a follow_weekday between 0 (Sunday) and 6 (Saturday)")
+		}
+	default:
+		return fmt.Errorf("invalid kind %s (use %s or %s)", closure.Kind, KindClosed, KindMakeUp)
+	}
+
+	if closure.EndDate.Before(closure.StartDate) {
+		return errors.New("end date must not be before start date")
+	}
+
+	if closure.EndDate.Sub(closure.StartDate) >= maxClosureDays*24*time.Hour {
+		return fmt.Errorf("a closure cannot span more than %d days", maxClosureDays)
+	}
+
+	return nil
+}
+
+func (s *closureService) CreateClosure(ctx context.Context, req *CreateClosureRequest, userID string) (*Closure, error) {
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, req.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, err := calendar.ParseDate(req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %w", err)
+	}
+
+	endDate := startDate
+	if req.EndDate != "" {
+		endDate, err = calendar.ParseDate(req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end date: %w", err)
+		}
+	}
+
+	closure := &Closure{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: req.OrganizationID,
+		Kind:           req.Kind,
+		Name:           req.Name,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		FollowWeekday:  req.FollowWeekday,
+		Source:         SourceManual,
+		CreatedBy:      userID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := validateClosure(closure); err != nil {
+		return nil, err
+	}
+
+	if err := s.ClosureRepository.CreateClosure(ctx, closure); err != nil {
+		return nil, fmt.Errorf("failed to create closure: %w", err)
+	}
+
+	s.notifyChanged(ctx, closure.OrganizationID, closure)
+
+	return closure, nil
+}
+
+func (s *closureService) GetClosure(ctx context.Context, id string) (*Closure, error) {
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid closure id format")
+	}
+
+	closure, err := s.ClosureRepository.GetClosureByID(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get closure: %w", err)
+	}
+
+	if closure == nil {
+		return nil, errors.New("closure not found")
+	}
+
+	return closure, nil
+}
+
+// GetClosures lists the closures of the organization, only those overlapping
+// startDate to endDate when both are given.
+func (s *closureService) GetClosures(ctx context.Context, orgID, startDate, endDate string) ([]*Closure, error) {
+
+	if orgID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	var closures []*Closure
+	if startDate == "" && endDate == "" {
+		all, err := s.ClosureRepository.GetClosuresByOrganization(ctx, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get closures: %w", err)
+		}
+		closures = all
+	} else {
+		if startDate == "" || endDate == "" {
+			return nil, errors.New("start and end date are required together")
+		}
+
+		calendar, err := s.OrganizationSettingService.GetCalendar(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+
+		start, err := calendar.ParseDate(startDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start date: %w", err)
+		}
+
+		end, err := calendar.ParseDate(endDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end date: %w", err)
+		}
+
+		inRange, err := s.ClosureRepository.GetClosuresInRange(ctx, start, end, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get closures: %w", err)
+		}
+		closures = inRange
+	}
+
+	if closures == nil {
+		closures = make([]*Closure, 0)
+	}
+
+	return closures, nil
+}
+
+func (s *closureService) UpdateClosure(ctx context.Context, id string, req *UpdateClosureRequest) (*Closure, error) {
+
+	closure, err := s.GetClosure(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := helper.CheckIfMatch(ctx, closure.Version); err != nil {
+		return nil, err
+	}
+
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, closure.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *closure
+
+	if req.Name != "" {
+		closure.Name = req.Name
+	}
+
+	if req.StartDate != "" {
+		closure.StartDate, err = calendar.ParseDate(req.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start date: %w", err)
+		}
+	}
+
+	if req.EndDate != "" {
+		closure.EndDate, err = calendar.ParseDate(req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end date: %w", err)
+		}
+	}
+
+	if req.FollowWeekday != nil {
+		closure.FollowWeekday = req.FollowWeekday
+	}
+
+	if err := validateClosure(closure); err != nil {
+		return nil, err
+	}
+
+	closure.UpdatedAt = time.Now()
+	if err := s.ClosureRepository.UpdateClosure(ctx, closure); err != nil {
+		return nil, err
+	}
+
+	s.notifyChanged(ctx, closure.OrganizationID, &previous, closure)
+
+	return closure, nil
+}
+
+func (s *closureService) DeleteClosure(ctx context.Context, id string) error {
+
+	closure, err := s.GetClosure(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := helper.CheckIfMatch(ctx, closure.Version); err != nil {
+		return err
+	}
+
+	if err := s.ClosureRepository.DeleteClosure(ctx, closure.ID); err != nil {
+		return fmt.Errorf("failed to delete closure: %w", err)
+	}
+
+	s.notifyChanged(ctx, closure.OrganizationID, closure)
+
+	return nil
+}
+
+// ImportICal adds the events of an iCalendar file as closed days. Events are
+// matched on their UID, so importing a newer version of the same calendar
+// updates the closures it created before instead of duplicating them.
+// Recurring events are skipped; holiday calendars list each year's date.
+func (s *closureService) ImportICal(ctx context.Context, orgID string, r io.Reader, userID string) (*ImportICalResponse, error) {
+
+	if orgID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := ical.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid iCalendar file: %w", err)
+	}
+
+	result := &ImportICalResponse{Skipped: []*SkippedICalItem{}}
+	var changed []*Closure
+
+	for _, event := range events {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, &SkippedICalItem{UID: event.UID, Summary: event.Summary, Reason: reason})
+		}
+
+		if event.RRule != "" {
+			skip("recurring events are not supported")
+			continue
+		}
+
+		startDate, endDate := eventDays(event, calendar)
+
+		uid := event.UID
+		if uid == "" {
+			uid = fmt.Sprintf("%s@%s", startDate.Format("20060102"), event.Summary)
+		}
+
+		name := event.Summary
+		if name == "" {
+			name = "Holiday"
+		}
+
+		existing, err := s.ClosureRepository.GetClosureByUID(ctx, orgID, uid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get closure: %w", err)
+		}
+
+		if existing == nil {
+			closure := &Closure{
+				ID:             primitive.NewObjectID(),
+				OrganizationID: orgID,
+				Kind:           KindClosed,
+				Name:           name,
+				StartDate:      startDate,
+				EndDate:        endDate,
+				Source:         SourceICal,
+				UID:            uid,
+				CreatedBy:      userID,
+				CreatedAt:      time.Now(),
+				UpdatedAt:      time.Now(),
+			}
+			if err := validateClosure(closure); err != nil {
+				skip(err.Error())
+				continue
+			}
+			if err := s.ClosureRepository.CreateClosure(ctx, closure); err != nil {
+				return nil, fmt.Errorf("failed to create closure: %w", err)
+			}
+			result.Created++
+			changed = append(changed, closure)
+			continue
+		}
+
+		if existing.Name == name && existing.StartDate.Equal(startDate) && existing.EndDate.Equal(endDate) {
+			result.Unchanged++
+			continue
+		}
+
+		previous := *existing
+		existing.Name = name
+		existing.StartDate = startDate
+		existing.EndDate = endDate
+		existing.UpdatedAt = time.Now()
+		if err := validateClosure(existing); err != nil {
+			skip(err.Error())
+			continue
+		}
+		if err := s.ClosureRepository.UpdateClosure(ctx, existing); err != nil {
+			return nil, err
+		}
+		result.Updated++
+		changed = append(changed, &previous, existing)
+	}
+
+	if len(changed) > 0 {
+		s.notifyChanged(ctx, orgID, changed...)
+	}
+
+	return result, nil
+}
+
+// eventDays returns the first and last day an event covers. DTEND is
+// exclusive: an all-day event ending on the 3rd covers up to the 2nd.
+func eventDays(event *ical.Event, calendar *organization_setting.Calendar) (time.Time, time.Time) {
+	day := func(t time.Time) time.Time {
+		local := t.In(calendar.Location)
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	if event.AllDay {
+		startDate := event.Start
+		endDate := startDate
+		if event.End != nil && event.End.After(startDate) {
+			endDate = event.End.AddDate(0, 0, -1)
+		}
+		return startDate, endDate
+	}
+
+	startDate := day(event.Start)
+	endDate := startDate
+	if event.End != nil && event.End.After(event.Start) {
+		endDate = day(event.End.Add(-time.Nanosecond))
+	}
+	return startDate, endDate
+}
+
+// GetCalendar returns the closures of the organization between startDate and
+// endDate indexed by day.
+func (s *closureService) GetCalendar(ctx context.Context, organizationID string, startDate, endDate time.Time) (*Calendar, error) {
+
+	closures, err := s.ClosureRepository.GetClosuresInRange(ctx, startDate, endDate, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get closures: %w", err)
+	}
+
+	return NewCalendar(closures), nil
+}
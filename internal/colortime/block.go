@@ -0,0 +1,303 @@
+package colortime
+
+import (
+	"colortime-service/helper"
+	"colortime-service/pkg/slotblock"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateColorBlock adds an empty block at the end of a day of the week.
+func (s *colorTimeService) CreateColorBlock(ctx context.Context, weekColorTimeID string, req *CreateColorBlockRequest, userID string) (*ColorBlock, error) {
+
+	week, err := s.weekForBlockEdit(ctx, weekColorTimeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, week.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := calendar.ParseDate(req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %w", err)
+	}
+
+	if date.Before(week.StartDate) || date.After(week.EndDate) {
+		return nil, errors.New("date is outside the week")
+	}
+
+	var day *ColorTime
+	for _, colorTime := range week.ColorTimes {
+		if calendar.SameDay(colorTime.Date, date) {
+			day = colorTime
+			break
+		}
+	}
+
+	if day == nil {
+		day = &ColorTime{
+			ID:        primitive.NewObjectID(),
+			Date:      date,
+			TimeSlots: []*ColorBlock{},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		week.ColorTimes = append(week.ColorTimes, day)
+		sort.SliceStable(week.ColorTimes, func(i, j int) bool { return week.ColorTimes[i].Date.Before(week.ColorTimes[j].Date) })
+	}
+
+	block := &ColorBlock{
+		BlockID: primitive.NewObjectID(),
+		Slots:   []*ColortimeSlot{},
+	}
+	day.TimeSlots = append(day.TimeSlots, block)
+	day.UpdatedAt = time.Now()
+
+	if err := s.saveColorBlocks(ctx, week, nil); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// MoveColorBlock shifts every slot of the block so the block starts at
+// req.StartTime, keeping their durations and the gaps between them. The moved
+// slots keep their new time when the week is synced with its defaults again.
+func (s *colorTimeService) MoveColorBlock(ctx context.Context, weekColorTimeID, blockID string, req *MoveBlockRequest, userID string) error {
+
+	week, err := s.weekForBlockEdit(ctx, weekColorTimeID, userID)
+	if err != nil {
+		return err
+	}
+
+	day, block, _, err := findColorBlock(week, blockID)
+	if err != nil {
+		return err
+	}
+
+	if err := slotblock.Move(block.Slots, req.StartTime); err != nil {
+		return err
+	}
+
+	if err := checkColorBlockSlots(day, block.Slots); err != nil {
+		return err
+	}
+
+	return s.saveColorBlocks(ctx, week, block.Slots)
+}
+
+// ReorderColorBlockSlots puts the slots of the block in the order of
+// req.SlotIDs, laid out as slotblock.Reorder does. Like a moved block, the
+// reordered slots keep their time through later syncs.
+func (s *colorTimeService) ReorderColorBlockSlots(ctx context.Context, weekColorTimeID, blockID string, req *ReorderBlockSlotsRequest, userID string) error {
+
+	week, err := s.weekForBlockEdit(ctx, weekColorTimeID, userID)
+	if err != nil {
+		return err
+	}
+
+	day, block, _, err := findColorBlock(week, blockID)
+	if err != nil {
+		return err
+	}
+
+	ordered, err := slotblock.Reorder(block.Slots, req.SlotIDs)
+	if err != nil {
+		return err
+	}
+	block.Slots = ordered
+
+	if err := checkColorBlockSlots(day, block.Slots); err != nil {
+		return err
+	}
+
+	return s.saveColorBlocks(ctx, week, block.Slots)
+}
+
+// SplitColorBlock moves req.SlotID and the slots after it into a new block
+// right after the block and returns the new block.
+func (s *colorTimeService) SplitColorBlock(ctx context.Context, weekColorTimeID, blockID string, req *SplitBlockRequest, userID string) (*ColorBlock, error) {
+
+	week, err := s.weekForBlockEdit(ctx, weekColorTimeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	day, block, index, err := findColorBlock(week, blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	at, err := slotblock.SplitIndex(block.Slots, req.SlotID)
+	if err != nil {
+		return nil, err
+	}
+
+	newBlock := &ColorBlock{
+		BlockID: primitive.NewObjectID(),
+		Slots:   append([]*ColortimeSlot(nil), block.Slots[at:]...),
+	}
+	block.Slots = block.Slots[:at]
+
+	for _, slot := range newBlock.Slots {
+		slot.UpdatedAt = time.Now()
+	}
+	slotblock.Renumber(newBlock.Slots)
+
+	day.TimeSlots = append(day.TimeSlots[:index+1], append([]*ColorBlock{newBlock}, day.TimeSlots[index+1:]...)...)
+	day.UpdatedAt = time.Now()
+
+	if err := s.saveColorBlocks(ctx, week, nil); err != nil {
+		return nil, err
+	}
+
+	return newBlock, nil
+}
+
+// MergeColorBlocks moves the slots of req.BlockID into the block and removes
+// req.BlockID. Both blocks must be on the same day.
+func (s *colorTimeService) MergeColorBlocks(ctx context.Context, weekColorTimeID, blockID string, req *MergeBlocksRequest, userID string) error {
+
+	week, err := s.weekForBlockEdit(ctx, weekColorTimeID, userID)
+	if err != nil {
+		return err
+	}
+
+	day, block, _, err := findColorBlock(week, blockID)
+	if err != nil {
+		return err
+	}
+
+	otherDay, other, otherIndex, err := findColorBlock(week, req.BlockID)
+	if err != nil {
+		return err
+	}
+
+	if other == block {
+		return errors.New("cannot merge a block into itself")
+	}
+
+	if otherDay != day {
+		return errors.New("blocks are on different days")
+	}
+
+	for _, slot := range other.Slots {
+		slot.UpdatedAt = time.Now()
+	}
+
+	block.Slots = append(block.Slots, other.Slots...)
+	day.TimeSlots = append(day.TimeSlots[:otherIndex], day.TimeSlots[otherIndex+1:]...)
+
+	if err := checkColorBlockSlots(day, block.Slots); err != nil {
+		return err
+	}
+
+	slotblock.Renumber(block.Slots)
+	day.UpdatedAt = time.Now()
+
+	return s.saveColorBlocks(ctx, week, block.Slots)
+}
+
+func (s *colorTimeService) weekForBlockEdit(ctx context.Context, weekColorTimeID, userID string) (*WeekColorTime, error) {
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	weekObjectID, err := primitive.ObjectIDFromHex(weekColorTimeID)
+	if err != nil {
+		return nil, errors.New("invalid week colortime ID format")
+	}
+
+	week, err := s.ColorTimeRepository.GetColorTimeWeekByID(ctx, weekObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get week colortime: %w", err)
+	}
+
+	if week == nil {
+		return nil, errors.New("week colortime not found")
+	}
+
+	// Member weeks only hold overrides of the group week's slots
+	if week.GroupID != nil {
+		return nil, errors.New("blocks of a group member's week are edited on the group week")
+	}
+
+	if err := helper.CheckIfMatch(ctx, week.Version); err != nil {
+		return nil, err
+	}
+
+	return week, nil
+}
+
+// saveColorBlocks writes the week and renumbers the tracking series of the
+// moved slots, whose schedule order may have changed.
+func (s *colorTimeService) saveColorBlocks(ctx context.Context, week *WeekColorTime, moved []*ColortimeSlot) error {
+	week.UpdatedAt = time.Now()
+
+	if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week); err != nil {
+		return fmt.Errorf("failed to update week colortime: %w", err)
+	}
+
+	renumbered := make(map[string]bool)
+	for _, slot := range moved {
+		if slot.Tracking == "" || renumbered[slot.Tracking] {
+			continue
+		}
+		renumbered[slot.Tracking] = true
+
+		if err := s.normalizeTrackingGlobal(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, slot.Tracking); err != nil {
+			return fmt.Errorf("failed to normalize tracking global: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func findColorBlock(week *WeekColorTime, blockID string) (*ColorTime, *ColorBlock, int, error) {
+
+	blockObjectID, err := primitive.ObjectIDFromHex(blockID)
+	if err != nil {
+		return nil, nil, 0, errors.New("invalid block ID format")
+	}
+
+	for _, day := range week.ColorTimes {
+		for i, block := range day.TimeSlots {
+			if block.BlockID == blockObjectID {
+				return day, block, i, nil
+			}
+		}
+	}
+
+	return nil, nil, 0, errors.New("block not found")
+}
+
+// checkColorBlockSlots rejects changed slots that leave their day or overlap
+// any other slot of the day.
+func checkColorBlockSlots(day *ColorTime, changed []*ColortimeSlot) error {
+
+	var allSlots []*ColortimeSlot
+	for _, block := range day.TimeSlots {
+		allSlots = append(allSlots, block.Slots...)
+	}
+
+	for _, slot := range changed {
+		if !slotblock.FitsInDay(slot.StartTime, slot.EndTime) {
+			return fmt.Errorf("slot %q would not fit in the day", slot.Title)
+		}
+
+		if other, overlaps := slotblock.Overlapping(slot, allSlots); overlaps {
+			return fmt.Errorf("slot %q conflicts with slot %q", slot.Title, other.Title)
+		}
+	}
+
+	return nil
+}
@@ -50,13 +50,19 @@ func (h *ColorTimeHandler) AddTopicToColorTimeWeek(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.ColorTimeService.AddTopicToColorTimeWeek(ctx, id, &req, userID.(string))
-
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
 		helper.SendError(c, http.StatusBadRequest, err, nil)
 		return
 	}
 
+	err = h.ColorTimeService.AddTopicToColorTimeWeek(ctx, id, &req, userID.(string))
+
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "topic added to color time week successfully", nil)
 
 }
@@ -93,6 +99,8 @@ func (h *ColorTimeHandler) GetToColorTimeWeek(c *gin.Context) {
 		return
 	}
 
+	groupID := c.Query("group_id")
+
 	languageIDStr := c.Query("language_id")
 	var languageID *int
 	if languageIDStr != "" {
@@ -109,13 +117,14 @@ func (h *ColorTimeHandler) GetToColorTimeWeek(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	data, err := h.ColorTimeService.GetColorTimeWeek(ctx, userID, role, orgID, start, end, languageID)
+	data, err := h.ColorTimeService.GetColorTimeWeek(ctx, userID, role, orgID, start, end, groupID, languageID)
 
 	if err != nil {
 		helper.SendError(c, http.StatusBadRequest, err, nil)
 		return
 	}
 
+	helper.SetETag(c, data.Version)
 	helper.SendSuccess(c, http.StatusOK, "topic to color time week fetched successfully", data)
 
 }
@@ -136,13 +145,19 @@ func (h *ColorTimeHandler) DeleteTopicToColorTimeWeek(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.ColorTimeService.DeleteTopicToColorTimeWeek(ctx, id)
-
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
 		helper.SendError(c, http.StatusBadRequest, err, nil)
 		return
 	}
 
+	err = h.ColorTimeService.DeleteTopicToColorTimeWeek(ctx, id)
+
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "topic deleted from color time week successfully", nil)
 
 }
@@ -169,13 +184,19 @@ func (h *ColorTimeHandler) AddTopicToColorTimeDay(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.ColorTimeService.AddTopicToColorTimeDay(ctx, id, &req)
-
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
 		helper.SendError(c, http.StatusBadRequest, err, nil)
 		return
 	}
 
+	err = h.ColorTimeService.AddTopicToColorTimeDay(ctx, id, &req)
+
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "topic added to color time day successfully", nil)
 
 }
@@ -202,13 +223,19 @@ func (h *ColorTimeHandler) DeleteTopicToColorTimeDay(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.ColorTimeService.DeleteTopicToColorTimeDay(ctx, id, &req)
-
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
 		helper.SendError(c, http.StatusBadRequest, err, nil)
 		return
 	}
 
+	err = h.ColorTimeService.DeleteTopicToColorTimeDay(ctx, id, &req)
+
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "topic deleted from color time day successfully", nil)
 
 }
@@ -242,16 +269,66 @@ func (h *ColorTimeHandler) UpdateColorSlotHandler(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.ColorTimeService.UpdateColorSlot(ctx, weekColorTimeID, slotID, &req, userID.(string))
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
 		helper.SendError(c, http.StatusBadRequest, err, nil)
 		return
 	}
 
+	err = h.ColorTimeService.UpdateColorSlot(ctx, weekColorTimeID, slotID, &req, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "color slot updated successfully", nil)
 
 }
 
+func (h *ColorTimeHandler) ResolveOrphanedSlot(c *gin.Context) {
+	weekColorTimeID := c.Param("week_colortime_id")
+	slotID := c.Param("slot_id")
+
+	var req ResolveOrphanedSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	if userID == "" {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	ctx, err := helper.WithIfMatch(ctx, c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	err = h.ColorTimeService.ResolveOrphanedSlot(ctx, weekColorTimeID, slotID, &req, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "orphaned slot resolved successfully", nil)
+}
+
 func (h *ColorTimeHandler) GetColorTimeDay(c *gin.Context) {
 	orgID := c.Query("org_id")
 	if orgID == "" {
@@ -277,6 +354,8 @@ func (h *ColorTimeHandler) GetColorTimeDay(c *gin.Context) {
 		return
 	}
 
+	groupID := c.Query("group_id")
+
 	token, exists := c.Get(constants.Token)
 	if !exists {
 		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
@@ -285,12 +364,17 @@ func (h *ColorTimeHandler) GetColorTimeDay(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	data, err := h.ColorTimeService.GetColorTimeDay(ctx, orgID, date, userID, role)
+	data, err := h.ColorTimeService.GetColorTimeDay(ctx, orgID, date, userID, role, groupID)
 	if err != nil {
 		helper.SendError(c, http.StatusInternalServerError, err, nil)
 		return
 	}
 
+	if data == nil {
+		helper.SendError(c, http.StatusNotFound, errors.New("color time day not found"), nil)
+		return
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "color time day retrieved successfully", data)
 }
 
@@ -329,3 +413,269 @@ func (h *ColorTimeHandler) GetTopicByTerm(c *gin.Context) {
 
 	helper.SendSuccess(c, http.StatusOK, "topic by term retrieved successfully", data)
 }
+
+func (h *ColorTimeHandler) SyncColorTimeWeek(c *gin.Context) {
+
+	var req SyncColorTimeWeekRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	data, err := h.ColorTimeService.SyncColorTimeWeek(ctx, &req)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "color time week synced successfully", data)
+}
+
+func (h *ColorTimeHandler) GetSyncJobs(c *gin.Context) {
+
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	data, err := h.ColorTimeService.GetSyncJobs(c, orgID)
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get sync jobs successfully", data)
+}
+
+func (h *ColorTimeHandler) GetSyncJob(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	data, err := h.ColorTimeService.GetSyncJob(c, id)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if data == nil {
+		helper.SendError(c, http.StatusNotFound, errors.New("sync job not found"), nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get sync job successfully", data)
+}
+
+func (h *ColorTimeHandler) GetTrackingSeries(c *gin.Context) {
+
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	userID := c.Query("user_id")
+	if userID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("user_id is required"), nil)
+		return
+	}
+
+	role := c.Query("role")
+	if role == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("role is required"), nil)
+		return
+	}
+
+	data, err := h.ColorTimeService.GetTrackingSeries(c, orgID, userID, role)
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get tracking series successfully", data)
+}
+
+func (h *ColorTimeHandler) RenameTracking(c *gin.Context) {
+
+	var req RenameTrackingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := h.ColorTimeService.RenameTracking(c, &req); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "tracking renamed successfully", nil)
+}
+
+func (h *ColorTimeHandler) MergeTracking(c *gin.Context) {
+
+	var req MergeTrackingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := h.ColorTimeService.MergeTracking(c, &req); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "tracking merged successfully", nil)
+}
+
+func (h *ColorTimeHandler) RenumberTracking(c *gin.Context) {
+
+	var req RenumberTrackingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := h.ColorTimeService.RenumberTracking(c, &req); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "tracking renumbered successfully", nil)
+}
+
+func blockEditContext(c *gin.Context) (context.Context, string, bool) {
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists || userID == "" {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return nil, "", false
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return nil, "", false
+	}
+
+	ctx, err := helper.WithIfMatch(context.WithValue(c, constants.TokenKey, token), c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return nil, "", false
+	}
+
+	return ctx, userID.(string), true
+}
+
+func (h *ColorTimeHandler) CreateColorBlock(c *gin.Context) {
+	var req CreateColorBlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	block, err := h.ColorTimeService.CreateColorBlock(ctx, c.Param("week_colortime_id"), &req, userID)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "color block created successfully", block)
+}
+
+func (h *ColorTimeHandler) MoveColorBlock(c *gin.Context) {
+	var req MoveBlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.ColorTimeService.MoveColorBlock(ctx, c.Param("week_colortime_id"), c.Param("block_id"), &req, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "color block moved successfully", nil)
+}
+
+func (h *ColorTimeHandler) ReorderColorBlockSlots(c *gin.Context) {
+	var req ReorderBlockSlotsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.ColorTimeService.ReorderColorBlockSlots(ctx, c.Param("week_colortime_id"), c.Param("block_id"), &req, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "color block slots reordered successfully", nil)
+}
+
+func (h *ColorTimeHandler) SplitColorBlock(c *gin.Context) {
+	var req SplitBlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	block, err := h.ColorTimeService.SplitColorBlock(ctx, c.Param("week_colortime_id"), c.Param("block_id"), &req, userID)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "color block split successfully", block)
+}
+
+func (h *ColorTimeHandler) MergeColorBlocks(c *gin.Context) {
+	var req MergeBlocksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.ColorTimeService.MergeColorBlocks(ctx, c.Param("week_colortime_id"), c.Param("block_id"), &req, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "color blocks merged successfully", nil)
+}
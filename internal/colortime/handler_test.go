@@ -0,0 +1,509 @@
+package colortime
+
+import (
+	"bytes"
+	"colortime-service/helper"
+	"colortime-service/internal/default_colortime"
+	"colortime-service/internal/organization_setting"
+	"colortime-service/internal/user"
+	"colortime-service/pkg/constants"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeColorTimeRepository keeps weeks in memory. Methods the tests do not
+// reach are left to the embedded nil interface and panic when called.
+type fakeColorTimeRepository struct {
+	ColorTimeRepository
+
+	mu    sync.Mutex
+	weeks map[primitive.ObjectID]*WeekColorTime
+
+	// afterRead runs once after the next read by id, standing in for a
+	// concurrent request writing between this request's read and write
+	afterRead func(week *WeekColorTime)
+}
+
+func newFakeColorTimeRepository(weeks ...*WeekColorTime) *fakeColorTimeRepository {
+	r := &fakeColorTimeRepository{weeks: make(map[primitive.ObjectID]*WeekColorTime)}
+	for _, week := range weeks {
+		r.weeks[week.ID] = copyWeek(week)
+	}
+	return r
+}
+
+// copyWeek round-trips the week through BSON, like a read from the database.
+func copyWeek(week *WeekColorTime) *WeekColorTime {
+	raw, err := bson.Marshal(week)
+	if err != nil {
+		panic(err)
+	}
+
+	var copied WeekColorTime
+	if err := bson.Unmarshal(raw, &copied); err != nil {
+		panic(err)
+	}
+	return &copied
+}
+
+func (r *fakeColorTimeRepository) CreateColorTimeWeek(ctx context.Context, week *WeekColorTime) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.weeks[week.ID] = copyWeek(week)
+	return nil
+}
+
+func (r *fakeColorTimeRepository) GetColorTimeWeek(ctx context.Context, startDate, endDate *time.Time, organizationID, userID, role string) (*WeekColorTime, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, week := range r.weeks {
+		if week.OrganizationID == organizationID && week.Owner.OwnerID == userID && week.Owner.OwnerRole == role &&
+			!week.StartDate.After(*endDate) && !week.EndDate.Before(*startDate) {
+			return copyWeek(week), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeColorTimeRepository) GetColorTimeWeeksInRange(ctx context.Context, startDate, endDate *time.Time, organizationID, userID, role string) ([]*WeekColorTime, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var weeks []*WeekColorTime
+	for _, week := range r.weeks {
+		if week.OrganizationID == organizationID && week.Owner.OwnerID == userID && week.Owner.OwnerRole == role &&
+			!week.StartDate.After(*endDate) && !week.EndDate.Before(*startDate) {
+			weeks = append(weeks, copyWeek(week))
+		}
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].StartDate.Before(weeks[j].StartDate) })
+	return weeks, nil
+}
+
+func (r *fakeColorTimeRepository) GetColorTimeWeekByID(ctx context.Context, id primitive.ObjectID) (*WeekColorTime, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	week, exists := r.weeks[id]
+	if !exists {
+		return nil, nil
+	}
+
+	copied := copyWeek(week)
+	if r.afterRead != nil {
+		r.afterRead(week)
+		r.afterRead = nil
+	}
+	return copied, nil
+}
+
+func (r *fakeColorTimeRepository) UpdateColorTimeWeek(ctx context.Context, id primitive.ObjectID, week *WeekColorTime) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.weeks[id]
+	if !exists {
+		return mongo.ErrNoDocuments
+	}
+	if stored.Version != week.Version {
+		return &helper.VersionConflictError{CurrentVersion: stored.Version}
+	}
+
+	week.Version++
+	r.weeks[id] = copyWeek(week)
+	return nil
+}
+
+func (r *fakeColorTimeRepository) UpdateSlotFields(ctx context.Context, weekID, slotID primitive.ObjectID, version int64, fields bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	week, exists := r.weeks[weekID]
+	if !exists {
+		return mongo.ErrNoDocuments
+	}
+	if version != helper.AnyVersion && week.Version != version {
+		return &helper.VersionConflictError{CurrentVersion: week.Version}
+	}
+
+	for _, day := range week.ColorTimes {
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.SlotID != slotID {
+					continue
+				}
+				raw, err := bson.Marshal(fields)
+				if err != nil {
+					return err
+				}
+				if err := bson.Unmarshal(raw, slot); err != nil {
+					return err
+				}
+				week.Version++
+				return nil
+			}
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (r *fakeColorTimeRepository) SetWeekTopic(ctx context.Context, weekID primitive.ObjectID, version int64, topicID *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	week, exists := r.weeks[weekID]
+	if !exists {
+		return mongo.ErrNoDocuments
+	}
+	if version != helper.AnyVersion && week.Version != version {
+		return &helper.VersionConflictError{CurrentVersion: week.Version}
+	}
+
+	week.TopicID = topicID
+	week.Version++
+	return nil
+}
+
+func (r *fakeColorTimeRepository) CountTrackingUsage(ctx context.Context, organizationID, userID, role, tracking string) (int, error) {
+	return 0, nil
+}
+
+// GetTrackingOccurrences lists the slots in storage order, leaving the
+// schedule order to the caller as the database does for member overrides.
+func (r *fakeColorTimeRepository) GetTrackingOccurrences(ctx context.Context, organizationID, userID, role, tracking string) ([]*TrackingOccurrence, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var occurrences []*TrackingOccurrence
+	for _, week := range r.weeks {
+		if week.OrganizationID != organizationID || week.Owner.OwnerID != userID || week.Owner.OwnerRole != role {
+			continue
+		}
+		for _, day := range week.ColorTimes {
+			for _, block := range day.TimeSlots {
+				for _, slot := range block.Slots {
+					if slot.Tracking != tracking {
+						continue
+					}
+					occurrences = append(occurrences, &TrackingOccurrence{
+						WeekID:      week.ID,
+						SlotID:      slot.SlotID,
+						Tracking:    slot.Tracking,
+						UseCount:    slot.UseCount,
+						Title:       slot.Title,
+						Date:        day.Date,
+						GroupSlotID: slot.GroupSlotID,
+						StartTime:   slot.StartTime,
+						CreatedAt:   slot.CreatedAt,
+					})
+				}
+			}
+		}
+	}
+	return occurrences, nil
+}
+
+type fakeDefaultColorTimeRepository struct {
+	default_colortime.DefaultColorTimeRepository
+}
+
+func (fakeDefaultColorTimeRepository) GetDefaultDayColorTimesInRange(ctx context.Context, startDate, endDate time.Time, organizationID string) ([]*default_colortime.DefaultDayColorTime, error) {
+	return nil, nil
+}
+
+type fakeUserService struct {
+	user.UserService
+	group *user.GroupInfor
+}
+
+func (f fakeUserService) GetGroupInfor(ctx context.Context, groupID string) (*user.GroupInfor, error) {
+	return f.group, nil
+}
+
+func (f fakeUserService) GetStudentInfor(ctx context.Context, studentID string) (*user.UserInfor, error) {
+	return &user.UserInfor{UserID: studentID}, nil
+}
+
+type fakeOrganizationSettingService struct {
+	organization_setting.OrganizationSettingService
+}
+
+func (fakeOrganizationSettingService) GetCalendar(ctx context.Context, organizationID string) (*organization_setting.Calendar, error) {
+	return organization_setting.DefaultCalendar(), nil
+}
+
+func newTestRouter(service ColorTimeService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewColorTimeHandler(service)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(constants.UserID, "teacher-1")
+		c.Set(constants.Token, "token")
+	})
+	router.GET("/week", handler.GetToColorTimeWeek)
+	router.PUT("/week/:week_colortime_id/slot/:slot_id", handler.UpdateColorSlotHandler)
+	router.POST("/add-topic/week/:id", handler.AddTopicToColorTimeWeek)
+	return router
+}
+
+func TestMemberWeekGetThenPut(t *testing.T) {
+	const orgID, groupID, memberID = "org-1", "group-1", "student-1"
+
+	startDate := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 0, 6)
+	groupSlotID := primitive.NewObjectID()
+	otherGroupSlotID := primitive.NewObjectID()
+
+	groupWeek := &WeekColorTime{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: orgID,
+		Owner:          &Owner{OwnerID: groupID, OwnerRole: OwnerRoleGroup},
+		StartDate:      startDate,
+		EndDate:        endDate,
+		ColorTimes: []*ColorTime{{
+			ID:   primitive.NewObjectID(),
+			Date: startDate,
+			TimeSlots: []*ColorBlock{{
+				BlockID: primitive.NewObjectID(),
+				Slots: []*ColortimeSlot{
+					{SlotID: groupSlotID, Title: "Circle time", StartTime: startDate.Add(8 * time.Hour), EndTime: startDate.Add(9 * time.Hour)},
+					{SlotID: otherGroupSlotID, Title: "Outdoor play", StartTime: startDate.Add(9 * time.Hour), EndTime: startDate.Add(10 * time.Hour)},
+				},
+			}},
+		}},
+		SyncedAt: time.Now(),
+		Version:  4,
+	}
+
+	// The member already overrode one slot, so its week is past version 0
+	memberWeek := &WeekColorTime{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: orgID,
+		Owner:          &Owner{OwnerID: memberID, OwnerRole: "student"},
+		GroupID:        &[]string{groupID}[0],
+		StartDate:      startDate,
+		EndDate:        endDate,
+		ColorTimes: []*ColorTime{{
+			ID:   primitive.NewObjectID(),
+			Date: startDate,
+			TimeSlots: []*ColorBlock{{
+				BlockID: groupWeek.ColorTimes[0].TimeSlots[0].BlockID,
+				Slots: []*ColortimeSlot{
+					{SlotID: primitive.NewObjectID(), GroupSlotID: &otherGroupSlotID, Tracking: "T0", UseCount: 1},
+				},
+			}},
+		}},
+		Version: 1,
+	}
+
+	service := NewColorTimeService(
+		newFakeColorTimeRepository(groupWeek, memberWeek),
+		fakeDefaultColorTimeRepository{},
+		nil, nil, nil,
+		fakeUserService{group: &user.GroupInfor{GroupID: groupID, Members: []*user.UserInfor{{UserID: memberID}}}},
+		nil,
+		fakeOrganizationSettingService{},
+		nil,
+	)
+	router := newTestRouter(service)
+
+	query := url.Values{
+		"user_id":  {memberID},
+		"role":     {"student"},
+		"org_id":   {orgID},
+		"group_id": {groupID},
+		"start":    {"2026-10-12"},
+		"end":      {"2026-10-18"},
+	}
+
+	// Every PUT goes to the week and slot of the GET before it and sends back
+	// its ETag, as a client would
+	for attempt := 1; attempt <= 2; attempt++ {
+		get := httptest.NewRecorder()
+		router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/week?"+query.Encode(), nil))
+		if get.Code != http.StatusOK {
+			t.Fatalf("attempt %d: GET status = %d, body %s", attempt, get.Code, get.Body)
+		}
+
+		var body struct {
+			Data TopicToColorTimeWeekResponse `json:"data"`
+		}
+		if err := json.Unmarshal(get.Body.Bytes(), &body); err != nil {
+			t.Fatalf("attempt %d: decode GET response: %v", attempt, err)
+		}
+
+		slot := findSlotResponse(body.Data.ColorTimes, "Circle time")
+		if slot == nil {
+			t.Fatalf("attempt %d: slot Circle time missing from the week", attempt)
+		}
+
+		etag := get.Header().Get("ETag")
+		put := httptest.NewRequest(http.MethodPut, "/week/"+body.Data.ID.Hex()+"/slot/"+slot.SlotID.Hex(),
+			bytes.NewBufferString(fmt.Sprintf(`{"tracking":"T%d"}`, attempt)))
+		put.Header.Set("If-Match", etag)
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, put)
+		if res.Code != http.StatusOK {
+			t.Fatalf("attempt %d: PUT with If-Match %s status = %d, body %s", attempt, etag, res.Code, res.Body)
+		}
+	}
+}
+
+func findSlotResponse(days []*ColorTimeResponse, title string) *SlotResponse {
+	for _, day := range days {
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.Title == title {
+					return slot
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestWritesWithStaleVersionConflict(t *testing.T) {
+	startDate := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	slotID := primitive.NewObjectID()
+
+	newWeek := func() *WeekColorTime {
+		return &WeekColorTime{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: "org-1",
+			Owner:          &Owner{OwnerID: "student-1", OwnerRole: "student"},
+			StartDate:      startDate,
+			EndDate:        startDate.AddDate(0, 0, 6),
+			ColorTimes: []*ColorTime{{
+				ID:   primitive.NewObjectID(),
+				Date: startDate,
+				TimeSlots: []*ColorBlock{{
+					BlockID: primitive.NewObjectID(),
+					Slots:   []*ColortimeSlot{{SlotID: slotID, Title: "Circle time", Tracking: "T0", UseCount: 1}},
+				}},
+			}},
+			Version: 3,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		// concurrent writes the week after the request read it
+		concurrent bool
+		path       func(week *WeekColorTime) (string, string, string)
+	}{
+		{
+			name:    "slot update with stale If-Match",
+			ifMatch: `"2"`,
+			path: func(week *WeekColorTime) (string, string, string) {
+				return http.MethodPut, "/week/" + week.ID.Hex() + "/slot/" + slotID.Hex(), `{"tracking":"T1"}`
+			},
+		},
+		{
+			name:       "slot update racing another writer",
+			ifMatch:    `"3"`,
+			concurrent: true,
+			path: func(week *WeekColorTime) (string, string, string) {
+				return http.MethodPut, "/week/" + week.ID.Hex() + "/slot/" + slotID.Hex(), `{"tracking":"T1"}`
+			},
+		},
+		{
+			name:    "week topic with stale If-Match",
+			ifMatch: `"2"`,
+			path: func(week *WeekColorTime) (string, string, string) {
+				return http.MethodPost, "/add-topic/week/" + week.ID.Hex(), `{"topic_id":"topic-1"}`
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			week := newWeek()
+			repo := newFakeColorTimeRepository(week)
+			if tt.concurrent {
+				repo.afterRead = func(stored *WeekColorTime) { stored.Version++ }
+			}
+
+			service := NewColorTimeService(repo, fakeDefaultColorTimeRepository{}, nil, nil, nil,
+				fakeUserService{}, nil, fakeOrganizationSettingService{}, nil)
+			router := newTestRouter(service)
+
+			method, path, body := tt.path(week)
+			req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+			req.Header.Set("If-Match", tt.ifMatch)
+
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			if res.Code != http.StatusConflict {
+				t.Fatalf("status = %d, want %d, body %s", res.Code, http.StatusConflict, res.Body)
+			}
+
+			stored := repo.weeks[week.ID]
+			if slot := stored.ColorTimes[0].TimeSlots[0].Slots[0]; slot.Tracking != "T0" || stored.TopicID != nil {
+				t.Fatalf("week was written despite the conflict: tracking %q, topic %v", slot.Tracking, stored.TopicID)
+			}
+		})
+	}
+}
+
+func TestFirstWeekGetDoesNotStore(t *testing.T) {
+	repo := newFakeColorTimeRepository()
+	service := NewColorTimeService(repo, fakeDefaultColorTimeRepository{}, nil, nil, nil, fakeUserService{}, nil, fakeOrganizationSettingService{}, nil)
+	router := newTestRouter(service)
+
+	query := url.Values{
+		"user_id": {"teacher-1"},
+		"role":    {"teacher"},
+		"org_id":  {"org-1"},
+		"start":   {"2026-10-12"},
+		"end":     {"2026-10-18"},
+	}
+
+	var ids []primitive.ObjectID
+	for attempt := 1; attempt <= 2; attempt++ {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/week?"+query.Encode(), nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("attempt %d: GET status = %d, body %s", attempt, res.Code, res.Body)
+		}
+
+		var body struct {
+			Data TopicToColorTimeWeekResponse `json:"data"`
+		}
+		if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+			t.Fatalf("attempt %d: decode GET response: %v", attempt, err)
+		}
+		ids = append(ids, body.Data.ID)
+	}
+
+	if len(repo.weeks) != 0 {
+		t.Fatalf("GET stored %d weeks, want none before the sync", len(repo.weeks))
+	}
+	if ids[0] != ids[1] {
+		t.Fatalf("GET returned week %s then %s, want the same id", ids[0].Hex(), ids[1].Hex())
+	}
+
+	// The background sync stores the week under the id the GETs returned
+	startDate := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	week, err := service.(*colorTimeService).syncWeek(context.Background(), "org-1", &Owner{OwnerID: "teacher-1", OwnerRole: "teacher"}, startDate, startDate.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("syncWeek: %v", err)
+	}
+	if _, stored := repo.weeks[ids[0]]; !stored || week.ID != ids[0] {
+		t.Fatalf("sync stored week %s, want %s", week.ID.Hex(), ids[0].Hex())
+	}
+}
@@ -1,15 +1,21 @@
 package colortime
 
 import (
+	"colortime-service/internal/closure"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// OwnerRoleGroup marks a week owned by a class/group rather than a single user.
+// Member weeks point at the group through GroupID and only store overrides.
+const OwnerRoleGroup = "group"
+
 type WeekColorTime struct {
 	ID             primitive.ObjectID `bson:"_id" json:"id"`
 	OrganizationID string             `bson:"organization_id" json:"organization_id"`
 	Owner          *Owner             `json:"owner" bson:"owner"`
+	GroupID        *string            `bson:"group_id,omitempty" json:"group_id,omitempty"`
 	StartDate      time.Time          `bson:"start_date" json:"start_date"`
 	EndDate        time.Time          `bson:"end_date" json:"end_date"`
 	TopicID        *string            `bson:"topic_id" json:"topic_id"`
@@ -17,15 +23,18 @@ type WeekColorTime struct {
 	CreatedBy      string             `bson:"created_by" json:"created_by"`
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	SyncedAt       time.Time          `bson:"synced_at" json:"synced_at"` // last time defaults were merged into this week
+	Version        int64              `bson:"version" json:"version"`     // bumped on every update, see UpdateColorTimeWeek
 }
 
 type ColorTime struct {
-	ID        primitive.ObjectID `bson:"_id" json:"id"`
-	Date      time.Time          `bson:"date" json:"date"`
-	TopicID   *string            `bson:"topic_id" json:"topic_id"`
-	TimeSlots []*ColorBlock      `bson:"time_slots" json:"time_slots"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID        primitive.ObjectID  `bson:"_id" json:"id"`
+	Date      time.Time           `bson:"date" json:"date"`
+	TopicID   *string             `bson:"topic_id" json:"topic_id"`
+	TimeSlots []*ColorBlock       `bson:"time_slots" json:"time_slots"`
+	Closure   *closure.DayClosure `bson:"closure,omitempty" json:"closure,omitempty"` // closed or make-up day, copied from the default day on sync
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
 }
 
 type ColorBlock struct {
@@ -37,6 +46,7 @@ type ColorBlock struct {
 type ColortimeSlot struct {
 	SlotID                primitive.ObjectID       `json:"slot_id" bson:"slot_id"`
 	SlotIDOld             *primitive.ObjectID      `json:"slot_id_old" bson:"slot_id_old"`
+	GroupSlotID           *primitive.ObjectID      `json:"group_slot_id,omitempty" bson:"group_slot_id,omitempty"`
 	Sessions              int                      `json:"sessions" bson:"sessions"`
 	Title                 string                   `json:"title" bson:"title"`
 	ColorTimeSlotLanguage []*ColorTimeSlotLanguage `json:"color_time_slot_language" bson:"color_time_slot_language"`
@@ -48,10 +58,65 @@ type ColortimeSlot struct {
 	Color                 string                   `json:"color" bson:"color"`
 	Note                  string                   `json:"note" bson:"note"`
 	ProductID             *string                  `json:"product_id" bson:"product_id"`
+	Status                string                   `json:"status,omitempty" bson:"status,omitempty"`
+	OrphanedAt            *time.Time               `json:"orphaned_at,omitempty" bson:"orphaned_at,omitempty"`
+	TimeCustomized        bool                     `json:"time_customized,omitempty" bson:"time_customized,omitempty"` // moved by a block edit, syncs keep its time
 	CreatedAt             time.Time                `json:"created_at" bson:"created_at"`
 	UpdatedAt             time.Time                `json:"updated_at" bson:"updated_at"`
 }
 
+func (s *ColortimeSlot) ID() primitive.ObjectID { return s.SlotID }
+
+func (s *ColortimeSlot) Span() (time.Time, time.Time) { return s.StartTime, s.EndTime }
+
+// Reschedule moves the slot off the time of its default slot for good: syncs
+// no longer copy the default time over it.
+func (s *ColortimeSlot) Reschedule(start, end time.Time) {
+	s.StartTime, s.EndTime = start, end
+	s.TimeCustomized = true
+	s.UpdatedAt = time.Now()
+}
+
+func (s *ColortimeSlot) SetSessions(sessions int) { s.Sessions = sessions }
+
+// Slot statuses. An empty status is a slot still backed by its default slot.
+const (
+	// SlotStatusOrphaned marks a slot whose default slot was deleted while the
+	// student had tracking or a product on it. It waits for a teacher to resolve.
+	SlotStatusOrphaned = "orphaned"
+	// SlotStatusDetached marks a slot a teacher chose to keep after its default
+	// slot was deleted. Syncs leave it alone.
+	SlotStatusDetached = "detached"
+)
+
+const (
+	ResolveOrphanedSlotKeep   = "keep"
+	ResolveOrphanedSlotRemove = "remove"
+)
+
+// TrackingOccurrence is one slot carrying a tracking, as listed by
+// GetTrackingOccurrences and GetTrackingSeries.
+type TrackingOccurrence struct {
+	WeekID   primitive.ObjectID `bson:"week_id"`
+	SlotID   primitive.ObjectID `bson:"slot_id"`
+	Tracking string             `bson:"tracking"`
+	UseCount int                `bson:"use_count"`
+	Title    string             `bson:"title"`
+	Date     time.Time          `bson:"date"`
+	// Set on member overrides, which take title and time from the group slot
+	GroupSlotID *primitive.ObjectID `bson:"group_slot_id,omitempty"`
+	StartTime   time.Time           `bson:"start_time"`
+	CreatedAt   time.Time           `bson:"created_at"`
+}
+
+// TrackingKey identifies one tracking series of an owner.
+type TrackingKey struct {
+	OrganizationID string `bson:"organization_id"`
+	OwnerID        string `bson:"owner_id"`
+	OwnerRole      string `bson:"owner_role"`
+	Tracking       string `bson:"tracking"`
+}
+
 type ColorTimeSlotLanguage struct {
 	LanguageID int    `json:"language_id" bson:"language_id"`
 	Title      string `json:"title" bson:"title"`
@@ -61,3 +126,33 @@ type Owner struct {
 	OwnerID   string `json:"owner_id" bson:"owner_id"`
 	OwnerRole string `json:"owner_role" bson:"owner_role"`
 }
+
+const (
+	SyncJobStatusPending   = "pending"
+	SyncJobStatusRunning   = "running"
+	SyncJobStatusCompleted = "completed"
+	SyncJobStatusFailed    = "failed"
+)
+
+// SyncJob tracks the rollout of default day changes to the materialized weeks
+// of an organization.
+type SyncJob struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	Dates          []time.Time        `bson:"dates" json:"dates"`
+	Status         string             `bson:"status" json:"status"`
+	TotalWeeks     int                `bson:"total_weeks" json:"total_weeks"`
+	ProcessedWeeks int                `bson:"processed_weeks" json:"processed_weeks"`
+	FailedWeeks    int                `bson:"failed_weeks" json:"failed_weeks"`
+	Failures       []*SyncJobFailure  `bson:"failures" json:"failures"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	StartedAt      *time.Time         `bson:"started_at" json:"started_at"`
+	FinishedAt     *time.Time         `bson:"finished_at" json:"finished_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type SyncJobFailure struct {
+	WeekID primitive.ObjectID `bson:"week_id" json:"week_id"`
+	Owner  *Owner             `bson:"owner" json:"owner"`
+	Error  string             `bson:"error" json:"error"`
+}
@@ -1,12 +1,15 @@
 package colortime
 
 import (
+	"colortime-service/helper"
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ColorTimeRepository interface {
@@ -16,9 +19,19 @@ type ColorTimeRepository interface {
 	GetColorTimeWeekByID(ctx context.Context, id primitive.ObjectID) (*WeekColorTime, error)
 	UpdateColorTimeWeek(ctx context.Context, id primitive.ObjectID, colortimeWeek *WeekColorTime) error
 	CountTrackingUsage(ctx context.Context, organizationID, userID, role, tracking string) (int, error)
-	GetAllSlotsByTracking(ctx context.Context, organizationID, userID, role, tracking string) ([]*ColortimeSlot, []*WeekColorTime, error)
+	GetTrackingOccurrences(ctx context.Context, organizationID, userID, role, tracking string) ([]*TrackingOccurrence, error)
+	GetTrackingSeries(ctx context.Context, organizationID, userID, role string) ([]*TrackingOccurrence, error)
+	RenameTracking(ctx context.Context, organizationID, userID, role, from, to string) (int64, error)
+	GetTrackingKeys(ctx context.Context, organizationID string) ([]*TrackingKey, error)
+	EnsureIndexes(ctx context.Context) error
 
 	GetWeekByDate(ctx context.Context, date time.Time, organizationID, userID, role string) (*WeekColorTime, error)
+	GetColorTimeWeeksByDate(ctx context.Context, date time.Time, organizationID string) ([]*WeekColorTime, error)
+
+	UpdateSlotFields(ctx context.Context, weekID, slotID primitive.ObjectID, version int64, fields bson.M) error
+	RemoveSlot(ctx context.Context, weekID, slotID primitive.ObjectID, version int64) error
+	SetDayTopic(ctx context.Context, weekID primitive.ObjectID, version int64, date time.Time, topicID *string) error
+	SetWeekTopic(ctx context.Context, weekID primitive.ObjectID, version int64, topicID *string) error
 }
 
 type colorTimeRepository struct {
@@ -75,89 +88,311 @@ func (r *colorTimeRepository) GetColorTimeWeekByID(ctx context.Context, id primi
 
 }
 
+// UpdateColorTimeWeek only writes when the stored document is still at the version
+// that was read, and bumps the version on success.
 func (r *colorTimeRepository) UpdateColorTimeWeek(ctx context.Context, id primitive.ObjectID, colortimeWeek *WeekColorTime) error {
-	_, err := r.ColorTimeCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": colortimeWeek})
-	return err
+	readVersion := colortimeWeek.Version
+	colortimeWeek.Version = readVersion + 1
+
+	result, err := r.ColorTimeCollection.UpdateOne(ctx, helper.VersionFilter(id, readVersion), bson.M{"$set": colortimeWeek})
+	if err != nil {
+		colortimeWeek.Version = readVersion
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		colortimeWeek.Version = readVersion
+		return r.versionConflict(ctx, id)
+	}
+
+	return nil
 }
 
-func (r *colorTimeRepository) CountTrackingUsage(ctx context.Context, organizationID, userID, role, tracking string) (int, error) {
-	filter := bson.M{
-		"organization_id":  organizationID,
-		"owner.owner_id":   userID,
-		"owner.owner_role": role,
+func (r *colorTimeRepository) versionConflict(ctx context.Context, id primitive.ObjectID) error {
+	var current struct {
+		Version int64 `bson:"version"`
 	}
 
-	cursor, err := r.ColorTimeCollection.Find(ctx, filter)
+	if err := r.ColorTimeCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&current); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("document not found")
+		}
+		return err
+	}
+
+	return &helper.VersionConflictError{CurrentVersion: current.Version}
+}
+
+// UpdateSlotFields sets the given slot fields (bson names) on one slot of the
+// week without rewriting the rest of the document. Like the other patches it
+// only writes while the week is at version; see helper.PatchDocument.
+func (r *colorTimeRepository) UpdateSlotFields(ctx context.Context, weekID, slotID primitive.ObjectID, version int64, fields bson.M) error {
+	filter := bson.M{"_id": weekID, "colortimes.time_slots.slots.slot_id": slotID}
+	update := bson.M{"$set": helper.PrefixFields("colortimes.$[].time_slots.$[].slots.$[slot].", fields)}
+
+	return helper.PatchDocument(ctx, r.ColorTimeCollection, filter, version, update, bson.M{"slot.slot_id": slotID})
+}
+
+func (r *colorTimeRepository) RemoveSlot(ctx context.Context, weekID, slotID primitive.ObjectID, version int64) error {
+	filter := bson.M{"_id": weekID, "colortimes.time_slots.slots.slot_id": slotID}
+	update := bson.M{"$pull": bson.M{"colortimes.$[].time_slots.$[].slots": bson.M{"slot_id": slotID}}}
+
+	return helper.PatchDocument(ctx, r.ColorTimeCollection, filter, version, update)
+}
+
+func (r *colorTimeRepository) SetDayTopic(ctx context.Context, weekID primitive.ObjectID, version int64, date time.Time, topicID *string) error {
+	y, m, d := date.UTC().Date()
+	dayRange := bson.M{
+		"$gte": time.Date(y, m, d, 0, 0, 0, 0, time.UTC),
+		"$lt":  time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC),
+	}
+
+	filter := bson.M{"_id": weekID, "colortimes": bson.M{"$elemMatch": bson.M{"date": dayRange}}}
+	update := bson.M{"$set": bson.M{
+		"colortimes.$[day].topic_id":   topicID,
+		"colortimes.$[day].updated_at": time.Now(),
+	}}
+
+	return helper.PatchDocument(ctx, r.ColorTimeCollection, filter, version, update, bson.M{"day.date": dayRange})
+}
+
+func (r *colorTimeRepository) SetWeekTopic(ctx context.Context, weekID primitive.ObjectID, version int64, topicID *string) error {
+	return helper.PatchDocument(ctx, r.ColorTimeCollection, bson.M{"_id": weekID}, version, bson.M{"$set": bson.M{"topic_id": topicID}})
+}
+
+// trackingPipeline unwinds the owner's weeks down to the slots carrying
+// tracking. The leading $match uses the tracking index so only weeks that
+// contain the tracking are unwound.
+func trackingPipeline(organizationID, userID, role, tracking string) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"organization_id":                      organizationID,
+			"owner.owner_id":                       userID,
+			"owner.owner_role":                     role,
+			"colortimes.time_slots.slots.tracking": tracking,
+		}}},
+		{{Key: "$unwind", Value: "$colortimes"}},
+		{{Key: "$unwind", Value: "$colortimes.time_slots"}},
+		{{Key: "$unwind", Value: "$colortimes.time_slots.slots"}},
+		{{Key: "$match", Value: bson.M{"colortimes.time_slots.slots.tracking": tracking}}},
+	}
+}
+
+func (r *colorTimeRepository) CountTrackingUsage(ctx context.Context, organizationID, userID, role, tracking string) (int, error) {
+
+	pipeline := append(trackingPipeline(organizationID, userID, role, tracking),
+		bson.D{{Key: "$count", Value: "count"}},
+	)
+
+	cursor, err := r.ColorTimeCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, err
 	}
 	defer cursor.Close(ctx)
 
-	totalCount := 0
-	for cursor.Next(ctx) {
-		var week WeekColorTime
-		if err := cursor.Decode(&week); err != nil {
-			return 0, err
-		}
+	var result []struct {
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
 
-		// Count tracking in this week
-		for _, colorTime := range week.ColorTimes {
-			for _, block := range colorTime.TimeSlots {
-				for _, slot := range block.Slots {
-					if slot.Tracking == tracking {
-						totalCount++
-					}
-				}
-			}
-		}
+	if len(result) == 0 {
+		return 0, nil
 	}
 
-	if err := cursor.Err(); err != nil {
-		return 0, err
+	return result[0].Count, nil
+}
+
+// occurrenceStages projects unwound slots to TrackingOccurrence and sorts them
+// by the day they are scheduled on, then the time of day they start. Slot
+// start times carry no reliable date, so only hour and minute are compared.
+// Ties are broken by creation time and slot id to keep numbering stable.
+// Member overrides have no start time of their own and are moved into place by
+// scheduleOccurrences.
+func occurrenceStages(byTracking bool) []bson.D {
+	sort := bson.D{}
+	if byTracking {
+		sort = append(sort, bson.E{Key: "tracking", Value: 1})
+	}
+	sort = append(sort,
+		bson.E{Key: "date", Value: 1},
+		bson.E{Key: "start_minute", Value: 1},
+		bson.E{Key: "created_at", Value: 1},
+		bson.E{Key: "slot_id", Value: 1},
+	)
+
+	return []bson.D{
+		{{Key: "$project", Value: bson.M{
+			"_id":           0,
+			"week_id":       "$_id",
+			"slot_id":       "$colortimes.time_slots.slots.slot_id",
+			"tracking":      "$colortimes.time_slots.slots.tracking",
+			"use_count":     "$colortimes.time_slots.slots.use_count",
+			"title":         "$colortimes.time_slots.slots.title",
+			"group_slot_id": "$colortimes.time_slots.slots.group_slot_id",
+			"date":          "$colortimes.date",
+			"start_time":    "$colortimes.time_slots.slots.start_time",
+			"created_at":    "$colortimes.time_slots.slots.created_at",
+			"start_minute": bson.M{"$add": bson.A{
+				bson.M{"$multiply": bson.A{bson.M{"$hour": "$colortimes.time_slots.slots.start_time"}, 60}},
+				bson.M{"$minute": "$colortimes.time_slots.slots.start_time"},
+			}},
+		}}},
+		{{Key: "$sort", Value: sort}},
 	}
+}
+
+// GetTrackingOccurrences lists the owner's slots carrying tracking in schedule
+// order, without loading the weeks themselves.
+func (r *colorTimeRepository) GetTrackingOccurrences(ctx context.Context, organizationID, userID, role, tracking string) ([]*TrackingOccurrence, error) {
+
+	pipeline := append(trackingPipeline(organizationID, userID, role, tracking), occurrenceStages(false)...)
 
-	return totalCount, nil
+	cursor, err := r.ColorTimeCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var occurrences []*TrackingOccurrence
+	if err := cursor.All(ctx, &occurrences); err != nil {
+		return nil, err
+	}
+
+	return occurrences, nil
 }
 
-func (r *colorTimeRepository) GetAllSlotsByTracking(ctx context.Context, organizationID, userID, role, tracking string) ([]*ColortimeSlot, []*WeekColorTime, error) {
+// GetTrackingSeries lists every tracked slot of the owner grouped by tracking,
+// each series in schedule order.
+func (r *colorTimeRepository) GetTrackingSeries(ctx context.Context, organizationID, userID, role string) ([]*TrackingOccurrence, error) {
+
+	trackedSlot := bson.M{"$nin": []interface{}{"", nil}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"organization_id":                      organizationID,
+			"owner.owner_id":                       userID,
+			"owner.owner_role":                     role,
+			"colortimes.time_slots.slots.tracking": trackedSlot,
+		}}},
+		{{Key: "$unwind", Value: "$colortimes"}},
+		{{Key: "$unwind", Value: "$colortimes.time_slots"}},
+		{{Key: "$unwind", Value: "$colortimes.time_slots.slots"}},
+		{{Key: "$match", Value: bson.M{"colortimes.time_slots.slots.tracking": trackedSlot}}},
+	}
+	pipeline = append(pipeline, occurrenceStages(true)...)
 
-	filter := bson.M{
-		"organization_id":  organizationID,
-		"owner.owner_id":   userID,
-		"owner.owner_role": role,
+	cursor, err := r.ColorTimeCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	cursor, err := r.ColorTimeCollection.Find(ctx, filter)
+	var occurrences []*TrackingOccurrence
+	if err := cursor.All(ctx, &occurrences); err != nil {
+		return nil, err
+	}
+
+	return occurrences, nil
+}
+
+// GetTrackingKeys lists every distinct (organization, owner, tracking) in use,
+// restricted to one organization when organizationID is set.
+func (r *colorTimeRepository) GetTrackingKeys(ctx context.Context, organizationID string) ([]*TrackingKey, error) {
+
+	match := bson.M{"colortimes.time_slots.slots.tracking": bson.M{"$nin": []interface{}{"", nil}}}
+	if organizationID != "" {
+		match["organization_id"] = organizationID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$colortimes"}},
+		{{Key: "$unwind", Value: "$colortimes.time_slots"}},
+		{{Key: "$unwind", Value: "$colortimes.time_slots.slots"}},
+		{{Key: "$match", Value: bson.M{"colortimes.time_slots.slots.tracking": bson.M{"$nin": []interface{}{"", nil}}}}},
+		{{Key: "$group", Value: bson.M{"_id": bson.M{
+			"organization_id": "$organization_id",
+			"owner_id":        "$owner.owner_id",
+			"owner_role":      "$owner.owner_role",
+			"tracking":        "$colortimes.time_slots.slots.tracking",
+		}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$_id"}}},
+	}
+
+	cursor, err := r.ColorTimeCollection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var slots []*ColortimeSlot
-	var weeks []*WeekColorTime
+	var keys []*TrackingKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
 
-	for cursor.Next(ctx) {
+	return keys, nil
+}
 
-		var week WeekColorTime
-		if err := cursor.Decode(&week); err != nil {
-			return nil, nil, err
-		}
+// RenameTracking replaces the tracking code on every slot of the owner that
+// carries from, and returns how many weeks were touched.
+func (r *colorTimeRepository) RenameTracking(ctx context.Context, organizationID, userID, role, from, to string) (int64, error) {
 
-		weeks = append(weeks, &week)
+	filter := bson.M{
+		"organization_id":                      organizationID,
+		"owner.owner_id":                       userID,
+		"owner.owner_role":                     role,
+		"colortimes.time_slots.slots.tracking": from,
+	}
 
-		for _, colorTime := range week.ColorTimes {
-			for _, block := range colorTime.TimeSlots {
-				for _, slot := range block.Slots {
-					if slot.Tracking == tracking {
-						slots = append(slots, slot)
-					}
-				}
-			}
-		}
+	update := bson.M{
+		"$set": bson.M{
+			"colortimes.$[].time_slots.$[].slots.$[slot].tracking": to,
+			"updated_at": time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"slot.tracking": from}},
+	})
 
+	result, err := r.ColorTimeCollection.UpdateMany(ctx, filter, update, opts)
+	if err != nil {
+		return 0, err
 	}
 
-	return slots, weeks, nil
+	return result.ModifiedCount, nil
+}
+
+// EnsureIndexes creates the indexes the tracking aggregations rely on.
+func (r *colorTimeRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.ColorTimeCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "organization_id", Value: 1},
+			{Key: "owner.owner_id", Value: 1},
+			{Key: "owner.owner_role", Value: 1},
+			{Key: "colortimes.time_slots.slots.tracking", Value: 1},
+		},
+		Options: options.Index().SetName("owner_tracking"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// One week per owner and start date, so two syncs racing to store the
+	// same week fail instead of duplicating it
+	_, err = r.ColorTimeCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "organization_id", Value: 1},
+			{Key: "owner.owner_id", Value: 1},
+			{Key: "owner.owner_role", Value: 1},
+			{Key: "start_date", Value: 1},
+		},
+		Options: options.Index().SetName("owner_week").SetUnique(true),
+	})
+	return err
 }
 
 func (r *colorTimeRepository) GetWeekByDate(ctx context.Context, date time.Time, organizationID, userID, role string) (*WeekColorTime, error) {
@@ -209,3 +444,141 @@ func (r *colorTimeRepository) GetColorTimeWeeksInRange(ctx context.Context, star
 
 	return weeks, nil
 }
+
+func (r *colorTimeRepository) GetColorTimeWeeksByDate(ctx context.Context, date time.Time, organizationID string) ([]*WeekColorTime, error) {
+	filter := bson.M{
+		"organization_id": organizationID,
+		"start_date":      bson.M{"$lte": date},
+		"end_date":        bson.M{"$gte": date},
+	}
+
+	cursor, err := r.ColorTimeCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var weeks []*WeekColorTime
+	if err := cursor.All(ctx, &weeks); err != nil {
+		return nil, err
+	}
+
+	return weeks, nil
+}
+
+type SyncJobRepository interface {
+	CreateSyncJob(ctx context.Context, job *SyncJob) error
+	GetSyncJobByID(ctx context.Context, id primitive.ObjectID) (*SyncJob, error)
+	GetSyncJobs(ctx context.Context, organizationID string, limit int64) ([]*SyncJob, error)
+	GetUnfinishedSyncJobs(ctx context.Context) ([]*SyncJob, error)
+	StartSyncJob(ctx context.Context, id primitive.ObjectID, totalWeeks int) error
+	RecordSyncJobProgress(ctx context.Context, id primitive.ObjectID, failure *SyncJobFailure) error
+	FinishSyncJob(ctx context.Context, id primitive.ObjectID, status string) error
+}
+
+type syncJobRepository struct {
+	SyncJobCollection *mongo.Collection
+}
+
+func NewSyncJobRepository(syncJobCollection *mongo.Collection) SyncJobRepository {
+	return &syncJobRepository{
+		SyncJobCollection: syncJobCollection,
+	}
+}
+
+func (r *syncJobRepository) CreateSyncJob(ctx context.Context, job *SyncJob) error {
+	_, err := r.SyncJobCollection.InsertOne(ctx, job)
+	return err
+}
+
+func (r *syncJobRepository) GetSyncJobByID(ctx context.Context, id primitive.ObjectID) (*SyncJob, error) {
+
+	var job SyncJob
+
+	if err := r.SyncJobCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (r *syncJobRepository) GetSyncJobs(ctx context.Context, organizationID string, limit int64) ([]*SyncJob, error) {
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.SyncJobCollection.Find(ctx, bson.M{"organization_id": organizationID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*SyncJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (r *syncJobRepository) GetUnfinishedSyncJobs(ctx context.Context) ([]*SyncJob, error) {
+
+	filter := bson.M{
+		"status": bson.M{"$in": []string{SyncJobStatusPending, SyncJobStatusRunning}},
+	}
+
+	cursor, err := r.SyncJobCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*SyncJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (r *syncJobRepository) StartSyncJob(ctx context.Context, id primitive.ObjectID, totalWeeks int) error {
+	now := time.Now()
+	_, err := r.SyncJobCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":          SyncJobStatusRunning,
+		"total_weeks":     totalWeeks,
+		"processed_weeks": 0,
+		"failed_weeks":    0,
+		"failures":        []*SyncJobFailure{},
+		"started_at":      now,
+		"updated_at":      now,
+	}})
+	return err
+}
+
+func (r *syncJobRepository) RecordSyncJobProgress(ctx context.Context, id primitive.ObjectID, failure *SyncJobFailure) error {
+
+	update := bson.M{
+		"$inc": bson.M{"processed_weeks": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	if failure != nil {
+		update["$inc"] = bson.M{"processed_weeks": 1, "failed_weeks": 1}
+		update["$push"] = bson.M{"failures": failure}
+	}
+
+	_, err := r.SyncJobCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *syncJobRepository) FinishSyncJob(ctx context.Context, id primitive.ObjectID, status string) error {
+	now := time.Now()
+	_, err := r.SyncJobCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":      status,
+		"finished_at": now,
+		"updated_at":  now,
+	}})
+	return err
+}
@@ -111,3 +111,59 @@ type UpdateColorSlotRequest struct {
 	ProductID string `json:"product_id"`
 	Tracking  string `json:"tracking" binding:"required"`
 }
+
+type ResolveOrphanedSlotRequest struct {
+	Action string `json:"action" binding:"required"`
+}
+
+type SyncColorTimeWeekRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+	UserID         string `json:"user_id" binding:"required"`
+	Role           string `json:"role" binding:"required"`
+	GroupID        string `json:"group_id"` // links a member week to the group week
+	StartDate      string `json:"start_date" binding:"required"`
+	EndDate        string `json:"end_date" binding:"required"`
+}
+
+type RenameTrackingRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+	UserID         string `json:"user_id" binding:"required"`
+	Role           string `json:"role" binding:"required"`
+	Tracking       string `json:"tracking" binding:"required"`
+	NewTracking    string `json:"new_tracking" binding:"required"`
+}
+
+type MergeTrackingRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+	UserID         string `json:"user_id" binding:"required"`
+	Role           string `json:"role" binding:"required"`
+	SourceTracking string `json:"source_tracking" binding:"required"`
+	TargetTracking string `json:"target_tracking" binding:"required"`
+}
+
+type RenumberTrackingRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+	UserID         string `json:"user_id" binding:"required"`
+	Role           string `json:"role" binding:"required"`
+	Tracking       string `json:"tracking" binding:"required"`
+}
+
+type CreateColorBlockRequest struct {
+	Date string `json:"date" binding:"required"` // day of the week the block is added to
+}
+
+type MoveBlockRequest struct {
+	StartTime string `json:"start_time" binding:"required"` // new start of the block, "HH:MM"
+}
+
+type ReorderBlockSlotsRequest struct {
+	SlotIDs []string `json:"slot_ids" binding:"required"` // every slot of the block in the new order
+}
+
+type SplitBlockRequest struct {
+	SlotID string `json:"slot_id" binding:"required"` // first slot of the new block
+}
+
+type MergeBlocksRequest struct {
+	BlockID string `json:"block_id" binding:"required"` // block of the same day merged into the block of the path and removed
+}
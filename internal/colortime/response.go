@@ -1,6 +1,7 @@
 package colortime
 
 import (
+	"colortime-service/internal/closure"
 	"colortime-service/internal/user"
 	"time"
 
@@ -8,19 +9,21 @@ import (
 )
 
 type ColorTimeResponse struct {
-	ID        primitive.ObjectID `bson:"_id" json:"id"`
-	Date      time.Time          `bson:"date" json:"date"`
-	Topic     Topic              `bson:"topic" json:"topic"`
-	TopicWeek *Topic             `bson:"topic_week,omitempty" json:"topic_week,omitempty"`
-	TimeSlots []*BlockResponse   `bson:"time_slots" json:"time_slots"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID        primitive.ObjectID  `bson:"_id" json:"id"`
+	Date      time.Time           `bson:"date" json:"date"`
+	Topic     Topic               `bson:"topic" json:"topic"`
+	TopicWeek *Topic              `bson:"topic_week,omitempty" json:"topic_week,omitempty"`
+	TimeSlots []*BlockResponse    `bson:"time_slots" json:"time_slots"`
+	Closure   *closure.DayClosure `bson:"closure,omitempty" json:"closure,omitempty"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
 }
 
 type TopicToColorTimeWeekResponse struct {
 	ID             primitive.ObjectID   `bson:"_id" json:"id"`
 	OrganizationID string               `bson:"organization_id" json:"organization_id"`
 	Owner          *user.UserInfor      `json:"owner" bson:"owner"`
+	Group          *user.GroupInfor     `json:"group,omitempty" bson:"group,omitempty"`
 	StartDate      time.Time            `bson:"start_date" json:"start_date"`
 	EndDate        time.Time            `bson:"end_date" json:"end_date"`
 	Topic          Topic                `bson:"topic" json:"topic"`
@@ -28,6 +31,7 @@ type TopicToColorTimeWeekResponse struct {
 	CreatedBy      string               `bson:"created_by" json:"created_by"`
 	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+	Version        int64                `bson:"version" json:"version"`
 }
 
 type Topic struct {
@@ -50,6 +54,7 @@ type ProductInfo struct {
 type SlotResponse struct {
 	SlotID                primitive.ObjectID       `json:"slot_id"`
 	SlotIDOld             primitive.ObjectID       `json:"slot_id_old"`
+	GroupSlotID           *primitive.ObjectID      `json:"group_slot_id,omitempty"`
 	Sessions              int                      `json:"sessions"`
 	Title                 string                   `json:"title"`
 	ColorTimeSlotLanguage []*ColorTimeSlotLanguage `json:"color_time_slot_language"`
@@ -62,6 +67,8 @@ type SlotResponse struct {
 	Note                  string                   `json:"note"`
 	ProductID             *string                  `json:"product_id"`
 	Product               *ProductInfo             `json:"product,omitempty"`
+	ProductUnavailable    bool                     `json:"product_unavailable,omitempty"` // product_id is set but the product could not be looked up
+	Status                string                   `json:"status,omitempty"`
 	CreatedAt             time.Time                `json:"created_at"`
 	UpdatedAt             time.Time                `json:"updated_at"`
 }
@@ -102,4 +109,34 @@ type TopicByTermResponse struct {
 
 	// Tuần sau (từ tuần hiện tại tới hết kì)
 	UpcomingWeeks []*WeekTopicInfo `json:"upcoming_weeks"`
+
+	// Tuần không tải được chủ đề hoặc từ vựng
+	FailedWeeks []*WeekTopicError `json:"failed_weeks"`
+}
+
+// WeekTopicError reports a week whose topic could not be loaded. Part is
+// "topic" when the week is left out of the response, "vocabularies" when it is
+// listed without vocabularies.
+type WeekTopicError struct {
+	WeekNumber int       `json:"week_number"`
+	StartDate  time.Time `json:"start_date"`
+	EndDate    time.Time `json:"end_date"`
+	TopicID    string    `json:"topic_id"`
+	Part       string    `json:"part"`
+	Error      string    `json:"error"`
+}
+
+type TrackingSeriesResponse struct {
+	Tracking    string                        `json:"tracking"`
+	Count       int                           `json:"count"`
+	Occurrences []*TrackingOccurrenceResponse `json:"occurrences"`
+}
+
+type TrackingOccurrenceResponse struct {
+	WeekID    primitive.ObjectID `json:"week_id"`
+	SlotID    primitive.ObjectID `json:"slot_id"`
+	Title     string             `json:"title"`
+	Date      time.Time          `json:"date"`
+	StartTime time.Time          `json:"start_time"`
+	UseCount  int                `json:"use_count"`
 }
@@ -11,14 +11,29 @@ func RegisterRoutes(r *gin.Engine, colorTimeHandler *ColorTimeHandler) {
 	{
 
 		colorTime.GET("/week", colorTimeHandler.GetToColorTimeWeek)
+		colorTime.POST("/week/sync", colorTimeHandler.SyncColorTimeWeek)
+		colorTime.GET("/sync-jobs", colorTimeHandler.GetSyncJobs)
+		colorTime.GET("/sync-jobs/:id", colorTimeHandler.GetSyncJob)
 		colorTime.POST("/add-topic/week/:id", colorTimeHandler.AddTopicToColorTimeWeek)
 		colorTime.DELETE("/delete-topic/week/:id", colorTimeHandler.DeleteTopicToColorTimeWeek)
 		colorTime.POST("/add-topic/day/:id", colorTimeHandler.AddTopicToColorTimeDay)
 		colorTime.DELETE("/delete-topic/day/:id", colorTimeHandler.DeleteTopicToColorTimeDay)
 
 		colorTime.PUT("/week/:week_colortime_id/slot/:slot_id", colorTimeHandler.UpdateColorSlotHandler)
+		colorTime.PUT("/week/:week_colortime_id/slot/:slot_id/resolve", colorTimeHandler.ResolveOrphanedSlot)
+
+		colorTime.POST("/week/:week_colortime_id/blocks", colorTimeHandler.CreateColorBlock)
+		colorTime.PUT("/week/:week_colortime_id/blocks/:block_id/move", colorTimeHandler.MoveColorBlock)
+		colorTime.PUT("/week/:week_colortime_id/blocks/:block_id/reorder", colorTimeHandler.ReorderColorBlockSlots)
+		colorTime.POST("/week/:week_colortime_id/blocks/:block_id/split", colorTimeHandler.SplitColorBlock)
+		colorTime.POST("/week/:week_colortime_id/blocks/:block_id/merge", colorTimeHandler.MergeColorBlocks)
 
 		colorTime.GET("/day", colorTimeHandler.GetColorTimeDay)
 		colorTime.GET("/topic/term", colorTimeHandler.GetTopicByTerm)
+
+		colorTime.GET("/tracking", colorTimeHandler.GetTrackingSeries)
+		colorTime.PUT("/tracking/rename", colorTimeHandler.RenameTracking)
+		colorTime.POST("/tracking/merge", colorTimeHandler.MergeTracking)
+		colorTime.POST("/tracking/renumber", colorTimeHandler.RenumberTracking)
 	}
 }
@@ -1,31 +1,57 @@
 package colortime
 
 import (
+	"colortime-service/helper"
 	"colortime-service/internal/default_colortime"
 	"colortime-service/internal/language"
+	"colortime-service/internal/organization_setting"
 	"colortime-service/internal/product"
 	"colortime-service/internal/term"
 	"colortime-service/internal/topic"
 	"colortime-service/internal/user"
+	"colortime-service/pkg/consul"
 	"context"
+	"crypto/sha1"
 	"errors"
 	"fmt"
+	"log"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ColorTimeService interface {
 	AddTopicToColorTimeWeek(ctx context.Context, id string, req *AddTopicToColorTimeWeekRequest, userID string) error
-	GetColorTimeWeek(ctx context.Context, userID, role, orgID, start, end string, languageID *int) (*TopicToColorTimeWeekResponse, error)
+	GetColorTimeWeek(ctx context.Context, userID, role, orgID, start, end, groupID string, languageID *int) (*TopicToColorTimeWeekResponse, error)
 	DeleteTopicToColorTimeWeek(ctx context.Context, id string) error
 	AddTopicToColorTimeDay(ctx context.Context, id string, req *AddTopicToColorTimeDayRequest) error
 	DeleteTopicToColorTimeDay(ctx context.Context, id string, req *DeleteTopicToColorTimeDayRequest) error
 
 	UpdateColorSlot(ctx context.Context, weekColorTimeID, slotID string, req *UpdateColorSlotRequest, userID string) error
-	GetColorTimeDay(ctx context.Context, orgID, date, userID, role string) (*ColorTimeResponse, error)
+	ResolveOrphanedSlot(ctx context.Context, weekColorTimeID, slotID string, req *ResolveOrphanedSlotRequest, userID string) error
+	CreateColorBlock(ctx context.Context, weekColorTimeID string, req *CreateColorBlockRequest, userID string) (*ColorBlock, error)
+	MoveColorBlock(ctx context.Context, weekColorTimeID, blockID string, req *MoveBlockRequest, userID string) error
+	ReorderColorBlockSlots(ctx context.Context, weekColorTimeID, blockID string, req *ReorderBlockSlotsRequest, userID string) error
+	SplitColorBlock(ctx context.Context, weekColorTimeID, blockID string, req *SplitBlockRequest, userID string) (*ColorBlock, error)
+	MergeColorBlocks(ctx context.Context, weekColorTimeID, blockID string, req *MergeBlocksRequest, userID string) error
+	GetColorTimeDay(ctx context.Context, orgID, date, userID, role, groupID string) (*ColorTimeResponse, error)
 	GetTopicByTerm(ctx context.Context, orgID, userID, role string) (*TopicByTermResponse, error)
+
+	SyncColorTimeWeek(ctx context.Context, req *SyncColorTimeWeekRequest) (*WeekColorTime, error)
+	RunSyncWorker(ctx context.Context)
+	NotifyDefaultDayChanged(ctx context.Context, organizationID string, dates []time.Time)
+	GetSyncJob(ctx context.Context, id string) (*SyncJob, error)
+	GetSyncJobs(ctx context.Context, organizationID string) ([]*SyncJob, error)
+
+	GetTrackingSeries(ctx context.Context, orgID, userID, role string) ([]*TrackingSeriesResponse, error)
+	RenameTracking(ctx context.Context, req *RenameTrackingRequest) error
+	MergeTracking(ctx context.Context, req *MergeTrackingRequest) error
+	RenumberTracking(ctx context.Context, req *RenumberTrackingRequest) error
 }
 
 type colorTimeService struct {
@@ -36,6 +62,10 @@ type colorTimeService struct {
 	TermService                term.TermService
 	UserService                user.UserService
 	TopicService               topic.TopicService
+	OrganizationSettingService organization_setting.OrganizationSettingService
+	SyncJobRepository          SyncJobRepository
+	syncQueue                  *weekSyncQueue
+	propagationJobs            chan primitive.ObjectID
 }
 
 func NewColorTimeService(colorTimeRepository ColorTimeRepository,
@@ -44,7 +74,9 @@ func NewColorTimeService(colorTimeRepository ColorTimeRepository,
 	languageService language.MessageLanguageGateway,
 	termService term.TermService,
 	userService user.UserService,
-	topicService topic.TopicService) ColorTimeService {
+	topicService topic.TopicService,
+	organizationSettingService organization_setting.OrganizationSettingService,
+	syncJobRepository SyncJobRepository) ColorTimeService {
 	return &colorTimeService{
 		ColorTimeRepository:        colorTimeRepository,
 		DefaultColorTimeRepository: defaultColorTimeRepository,
@@ -53,6 +85,10 @@ func NewColorTimeService(colorTimeRepository ColorTimeRepository,
 		TermService:                termService,
 		UserService:                userService,
 		TopicService:               topicService,
+		OrganizationSettingService: organizationSettingService,
+		SyncJobRepository:          syncJobRepository,
+		syncQueue:                  newWeekSyncQueue(),
+		propagationJobs:            make(chan primitive.ObjectID, propagationQueueSize),
 	}
 }
 
@@ -66,24 +102,15 @@ func (s *colorTimeService) AddTopicToColorTimeWeek(ctx context.Context, id strin
 		return errors.New("topic id is required")
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return errors.New("invalid id format")
-	}
-
-	colortimeWeek, err := s.ColorTimeRepository.GetColorTimeWeekByID(ctx, objectID)
+	week, err := s.weekForPatch(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	if colortimeWeek == nil {
-		return fmt.Errorf("color time week not found")
-	}
-
-	colortimeWeek.TopicID = &req.TopicID
-	colortimeWeek.UpdatedAt = time.Now()
-
-	if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, colortimeWeek.ID, colortimeWeek); err != nil {
+	if err := s.ColorTimeRepository.SetWeekTopic(ctx, week.ID, week.Version, &req.TopicID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("color time week not found")
+		}
 		return err
 	}
 
@@ -91,7 +118,10 @@ func (s *colorTimeService) AddTopicToColorTimeWeek(ctx context.Context, id strin
 
 }
 
-func (s *colorTimeService) GetColorTimeWeek(ctx context.Context, userID, role, orgID, start, end string, languageID *int) (*TopicToColorTimeWeekResponse, error) {
+// Most days of a week rendered at the same time
+const dayRenderWorkers = 4
+
+func (s *colorTimeService) GetColorTimeWeek(ctx context.Context, userID, role, orgID, start, end, groupID string, languageID *int) (*TopicToColorTimeWeekResponse, error) {
 
 	if userID == "" {
 		return nil, errors.New("user id is required")
@@ -109,104 +139,31 @@ func (s *colorTimeService) GetColorTimeWeek(ctx context.Context, userID, role, o
 		return nil, errors.New("start and end date are required")
 	}
 
-	startDate, err := time.Parse("2006-01-02", start)
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, orgID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid start date: %w", err)
+		return nil, err
 	}
 
-	endDate, err := time.Parse("2006-01-02", end)
+	startDate, err := calendar.ParseDate(start)
 	if err != nil {
-		return nil, fmt.Errorf("invalid end date: %w", err)
+		return nil, fmt.Errorf("invalid start date: %w", err)
 	}
 
-	defaultDayColorTimes, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, startDate, endDate, orgID)
+	endDate, err := calendar.ParseDate(end)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get default day colortimes: %w", err)
+		return nil, fmt.Errorf("invalid end date: %w", err)
 	}
 
-	var colortimeWeek *WeekColorTime
-
-	existingWeek, err := s.ColorTimeRepository.GetColorTimeWeek(ctx, &startDate, &endDate, orgID, userID, role)
+	colortimeWeek, group, err := s.resolveWeek(ctx, orgID, userID, role, groupID, startDate, endDate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing week: %w", err)
-	}
-
-	if len(defaultDayColorTimes) > 0 {
-		colorTimes := cloneDefaultDayColorTimesToColorTimes(defaultDayColorTimes)
-
-		if existingWeek != nil {
-			updatedColorTimes := s.mergeColorTimes(existingWeek.ColorTimes, colorTimes)
-			existingWeek.ColorTimes = updatedColorTimes
-
-			// Sync with latest default data
-			s.syncColorTimesWithDefault(existingWeek.ColorTimes, defaultDayColorTimes)
-
-			existingWeek.UpdatedAt = time.Now()
-
-			if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, existingWeek.ID, existingWeek); err != nil {
-				return nil, fmt.Errorf("failed to update existing week: %w", err)
-			}
-			colortimeWeek = existingWeek
-		} else {
-			owner := &Owner{
-				OwnerID:   userID,
-				OwnerRole: role,
-			}
-			newColorTimeWeek := &WeekColorTime{
-				ID:             primitive.NewObjectID(),
-				OrganizationID: orgID,
-				Owner:          owner,
-				StartDate:      startDate,
-				EndDate:        endDate,
-				TopicID:        nil,
-				ColorTimes:     colorTimes,
-				CreatedBy:      userID,
-				CreatedAt:      time.Now(),
-				UpdatedAt:      time.Now(),
-			}
-
-			// Sync with latest default data before saving
-			s.syncColorTimesWithDefault(newColorTimeWeek.ColorTimes, defaultDayColorTimes)
-
-			if err := s.ColorTimeRepository.CreateColorTimeWeek(ctx, newColorTimeWeek); err != nil {
-				return nil, err
-			}
-			colortimeWeek = newColorTimeWeek
-		}
-	} else {
-		if existingWeek != nil {
-			// Return existing week if it already exists
-			colortimeWeek = existingWeek
-		} else {
-			// Create new empty week only if it doesn't exist
-			owner := &Owner{
-				OwnerID:   userID,
-				OwnerRole: role,
-			}
-			newColorTimeWeek := &WeekColorTime{
-				ID:             primitive.NewObjectID(),
-				OrganizationID: orgID,
-				Owner:          owner,
-				StartDate:      startDate,
-				EndDate:        endDate,
-				TopicID:        nil,
-				ColorTimes:     []*ColorTime{},
-				CreatedBy:      userID,
-				CreatedAt:      time.Now(),
-				UpdatedAt:      time.Now(),
-			}
-
-			if err := s.ColorTimeRepository.CreateColorTimeWeek(ctx, newColorTimeWeek); err != nil {
-				return nil, err
-			}
-			colortimeWeek = newColorTimeWeek
-		}
+		return nil, err
 	}
 
 	var weekTopic Topic
 	if colortimeWeek.TopicID != nil && *colortimeWeek.TopicID != "" {
 		topic, err := s.TopicService.GetTopicInfor(ctx, *colortimeWeek.TopicID)
-		if err != nil {
+		// A deleted topic is shown as no topic
+		if err != nil && !errors.Is(err, consul.ErrNotFound) {
 			return nil, err
 		}
 		if topic != nil {
@@ -217,42 +174,80 @@ func (s *colorTimeService) GetColorTimeWeek(ctx context.Context, userID, role, o
 		}
 	}
 
-	colorTimeResponses := make([]*ColorTimeResponse, 0, len(colortimeWeek.ColorTimes))
+	products := s.productsForDays(ctx, colortimeWeek.ColorTimes)
+
+	// Days are rendered concurrently, each one looking up its topic
+	colorTimeResponses := make([]*ColorTimeResponse, len(colortimeWeek.ColorTimes))
+	dayErrs := make([]error, len(colortimeWeek.ColorTimes))
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, dayRenderWorkers)
 
-	for _, day := range colortimeWeek.ColorTimes {
-		var dayTopic Topic
+	for i, day := range colortimeWeek.ColorTimes {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int, day *ColorTime) {
+			defer wg.Done()
+			defer func() { <-workers }()
 
-		if day.TopicID != nil && *day.TopicID != "" {
-			topic, err := s.TopicService.GetTopicInfor(ctx, *day.TopicID)
+			var dayTopic Topic
+
+			if day.TopicID != nil && *day.TopicID != "" {
+				topic, err := s.TopicService.GetTopicInfor(ctx, *day.TopicID)
+				if err != nil && !errors.Is(err, consul.ErrNotFound) {
+					dayErrs[i] = fmt.Errorf("failed to fetch topic for day %v: %w", day.Date, err)
+					return
+				}
+				if topic != nil {
+					dayTopic = Topic{
+						ID:   topic.ID,
+						Name: topic.Name,
+					}
+				}
+			}
+
+			blockResponses, err := s.convertBlocksWithProductInfo(day.TimeSlots, calendar, day.Date, languageID, products)
 			if err != nil {
-				return nil, fmt.Errorf("failed to fetch topic for day %v: %w", day.Date, err)
+				dayErrs[i] = fmt.Errorf("failed to convert blocks for day %v: %w", day.Date, err)
+				return
 			}
-			if topic != nil {
-				dayTopic = Topic{
-					ID:   topic.ID,
-					Name: topic.Name,
-				}
+
+			colorTimeResponses[i] = &ColorTimeResponse{
+				ID:        day.ID,
+				Date:      day.Date,
+				Topic:     dayTopic,
+				TopicWeek: nil,
+				TimeSlots: blockResponses,
+				Closure:   day.Closure,
+				CreatedAt: day.CreatedAt,
+				UpdatedAt: day.UpdatedAt,
 			}
-		}
+		}(i, day)
+	}
 
-		blockResponses, err := s.convertBlocksWithProductInfo(ctx, day.TimeSlots, day.Date, languageID)
+	wg.Wait()
+
+	for _, err := range dayErrs {
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert blocks for day %v: %w", day.Date, err)
+			return nil, err
 		}
+	}
 
-		colorTimeResponses = append(colorTimeResponses, &ColorTimeResponse{
-			ID:        day.ID,
-			Date:      day.Date,
-			Topic:     dayTopic,
-			TopicWeek: nil,
-			TimeSlots: blockResponses,
-			CreatedAt: day.CreatedAt,
-			UpdatedAt: day.UpdatedAt,
-		})
+	if colortimeWeek.Owner != nil && colortimeWeek.Owner.OwnerRole == OwnerRoleGroup && group == nil {
+		group, err = s.UserService.GetGroupInfor(ctx, colortimeWeek.Owner.OwnerID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var studentInfor *user.UserInfor
-	if colortimeWeek.Owner != nil {
+	if colortimeWeek.Owner != nil && colortimeWeek.Owner.OwnerRole == OwnerRoleGroup {
+		studentInfor = &user.UserInfor{
+			UserID:         group.GroupID,
+			UserName:       group.GroupName,
+			OrganizationID: group.OrganizationID,
+		}
+	} else if colortimeWeek.Owner != nil {
 		student, err := s.UserService.GetStudentInfor(ctx, colortimeWeek.Owner.OwnerID)
 		if err != nil {
 			return nil, err
@@ -268,6 +263,7 @@ func (s *colorTimeService) GetColorTimeWeek(ctx context.Context, userID, role, o
 		ID:             colortimeWeek.ID,
 		OrganizationID: colortimeWeek.OrganizationID,
 		Owner:          studentInfor,
+		Group:          group,
 		StartDate:      colortimeWeek.StartDate,
 		EndDate:        colortimeWeek.EndDate,
 		Topic:          weekTopic,
@@ -275,31 +271,412 @@ func (s *colorTimeService) GetColorTimeWeek(ctx context.Context, userID, role, o
 		CreatedBy:      colortimeWeek.CreatedBy,
 		CreatedAt:      colortimeWeek.CreatedAt,
 		UpdatedAt:      colortimeWeek.UpdatedAt,
+		Version:        colortimeWeek.Version,
 	}
 
 	return result, nil
 }
 
-func (s *colorTimeService) DeleteTopicToColorTimeWeek(ctx context.Context, id string) error {
+// resolveWeek returns the effective week for the owner, overlaying the group
+// week when the owner belongs to one.
+func (s *colorTimeService) resolveWeek(ctx context.Context, orgID, userID, role, groupID string, startDate, endDate time.Time) (*WeekColorTime, *user.GroupInfor, error) {
+
+	var colortimeWeek *WeekColorTime
+	var group *user.GroupInfor
+	var err error
+
+	if groupID != "" && role != OwnerRoleGroup {
+		group, err = s.UserService.GetGroupInfor(ctx, groupID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !group.HasMember(userID) {
+			return nil, nil, fmt.Errorf("user %s is not a member of group %s", userID, groupID)
+		}
+
+		colortimeWeek, err = s.memberWeek(ctx, orgID, &Owner{OwnerID: userID, OwnerRole: role}, groupID, startDate, endDate)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		colortimeWeek, err = s.loadWeek(ctx, orgID, &Owner{OwnerID: userID, OwnerRole: role}, startDate, endDate)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if colortimeWeek.GroupID != nil {
+		colortimeWeek, err = s.overlayGroupWeek(ctx, colortimeWeek, true)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return colortimeWeek, group, nil
+}
+
+// memberWeek returns the stored week of a group member without writing it.
+// A member with no week yet, or whose week is not linked to the group, gets
+// one built in memory and a background sync that stores it.
+func (s *colorTimeService) memberWeek(ctx context.Context, orgID string, owner *Owner, groupID string, startDate, endDate time.Time) (*WeekColorTime, error) {
+
+	existingWeek, err := s.ColorTimeRepository.GetColorTimeWeek(ctx, &startDate, &endDate, orgID, owner.OwnerID, owner.OwnerRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing week: %w", err)
+	}
+
+	if existingWeek != nil && existingWeek.GroupID != nil && *existingWeek.GroupID == groupID {
+		return existingWeek, nil
+	}
+
+	s.syncQueue.enqueue(weekSyncJob{
+		OrganizationID: orgID,
+		Owner:          *owner,
+		GroupID:        groupID,
+		StartDate:      startDate,
+		EndDate:        endDate,
+	})
+
+	if existingWeek == nil {
+		return newMemberWeek(orgID, owner, groupID, startDate, endDate), nil
+	}
+
+	groupWeek, err := s.loadWeek(ctx, orgID, &Owner{OwnerID: groupID, OwnerRole: OwnerRoleGroup}, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group week: %w", err)
+	}
+
+	existingWeek.GroupID = &groupID
+	existingWeek.ColorTimes = extractMemberOverrides(existingWeek.ColorTimes, groupWeek)
+
+	return existingWeek, nil
+}
+
+// newMemberWeek returns an empty member week, not yet stored.
+func newMemberWeek(orgID string, owner *Owner, groupID string, startDate, endDate time.Time) *WeekColorTime {
+	return &WeekColorTime{
+		ID:             weekID(orgID, owner, groupID, startDate),
+		OrganizationID: orgID,
+		Owner:          owner,
+		GroupID:        &groupID,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		TopicID:        nil,
+		ColorTimes:     []*ColorTime{},
+		CreatedBy:      owner.OwnerID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+}
+
+// weekID is the id a week is stored with, so a week shown before the sync
+// stores it keeps the same id afterwards. groupID is empty for weeks that are
+// not linked to a group.
+func weekID(orgID string, owner *Owner, groupID string, startDate time.Time) primitive.ObjectID {
+	hash := sha1.Sum([]byte(strings.Join([]string{orgID, owner.OwnerID, owner.OwnerRole, groupID, startDate.Format("2006-01-02")}, "\x00")))
+
+	var id primitive.ObjectID
+	copy(id[:], hash[:])
+	return id
+}
+
+// overlayGroupWeek returns the effective week of a group member: the group week
+// with the member's topics and slot overrides applied on top. When load is
+// false the stored group week is used as is, without creating it or merging in
+// newer defaults.
+func (s *colorTimeService) overlayGroupWeek(ctx context.Context, memberWeek *WeekColorTime, load bool) (*WeekColorTime, error) {
+
+	if memberWeek.GroupID == nil {
+		return memberWeek, nil
+	}
+
+	groupOwner := &Owner{OwnerID: *memberWeek.GroupID, OwnerRole: OwnerRoleGroup}
+
+	var groupWeek *WeekColorTime
+	var err error
+	if load {
+		groupWeek, err = s.loadWeek(ctx, memberWeek.OrganizationID, groupOwner, memberWeek.StartDate, memberWeek.EndDate)
+	} else {
+		groupWeek, err = s.ColorTimeRepository.GetColorTimeWeek(ctx, &memberWeek.StartDate, &memberWeek.EndDate, memberWeek.OrganizationID, groupOwner.OwnerID, groupOwner.OwnerRole)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group week: %w", err)
+	}
+
+	if groupWeek == nil {
+		return memberWeek, nil
+	}
+
+	return mergeMemberWeek(groupWeek, memberWeek), nil
+}
+
+func mergeMemberWeek(groupWeek, memberWeek *WeekColorTime) *WeekColorTime {
+
+	overrides := make(map[primitive.ObjectID]*ColortimeSlot)
+	memberDays := make(map[string]*ColorTime)
+	for _, day := range memberWeek.ColorTimes {
+		memberDays[day.Date.Format("2006-01-02")] = day
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.GroupSlotID != nil {
+					overrides[*slot.GroupSlotID] = slot
+				}
+			}
+		}
+	}
+
+	topicID := groupWeek.TopicID
+	if memberWeek.TopicID != nil && *memberWeek.TopicID != "" {
+		topicID = memberWeek.TopicID
+	}
+
+	merged := &WeekColorTime{
+		ID:             memberWeek.ID,
+		OrganizationID: memberWeek.OrganizationID,
+		Owner:          memberWeek.Owner,
+		GroupID:        memberWeek.GroupID,
+		StartDate:      memberWeek.StartDate,
+		EndDate:        memberWeek.EndDate,
+		TopicID:        topicID,
+		ColorTimes:     make([]*ColorTime, 0, len(groupWeek.ColorTimes)),
+		CreatedBy:      memberWeek.CreatedBy,
+		CreatedAt:      memberWeek.CreatedAt,
+		UpdatedAt:      memberWeek.UpdatedAt,
+		SyncedAt:       memberWeek.SyncedAt,
+		Version:        memberWeek.Version, // writes go to the member week
+	}
+
+	for _, groupDay := range groupWeek.ColorTimes {
+		day := &ColorTime{
+			ID:        groupDay.ID,
+			Date:      groupDay.Date,
+			TopicID:   groupDay.TopicID,
+			TimeSlots: make([]*ColorBlock, 0, len(groupDay.TimeSlots)),
+			Closure:   groupDay.Closure,
+			CreatedAt: groupDay.CreatedAt,
+			UpdatedAt: groupDay.UpdatedAt,
+		}
+
+		if memberDay, exists := memberDays[groupDay.Date.Format("2006-01-02")]; exists {
+			day.ID = memberDay.ID
+			if memberDay.TopicID != nil && *memberDay.TopicID != "" {
+				day.TopicID = memberDay.TopicID
+			}
+		}
+
+		for _, groupBlock := range groupDay.TimeSlots {
+			block := &ColorBlock{
+				BlockID:    groupBlock.BlockID,
+				BlockIDOld: groupBlock.BlockIDOld,
+				Slots:      make([]*ColortimeSlot, 0, len(groupBlock.Slots)),
+			}
+			for _, groupSlot := range groupBlock.Slots {
+				if override, exists := overrides[groupSlot.SlotID]; exists {
+					block.Slots = append(block.Slots, applyMemberOverride(groupSlot, override))
+				} else {
+					block.Slots = append(block.Slots, groupSlot)
+				}
+			}
+			day.TimeSlots = append(day.TimeSlots, block)
+		}
+
+		merged.ColorTimes = append(merged.ColorTimes, day)
+	}
+
+	return merged
+}
+
+// applyMemberOverride returns the group slot with the member-owned fields of
+// override, so teacher edits to the group slot still reach the member.
+func applyMemberOverride(groupSlot, override *ColortimeSlot) *ColortimeSlot {
+	slot := *groupSlot
+	slot.SlotID = override.SlotID
+	slot.GroupSlotID = override.GroupSlotID
+	slot.Tracking = override.Tracking
+	slot.UseCount = override.UseCount
+	slot.ProductID = override.ProductID
+	slot.Status = override.Status
+	slot.OrphanedAt = override.OrphanedAt
+	return &slot
+}
+
+// memberOverride keeps only what a member week stores of slot; everything
+// else is read from the group slot.
+func memberOverride(slot *ColortimeSlot) *ColortimeSlot {
+	return &ColortimeSlot{
+		SlotID:      slot.SlotID,
+		GroupSlotID: slot.GroupSlotID,
+		Tracking:    slot.Tracking,
+		UseCount:    slot.UseCount,
+		ProductID:   slot.ProductID,
+		Status:      slot.Status,
+		OrphanedAt:  slot.OrphanedAt,
+		CreatedAt:   slot.CreatedAt,
+		UpdatedAt:   slot.UpdatedAt,
+	}
+}
+
+// extractMemberOverrides keeps only the days and slots that carry data a member
+// entered themselves (topic, tracking or product), linking each kept slot to the
+// group slot cloned from the same default slot.
+func extractMemberOverrides(colorTimes []*ColorTime, groupWeek *WeekColorTime) []*ColorTime {
+
+	groupSlots := make(map[primitive.ObjectID]*ColortimeSlot)
+	for _, day := range groupWeek.ColorTimes {
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.SlotIDOld != nil {
+					groupSlots[*slot.SlotIDOld] = slot
+				}
+			}
+		}
+	}
+
+	result := make([]*ColorTime, 0)
+	for _, day := range colorTimes {
+		blocks := make([]*ColorBlock, 0)
+		for _, block := range day.TimeSlots {
+			slots := make([]*ColortimeSlot, 0)
+			for _, slot := range block.Slots {
+				if slot.Tracking == "" && (slot.ProductID == nil || *slot.ProductID == "") {
+					continue
+				}
+				if slot.GroupSlotID == nil && slot.SlotIDOld != nil {
+					if groupSlot, exists := groupSlots[*slot.SlotIDOld]; exists {
+						slot.GroupSlotID = &groupSlot.SlotID
+					}
+				}
+				if slot.GroupSlotID != nil {
+					slots = append(slots, memberOverride(slot))
+				}
+			}
+			if len(slots) > 0 {
+				blocks = append(blocks, &ColorBlock{BlockID: block.BlockID, BlockIDOld: block.BlockIDOld, Slots: slots})
+			}
+		}
+		if len(blocks) > 0 || (day.TopicID != nil && *day.TopicID != "") {
+			day.TimeSlots = blocks
+			result = append(result, day)
+		}
+	}
+	return result
+}
+
+func (s *colorTimeService) findGroupSlot(ctx context.Context, memberWeek *WeekColorTime, slotID primitive.ObjectID) (*ColorTime, *ColorBlock, *ColortimeSlot, error) {
+
+	groupWeek, err := s.ColorTimeRepository.GetColorTimeWeek(ctx, &memberWeek.StartDate, &memberWeek.EndDate, memberWeek.OrganizationID, *memberWeek.GroupID, OwnerRoleGroup)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get group week: %w", err)
+	}
+
+	if groupWeek == nil {
+		return nil, nil, nil, nil
+	}
+
+	for _, day := range groupWeek.ColorTimes {
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.SlotID == slotID {
+					return day, block, slot, nil
+				}
+			}
+		}
+	}
+
+	return nil, nil, nil, nil
+}
+
+// addMemberOverride adds an override of a group slot to the member week so
+// member-only fields can be stored without touching the group week.
+func addMemberOverride(calendar *organization_setting.Calendar, memberWeek *WeekColorTime, groupDay *ColorTime, groupBlock *ColorBlock, groupSlot *ColortimeSlot) *ColortimeSlot {
+
+	var memberDay *ColorTime
+	for _, day := range memberWeek.ColorTimes {
+		if calendar.SameDay(day.Date, groupDay.Date) {
+			memberDay = day
+			break
+		}
+	}
+
+	if memberDay == nil {
+		memberDay = &ColorTime{
+			ID:        primitive.NewObjectID(),
+			Date:      groupDay.Date,
+			TimeSlots: []*ColorBlock{},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		memberWeek.ColorTimes = append(memberWeek.ColorTimes, memberDay)
+	}
+
+	var memberBlock *ColorBlock
+	for _, block := range memberDay.TimeSlots {
+		if block.BlockID == groupBlock.BlockID {
+			memberBlock = block
+			break
+		}
+	}
+
+	if memberBlock == nil {
+		memberBlock = &ColorBlock{
+			BlockID:    groupBlock.BlockID,
+			BlockIDOld: groupBlock.BlockIDOld,
+			Slots:      []*ColortimeSlot{},
+		}
+		memberDay.TimeSlots = append(memberDay.TimeSlots, memberBlock)
+	}
+
+	override := &ColortimeSlot{
+		SlotID:      primitive.NewObjectID(),
+		GroupSlotID: &groupSlot.SlotID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	memberBlock.Slots = append(memberBlock.Slots, override)
+
+	return override
+}
+
+// weekForPatch loads the week a patch is about to write and checks the
+// request's If-Match against it. The patch itself is made at the version read
+// here, so a write landing in between is a conflict as well.
+func (s *colorTimeService) weekForPatch(ctx context.Context, id string) (*WeekColorTime, error) {
 
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid id format")
+		return nil, errors.New("invalid id format")
 	}
 
-	colortimeWeek, err := s.ColorTimeRepository.GetColorTimeWeekByID(ctx, objectID)
+	week, err := s.ColorTimeRepository.GetColorTimeWeekByID(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get week colortime: %w", err)
+	}
+
+	if week == nil {
+		return nil, errors.New("color time week not found")
+	}
+
+	if err := helper.CheckIfMatch(ctx, week.Version); err != nil {
+		return nil, err
+	}
+
+	return week, nil
+}
+
+func (s *colorTimeService) DeleteTopicToColorTimeWeek(ctx context.Context, id string) error {
+
+	week, err := s.weekForPatch(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	if colortimeWeek == nil {
-		return fmt.Errorf("color time week not found")
-	} else {
-		colortimeWeek.TopicID = nil
-		colortimeWeek.UpdatedAt = time.Now()
-		if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, colortimeWeek.ID, colortimeWeek); err != nil {
-			return err
+	if err := s.ColorTimeRepository.SetWeekTopic(ctx, week.ID, week.Version, nil); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("color time week not found")
 		}
+		return err
 	}
 
 	return nil
@@ -317,11 +694,6 @@ func (s *colorTimeService) AddTopicToColorTimeDay(ctx context.Context, id string
 		return fmt.Errorf("date is required")
 	}
 
-	dateParse, err := time.Parse("2006-01-02", req.Date)
-	if err != nil {
-		return err
-	}
-
 	if req.TopicID == "" {
 		return fmt.Errorf("topic id is required")
 	}
@@ -335,76 +707,204 @@ func (s *colorTimeService) AddTopicToColorTimeDay(ctx context.Context, id string
 		return fmt.Errorf("color time day not found")
 	}
 
-	found := false
+	if err := helper.CheckIfMatch(ctx, week.Version); err != nil {
+		return err
+	}
+
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, week.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	dateParse, err := calendar.ParseDate(req.Date)
+	if err != nil {
+		return err
+	}
+
 	for _, day := range week.ColorTimes {
-		if sameDay(day.Date, dateParse) {
-			day.TopicID = &req.TopicID
-			day.UpdatedAt = time.Now()
-			found = true
+		if calendar.SameDay(day.Date, dateParse) {
+			return s.ColorTimeRepository.SetDayTopic(ctx, week.ID, week.Version, day.Date, &req.TopicID)
+		}
+	}
+
+	// Member weeks only store the days they override
+	if week.GroupID == nil || dateParse.Before(week.StartDate) || dateParse.After(week.EndDate) {
+		return fmt.Errorf("color time day not found")
+	}
+
+	week.ColorTimes = append(week.ColorTimes, &ColorTime{
+		ID:        primitive.NewObjectID(),
+		Date:      dateParse,
+		TopicID:   &req.TopicID,
+		TimeSlots: []*ColorBlock{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+
+	week.UpdatedAt = time.Now()
+	return s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week)
+
+}
+
+func (s *colorTimeService) DeleteTopicToColorTimeDay(ctx context.Context, id string, req *DeleteTopicToColorTimeDayRequest) error {
+
+	if req.Date == "" {
+		return fmt.Errorf("date is required")
+	}
+
+	dateParse, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date format: %w", err)
+	}
+
+	week, err := s.weekForPatch(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ColorTimeRepository.SetDayTopic(ctx, week.ID, week.Version, dateParse, nil); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("color time day not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *colorTimeService) UpdateColorSlot(ctx context.Context, weekColorTimeID, slotID string, req *UpdateColorSlotRequest, userID string) error {
+
+	if userID == "" {
+		return errors.New("user id is required")
+	}
+
+	if req.Tracking == "" {
+		return errors.New("tracking is required")
+	}
+
+	weekObjectID, err := primitive.ObjectIDFromHex(weekColorTimeID)
+	if err != nil {
+		return errors.New("invalid week colortime ID format")
+	}
+
+	slotObjectID, err := primitive.ObjectIDFromHex(slotID)
+	if err != nil {
+		return errors.New("invalid slot ID format")
+	}
+
+	week, err := s.ColorTimeRepository.GetColorTimeWeekByID(ctx, weekObjectID)
+	if err != nil {
+		return fmt.Errorf("failed to get week colortime: %w", err)
+	}
+
+	if week == nil {
+		return errors.New("week colortime not found")
+	}
+
+	if err := helper.CheckIfMatch(ctx, week.Version); err != nil {
+		return err
+	}
+
+	var targetSlot *ColortimeSlot
+
+	for _, colorTime := range week.ColorTimes {
+		for _, block := range colorTime.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.SlotID == slotObjectID {
+					targetSlot = slot
+					break
+				}
+			}
+			if targetSlot != nil {
+				break
+			}
+		}
+		if targetSlot != nil {
 			break
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("color time day not found")
+	// A new member override changes the week structure and needs a full write
+	isOverride := false
+	if targetSlot == nil && week.GroupID != nil {
+		groupDay, groupBlock, groupSlot, err := s.findGroupSlot(ctx, week, slotObjectID)
+		if err != nil {
+			return err
+		}
+		if groupSlot != nil {
+			calendar, err := s.OrganizationSettingService.GetCalendar(ctx, week.OrganizationID)
+			if err != nil {
+				return err
+			}
+			targetSlot = addMemberOverride(calendar, week, groupDay, groupBlock, groupSlot)
+			isOverride = true
+		}
 	}
 
-	week.UpdatedAt = time.Now()
-	return s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week)
+	if targetSlot == nil {
+		return errors.New("slot not found")
+	}
 
-}
+	oldTracking := targetSlot.Tracking
+	targetTracking := req.Tracking
 
-func (s *colorTimeService) DeleteTopicToColorTimeDay(ctx context.Context, id string, req *DeleteTopicToColorTimeDayRequest) error {
+	targetSlot.ProductID = nil
+	if req.ProductID != "" {
+		targetSlot.ProductID = &req.ProductID
+	}
 
-	objectID, err := primitive.ObjectIDFromHex(id)
+	trackingCount, err := s.ColorTimeRepository.CountTrackingUsage(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, req.Tracking)
 	if err != nil {
-		return errors.New("invalid id format")
+		return fmt.Errorf("failed to count tracking usage: %w", err)
 	}
 
-	if req.Date == "" {
-		return fmt.Errorf("date is required")
-	}
+	targetSlot.Tracking = req.Tracking
+	targetSlot.UseCount = trackingCount + 1
 
-	dateParse, err := time.Parse("2006-01-02", req.Date)
-	if err != nil {
-		return fmt.Errorf("invalid date format: %w", err)
-	}
+	targetSlot.UpdatedAt = time.Now()
 
-	week, err := s.ColorTimeRepository.GetColorTimeWeekByID(ctx, objectID)
-	if err != nil {
-		return err
+	if isOverride {
+		week.UpdatedAt = time.Now()
+		err = s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week)
+	} else {
+		err = s.ColorTimeRepository.UpdateSlotFields(ctx, week.ID, targetSlot.SlotID, week.Version, bson.M{
+			"tracking":   targetSlot.Tracking,
+			"use_count":  targetSlot.UseCount,
+			"product_id": targetSlot.ProductID,
+			"updated_at": targetSlot.UpdatedAt,
+		})
 	}
-
-	if week == nil {
-		return fmt.Errorf("week not found")
+	if err != nil {
+		return fmt.Errorf("failed to update week colortime: %w", err)
 	}
 
-	found := false
-	for i := range week.ColorTimes {
-		if sameDay(week.ColorTimes[i].Date, dateParse) {
-			week.ColorTimes[i].TopicID = nil
-			week.ColorTimes[i].UpdatedAt = time.Now()
-			found = true
-			break
+	if oldTracking != targetTracking && oldTracking != "" {
+		if err := s.normalizeTrackingGlobal(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, oldTracking); err != nil {
+			return fmt.Errorf("failed to normalize tracking global: %w", err)
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("color time day not found")
+	// The slot was appended at the end of the series; renumber so its use
+	// count follows the date it is scheduled on
+	if targetTracking != "" {
+		if err := s.normalizeTrackingGlobal(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, targetTracking); err != nil {
+			return fmt.Errorf("failed to normalize tracking global: %w", err)
+		}
 	}
 
-	week.UpdatedAt = time.Now()
-	return s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week)
+	return nil
 }
 
-func (s *colorTimeService) UpdateColorSlot(ctx context.Context, weekColorTimeID, slotID string, req *UpdateColorSlotRequest, userID string) error {
+// ResolveOrphanedSlot lets a teacher either keep an orphaned slot as a
+// standalone slot or remove it together with its tracking.
+func (s *colorTimeService) ResolveOrphanedSlot(ctx context.Context, weekColorTimeID, slotID string, req *ResolveOrphanedSlotRequest, userID string) error {
 
 	if userID == "" {
 		return errors.New("user id is required")
 	}
 
-	if req.Tracking == "" {
-		return errors.New("tracking is required")
+	if req.Action != ResolveOrphanedSlotKeep && req.Action != ResolveOrphanedSlotRemove {
+		return errors.New("action must be keep or remove")
 	}
 
 	weekObjectID, err := primitive.ObjectIDFromHex(weekColorTimeID)
@@ -426,6 +926,10 @@ func (s *colorTimeService) UpdateColorSlot(ctx context.Context, weekColorTimeID,
 		return errors.New("week colortime not found")
 	}
 
+	if err := helper.CheckIfMatch(ctx, week.Version); err != nil {
+		return err
+	}
+
 	var targetSlot *ColortimeSlot
 
 	for _, colorTime := range week.ColorTimes {
@@ -449,34 +953,25 @@ func (s *colorTimeService) UpdateColorSlot(ctx context.Context, weekColorTimeID,
 		return errors.New("slot not found")
 	}
 
-	oldTracking := targetSlot.Tracking
-	targetTracking := req.Tracking
-
-	targetSlot.ProductID = nil
-	if req.ProductID != "" {
-		targetSlot.ProductID = &req.ProductID
+	if targetSlot.Status != SlotStatusOrphaned {
+		return errors.New("slot is not orphaned")
 	}
 
-	trackingCount, err := s.ColorTimeRepository.CountTrackingUsage(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, req.Tracking)
-	if err != nil {
-		return fmt.Errorf("failed to count tracking usage: %w", err)
+	if req.Action == ResolveOrphanedSlotKeep {
+		err = s.ColorTimeRepository.UpdateSlotFields(ctx, week.ID, targetSlot.SlotID, week.Version, bson.M{
+			"status":      SlotStatusDetached,
+			"orphaned_at": nil,
+			"updated_at":  time.Now(),
+		})
+	} else {
+		err = s.ColorTimeRepository.RemoveSlot(ctx, week.ID, targetSlot.SlotID, week.Version)
 	}
-
-	targetSlot.Tracking = req.Tracking
-	targetSlot.UseCount = trackingCount + 1
-
-	targetSlot.UpdatedAt = time.Now()
-	week.UpdatedAt = time.Now()
-
-	if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to update week colortime: %w", err)
 	}
 
-	if oldTracking != targetTracking && oldTracking != "" {
-		if err := s.normalizeTrackingGlobal(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, oldTracking); err != nil {
-			return fmt.Errorf("failed to normalize tracking global: %w", err)
-		}
-		if err := s.normalizeTrackingGlobal(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, targetTracking); err != nil {
+	if req.Action == ResolveOrphanedSlotRemove && targetSlot.Tracking != "" {
+		if err := s.normalizeTrackingGlobal(ctx, week.OrganizationID, week.Owner.OwnerID, week.Owner.OwnerRole, targetSlot.Tracking); err != nil {
 			return fmt.Errorf("failed to normalize tracking global: %w", err)
 		}
 	}
@@ -484,7 +979,7 @@ func (s *colorTimeService) UpdateColorSlot(ctx context.Context, weekColorTimeID,
 	return nil
 }
 
-func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, userID, role string) (*ColorTimeResponse, error) {
+func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, userID, role, groupID string) (*ColorTimeResponse, error) {
 
 	if orgID == "" {
 		return nil, errors.New("organization id is required")
@@ -494,22 +989,19 @@ func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, use
 		return nil, errors.New("date is required")
 	}
 
-	parsedDate, err := time.Parse("2006-01-02", date)
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, orgID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %w", err)
+		return nil, err
 	}
 
-	start, end, err := GetWeekRangeStr(date)
+	parsedDate, err := calendar.ParseDate(date)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get week range: %w", err)
+		return nil, fmt.Errorf("invalid date format: %w", err)
 	}
 
-	_, err = s.GetColorTimeWeek(ctx, userID, role, orgID, start, end, nil)
-	if err != nil {
-		return nil, err
-	}
+	startDate, endDate := calendar.WeekRange(parsedDate)
 
-	colorTime, err := s.ColorTimeRepository.GetWeekByDate(ctx, parsedDate, orgID, userID, role)
+	colorTime, _, err := s.resolveWeek(ctx, orgID, userID, role, groupID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -521,7 +1013,7 @@ func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, use
 	var weekTopic Topic
 	if colorTime.TopicID != nil && *colorTime.TopicID != "" {
 		topic, err := s.TopicService.GetTopicInfor(ctx, *colorTime.TopicID)
-		if err != nil {
+		if err != nil && !errors.Is(err, consul.ErrNotFound) {
 			return nil, fmt.Errorf("failed to fetch topic for week: %w", err)
 		}
 		if topic != nil {
@@ -542,11 +1034,11 @@ func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, use
 	}
 
 	for _, day := range colorTime.ColorTimes {
-		if sameDay(day.Date, parsedDate) {
+		if calendar.SameDay(day.Date, parsedDate) {
 			var dayTopic Topic
 			if day.TopicID != nil && *day.TopicID != "" {
 				topic, err := s.TopicService.GetTopicInfor(ctx, *day.TopicID)
-				if err != nil {
+				if err != nil && !errors.Is(err, consul.ErrNotFound) {
 					return nil, fmt.Errorf("failed to fetch topic for day %v: %w", day.Date, err)
 				}
 				if topic != nil {
@@ -566,7 +1058,7 @@ func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, use
 				}
 			}
 
-			blockResponses, err := s.convertBlocksWithProductInfo(ctx, day.TimeSlots, day.Date, nil)
+			blockResponses, err := s.convertBlocksWithProductInfo(day.TimeSlots, calendar, day.Date, nil, s.productsForDays(ctx, []*ColorTime{day}))
 			if err != nil {
 				return nil, fmt.Errorf("failed to convert blocks for day %v: %w", day.Date, err)
 			}
@@ -582,6 +1074,7 @@ func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, use
 				Topic:     dayTopic,
 				TopicWeek: dayTopicWeek,
 				TimeSlots: blockResponses,
+				Closure:   day.Closure,
 				CreatedAt: day.CreatedAt,
 				UpdatedAt: day.UpdatedAt,
 			}, nil
@@ -591,29 +1084,6 @@ func (s *colorTimeService) GetColorTimeDay(ctx context.Context, orgID, date, use
 	return nil, nil
 }
 
-func GetWeekRangeStr(dateStr string) (string, string, error) {
-	date, err := time.Parse("2006-01-02", dateStr)
-	if err != nil {
-		return "", "", err
-	}
-
-	weekday := int(date.Weekday())
-	if weekday == 0 {
-		weekday = 7
-	}
-
-	start := date.AddDate(0, 0, -(weekday - 1))
-	end := start.AddDate(0, 0, 6)
-
-	return start.Format("2006-01-02"), end.Format("2006-01-02"), nil
-}
-
-func sameDay(a, b time.Time) bool {
-	y1, m1, d1 := a.Date()
-	y2, m2, d2 := b.Date()
-	return y1 == y2 && m1 == m2 && d1 == d2
-}
-
 func (s *colorTimeService) syncColorTimesWithDefault(colorTimes []*ColorTime, defaultDayColorTimes []*default_colortime.DefaultDayColorTime) {
 	// Create map from date to default day for quick lookup
 	defaultMap := make(map[string]*default_colortime.DefaultDayColorTime)
@@ -641,10 +1111,13 @@ func (s *colorTimeService) syncColorTimesWithDefault(colorTimes []*ColorTime, de
 					if colorSlot.SlotIDOld != nil {
 						slotIDStr := colorSlot.SlotIDOld.Hex()
 						if defaultSlot, exists := defaultSlotMap[slotIDStr]; exists {
-							// Sync fields from default to colortime
-							colorSlot.StartTime = defaultSlot.StartTime
-							colorSlot.EndTime = defaultSlot.EndTime
-							colorSlot.Duration = defaultSlot.Duration
+							// Sync fields from default to colortime, but the
+							// time of a slot moved on the student's own week
+							if !colorSlot.TimeCustomized {
+								colorSlot.StartTime = defaultSlot.StartTime
+								colorSlot.EndTime = defaultSlot.EndTime
+								colorSlot.Duration = defaultSlot.Duration
+							}
 							colorSlot.Title = defaultSlot.Title
 							colorSlot.Color = defaultSlot.Color
 							colorSlot.Note = defaultSlot.Note
@@ -716,60 +1189,92 @@ func cloneDefaultDayColorTimesToColorTimes(defaultDayColorTimes []*default_color
 
 func (s *colorTimeService) normalizeTrackingGlobal(ctx context.Context, organizationID, userID, role, tracking string) error {
 
-	slots, weeks, err := s.ColorTimeRepository.GetAllSlotsByTracking(ctx, organizationID, userID, role, tracking)
+	_, err := RenumberTrackingSeries(ctx, s.ColorTimeRepository, &TrackingKey{
+		OrganizationID: organizationID,
+		OwnerID:        userID,
+		OwnerRole:      role,
+		Tracking:       tracking,
+	}, false)
+
+	return err
+}
+
+// RenumberTrackingSeries numbers the slots of one tracking series 1..n in
+// schedule order and returns how many slots got a different use count. With
+// dryRun set nothing is written.
+func RenumberTrackingSeries(ctx context.Context, repo ColorTimeRepository, key *TrackingKey, dryRun bool) (int, error) {
+
+	occurrences, err := repo.GetTrackingOccurrences(ctx, key.OrganizationID, key.OwnerID, key.OwnerRole, key.Tracking)
 	if err != nil {
-		return fmt.Errorf("failed to get all slots by tracking: %w", err)
+		return 0, fmt.Errorf("failed to get tracking occurrences: %w", err)
 	}
 
-	sort.Slice(slots, func(i, j int) bool {
-		return slots[i].CreatedAt.Before(slots[j].CreatedAt)
-	})
+	if err := scheduleOccurrences(ctx, repo, occurrences); err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i, occurrence := range occurrences {
+		if occurrence.UseCount == i+1 {
+			continue
+		}
+		changed++
+
+		if dryRun {
+			continue
+		}
 
-	for i, slot := range slots {
-		slot.UseCount = i + 1
-		slot.UpdatedAt = time.Now()
+		err := repo.UpdateSlotFields(ctx, occurrence.WeekID, occurrence.SlotID, helper.AnyVersion, bson.M{
+			"use_count":  i + 1,
+			"updated_at": time.Now(),
+		})
+		// A slot removed meanwhile has nothing left to number
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return changed, err
+		}
 	}
 
-	for _, week := range weeks {
-		week.UpdatedAt = time.Now()
-		if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week); err != nil {
-			return err
+	return changed, nil
+}
+
+// productsForDays looks up, in one batch, the products of every slot of the
+// days. Products that could not be looked up are left out of the map.
+func (s *colorTimeService) productsForDays(ctx context.Context, days []*ColorTime) map[string]*product.Product {
+	var productIDs []string
+	for _, day := range days {
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.ProductID != nil && *slot.ProductID != "" {
+					productIDs = append(productIDs, *slot.ProductID)
+				}
+			}
 		}
 	}
 
-	return nil
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	products, err := s.ProductService.GetProductsInfor(ctx, productIDs)
+	if err != nil {
+		log.Printf("[WARN] failed to get product info: %v", err)
+	}
 
+	return products
 }
 
-func (s *colorTimeService) convertBlocksWithProductInfo(ctx context.Context, blocks []*ColorBlock, currentDate time.Time, languageID *int) ([]*BlockResponse, error) {
+// convertBlocksWithProductInfo builds the block responses of a day. Slots whose
+// product is missing from products are marked product_unavailable.
+func (s *colorTimeService) convertBlocksWithProductInfo(blocks []*ColorBlock, calendar *organization_setting.Calendar, currentDate time.Time, languageID *int, products map[string]*product.Product) ([]*BlockResponse, error) {
 	blockResponses := make([]*BlockResponse, 0, len(blocks))
 
 	for _, block := range blocks {
 		slotResponses := make([]*SlotResponse, 0, len(block.Slots))
 
 		for _, slot := range block.Slots {
-			// Combine slot time with current date
-			startDateTime := time.Date(
-				currentDate.Year(),
-				currentDate.Month(),
-				currentDate.Day(),
-				slot.StartTime.Hour(),
-				slot.StartTime.Minute(),
-				slot.StartTime.Second(),
-				slot.StartTime.Nanosecond(),
-				slot.StartTime.Location(),
-			)
-
-			endDateTime := time.Date(
-				currentDate.Year(),
-				currentDate.Month(),
-				currentDate.Day(),
-				slot.EndTime.Hour(),
-				slot.EndTime.Minute(),
-				slot.EndTime.Second(),
-				slot.EndTime.Nanosecond(),
-				slot.EndTime.Location(),
-			)
+			// Combine slot time with current date in the organization's timezone
+			startDateTime := calendar.At(currentDate, slot.StartTime)
+			endDateTime := calendar.At(currentDate, slot.EndTime)
 
 			var colorTimeSlotLanguage []*ColorTimeSlotLanguage
 			for _, slotLanguage := range slot.ColorTimeSlotLanguage {
@@ -778,9 +1283,16 @@ func (s *colorTimeService) convertBlocksWithProductInfo(ctx context.Context, blo
 				}
 			}
 
+			// Slots and blocks added on the week itself have no default
+			var slotIDOld primitive.ObjectID
+			if slot.SlotIDOld != nil {
+				slotIDOld = *slot.SlotIDOld
+			}
+
 			slotResponse := &SlotResponse{
 				SlotID:                slot.SlotID,
-				SlotIDOld:             *slot.SlotIDOld,
+				SlotIDOld:             slotIDOld,
+				GroupSlotID:           slot.GroupSlotID,
 				Sessions:              slot.Sessions,
 				Title:                 slot.Title,
 				ColorTimeSlotLanguage: colorTimeSlotLanguage,
@@ -792,23 +1304,24 @@ func (s *colorTimeService) convertBlocksWithProductInfo(ctx context.Context, blo
 				Color:                 slot.Color,
 				Note:                  slot.Note,
 				ProductID:             slot.ProductID,
+				Status:                slot.Status,
 				CreatedAt:             slot.CreatedAt,
 				UpdatedAt:             slot.UpdatedAt,
 			}
 
 			if slot.ProductID != nil && *slot.ProductID != "" {
-				product, err := s.ProductService.GetProductInfor(ctx, *slot.ProductID)
-				if err != nil {
-					fmt.Printf("Warning: failed to get product info for ID %s: %v\n", *slot.ProductID, err)
-				} else if product != nil {
+				slotProduct, ok := products[*slot.ProductID]
+				if !ok || slotProduct == nil {
+					slotResponse.ProductUnavailable = true
+				} else {
 					slotResponse.Product = &ProductInfo{
-						ID:                   product.ID.Hex(),
-						ProductName:          product.ProductName,
-						OriginalPriceStore:   product.OriginalPriceStore,
-						OriginalPriceService: product.OriginalPriceService,
-						ProductImage:         product.ProductImage,
-						TopicName:            product.TopicName,
-						CategoryName:         product.CategoryName,
+						ID:                   slotProduct.ID.Hex(),
+						ProductName:          slotProduct.ProductName,
+						OriginalPriceStore:   slotProduct.OriginalPriceStore,
+						OriginalPriceService: slotProduct.OriginalPriceService,
+						ProductImage:         slotProduct.ProductImage,
+						TopicName:            slotProduct.TopicName,
+						CategoryName:         slotProduct.CategoryName,
 					}
 				}
 			}
@@ -816,9 +1329,14 @@ func (s *colorTimeService) convertBlocksWithProductInfo(ctx context.Context, blo
 			slotResponses = append(slotResponses, slotResponse)
 		}
 
+		var blockIDOld primitive.ObjectID
+		if block.BlockIDOld != nil {
+			blockIDOld = *block.BlockIDOld
+		}
+
 		blockResponse := &BlockResponse{
 			BlockID:    block.BlockID,
-			BlockIDOld: *block.BlockIDOld,
+			BlockIDOld: blockIDOld,
 			Slots:      slotResponses,
 		}
 
@@ -856,6 +1374,22 @@ func (s *colorTimeService) mergeColorTimes(existingCT, defaultCT []*ColorTime) [
 		}
 	}
 
+	// Days whose default day was deleted
+	for _, e := range existingCT {
+		if _, exists := existingMap[e.Date.Format("2006-01-02")]; !exists {
+			continue
+		}
+
+		e.TimeSlots = retireBlocks(e.TimeSlots)
+		if len(e.TimeSlots) > 0 || e.TopicID != nil {
+			merged = append(merged, e)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Date.Before(merged[j].Date)
+	})
+
 	return merged
 }
 
@@ -870,35 +1404,88 @@ func (s *colorTimeService) mergeSingleColorTime(existingCT, defaultCT *ColorTime
 		UpdatedAt: time.Now(),
 	}
 
+	// Slots are matched across the whole day, so slots a teacher moved to
+	// another block, split off or merged in stay where they were put
+	existingSlots := make(map[string]*ColortimeSlot)
 	existingBlocksMap := make(map[string]*ColorBlock)
 	for _, b := range existingCT.TimeSlots {
-		key := ""
-		if b.BlockIDOld != nil {
-			key = b.BlockIDOld.Hex()
-		} else {
-			key = b.BlockID.Hex()
+		existingBlocksMap[blockMergeKey(b)] = b
+		for _, slot := range b.Slots {
+			existingSlots[slotMergeKey(slot)] = slot
 		}
+	}
 
-		existingBlocksMap[key] = b
+	defaultSlots := make(map[string]bool)
+	for _, defaultBlock := range defaultCT.TimeSlots {
+		for _, defaultSlot := range defaultBlock.Slots {
+			defaultSlots[defaultSlot.SlotIDOld.Hex()] = true
+		}
 	}
 
 	for _, defaultBlock := range defaultCT.TimeSlots {
 		defKey := defaultBlock.BlockIDOld.Hex()
 
 		if userBlock, exists := existingBlocksMap[defKey]; exists {
-			mergedBlock := s.mergeSingleBlock(userBlock, defaultBlock)
+			mergedBlock := s.mergeSingleBlock(userBlock, defaultBlock, existingSlots, defaultSlots)
 			mergedCT.TimeSlots = append(mergedCT.TimeSlots, mergedBlock)
 			delete(existingBlocksMap, defKey)
-		} else {
-			newBlock := cloneBlock(defaultBlock)
-			newBlock.BlockIDOld = &defaultBlock.BlockID
+			continue
+		}
+
+		if len(defaultBlock.Slots) == 0 {
+			mergedCT.TimeSlots = append(mergedCT.TimeSlots, cloneBlock(defaultBlock))
+			continue
+		}
+
+		// Only the default slots the student day has nowhere
+		newBlock := &ColorBlock{
+			BlockID:    primitive.NewObjectID(),
+			BlockIDOld: defaultBlock.BlockIDOld,
+			Slots:      make([]*ColortimeSlot, 0),
+		}
+		for _, defaultSlot := range defaultBlock.Slots {
+			if userSlot, exists := existingSlots[defaultSlot.SlotIDOld.Hex()]; exists {
+				restoreSlot(userSlot)
+				continue
+			}
+			newBlock.Slots = append(newBlock.Slots, cloneSlot(defaultSlot))
+		}
+		if len(newBlock.Slots) > 0 {
 			mergedCT.TimeSlots = append(mergedCT.TimeSlots, newBlock)
 		}
 	}
+
+	// Blocks without a default block: created by a teacher, split off, or
+	// whose default block was deleted
+	for _, b := range existingCT.TimeSlots {
+		if _, exists := existingBlocksMap[blockMergeKey(b)]; !exists {
+			continue
+		}
+
+		slots := make([]*ColortimeSlot, 0, len(b.Slots))
+		for _, slot := range b.Slots {
+			if defaultSlots[slotMergeKey(slot)] {
+				restoreSlot(slot)
+				slots = append(slots, slot)
+			} else if retired := retireSlot(slot); retired != nil {
+				slots = append(slots, retired)
+			}
+		}
+
+		// Keep empty blocks a teacher created, drop the ones emptied here
+		if len(slots) > 0 || (len(b.Slots) == 0 && b.BlockIDOld == nil) {
+			b.Slots = slots
+			mergedCT.TimeSlots = append(mergedCT.TimeSlots, b)
+		}
+	}
+
 	return mergedCT
 }
 
-func (s *colorTimeService) mergeSingleBlock(existingBlock, defaultBlock *ColorBlock) *ColorBlock {
+// mergeSingleBlock merges a default block into the student block cloned from
+// it. existingSlots holds every slot of the student day and defaultSlots every
+// default slot of the day, both by merge key.
+func (s *colorTimeService) mergeSingleBlock(existingBlock, defaultBlock *ColorBlock, existingSlots map[string]*ColortimeSlot, defaultSlots map[string]bool) *ColorBlock {
 
 	mergedBlock := &ColorBlock{
 		BlockID:    existingBlock.BlockID,
@@ -906,33 +1493,118 @@ func (s *colorTimeService) mergeSingleBlock(existingBlock, defaultBlock *ColorBl
 		Slots:      make([]*ColortimeSlot, 0),
 	}
 
-	existingSlotsMap := make(map[string]*ColortimeSlot)
-	for _, s := range existingBlock.Slots {
-		key := ""
-		if s.SlotIDOld != nil {
-			key = s.SlotIDOld.Hex()
-		} else {
-			key = s.SlotID.Hex()
-		}
-		existingSlotsMap[key] = s
+	inBlock := make(map[*ColortimeSlot]bool, len(existingBlock.Slots))
+	for _, slot := range existingBlock.Slots {
+		inBlock[slot] = true
 	}
 
+	placed := make(map[*ColortimeSlot]bool)
 	for _, defaultSlot := range defaultBlock.Slots {
-		defKey := defaultSlot.SlotIDOld.Hex()
+		userSlot, exists := existingSlots[defaultSlot.SlotIDOld.Hex()]
+		if !exists {
+			mergedBlock.Slots = append(mergedBlock.Slots, cloneSlot(defaultSlot))
+			continue
+		}
+
+		restoreSlot(userSlot)
 
-		if userSlot, exists := existingSlotsMap[defKey]; exists {
+		// Moved to another block by a teacher, it stays there
+		if inBlock[userSlot] {
 			mergedBlock.Slots = append(mergedBlock.Slots, userSlot)
-			delete(existingSlotsMap, defKey)
-		} else {
-			newSlot := cloneSlot(defaultSlot)
-			newSlot.SlotIDOld = &defaultSlot.SlotID
-			mergedBlock.Slots = append(mergedBlock.Slots, newSlot)
+			placed[userSlot] = true
+		}
+	}
+
+	// Slots moved in from another block, standalone slots, and slots whose
+	// default slot was deleted
+	for _, slot := range existingBlock.Slots {
+		if placed[slot] {
+			continue
+		}
+		if defaultSlots[slotMergeKey(slot)] {
+			mergedBlock.Slots = append(mergedBlock.Slots, slot)
+		} else if retired := retireSlot(slot); retired != nil {
+			mergedBlock.Slots = append(mergedBlock.Slots, retired)
 		}
 	}
 
 	return mergedBlock
 }
 
+// restoreSlot clears the orphaned status of a slot whose default slot is back,
+// e.g. after re-applying a template.
+func restoreSlot(slot *ColortimeSlot) {
+	if slot.Status == SlotStatusOrphaned {
+		slot.Status = ""
+		slot.OrphanedAt = nil
+	}
+}
+
+func blockMergeKey(b *ColorBlock) string {
+	if b.BlockIDOld != nil {
+		return b.BlockIDOld.Hex()
+	}
+	return b.BlockID.Hex()
+}
+
+func slotMergeKey(s *ColortimeSlot) string {
+	if s.SlotIDOld != nil {
+		return s.SlotIDOld.Hex()
+	}
+	return s.SlotID.Hex()
+}
+
+// retireBlocks applies retireSlot to blocks that no longer have a default
+// block and drops the blocks left empty.
+func retireBlocks(blocks []*ColorBlock) []*ColorBlock {
+	result := make([]*ColorBlock, 0, len(blocks))
+	for _, block := range blocks {
+		slots := make([]*ColortimeSlot, 0, len(block.Slots))
+		for _, slot := range block.Slots {
+			if retired := retireSlot(slot); retired != nil {
+				slots = append(slots, retired)
+			}
+		}
+		if len(slots) > 0 {
+			block.Slots = slots
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
+// retireSlot decides what happens to a slot whose default slot is gone. Slots
+// that never came from a default, or were kept by a teacher, stay as they are.
+// A slot carrying student data is flagged orphaned, any other slot is dropped.
+func retireSlot(slot *ColortimeSlot) *ColortimeSlot {
+	if slot.SlotIDOld == nil || slot.Status == SlotStatusDetached || slot.Status == SlotStatusOrphaned {
+		return slot
+	}
+
+	if slot.Tracking == "" && (slot.ProductID == nil || *slot.ProductID == "") {
+		return nil
+	}
+
+	now := time.Now()
+	slot.Status = SlotStatusOrphaned
+	slot.OrphanedAt = &now
+	slot.UpdatedAt = now
+	return slot
+}
+
+// hasDefaultBackedSlots reports whether the day still holds slots that a sync
+// would retire if its default day is gone.
+func hasDefaultBackedSlots(colorTime *ColorTime) bool {
+	for _, block := range colorTime.TimeSlots {
+		for _, slot := range block.Slots {
+			if slot.SlotIDOld != nil && slot.Status == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func cloneBlock(block *ColorBlock) *ColorBlock {
 	newBlock := *block
 	newBlock.BlockID = primitive.NewObjectID()
@@ -975,8 +1647,13 @@ func (s *colorTimeService) GetTopicByTerm(ctx context.Context, orgID, userID, ro
 		return nil, fmt.Errorf("failed to parse end date: %w", err)
 	}
 
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 2. Get current week number (0-based: week 0, 1, 2...)
-	currentWeekNumber := int(time.Since(startDate).Hours() / 168) // 168 hours = 1 week
+	currentWeekNumber := int(calendar.Today().Sub(startDate).Hours() / 168) // 168 hours = 1 week
 
 	// 3. Get all color time weeks for the term
 	colorTimeWeeks, err := s.ColorTimeRepository.GetColorTimeWeeksInRange(ctx, &startDate, &endDate, orgID, userID, role)
@@ -992,16 +1669,28 @@ func (s *colorTimeService) GetTopicByTerm(ctx context.Context, orgID, userID, ro
 			PreviousWeeks:     []*WeekTopicInfo{},
 			CurrentWeek:       nil,
 			UpcomingWeeks:     []*WeekTopicInfo{},
+			FailedWeeks:       []*WeekTopicError{},
 		}, nil
 	}
 
 	var previousWeeks []*WeekTopicInfo
 	var currentWeek *WeekTopicInfo
 	var upcomingWeeks []*WeekTopicInfo
+	failedWeeks := []*WeekTopicError{}
 
+	// 4. Keep the first week of each week number, then load its topic
+	var weeks []*WeekColorTime
+	var weekNums []int
+	var topicIDs []string
 	processedWeeks := make(map[int]bool)
+	processedTopics := make(map[string]bool)
 
 	for _, colorTimeWeek := range colorTimeWeeks {
+		colorTimeWeek, err := s.overlayGroupWeek(ctx, colorTimeWeek, false)
+		if err != nil {
+			return nil, err
+		}
+
 		// Calculate which week this colorTimeWeek belongs to
 		weekNum := int(colorTimeWeek.StartDate.Sub(startDate).Hours() / 168)
 
@@ -1011,48 +1700,74 @@ func (s *colorTimeService) GetTopicByTerm(ctx context.Context, orgID, userID, ro
 		}
 		processedWeeks[weekNum] = true
 
-		// Extract topic info
-		var topicInfo *WeekTopicInfo
-		if colorTimeWeek.TopicID != nil && *colorTimeWeek.TopicID != "" {
-			topic, err := s.TopicService.GetTopicInfor(ctx, *colorTimeWeek.TopicID)
-			if err == nil && topic != nil {
-				// Get vocabularies for this topic
-				var vocabularies []*VocabularyResponse
-				vocabs, vocabErr := s.TopicService.GetVocabularyInforByTopicID(ctx, *colorTimeWeek.TopicID)
-				if vocabErr == nil && vocabs != nil {
-					for _, vocab := range vocabs {
-						vocabularies = append(vocabularies, &VocabularyResponse{
-							ID:           vocab.ID,
-							Title:        vocab.Title,
-							MainImageUrl: vocab.MainImageUrl,
-						})
-					}
-				} else {
-					vocabularies = []*VocabularyResponse{}
-				}
+		if colorTimeWeek.TopicID == nil || *colorTimeWeek.TopicID == "" {
+			continue
+		}
 
-				topicInfo = &WeekTopicInfo{
-					WeekNumber:   weekNum,
-					StartDate:    colorTimeWeek.StartDate,
-					EndDate:      colorTimeWeek.EndDate,
-					TopicID:      topic.ID,
-					TopicName:    topic.Name,
-					MainImageUrl: topic.MainImageUrl,
-					VideoUrl:     topic.VideoUrl,
-					Vocabularies: vocabularies,
-				}
-			}
-			// if err != nil or topic == nil -> we silently skip (mimic original behavior)
+		weeks = append(weeks, colorTimeWeek)
+		weekNums = append(weekNums, weekNum)
+		if !processedTopics[*colorTimeWeek.TopicID] {
+			processedTopics[*colorTimeWeek.TopicID] = true
+			topicIDs = append(topicIDs, *colorTimeWeek.TopicID)
 		}
+	}
 
-		if topicInfo != nil {
-			if weekNum < currentWeekNumber {
-				previousWeeks = append(previousWeeks, topicInfo)
-			} else if weekNum == currentWeekNumber {
-				currentWeek = topicInfo
-			} else {
-				upcomingWeeks = append(upcomingWeeks, topicInfo)
-			}
+	lookups := s.lookupTopics(ctx, topicIDs)
+
+	// 5. Build the week topics, reporting the weeks that could not be loaded
+	for i, colorTimeWeek := range weeks {
+		weekNum := weekNums[i]
+		lookup := lookups[*colorTimeWeek.TopicID]
+
+		if lookup.topicErr != nil {
+			failedWeeks = append(failedWeeks, &WeekTopicError{
+				WeekNumber: weekNum,
+				StartDate:  colorTimeWeek.StartDate,
+				EndDate:    colorTimeWeek.EndDate,
+				TopicID:    *colorTimeWeek.TopicID,
+				Part:       "topic",
+				Error:      lookup.topicErr.Error(),
+			})
+			continue
+		}
+
+		if lookup.vocabErr != nil {
+			failedWeeks = append(failedWeeks, &WeekTopicError{
+				WeekNumber: weekNum,
+				StartDate:  colorTimeWeek.StartDate,
+				EndDate:    colorTimeWeek.EndDate,
+				TopicID:    *colorTimeWeek.TopicID,
+				Part:       "vocabularies",
+				Error:      lookup.vocabErr.Error(),
+			})
+		}
+
+		vocabularies := make([]*VocabularyResponse, 0, len(lookup.vocabularies))
+		for _, vocab := range lookup.vocabularies {
+			vocabularies = append(vocabularies, &VocabularyResponse{
+				ID:           vocab.ID,
+				Title:        vocab.Title,
+				MainImageUrl: vocab.MainImageUrl,
+			})
+		}
+
+		topicInfo := &WeekTopicInfo{
+			WeekNumber:   weekNum,
+			StartDate:    colorTimeWeek.StartDate,
+			EndDate:      colorTimeWeek.EndDate,
+			TopicID:      lookup.topic.ID,
+			TopicName:    lookup.topic.Name,
+			MainImageUrl: lookup.topic.MainImageUrl,
+			VideoUrl:     lookup.topic.VideoUrl,
+			Vocabularies: vocabularies,
+		}
+
+		if weekNum < currentWeekNumber {
+			previousWeeks = append(previousWeeks, topicInfo)
+		} else if weekNum == currentWeekNumber {
+			currentWeek = topicInfo
+		} else {
+			upcomingWeeks = append(upcomingWeeks, topicInfo)
 		}
 	}
 
@@ -1063,6 +1778,9 @@ func (s *colorTimeService) GetTopicByTerm(ctx context.Context, orgID, userID, ro
 	sort.Slice(upcomingWeeks, func(i, j int) bool {
 		return upcomingWeeks[i].WeekNumber < upcomingWeeks[j].WeekNumber
 	})
+	sort.Slice(failedWeeks, func(i, j int) bool {
+		return failedWeeks[i].WeekNumber < failedWeeks[j].WeekNumber
+	})
 
 	// 6. Return TopicByTermResponse
 	return &TopicByTermResponse{
@@ -1072,5 +1790,63 @@ func (s *colorTimeService) GetTopicByTerm(ctx context.Context, orgID, userID, ro
 		PreviousWeeks:     previousWeeks,
 		CurrentWeek:       currentWeek,
 		UpcomingWeeks:     upcomingWeeks,
+		FailedWeeks:       failedWeeks,
 	}, nil
 }
+
+// Most topics of a term looked up at the same time
+const topicLookupWorkers = 4
+
+type topicLookup struct {
+	topic        *topic.Topic
+	topicErr     error
+	vocabularies []*topic.Vocabulary
+	vocabErr     error
+}
+
+// lookupTopics loads the topics and their vocabularies concurrently. A topic
+// deleted in media-service gets an error matching consul.ErrNotFound, a topic
+// without vocabularies no error.
+func (s *colorTimeService) lookupTopics(ctx context.Context, topicIDs []string) map[string]*topicLookup {
+	results := make([]*topicLookup, len(topicIDs))
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, topicLookupWorkers)
+
+	for i, topicID := range topicIDs {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int, topicID string) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			lookup := &topicLookup{}
+			results[i] = lookup
+
+			topic, err := s.TopicService.GetTopicInfor(ctx, topicID)
+			if err == nil && topic == nil {
+				err = consul.ErrNotFound
+			}
+			if err != nil {
+				lookup.topicErr = err
+				return
+			}
+			lookup.topic = topic
+
+			vocabularies, err := s.TopicService.GetVocabularyInforByTopicID(ctx, topicID)
+			if err != nil && !errors.Is(err, consul.ErrNotFound) {
+				lookup.vocabErr = err
+			}
+			lookup.vocabularies = vocabularies
+		}(i, topicID)
+	}
+
+	wg.Wait()
+
+	lookups := make(map[string]*topicLookup, len(topicIDs))
+	for i, topicID := range topicIDs {
+		lookups[topicID] = results[i]
+	}
+
+	return lookups
+}
@@ -0,0 +1,246 @@
+package colortime
+
+import (
+	"colortime-service/internal/default_colortime"
+	"colortime-service/internal/term"
+	"colortime-service/internal/topic"
+	"colortime-service/pkg/consul"
+	"colortime-service/pkg/slotblock"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMergeMemberWeekTakesGroupSlotFields(t *testing.T) {
+	startDate := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	groupSlotID := primitive.NewObjectID()
+	productID := "product-1"
+
+	groupSlot := &ColortimeSlot{
+		SlotID:    groupSlotID,
+		Title:     "Circle time",
+		StartTime: startDate.Add(8 * time.Hour),
+		EndTime:   startDate.Add(9 * time.Hour),
+		Color:     "#ff0000",
+	}
+	groupWeek := &WeekColorTime{
+		ColorTimes: []*ColorTime{{
+			Date:      startDate,
+			TimeSlots: []*ColorBlock{{BlockID: primitive.NewObjectID(), Slots: []*ColortimeSlot{groupSlot}}},
+		}},
+	}
+
+	override := &ColortimeSlot{
+		SlotID:      primitive.NewObjectID(),
+		GroupSlotID: &groupSlotID,
+		Tracking:    "T1",
+		UseCount:    2,
+		ProductID:   &productID,
+	}
+	memberWeek := &WeekColorTime{
+		ColorTimes: []*ColorTime{{
+			Date:      startDate,
+			TimeSlots: []*ColorBlock{{BlockID: groupWeek.ColorTimes[0].TimeSlots[0].BlockID, Slots: []*ColortimeSlot{override}}},
+		}},
+	}
+
+	// The teacher edits the group slot after the member overrode it
+	groupSlot.Title = "Morning circle"
+	groupSlot.StartTime = startDate.Add(7 * time.Hour)
+	groupSlot.Color = "#00ff00"
+
+	slot := mergeMemberWeek(groupWeek, memberWeek).ColorTimes[0].TimeSlots[0].Slots[0]
+
+	if slot.Title != "Morning circle" || !slot.StartTime.Equal(groupSlot.StartTime) || slot.Color != "#00ff00" {
+		t.Errorf("merged slot = %q at %s in %s, want the group slot's title, time and color", slot.Title, slot.StartTime, slot.Color)
+	}
+	if slot.SlotID != override.SlotID || slot.Tracking != "T1" || slot.UseCount != 2 || slot.ProductID == nil || *slot.ProductID != productID {
+		t.Errorf("merged slot lost the member's fields: %+v", slot)
+	}
+}
+
+func TestRenumberTrackingSeriesOrdersMemberOverridesByGroupSlot(t *testing.T) {
+	const orgID, groupID, memberID = "org-1", "group-1", "student-1"
+
+	startDate := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 0, 6)
+	morningSlotID := primitive.NewObjectID()
+	afternoonSlotID := primitive.NewObjectID()
+
+	groupWeek := &WeekColorTime{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: orgID,
+		Owner:          &Owner{OwnerID: groupID, OwnerRole: OwnerRoleGroup},
+		StartDate:      startDate,
+		EndDate:        endDate,
+		ColorTimes: []*ColorTime{{
+			Date: startDate,
+			TimeSlots: []*ColorBlock{{
+				BlockID: primitive.NewObjectID(),
+				Slots: []*ColortimeSlot{
+					{SlotID: morningSlotID, Title: "Circle time", StartTime: startDate.Add(8 * time.Hour)},
+					{SlotID: afternoonSlotID, Title: "Outdoor play", StartTime: startDate.Add(14 * time.Hour)},
+				},
+			}},
+		}},
+	}
+
+	// The afternoon slot was tracked first, so creation order is the reverse
+	// of the schedule
+	afternoon := &ColortimeSlot{SlotID: primitive.NewObjectID(), GroupSlotID: &afternoonSlotID, Tracking: "T", UseCount: 1, CreatedAt: startDate}
+	morning := &ColortimeSlot{SlotID: primitive.NewObjectID(), GroupSlotID: &morningSlotID, Tracking: "T", UseCount: 2, CreatedAt: startDate.Add(time.Hour)}
+	memberWeek := &WeekColorTime{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: orgID,
+		Owner:          &Owner{OwnerID: memberID, OwnerRole: "student"},
+		GroupID:        &[]string{groupID}[0],
+		StartDate:      startDate,
+		EndDate:        endDate,
+		ColorTimes: []*ColorTime{{
+			Date: startDate,
+			TimeSlots: []*ColorBlock{{
+				BlockID: groupWeek.ColorTimes[0].TimeSlots[0].BlockID,
+				Slots:   []*ColortimeSlot{afternoon, morning},
+			}},
+		}},
+	}
+
+	repo := newFakeColorTimeRepository(groupWeek, memberWeek)
+	key := &TrackingKey{OrganizationID: orgID, OwnerID: memberID, OwnerRole: "student", Tracking: "T"}
+
+	changed, err := RenumberTrackingSeries(context.Background(), repo, key, false)
+	if err != nil {
+		t.Fatalf("RenumberTrackingSeries: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("changed = %d, want 2", changed)
+	}
+
+	want := map[primitive.ObjectID]int{morning.SlotID: 1, afternoon.SlotID: 2}
+	for _, slot := range repo.weeks[memberWeek.ID].ColorTimes[0].TimeSlots[0].Slots {
+		if slot.UseCount != want[slot.SlotID] {
+			t.Errorf("slot %s use count = %d, want %d", slot.SlotID.Hex(), slot.UseCount, want[slot.SlotID])
+		}
+	}
+}
+
+func TestSyncKeepsTimeOfMovedSlots(t *testing.T) {
+	date := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	clock := func(hour int) time.Time { return time.Date(0, 1, 1, hour, 0, 0, 0, time.UTC) }
+
+	movedDefaultID, keptDefaultID := primitive.NewObjectID(), primitive.NewObjectID()
+	defaultDay := &default_colortime.DefaultDayColorTime{
+		Date: date,
+		TimeSlots: []*default_colortime.DefaultColorBlock{{
+			BlockID: primitive.NewObjectID(),
+			Slots: []*default_colortime.DefaultColortimeSlot{
+				{SlotID: movedDefaultID, Title: "Circle time", StartTime: clock(8), EndTime: clock(9)},
+				{SlotID: keptDefaultID, Title: "Outdoor play", StartTime: clock(9), EndTime: clock(10)},
+			},
+		}},
+	}
+
+	moved := &ColortimeSlot{SlotID: primitive.NewObjectID(), SlotIDOld: &movedDefaultID, Title: "Circle time", StartTime: clock(8), EndTime: clock(9)}
+	kept := &ColortimeSlot{SlotID: primitive.NewObjectID(), SlotIDOld: &keptDefaultID, Title: "Outdoor play", StartTime: clock(9), EndTime: clock(10)}
+	days := []*ColorTime{{Date: date, TimeSlots: []*ColorBlock{{Slots: []*ColortimeSlot{moved, kept}}}}}
+
+	if err := slotblock.Move([]*ColortimeSlot{moved}, "14:00"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+
+	// The default day is edited afterwards
+	defaultDay.TimeSlots[0].Slots[0].Title = "Morning circle"
+	defaultDay.TimeSlots[0].Slots[1].StartTime = clock(10)
+	defaultDay.TimeSlots[0].Slots[1].EndTime = clock(11)
+
+	(&colorTimeService{}).syncColorTimesWithDefault(days, []*default_colortime.DefaultDayColorTime{defaultDay})
+
+	if !moved.StartTime.Equal(clock(14)) || !moved.EndTime.Equal(clock(15)) {
+		t.Errorf("moved slot synced back to %s-%s, want 14:00-15:00", moved.StartTime.Format("15:04"), moved.EndTime.Format("15:04"))
+	}
+	if moved.Title != "Morning circle" {
+		t.Errorf("moved slot title = %q, want the default title", moved.Title)
+	}
+	if !kept.StartTime.Equal(clock(10)) {
+		t.Errorf("slot left in place starts at %s, want the default time 10:00", kept.StartTime.Format("15:04"))
+	}
+}
+
+type fakeTermService struct {
+	term.TermService
+	current *term.TermInfor
+}
+
+func (f fakeTermService) GetCurrentTermByOrgID(ctx context.Context, orgID string) (*term.TermInfor, error) {
+	return f.current, nil
+}
+
+// fakeTopicService knows the topics in topics; any other id is not found.
+type fakeTopicService struct {
+	topic.TopicService
+	topics map[string]*topic.Topic
+}
+
+func (f fakeTopicService) GetTopicInfor(ctx context.Context, topicID string) (*topic.Topic, error) {
+	if found, exists := f.topics[topicID]; exists {
+		return found, nil
+	}
+	return nil, fmt.Errorf("topic %s: %w", topicID, consul.ErrNotFound)
+}
+
+func (f fakeTopicService) GetVocabularyInforByTopicID(ctx context.Context, topicID string) ([]*topic.Vocabulary, error) {
+	return nil, consul.ErrNotFound
+}
+
+func TestGetTopicByTermReportsDeletedTopics(t *testing.T) {
+	const orgID, userID = "org-1", "teacher-1"
+
+	termStart := time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)
+	owner := &Owner{OwnerID: userID, OwnerRole: "teacher"}
+	week := func(weekNum int, topicID string) *WeekColorTime {
+		startDate := termStart.AddDate(0, 0, 7*weekNum)
+		return &WeekColorTime{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: orgID,
+			Owner:          owner,
+			StartDate:      startDate,
+			EndDate:        startDate.AddDate(0, 0, 6),
+			TopicID:        &topicID,
+		}
+	}
+
+	service := &colorTimeService{
+		ColorTimeRepository:        newFakeColorTimeRepository(week(0, "farm"), week(1, "deleted")),
+		TermService:                fakeTermService{current: &term.TermInfor{ID: "term-1", StartDate: "2026-09-07", EndDate: "2027-01-15"}},
+		TopicService:               fakeTopicService{topics: map[string]*topic.Topic{"farm": {ID: "farm", Name: "Farm"}}},
+		OrganizationSettingService: fakeOrganizationSettingService{},
+	}
+
+	res, err := service.GetTopicByTerm(context.Background(), orgID, userID, "teacher")
+	if err != nil {
+		t.Fatalf("GetTopicByTerm: %v", err)
+	}
+
+	listed := len(res.PreviousWeeks) + len(res.UpcomingWeeks)
+	if res.CurrentWeek != nil {
+		listed++
+	}
+	if listed != 1 {
+		t.Errorf("%d weeks listed, want only the week whose topic exists", listed)
+	}
+
+	if len(res.FailedWeeks) != 1 {
+		t.Fatalf("failed weeks = %+v, want the week of the deleted topic", res.FailedWeeks)
+	}
+	failed := res.FailedWeeks[0]
+	if failed.WeekNumber != 1 || failed.TopicID != "deleted" || failed.Part != "topic" {
+		t.Errorf("failed week = %+v, want week 1, topic deleted, part topic", failed)
+	}
+	if !strings.Contains(failed.Error, consul.ErrNotFound.Error()) {
+		t.Errorf("failed week error = %q, want the not found reason", failed.Error)
+	}
+}
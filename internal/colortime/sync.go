@@ -0,0 +1,532 @@
+package colortime
+
+import (
+	"colortime-service/internal/closure"
+	"colortime-service/internal/default_colortime"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	weekSyncQueueSize    = 1024
+	propagationQueueSize = 64
+	syncJobListLimit     = 50
+
+	// How often jobs left pending by a full propagation queue are picked up
+	syncJobPollInterval = time.Minute
+)
+
+type weekSyncJob struct {
+	OrganizationID string
+	Owner          Owner
+	GroupID        string // set for a group member, whose week is linked to the group week
+	StartDate      time.Time
+	EndDate        time.Time
+}
+
+func (j weekSyncJob) key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s", j.OrganizationID, j.Owner.OwnerID, j.Owner.OwnerRole, j.GroupID,
+		j.StartDate.Format("2006-01-02"), j.EndDate.Format("2006-01-02"))
+}
+
+// weekSyncQueue de-duplicates pending syncs so a week viewed many times while
+// stale is only written once.
+type weekSyncQueue struct {
+	jobs    chan weekSyncJob
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func newWeekSyncQueue() *weekSyncQueue {
+	return &weekSyncQueue{
+		jobs:    make(chan weekSyncJob, weekSyncQueueSize),
+		pending: make(map[string]bool),
+	}
+}
+
+func (q *weekSyncQueue) enqueue(job weekSyncJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending[job.key()] {
+		return
+	}
+
+	select {
+	case q.jobs <- job:
+		q.pending[job.key()] = true
+	default:
+		log.Printf("[WARN] colortime sync queue is full, dropping sync for %s", job.key())
+	}
+}
+
+func (q *weekSyncQueue) done(job weekSyncJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, job.key())
+}
+
+// RunSyncWorker drains the sync queue and the default change propagation jobs
+// until ctx is cancelled. Unfinished jobs, left by a previous run or not
+// queued because the queue was full, are resumed at start and then polled.
+func (s *colorTimeService) RunSyncWorker(ctx context.Context) {
+	s.resumeSyncJobs(ctx)
+
+	ticker := time.NewTicker(syncJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.resumeSyncJobs(ctx)
+		case jobID := <-s.propagationJobs:
+			s.runSyncJob(ctx, jobID)
+		case job := <-s.syncQueue.jobs:
+			owner := job.Owner
+			var err error
+			if job.GroupID != "" {
+				_, err = s.syncMemberWeek(ctx, job.OrganizationID, &owner, job.GroupID, job.StartDate, job.EndDate)
+			} else {
+				_, err = s.syncWeek(ctx, job.OrganizationID, &owner, job.StartDate, job.EndDate)
+			}
+			if err != nil {
+				log.Printf("[ERROR] colortime sync failed for %s: %v", job.key(), err)
+			}
+			s.syncQueue.done(job)
+		}
+	}
+}
+
+func (s *colorTimeService) SyncColorTimeWeek(ctx context.Context, req *SyncColorTimeWeekRequest) (*WeekColorTime, error) {
+
+	if req.OrganizationID == "" {
+		return nil, fmt.Errorf("organization id is required")
+	}
+
+	if req.UserID == "" {
+		return nil, fmt.Errorf("user id is required")
+	}
+
+	if req.Role == "" {
+		return nil, fmt.Errorf("role is required")
+	}
+
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, req.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, err := calendar.ParseDate(req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %w", err)
+	}
+
+	endDate, err := calendar.ParseDate(req.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date: %w", err)
+	}
+
+	owner := &Owner{OwnerID: req.UserID, OwnerRole: req.Role}
+
+	if req.GroupID != "" && req.Role != OwnerRoleGroup {
+		group, err := s.UserService.GetGroupInfor(ctx, req.GroupID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !group.HasMember(req.UserID) {
+			return nil, fmt.Errorf("user %s is not a member of group %s", req.UserID, req.GroupID)
+		}
+
+		return s.syncMemberWeek(ctx, req.OrganizationID, owner, req.GroupID, startDate, endDate)
+	}
+
+	return s.syncWeek(ctx, req.OrganizationID, owner, startDate, endDate)
+}
+
+// syncMemberWeek stores the week of a group member linked to the group week,
+// syncing the group week first. A week stored before the member joined the
+// group keeps its own slots as overrides only where the member touched them.
+func (s *colorTimeService) syncMemberWeek(ctx context.Context, orgID string, owner *Owner, groupID string, startDate, endDate time.Time) (*WeekColorTime, error) {
+
+	existingWeek, err := s.ColorTimeRepository.GetColorTimeWeek(ctx, &startDate, &endDate, orgID, owner.OwnerID, owner.OwnerRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing week: %w", err)
+	}
+
+	if existingWeek != nil && existingWeek.GroupID != nil && *existingWeek.GroupID == groupID {
+		return existingWeek, nil
+	}
+
+	groupWeek, err := s.syncWeek(ctx, orgID, &Owner{OwnerID: groupID, OwnerRole: OwnerRoleGroup}, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group week: %w", err)
+	}
+
+	if existingWeek == nil {
+		memberWeek := newMemberWeek(orgID, owner, groupID, startDate, endDate)
+		if err := s.ColorTimeRepository.CreateColorTimeWeek(ctx, memberWeek); err != nil {
+			// Stored meanwhile by a concurrent sync, under the same id
+			if mongo.IsDuplicateKeyError(err) {
+				return s.ColorTimeRepository.GetColorTimeWeekByID(ctx, memberWeek.ID)
+			}
+			return nil, err
+		}
+		return memberWeek, nil
+	}
+
+	existingWeek.GroupID = &groupID
+	existingWeek.ColorTimes = extractMemberOverrides(existingWeek.ColorTimes, groupWeek)
+	existingWeek.UpdatedAt = time.Now()
+
+	if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, existingWeek.ID, existingWeek); err != nil {
+		return nil, fmt.Errorf("failed to link week to group: %w", err)
+	}
+
+	return existingWeek, nil
+}
+
+// loadWeek returns the stored week for the owner without writing it. A week
+// not stored yet is built from the defaults and a copy older than its defaults
+// is merged with them, both in memory, and queued for a background sync.
+func (s *colorTimeService) loadWeek(ctx context.Context, orgID string, owner *Owner, startDate, endDate time.Time) (*WeekColorTime, error) {
+
+	existingWeek, err := s.ColorTimeRepository.GetColorTimeWeek(ctx, &startDate, &endDate, orgID, owner.OwnerID, owner.OwnerRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing week: %w", err)
+	}
+
+	// Member weeks only hold overrides, the defaults live on the group week
+	if existingWeek != nil && existingWeek.GroupID != nil {
+		return existingWeek, nil
+	}
+
+	defaultDayColorTimes, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, startDate, endDate, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default day colortimes: %w", err)
+	}
+
+	if existingWeek == nil {
+		existingWeek = newWeek(orgID, owner, startDate, endDate)
+	} else if !isWeekStale(existingWeek, defaultDayColorTimes) {
+		return existingWeek, nil
+	}
+
+	s.syncQueue.enqueue(weekSyncJob{
+		OrganizationID: orgID,
+		Owner:          *owner,
+		StartDate:      startDate,
+		EndDate:        endDate,
+	})
+
+	s.applyDefaultsToWeek(existingWeek, defaultDayColorTimes)
+
+	return existingWeek, nil
+}
+
+// syncWeek materializes the owner's week from the default days. It is
+// idempotent: a week that is already up to date is returned without a write.
+func (s *colorTimeService) syncWeek(ctx context.Context, orgID string, owner *Owner, startDate, endDate time.Time) (*WeekColorTime, error) {
+
+	defaultDayColorTimes, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, startDate, endDate, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default day colortimes: %w", err)
+	}
+
+	existingWeek, err := s.ColorTimeRepository.GetColorTimeWeek(ctx, &startDate, &endDate, orgID, owner.OwnerID, owner.OwnerRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing week: %w", err)
+	}
+
+	if existingWeek != nil {
+		if err := s.saveSyncedWeek(ctx, existingWeek, defaultDayColorTimes); err != nil {
+			return nil, err
+		}
+		return existingWeek, nil
+	}
+
+	newColorTimeWeek := newWeek(orgID, owner, startDate, endDate)
+	newColorTimeWeek.SyncedAt = time.Now()
+
+	s.applyDefaultsToWeek(newColorTimeWeek, defaultDayColorTimes)
+
+	if err := s.ColorTimeRepository.CreateColorTimeWeek(ctx, newColorTimeWeek); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		// Stored meanwhile by a concurrent sync
+		existingWeek, err := s.ColorTimeRepository.GetColorTimeWeek(ctx, &startDate, &endDate, orgID, owner.OwnerID, owner.OwnerRole)
+		if err != nil || existingWeek == nil {
+			return nil, fmt.Errorf("failed to get week stored by a concurrent sync: %w", err)
+		}
+		if err := s.saveSyncedWeek(ctx, existingWeek, defaultDayColorTimes); err != nil {
+			return nil, err
+		}
+		return existingWeek, nil
+	}
+
+	return newColorTimeWeek, nil
+}
+
+// newWeek returns an empty week of an owner not linked to a group, not yet
+// stored.
+func newWeek(orgID string, owner *Owner, startDate, endDate time.Time) *WeekColorTime {
+	return &WeekColorTime{
+		ID:             weekID(orgID, owner, "", startDate),
+		OrganizationID: orgID,
+		Owner:          owner,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		TopicID:        nil,
+		ColorTimes:     []*ColorTime{},
+		CreatedBy:      owner.OwnerID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+}
+
+// saveSyncedWeek applies the default days to a stored week and writes it back.
+// Member weeks and weeks that are already up to date are left untouched.
+func (s *colorTimeService) saveSyncedWeek(ctx context.Context, week *WeekColorTime, defaultDayColorTimes []*default_colortime.DefaultDayColorTime) error {
+	if week.GroupID != nil || !isWeekStale(week, defaultDayColorTimes) {
+		return nil
+	}
+
+	s.applyDefaultsToWeek(week, defaultDayColorTimes)
+	week.SyncedAt = time.Now()
+	week.UpdatedAt = time.Now()
+
+	if err := s.ColorTimeRepository.UpdateColorTimeWeek(ctx, week.ID, week); err != nil {
+		return fmt.Errorf("failed to update existing week: %w", err)
+	}
+
+	return nil
+}
+
+func (s *colorTimeService) applyDefaultsToWeek(week *WeekColorTime, defaultDayColorTimes []*default_colortime.DefaultDayColorTime) {
+	colorTimes := cloneDefaultDayColorTimesToColorTimes(defaultDayColorTimes)
+	week.ColorTimes = s.mergeColorTimes(week.ColorTimes, colorTimes)
+
+	// Sync with latest default data
+	s.syncColorTimesWithDefault(week.ColorTimes, defaultDayColorTimes)
+
+	closures := make(map[string]*closure.DayClosure, len(defaultDayColorTimes))
+	for _, defaultDay := range defaultDayColorTimes {
+		closures[defaultDay.Date.Format("2006-01-02")] = defaultDay.Closure
+	}
+	for _, day := range week.ColorTimes {
+		day.Closure = closures[day.Date.Format("2006-01-02")]
+	}
+}
+
+// isWeekStale reports whether a default day was changed after the week was last
+// synced, a default day was added or removed since then, or a closure of one
+// of its days was added, changed or removed.
+func isWeekStale(week *WeekColorTime, defaultDayColorTimes []*default_colortime.DefaultDayColorTime) bool {
+	weekDays := make(map[string]*ColorTime, len(week.ColorTimes))
+	for _, day := range week.ColorTimes {
+		weekDays[day.Date.Format("2006-01-02")] = day
+	}
+
+	defaultDays := make(map[string]bool, len(defaultDayColorTimes))
+	for _, defaultDay := range defaultDayColorTimes {
+		dateStr := defaultDay.Date.Format("2006-01-02")
+		defaultDays[dateStr] = true
+
+		if defaultDay.UpdatedAt.After(week.SyncedAt) {
+			return true
+		}
+		weekDay, exists := weekDays[dateStr]
+		if !exists || !weekDay.Closure.Same(defaultDay.Closure) {
+			return true
+		}
+	}
+
+	for _, day := range week.ColorTimes {
+		if !defaultDays[day.Date.Format("2006-01-02")] && (hasDefaultBackedSlots(day) || day.Closure != nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NotifyDefaultDayChanged records a job that pushes the changed default days
+// into every stored week of the organization covering one of the dates.
+func (s *colorTimeService) NotifyDefaultDayChanged(ctx context.Context, organizationID string, dates []time.Time) {
+	if organizationID == "" || len(dates) == 0 {
+		return
+	}
+
+	job := &SyncJob{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		Dates:          dates,
+		Status:         SyncJobStatusPending,
+		Failures:       []*SyncJobFailure{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := s.SyncJobRepository.CreateSyncJob(ctx, job); err != nil {
+		log.Printf("[ERROR] failed to create colortime sync job for organization %s: %v", organizationID, err)
+		return
+	}
+
+	select {
+	case s.propagationJobs <- job.ID:
+	default:
+		// Left pending, the worker picks it up on its next poll
+		log.Printf("[WARN] colortime propagation queue is full, sync job %s stays pending", job.ID.Hex())
+	}
+}
+
+func (s *colorTimeService) GetSyncJob(ctx context.Context, id string) (*SyncJob, error) {
+
+	if id == "" {
+		return nil, fmt.Errorf("sync job id is required")
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync job id: %w", err)
+	}
+
+	return s.SyncJobRepository.GetSyncJobByID(ctx, objectID)
+}
+
+func (s *colorTimeService) GetSyncJobs(ctx context.Context, organizationID string) ([]*SyncJob, error) {
+
+	if organizationID == "" {
+		return nil, fmt.Errorf("organization id is required")
+	}
+
+	return s.SyncJobRepository.GetSyncJobs(ctx, organizationID, syncJobListLimit)
+}
+
+func (s *colorTimeService) resumeSyncJobs(ctx context.Context) {
+	jobs, err := s.SyncJobRepository.GetUnfinishedSyncJobs(ctx)
+	if err != nil {
+		log.Printf("[ERROR] failed to load unfinished colortime sync jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		s.runSyncJob(ctx, job.ID)
+	}
+}
+
+// runSyncJob syncs every week touched by the job. A failing week is recorded
+// on the job and does not stop the remaining weeks.
+func (s *colorTimeService) runSyncJob(ctx context.Context, jobID primitive.ObjectID) {
+
+	job, err := s.SyncJobRepository.GetSyncJobByID(ctx, jobID)
+	if err != nil || job == nil {
+		log.Printf("[ERROR] failed to load colortime sync job %s: %v", jobID.Hex(), err)
+		return
+	}
+
+	// Already run by a poll while it waited in the queue
+	if job.Status == SyncJobStatusCompleted || job.Status == SyncJobStatusFailed {
+		return
+	}
+
+	weeks, err := s.getWeeksForDates(ctx, job.OrganizationID, job.Dates)
+	if err != nil {
+		log.Printf("[ERROR] colortime sync job %s failed: %v", jobID.Hex(), err)
+		if err := s.SyncJobRepository.FinishSyncJob(ctx, jobID, SyncJobStatusFailed); err != nil {
+			log.Printf("[ERROR] failed to finish colortime sync job %s: %v", jobID.Hex(), err)
+		}
+		return
+	}
+
+	if err := s.SyncJobRepository.StartSyncJob(ctx, jobID, len(weeks)); err != nil {
+		log.Printf("[ERROR] failed to start colortime sync job %s: %v", jobID.Hex(), err)
+		return
+	}
+
+	failed := 0
+	for _, week := range weeks {
+		if ctx.Err() != nil {
+			// Stays running, resumed by the next worker
+			return
+		}
+
+		var failure *SyncJobFailure
+		if err := s.syncStoredWeek(ctx, week); err != nil {
+			failed++
+			failure = &SyncJobFailure{
+				WeekID: week.ID,
+				Owner:  week.Owner,
+				Error:  err.Error(),
+			}
+		}
+
+		if err := s.SyncJobRepository.RecordSyncJobProgress(ctx, jobID, failure); err != nil {
+			log.Printf("[ERROR] failed to record progress of colortime sync job %s: %v", jobID.Hex(), err)
+		}
+	}
+
+	status := SyncJobStatusCompleted
+	if failed > 0 {
+		status = SyncJobStatusFailed
+	}
+
+	if err := s.SyncJobRepository.FinishSyncJob(ctx, jobID, status); err != nil {
+		log.Printf("[ERROR] failed to finish colortime sync job %s: %v", jobID.Hex(), err)
+	}
+}
+
+// getWeeksForDates returns the stored weeks covering any of the dates, leaving
+// out member weeks since they read their defaults from the group week.
+func (s *colorTimeService) getWeeksForDates(ctx context.Context, organizationID string, dates []time.Time) ([]*WeekColorTime, error) {
+
+	seen := make(map[primitive.ObjectID]bool)
+	var result []*WeekColorTime
+
+	for _, date := range dates {
+		weeks, err := s.ColorTimeRepository.GetColorTimeWeeksByDate(ctx, date, organizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get weeks for %s: %w", date.Format("2006-01-02"), err)
+		}
+
+		for _, week := range weeks {
+			if week.GroupID != nil || seen[week.ID] {
+				continue
+			}
+			seen[week.ID] = true
+			result = append(result, week)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *colorTimeService) syncStoredWeek(ctx context.Context, week *WeekColorTime) error {
+
+	// Reload so edits made since the job listed the weeks are not overwritten
+	week, err := s.ColorTimeRepository.GetColorTimeWeekByID(ctx, week.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get week: %w", err)
+	}
+
+	if week == nil {
+		return nil
+	}
+
+	defaultDayColorTimes, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, week.StartDate, week.EndDate, week.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("failed to get default day colortimes: %w", err)
+	}
+
+	return s.saveSyncedWeek(ctx, week, defaultDayColorTimes)
+}
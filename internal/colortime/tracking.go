@@ -0,0 +1,210 @@
+package colortime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTrackingSeries lists the owner's tracking codes, each with its slots in
+// chronological order.
+func (s *colorTimeService) GetTrackingSeries(ctx context.Context, orgID, userID, role string) ([]*TrackingSeriesResponse, error) {
+
+	if orgID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	if role == "" {
+		return nil, errors.New("role is required")
+	}
+
+	occurrences, err := s.ColorTimeRepository.GetTrackingSeries(ctx, orgID, userID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracking series: %w", err)
+	}
+
+	if err := scheduleOccurrences(ctx, s.ColorTimeRepository, occurrences); err != nil {
+		return nil, err
+	}
+
+	result := make([]*TrackingSeriesResponse, 0)
+	var current *TrackingSeriesResponse
+
+	for _, occurrence := range occurrences {
+		if current == nil || current.Tracking != occurrence.Tracking {
+			current = &TrackingSeriesResponse{
+				Tracking:    occurrence.Tracking,
+				Occurrences: make([]*TrackingOccurrenceResponse, 0),
+			}
+			result = append(result, current)
+		}
+
+		current.Occurrences = append(current.Occurrences, &TrackingOccurrenceResponse{
+			WeekID:    occurrence.WeekID,
+			SlotID:    occurrence.SlotID,
+			Title:     occurrence.Title,
+			Date:      occurrence.Date,
+			StartTime: occurrence.StartTime,
+			UseCount:  occurrence.UseCount,
+		})
+		current.Count++
+	}
+
+	return result, nil
+}
+
+// scheduleOccurrences puts occurrences, listed by tracking, in schedule order.
+// Member overrides store neither title nor time, so both are taken from the
+// group slot they override before sorting; the database can only order them
+// by creation time.
+func scheduleOccurrences(ctx context.Context, repo ColorTimeRepository, occurrences []*TrackingOccurrence) error {
+
+	groupSlots := make(map[primitive.ObjectID]map[primitive.ObjectID]*ColortimeSlot)
+	for _, occurrence := range occurrences {
+		if occurrence.GroupSlotID == nil {
+			continue
+		}
+
+		slots, err := memberGroupSlots(ctx, repo, occurrence.WeekID, groupSlots)
+		if err != nil {
+			return err
+		}
+		if slot, exists := slots[*occurrence.GroupSlotID]; exists {
+			occurrence.Title, occurrence.StartTime = slot.Title, slot.StartTime
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		a, b := occurrences[i], occurrences[j]
+		if a.Tracking != b.Tracking {
+			return a.Tracking < b.Tracking
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if startA, startB := minuteOfDay(a.StartTime), minuteOfDay(b.StartTime); startA != startB {
+			return startA < startB
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.SlotID.Hex() < b.SlotID.Hex()
+	})
+
+	return nil
+}
+
+// minuteOfDay is the minute of the day t starts at in UTC, as $hour and
+// $minute read it in occurrenceStages.
+func minuteOfDay(t time.Time) int {
+	t = t.UTC()
+	return t.Hour()*60 + t.Minute()
+}
+
+// memberGroupSlots returns the slots of the group week that member week weekID
+// overlays, by slot id. Each week is loaded once through loaded.
+func memberGroupSlots(ctx context.Context, repo ColorTimeRepository, weekID primitive.ObjectID, loaded map[primitive.ObjectID]map[primitive.ObjectID]*ColortimeSlot) (map[primitive.ObjectID]*ColortimeSlot, error) {
+
+	if slots, exists := loaded[weekID]; exists {
+		return slots, nil
+	}
+
+	slots := make(map[primitive.ObjectID]*ColortimeSlot)
+	loaded[weekID] = slots
+
+	week, err := repo.GetColorTimeWeekByID(ctx, weekID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get week colortime: %w", err)
+	}
+	if week == nil || week.GroupID == nil {
+		return slots, nil
+	}
+
+	groupWeek, err := repo.GetColorTimeWeek(ctx, &week.StartDate, &week.EndDate, week.OrganizationID, *week.GroupID, OwnerRoleGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group week: %w", err)
+	}
+	if groupWeek == nil {
+		return slots, nil
+	}
+
+	for _, day := range groupWeek.ColorTimes {
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				slots[slot.SlotID] = slot
+			}
+		}
+	}
+
+	return slots, nil
+}
+
+// RenameTracking changes a tracking code on every slot of the owner. Renaming
+// onto a code that is already in use must go through MergeTracking.
+func (s *colorTimeService) RenameTracking(ctx context.Context, req *RenameTrackingRequest) error {
+
+	if req.Tracking == req.NewTracking {
+		return errors.New("new tracking must differ from tracking")
+	}
+
+	existing, err := s.ColorTimeRepository.CountTrackingUsage(ctx, req.OrganizationID, req.UserID, req.Role, req.NewTracking)
+	if err != nil {
+		return fmt.Errorf("failed to count tracking usage: %w", err)
+	}
+
+	if existing > 0 {
+		return fmt.Errorf("tracking %s already exists, merge the series instead", req.NewTracking)
+	}
+
+	modified, err := s.ColorTimeRepository.RenameTracking(ctx, req.OrganizationID, req.UserID, req.Role, req.Tracking, req.NewTracking)
+	if err != nil {
+		return fmt.Errorf("failed to rename tracking: %w", err)
+	}
+
+	if modified == 0 {
+		return errors.New("tracking not found")
+	}
+
+	return nil
+}
+
+// MergeTracking moves every slot of the source series into the target series
+// and renumbers the result.
+func (s *colorTimeService) MergeTracking(ctx context.Context, req *MergeTrackingRequest) error {
+
+	if req.SourceTracking == req.TargetTracking {
+		return errors.New("source and target tracking must differ")
+	}
+
+	modified, err := s.ColorTimeRepository.RenameTracking(ctx, req.OrganizationID, req.UserID, req.Role, req.SourceTracking, req.TargetTracking)
+	if err != nil {
+		return fmt.Errorf("failed to merge tracking: %w", err)
+	}
+
+	if modified == 0 {
+		return errors.New("source tracking not found")
+	}
+
+	if err := s.normalizeTrackingGlobal(ctx, req.OrganizationID, req.UserID, req.Role, req.TargetTracking); err != nil {
+		return fmt.Errorf("failed to normalize tracking global: %w", err)
+	}
+
+	return nil
+}
+
+func (s *colorTimeService) RenumberTracking(ctx context.Context, req *RenumberTrackingRequest) error {
+
+	if err := s.normalizeTrackingGlobal(ctx, req.OrganizationID, req.UserID, req.Role, req.Tracking); err != nil {
+		return fmt.Errorf("failed to normalize tracking global: %w", err)
+	}
+
+	return nil
+}
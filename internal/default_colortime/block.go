@@ -0,0 +1,281 @@
+package default_colortime
+
+import (
+	"colortime-service/helper"
+	"colortime-service/pkg/slotblock"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// dayBlockEdit is a stored default day being edited block-wise, together with
+// the slots of the day that are not stored on it: occurrences of slot series.
+type dayBlockEdit struct {
+	day          *DefaultDayColorTime
+	seriesSlots  []*DefaultColortimeSlot
+	seriesBlocks map[primitive.ObjectID]bool // blocks the series slots are expanded into
+}
+
+// CreateDefaultBlock adds an empty block at the end of the day.
+func (s *defaultColorTimeService) CreateDefaultBlock(ctx context.Context, dayID string, userID string) (*DefaultColorBlock, error) {
+
+	edit, err := s.dayForBlockEdit(ctx, dayID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &DefaultColorBlock{
+		BlockID: primitive.NewObjectID(),
+		Slots:   []*DefaultColortimeSlot{},
+	}
+	edit.day.TimeSlots = append(edit.day.TimeSlots, block)
+
+	if err := s.saveDefaultBlocks(ctx, edit.day); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// MoveDefaultBlock shifts every slot of the block so the block starts at
+// req.StartTime, keeping their durations and the gaps between them.
+func (s *defaultColorTimeService) MoveDefaultBlock(ctx context.Context, dayID, blockID string, req *MoveBlockRequest, userID string) error {
+
+	edit, err := s.dayForBlockEdit(ctx, dayID, userID)
+	if err != nil {
+		return err
+	}
+
+	block, _, err := edit.findBlock(blockID)
+	if err != nil {
+		return err
+	}
+
+	if err := slotblock.Move(block.Slots, req.StartTime); err != nil {
+		return err
+	}
+
+	if err := edit.checkSlots(block.Slots); err != nil {
+		return err
+	}
+
+	return s.saveDefaultBlocks(ctx, edit.day)
+}
+
+// ReorderDefaultBlockSlots puts the slots of the block in the order of
+// req.SlotIDs, laid out as slotblock.Reorder does.
+func (s *defaultColorTimeService) ReorderDefaultBlockSlots(ctx context.Context, dayID, blockID string, req *ReorderBlockSlotsRequest, userID string) error {
+
+	edit, err := s.dayForBlockEdit(ctx, dayID, userID)
+	if err != nil {
+		return err
+	}
+
+	block, _, err := edit.findBlock(blockID)
+	if err != nil {
+		return err
+	}
+
+	ordered, err := slotblock.Reorder(block.Slots, req.SlotIDs)
+	if err != nil {
+		return err
+	}
+	block.Slots = ordered
+
+	if err := edit.checkSlots(block.Slots); err != nil {
+		return err
+	}
+
+	return s.saveDefaultBlocks(ctx, edit.day)
+}
+
+// SplitDefaultBlock moves req.SlotID and the slots after it into a new block
+// right after the block and returns the new block.
+func (s *defaultColorTimeService) SplitDefaultBlock(ctx context.Context, dayID, blockID string, req *SplitBlockRequest, userID string) (*DefaultColorBlock, error) {
+
+	edit, err := s.dayForBlockEdit(ctx, dayID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	block, index, err := edit.findBlock(blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	at, err := slotblock.SplitIndex(block.Slots, req.SlotID)
+	if err != nil {
+		return nil, err
+	}
+
+	newBlock := &DefaultColorBlock{
+		BlockID: primitive.NewObjectID(),
+		Slots:   append([]*DefaultColortimeSlot(nil), block.Slots[at:]...),
+	}
+	block.Slots = block.Slots[:at]
+
+	for _, slot := range newBlock.Slots {
+		slot.UpdatedAt = time.Now()
+	}
+	slotblock.Renumber(newBlock.Slots)
+
+	edit.day.TimeSlots = append(edit.day.TimeSlots[:index+1], append([]*DefaultColorBlock{newBlock}, edit.day.TimeSlots[index+1:]...)...)
+
+	if err := s.saveDefaultBlocks(ctx, edit.day); err != nil {
+		return nil, err
+	}
+
+	return newBlock, nil
+}
+
+// MergeDefaultBlocks moves the slots of req.BlockID into the block and removes
+// req.BlockID.
+func (s *defaultColorTimeService) MergeDefaultBlocks(ctx context.Context, dayID, blockID string, req *MergeBlocksRequest, userID string) error {
+
+	edit, err := s.dayForBlockEdit(ctx, dayID, userID)
+	if err != nil {
+		return err
+	}
+
+	block, _, err := edit.findBlock(blockID)
+	if err != nil {
+		return err
+	}
+
+	other, otherIndex, err := edit.findBlock(req.BlockID)
+	if err != nil {
+		return err
+	}
+
+	if other == block {
+		return errors.New("cannot merge a block into itself")
+	}
+
+	for _, slot := range other.Slots {
+		slot.UpdatedAt = time.Now()
+	}
+
+	block.Slots = append(block.Slots, other.Slots...)
+	edit.day.TimeSlots = append(edit.day.TimeSlots[:otherIndex], edit.day.TimeSlots[otherIndex+1:]...)
+
+	if err := edit.checkSlots(block.Slots); err != nil {
+		return err
+	}
+
+	slotblock.Renumber(block.Slots)
+
+	return s.saveDefaultBlocks(ctx, edit.day)
+}
+
+func (s *defaultColorTimeService) dayForBlockEdit(ctx context.Context, dayID, userID string) (*dayBlockEdit, error) {
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	dayObjectID, err := primitive.ObjectIDFromHex(dayID)
+	if err != nil {
+		return nil, errors.New("invalid day id format")
+	}
+
+	day, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimeByID(ctx, dayObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get day: %w", err)
+	}
+
+	if day == nil {
+		return nil, fmt.Errorf("day not found")
+	}
+
+	if err := helper.CheckIfMatch(ctx, day.Version); err != nil {
+		return nil, err
+	}
+
+	expandedDays, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, day.Date, day.Date, day.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[primitive.ObjectID]bool)
+	for _, block := range day.TimeSlots {
+		for _, slot := range block.Slots {
+			stored[slot.SlotID] = true
+		}
+	}
+
+	edit := &dayBlockEdit{day: day, seriesBlocks: make(map[primitive.ObjectID]bool)}
+	for _, expanded := range expandedDays {
+		for _, block := range expanded.TimeSlots {
+			for _, slot := range block.Slots {
+				if !stored[slot.SlotID] {
+					edit.seriesSlots = append(edit.seriesSlots, slot)
+					edit.seriesBlocks[block.BlockID] = true
+				}
+			}
+		}
+	}
+
+	return edit, nil
+}
+
+// findBlock returns a stored block of the day. Blocks holding occurrences of a
+// slot series are refused: those slots follow their series and would be left
+// behind by a block edit.
+func (e *dayBlockEdit) findBlock(blockID string) (*DefaultColorBlock, int, error) {
+
+	blockObjectID, err := primitive.ObjectIDFromHex(blockID)
+	if err != nil {
+		return nil, 0, errors.New("invalid block id format")
+	}
+
+	if e.seriesBlocks[blockObjectID] {
+		return nil, 0, errors.New("block holds recurring slots, edit their series instead")
+	}
+
+	for i, block := range e.day.TimeSlots {
+		if block.BlockID == blockObjectID {
+			return block, i, nil
+		}
+	}
+
+	return nil, 0, errors.New("block not found")
+}
+
+// checkSlots rejects changed slots that leave their day or overlap any other
+// slot of the day, occurrences of slot series included.
+func (e *dayBlockEdit) checkSlots(changed []*DefaultColortimeSlot) error {
+
+	allSlots := append([]*DefaultColortimeSlot(nil), e.seriesSlots...)
+	for _, block := range e.day.TimeSlots {
+		allSlots = append(allSlots, block.Slots...)
+	}
+
+	for _, slot := range changed {
+		if !slotblock.FitsInDay(slot.StartTime, slot.EndTime) {
+			return fmt.Errorf("slot %q would not fit in the day", slot.Title)
+		}
+
+		if isTimeSlotConflict(slot.StartTime, slot.EndTime, allSlots, &slot.SlotID) {
+			return fmt.Errorf("slot %q conflicts with existing slots in the day", slot.Title)
+		}
+	}
+
+	return nil
+}
+
+func (s *defaultColorTimeService) saveDefaultBlocks(ctx context.Context, day *DefaultDayColorTime) error {
+	now := time.Now()
+	day.UpdatedAt = now
+	day.CustomizedAt = &now
+
+	if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, day.ID, day); err != nil {
+		return fmt.Errorf("failed to update day: %w", err)
+	}
+
+	s.notifyChanged(ctx, day.OrganizationID, day.Date)
+
+	return nil
+}
@@ -50,7 +50,7 @@ func (h *DefaultColorTimeHandler) CreateDefaultDayColorTime(c *gin.Context) {
 
 	dayColorTime, err := h.DefaultColorTimeService.CreateDefaultDayColorTime(ctx, &req, userID.(string))
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -98,6 +98,10 @@ func (h *DefaultColorTimeHandler) GetDefaultDayColorTime(c *gin.Context) {
 		return
 	}
 
+	if dayColorTime != nil {
+		helper.SetETag(c, dayColorTime.Version)
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "default day color time retrieved successfully", dayColorTime)
 }
 
@@ -187,7 +191,7 @@ func (h *DefaultColorTimeHandler) DeleteDefaultDayColorTime(c *gin.Context) {
 
 	err := h.DefaultColorTimeService.DeleteDefaultDayColorTime(ctx, id)
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -235,6 +239,12 @@ func (h *DefaultColorTimeHandler) UpdateDefaultColorSlot(c *gin.Context) {
 		return
 	}
 
+	var scope EditScopeRequest
+	if err := c.ShouldBindQuery(&scope); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
 	token, exists := c.Get(constants.Token)
 	if !exists {
 		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
@@ -243,9 +253,15 @@ func (h *DefaultColorTimeHandler) UpdateDefaultColorSlot(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.DefaultColorTimeService.UpdateDefaultColorSlot(ctx, dayID, slotID, &req)
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	err = h.DefaultColorTimeService.UpdateDefaultColorSlot(ctx, dayID, slotID, &req, &scope)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -265,6 +281,12 @@ func (h *DefaultColorTimeHandler) DeleteDefaultDayColorTimeSlot(c *gin.Context)
 		return
 	}
 
+	var scope EditScopeRequest
+	if err := c.ShouldBindQuery(&scope); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
 	userID, exists := c.Get(constants.UserID)
 	if !exists {
 		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
@@ -279,9 +301,15 @@ func (h *DefaultColorTimeHandler) DeleteDefaultDayColorTimeSlot(c *gin.Context)
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.DefaultColorTimeService.DeleteDefaultDayColorTimeSlot(ctx, dayID, slotID, userID.(string))
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	err = h.DefaultColorTimeService.DeleteDefaultDayColorTimeSlot(ctx, dayID, slotID, userID.(string), &scope)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -315,11 +343,250 @@ func (h *DefaultColorTimeHandler) DeleteDefaultDayColorTimeBlock(c *gin.Context)
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.DefaultColorTimeService.DeleteDefaultDayColorTimeBlock(ctx, dayID, blockID, userID.(string))
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	err = h.DefaultColorTimeService.DeleteDefaultDayColorTimeBlock(ctx, dayID, blockID, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	helper.SendSuccess(c, http.StatusOK, "block deleted successfully", nil)
-}	
\ No newline at end of file
+}	
+func (h *DefaultColorTimeHandler) GetSlotSeriesList(c *gin.Context) {
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	series, err := h.DefaultColorTimeService.GetSlotSeriesList(ctx, orgID)
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "slot series retrieved successfully", series)
+}
+
+func (h *DefaultColorTimeHandler) GetSlotSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+	if seriesID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("series id is required"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	series, err := h.DefaultColorTimeService.GetSlotSeries(ctx, seriesID)
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SetETag(c, series.Version)
+	helper.SendSuccess(c, http.StatusOK, "slot series retrieved successfully", series)
+}
+
+func (h *DefaultColorTimeHandler) DeleteSlotSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+	if seriesID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("series id is required"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	ctx, err := helper.WithIfMatch(ctx, c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := h.DefaultColorTimeService.DeleteSlotSeries(ctx, seriesID); err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "slot series deleted successfully", nil)
+}
+
+func (h *DefaultColorTimeHandler) ExcludeSeriesOccurrence(c *gin.Context) {
+	seriesID := c.Param("id")
+	if seriesID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("series id is required"), nil)
+		return
+	}
+
+	var req ExcludeSeriesOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	ctx, err := helper.WithIfMatch(ctx, c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := h.DefaultColorTimeService.ExcludeSeriesOccurrence(ctx, seriesID, &req); err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "series occurrence excluded successfully", nil)
+}
+
+// blockEditContext reads the user, the token and If-Match for the block
+// handlers, answering the request itself when one is missing.
+func blockEditContext(c *gin.Context) (context.Context, string, bool) {
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists || userID == "" {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return nil, "", false
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return nil, "", false
+	}
+
+	ctx, err := helper.WithIfMatch(context.WithValue(c, constants.TokenKey, token), c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return nil, "", false
+	}
+
+	return ctx, userID.(string), true
+}
+
+func (h *DefaultColorTimeHandler) CreateDefaultBlock(c *gin.Context) {
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	block, err := h.DefaultColorTimeService.CreateDefaultBlock(ctx, c.Param("id"), userID)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "block created successfully", block)
+}
+
+func (h *DefaultColorTimeHandler) MoveDefaultBlock(c *gin.Context) {
+	var request MoveBlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DefaultColorTimeService.MoveDefaultBlock(ctx, c.Param("id"), c.Param("block_id"), &request, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "block moved successfully", nil)
+}
+
+func (h *DefaultColorTimeHandler) ReorderDefaultBlockSlots(c *gin.Context) {
+	var request ReorderBlockSlotsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DefaultColorTimeService.ReorderDefaultBlockSlots(ctx, c.Param("id"), c.Param("block_id"), &request, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "block slots reordered successfully", nil)
+}
+
+func (h *DefaultColorTimeHandler) SplitDefaultBlock(c *gin.Context) {
+	var request SplitBlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	block, err := h.DefaultColorTimeService.SplitDefaultBlock(ctx, c.Param("id"), c.Param("block_id"), &request, userID)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "block split successfully", block)
+}
+
+func (h *DefaultColorTimeHandler) MergeDefaultBlocks(c *gin.Context) {
+	var request MergeBlocksRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DefaultColorTimeService.MergeDefaultBlocks(ctx, c.Param("id"), c.Param("block_id"), &request, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "blocks merged successfully", nil)
+}
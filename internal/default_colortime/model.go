@@ -1,6 +1,7 @@
 package default_colortime
 
 import (
+	"colortime-service/internal/closure"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,6 +15,17 @@ type DefaultDayColorTime struct {
 	CreatedBy      string               `bson:"created_by" json:"created_by"`
 	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+	Version        int64                `bson:"version" json:"version"`
+
+	// Closure in effect on the day, set when days are read in a range. Closed
+	// days are returned without slots.
+	Closure *closure.DayClosure `bson:"-" json:"closure,omitempty"`
+
+	// Template the day was last applied from, nil for days built by hand, and
+	// the last time the day was edited by hand. Both are written even when nil
+	// so a full update clears them.
+	Source       *TemplateSource `bson:"source" json:"source,omitempty"`
+	CustomizedAt *time.Time      `bson:"customized_at" json:"customized_at,omitempty"`
 
 	// Repeat configuration
 	IsBaseTemplate bool                `bson:"is_base_template" json:"is_base_template"` // true if this is the base template for repeating
@@ -67,11 +79,66 @@ type DefaultColortimeSlot struct {
 	Duration              int                             `json:"duration" bson:"duration"`
 	Color                 string                          `json:"color" bson:"color"`
 	Note                  string                          `json:"note" bson:"note"`
+	SeriesID              *primitive.ObjectID             `json:"series_id,omitempty" bson:"series_id,omitempty"` // recurring slot (series origin) this occurrence or override belongs to
+	Source                *TemplateSource                 `json:"source,omitempty" bson:"source,omitempty"`       // template slot this slot was applied from
 	CreatedAt             time.Time                       `json:"created_at" bson:"created_at"`
 	UpdatedAt             time.Time                       `json:"updated_at" bson:"updated_at"`
 }
 
+func (s *DefaultColortimeSlot) ID() primitive.ObjectID { return s.SlotID }
+
+func (s *DefaultColortimeSlot) Span() (time.Time, time.Time) { return s.StartTime, s.EndTime }
+
+func (s *DefaultColortimeSlot) Reschedule(start, end time.Time) {
+	s.StartTime, s.EndTime = start, end
+	s.UpdatedAt = time.Now()
+}
+
+func (s *DefaultColortimeSlot) SetSessions(sessions int) { s.Sessions = sessions }
+
+// TemplateSource records which template, and on slots which template slot, a
+// default day or slot was generated from and when.
+type TemplateSource struct {
+	TemplateID     primitive.ObjectID  `bson:"template_id" json:"template_id"`
+	TemplateSlotID *primitive.ObjectID `bson:"template_slot_id,omitempty" json:"template_slot_id,omitempty"`
+	TermID         string              `bson:"term_id" json:"term_id"`
+	AppliedAt      time.Time           `bson:"applied_at" json:"applied_at"`
+
+	// Set when the day was applied from a library template version, in which
+	// case TemplateID is the id of that version
+	LibraryTemplateID *primitive.ObjectID `bson:"library_template_id,omitempty" json:"library_template_id,omitempty"`
+	LibraryVersion    int                 `bson:"library_version,omitempty" json:"library_version,omitempty"`
+}
+
+// CustomizedSinceApplied reports whether the day was edited by hand after its
+// template was last applied. Days built by hand always count as customized.
+func (d *DefaultDayColorTime) CustomizedSinceApplied() bool {
+	if d.Source == nil {
+		return true
+	}
+	return d.CustomizedAt != nil && d.CustomizedAt.After(d.Source.AppliedAt)
+}
+
 type DefaultColorTimeSlotLanguage struct {
 	LanguageID int    `json:"language_id" bson:"language_id"`
 	Title      string `json:"title" bson:"title"`
 }
+
+// DefaultSlotSeries is a recurring default slot. The rule is stored once and
+// expanded into the days it falls on whenever a date range is read.
+type DefaultSlotSeries struct {
+	ID             primitive.ObjectID    `bson:"_id" json:"id"`
+	OriginID       primitive.ObjectID    `bson:"origin_id" json:"origin_id"` // first series of the recurring slot, kept when a series is split
+	OrganizationID string                `bson:"organization_id" json:"organization_id"`
+	RRule          string                `bson:"rrule" json:"rrule"`         // RFC 5545 RRULE, empty when the series only has rdates
+	DTStart        time.Time             `bson:"dtstart" json:"dtstart"`     // first occurrence
+	LastDate       *time.Time            `bson:"last_date" json:"last_date"` // last occurrence, nil when the series never ends
+	ExDates        []time.Time           `bson:"exdates" json:"exdates"`
+	RDates         []time.Time           `bson:"rdates" json:"rdates"`
+	BlockID        primitive.ObjectID    `bson:"block_id" json:"block_id"`
+	Slot           *DefaultColortimeSlot `bson:"slot" json:"slot"`
+	CreatedBy      string                `bson:"created_by" json:"created_by"`
+	CreatedAt      time.Time             `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time             `bson:"updated_at" json:"updated_at"`
+	Version        int64                 `bson:"version" json:"version"`
+}
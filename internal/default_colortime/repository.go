@@ -1,12 +1,16 @@
 package default_colortime
 
 import (
+	"colortime-service/helper"
+	"colortime-service/internal/closure"
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type DefaultColorTimeRepository interface {
@@ -18,15 +22,34 @@ type DefaultColorTimeRepository interface {
 	DeleteDefaultDayColorTime(ctx context.Context, id primitive.ObjectID) error
 	GetDefaultDayColorTimesInRange(ctx context.Context, startDate, endDate time.Time, organizationID string) ([]*DefaultDayColorTime, error)
 	GetAllDefaultDayColorTimes(ctx context.Context, organizationID string) ([]*DefaultDayColorTime, error)
+	GetDefaultDayColorTimesByTemplate(ctx context.Context, templateID primitive.ObjectID, from *time.Time) ([]*DefaultDayColorTime, error)
+
+	UpdateDefaultSlotFields(ctx context.Context, dayID, slotID primitive.ObjectID, version int64, fields bson.M) error
+	RemoveDefaultSlot(ctx context.Context, dayID, slotID primitive.ObjectID, version int64) error
+	RemoveDefaultBlock(ctx context.Context, dayID, blockID primitive.ObjectID, version int64) error
+	TouchDefaultDays(ctx context.Context, organizationID string, startDate time.Time, endDate *time.Time) error
+
+	CreateSlotSeries(ctx context.Context, series *DefaultSlotSeries) error
+	GetSlotSeriesByID(ctx context.Context, id primitive.ObjectID) (*DefaultSlotSeries, error)
+	GetSlotSeriesByOrganization(ctx context.Context, organizationID string) ([]*DefaultSlotSeries, error)
+	GetSlotSeriesInRange(ctx context.Context, startDate, endDate time.Time, organizationID string) ([]*DefaultSlotSeries, error)
+	GetSlotSeriesByOrigin(ctx context.Context, originID primitive.ObjectID) ([]*DefaultSlotSeries, error)
+	UpdateSlotSeries(ctx context.Context, series *DefaultSlotSeries) error
+	DeleteSlotSeries(ctx context.Context, id primitive.ObjectID) error
+	RemoveSeriesOverrides(ctx context.Context, organizationID string, originID primitive.ObjectID, startDate time.Time, endDate *time.Time) error
 }
 
 type defaultColorTimeRepository struct {
-	DefaultColorTimeCollection *mongo.Collection
+	DefaultColorTimeCollection  *mongo.Collection
+	DefaultSlotSeriesCollection *mongo.Collection
+	ClosureRepository           closure.ClosureRepository
 }
 
-func NewDefaultColorTimeRepository(defaultColorTimeCollection *mongo.Collection) DefaultColorTimeRepository {
+func NewDefaultColorTimeRepository(defaultColorTimeCollection, defaultSlotSeriesCollection *mongo.Collection, closureRepository closure.ClosureRepository) DefaultColorTimeRepository {
 	return &defaultColorTimeRepository{
-		DefaultColorTimeCollection: defaultColorTimeCollection,
+		DefaultColorTimeCollection:  defaultColorTimeCollection,
+		DefaultSlotSeriesCollection: defaultSlotSeriesCollection,
+		ClosureRepository:           closureRepository,
 	}
 }
 
@@ -36,11 +59,12 @@ func (r *defaultColorTimeRepository) CreateDefaultDayColorTime(ctx context.Conte
 }
 
 func (r *defaultColorTimeRepository) GetDefaultDayColorTime(ctx context.Context, date time.Time, organizationID string) (*DefaultDayColorTime, error) {
+	start, end := dayBounds(date)
 	filter := bson.M{
 		"organization_id": organizationID,
 		"date": bson.M{
-			"$gte": time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()),
-			"$lt":  time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 999999999, date.Location()),
+			"$gte": start,
+			"$lt":  end,
 		},
 	}
 
@@ -87,9 +111,72 @@ func (r *defaultColorTimeRepository) GetDefaultDayColorTimeBySlotID(ctx context.
 	return &dayColorTime, nil
 }
 
+// UpdateDefaultDayColorTime only writes when the stored document is still at the version
+// that was read, and bumps the version on success.
 func (r *defaultColorTimeRepository) UpdateDefaultDayColorTime(ctx context.Context, id primitive.ObjectID, dayColorTime *DefaultDayColorTime) error {
-	_, err := r.DefaultColorTimeCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": dayColorTime})
-	return err
+	readVersion := dayColorTime.Version
+	dayColorTime.Version = readVersion + 1
+
+	result, err := r.DefaultColorTimeCollection.UpdateOne(ctx, helper.VersionFilter(id, readVersion), bson.M{"$set": dayColorTime})
+	if err != nil {
+		dayColorTime.Version = readVersion
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		dayColorTime.Version = readVersion
+		return r.versionConflict(ctx, id)
+	}
+
+	return nil
+}
+
+func (r *defaultColorTimeRepository) versionConflict(ctx context.Context, id primitive.ObjectID) error {
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+
+	if err := r.DefaultColorTimeCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&current); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("document not found")
+		}
+		return err
+	}
+
+	return &helper.VersionConflictError{CurrentVersion: current.Version}
+}
+
+// UpdateDefaultSlotFields sets the given slot fields (bson names) on one slot
+// of the day without rewriting the rest of the document. Like RemoveDefaultSlot
+// and RemoveDefaultBlock it is an edit by hand and stamps customized_at, and
+// only writes while the day is at version.
+func (r *defaultColorTimeRepository) UpdateDefaultSlotFields(ctx context.Context, dayID, slotID primitive.ObjectID, version int64, fields bson.M) error {
+	filter := bson.M{"_id": dayID, "time_slots.slots.slot_id": slotID}
+	set := helper.PrefixFields("time_slots.$[].slots.$[slot].", fields)
+	set["customized_at"] = time.Now()
+	update := bson.M{"$set": set}
+
+	return helper.PatchDocument(ctx, r.DefaultColorTimeCollection, filter, version, update, bson.M{"slot.slot_id": slotID})
+}
+
+func (r *defaultColorTimeRepository) RemoveDefaultSlot(ctx context.Context, dayID, slotID primitive.ObjectID, version int64) error {
+	filter := bson.M{"_id": dayID, "time_slots.slots.slot_id": slotID}
+	update := bson.M{
+		"$pull": bson.M{"time_slots.$[].slots": bson.M{"slot_id": slotID}},
+		"$set":  bson.M{"customized_at": time.Now()},
+	}
+
+	return helper.PatchDocument(ctx, r.DefaultColorTimeCollection, filter, version, update)
+}
+
+func (r *defaultColorTimeRepository) RemoveDefaultBlock(ctx context.Context, dayID, blockID primitive.ObjectID, version int64) error {
+	filter := bson.M{"_id": dayID, "time_slots.block_id": blockID}
+	update := bson.M{
+		"$pull": bson.M{"time_slots": bson.M{"block_id": blockID}},
+		"$set":  bson.M{"customized_at": time.Now()},
+	}
+
+	return helper.PatchDocument(ctx, r.DefaultColorTimeCollection, filter, version, update)
 }
 
 func (r *defaultColorTimeRepository) DeleteDefaultDayColorTime(ctx context.Context, id primitive.ObjectID) error {
@@ -97,12 +184,19 @@ func (r *defaultColorTimeRepository) DeleteDefaultDayColorTime(ctx context.Conte
 	return err
 }
 
+// GetDefaultDayColorTimesInRange returns the stored days in the range with
+// the occurrences of every slot series expanded into them, including days
+// that only exist through a series, and the organization's closures applied.
+// The result is for reading: write paths load the stored day with
+// GetDefaultDayColorTime.
 func (r *defaultColorTimeRepository) GetDefaultDayColorTimesInRange(ctx context.Context, startDate, endDate time.Time, organizationID string) ([]*DefaultDayColorTime, error) {
+	start, _ := dayBounds(startDate)
+	_, end := dayBounds(endDate)
 	filter := bson.M{
 		"organization_id": organizationID,
 		"date": bson.M{
-			"$gte": time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location()),
-			"$lte": time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location()),
+			"$gte": start,
+			"$lt":  end,
 		},
 	}
 
@@ -118,7 +212,17 @@ func (r *defaultColorTimeRepository) GetDefaultDayColorTimesInRange(ctx context.
 		return nil, err
 	}
 
-	return dayColorTimes, nil
+	series, err := r.GetSlotSeriesInRange(ctx, startDate, endDate, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	closures, err := r.ClosureRepository.GetClosuresInRange(ctx, start, end.AddDate(0, 0, -1), organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return expandSlotSeries(organizationID, dayColorTimes, series, closure.NewCalendar(closures), start, end.AddDate(0, 0, -1)), nil
 }
 
 func (r *defaultColorTimeRepository) GetAllDefaultDayColorTimes(ctx context.Context, organizationID string) ([]*DefaultDayColorTime, error) {
@@ -140,3 +244,176 @@ func (r *defaultColorTimeRepository) GetAllDefaultDayColorTimes(ctx context.Cont
 
 	return dayColorTimes, nil
 }
+
+// GetDefaultDayColorTimesByTemplate returns the stored days last applied from
+// the template, from the day of from onwards when it is set.
+func (r *defaultColorTimeRepository) GetDefaultDayColorTimesByTemplate(ctx context.Context, templateID primitive.ObjectID, from *time.Time) ([]*DefaultDayColorTime, error) {
+	filter := bson.M{
+		"source.template_id": templateID,
+	}
+	if from != nil {
+		start, _ := dayBounds(*from)
+		filter["date"] = bson.M{"$gte": start}
+	}
+
+	var dayColorTimes []*DefaultDayColorTime
+
+	cursor, err := r.DefaultColorTimeCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &dayColorTimes); err != nil {
+		return nil, err
+	}
+
+	return dayColorTimes, nil
+}
+
+// dayBounds returns [start, end) of the stored day of date. Days are stored
+// as midnight UTC; callers resolve instants to a day with the organization
+// calendar before querying.
+func dayBounds(date time.Time) (time.Time, time.Time) {
+	y, m, d := date.UTC().Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// TouchDefaultDays bumps updated_at on the stored days of the organization
+// from startDate to endDate (open ended when nil) so weeks synced from them
+// are seen as stale, e.g. after a series covering those days was removed.
+func (r *defaultColorTimeRepository) TouchDefaultDays(ctx context.Context, organizationID string, startDate time.Time, endDate *time.Time) error {
+	start, _ := dayBounds(startDate)
+	dateRange := bson.M{"$gte": start}
+	if endDate != nil {
+		_, end := dayBounds(*endDate)
+		dateRange["$lt"] = end
+	}
+
+	_, err := r.DefaultColorTimeCollection.UpdateMany(ctx,
+		bson.M{"organization_id": organizationID, "date": dateRange},
+		bson.M{"$set": bson.M{"updated_at": time.Now()}, "$inc": bson.M{"version": 1}},
+	)
+	return err
+}
+
+func (r *defaultColorTimeRepository) CreateSlotSeries(ctx context.Context, series *DefaultSlotSeries) error {
+	_, err := r.DefaultSlotSeriesCollection.InsertOne(ctx, series)
+	return err
+}
+
+func (r *defaultColorTimeRepository) GetSlotSeriesByID(ctx context.Context, id primitive.ObjectID) (*DefaultSlotSeries, error) {
+	var series DefaultSlotSeries
+
+	if err := r.DefaultSlotSeriesCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&series); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &series, nil
+}
+
+func (r *defaultColorTimeRepository) GetSlotSeriesByOrganization(ctx context.Context, organizationID string) ([]*DefaultSlotSeries, error) {
+	return r.findSlotSeries(ctx, bson.M{"organization_id": organizationID})
+}
+
+// GetSlotSeriesInRange returns the series of the organization that may have an
+// occurrence between startDate and endDate.
+func (r *defaultColorTimeRepository) GetSlotSeriesInRange(ctx context.Context, startDate, endDate time.Time, organizationID string) ([]*DefaultSlotSeries, error) {
+	start, _ := dayBounds(startDate)
+	_, end := dayBounds(endDate)
+
+	return r.findSlotSeries(ctx, bson.M{
+		"organization_id": organizationID,
+		"dtstart":         bson.M{"$lt": end},
+		"$or": []bson.M{
+			{"last_date": nil},
+			{"last_date": bson.M{"$gte": start}},
+		},
+	})
+}
+
+// GetSlotSeriesByOrigin returns every series of one recurring slot, i.e. the
+// original series and the ones split off it.
+func (r *defaultColorTimeRepository) GetSlotSeriesByOrigin(ctx context.Context, originID primitive.ObjectID) ([]*DefaultSlotSeries, error) {
+	return r.findSlotSeries(ctx, bson.M{"$or": []bson.M{
+		{"_id": originID},
+		{"origin_id": originID},
+	}})
+}
+
+func (r *defaultColorTimeRepository) findSlotSeries(ctx context.Context, filter bson.M) ([]*DefaultSlotSeries, error) {
+	cursor, err := r.DefaultSlotSeriesCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "dtstart", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var series []*DefaultSlotSeries
+	if err := cursor.All(ctx, &series); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// UpdateSlotSeries only writes when the stored series is still at the version
+// that was read, and bumps the version on success.
+func (r *defaultColorTimeRepository) UpdateSlotSeries(ctx context.Context, series *DefaultSlotSeries) error {
+	readVersion := series.Version
+	series.Version = readVersion + 1
+
+	result, err := r.DefaultSlotSeriesCollection.UpdateOne(ctx, helper.VersionFilter(series.ID, readVersion), bson.M{"$set": series})
+	if err != nil {
+		series.Version = readVersion
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		series.Version = readVersion
+
+		current, err := r.GetSlotSeriesByID(ctx, series.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return errors.New("document not found")
+		}
+		return &helper.VersionConflictError{CurrentVersion: current.Version}
+	}
+
+	return nil
+}
+
+func (r *defaultColorTimeRepository) DeleteSlotSeries(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.DefaultSlotSeriesCollection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// RemoveSeriesOverrides pulls the stored single-day overrides of a recurring
+// slot from the days between startDate and endDate (open ended when nil).
+func (r *defaultColorTimeRepository) RemoveSeriesOverrides(ctx context.Context, organizationID string, originID primitive.ObjectID, startDate time.Time, endDate *time.Time) error {
+	start, _ := dayBounds(startDate)
+	dateRange := bson.M{"$gte": start}
+	if endDate != nil {
+		_, end := dayBounds(*endDate)
+		dateRange["$lt"] = end
+	}
+
+	_, err := r.DefaultColorTimeCollection.UpdateMany(ctx,
+		bson.M{
+			"organization_id":            organizationID,
+			"date":                       dateRange,
+			"time_slots.slots.series_id": originID,
+		},
+		bson.M{
+			"$pull": bson.M{"time_slots.$[].slots": bson.M{"series_id": originID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+			"$inc":  bson.M{"version": 1},
+		},
+	)
+	return err
+}
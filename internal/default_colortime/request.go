@@ -46,6 +46,24 @@ type CreateDefaultColorBlockWithSlotRequest struct {
 	RepeatDays     []int  `json:"repeat_days"`     // for weekly: [0=Sun,1=Mon,...,6=Sat], for custom dates
 }
 
+const (
+	EditScopeThis      = "this"
+	EditScopeFollowing = "following"
+	EditScopeAll       = "all"
+)
+
+// EditScopeRequest selects which occurrences of a recurring slot an update or
+// delete applies to. SeriesID is the slot's series_id and is needed for
+// occurrences on days that only exist through a series.
+type EditScopeRequest struct {
+	Scope    string `form:"scope"`
+	SeriesID string `form:"series_id"`
+}
+
+type ExcludeSeriesOccurrenceRequest struct {
+	Date string `json:"date" binding:"required"`
+}
+
 type AddSlotToDefaultColorBlockRequest struct {
 	Date           string `json:"date" binding:"required"`
 	BlockID        string `json:"block_id" binding:"required"`
@@ -81,13 +99,35 @@ type CreateDefaultDayColorTimeRequest struct {
 
 	BlockID string `json:"block_id"`
 
+	// Recurrence (optional): an RFC 5545 RRULE such as "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20250601"
+	// and dates to skip. Takes precedence over the legacy repeat fields below.
+	RRule   string   `json:"rrule"`
+	ExDates []string `json:"exdates"` // YYYY-MM-DD
+
+	// Legacy repeat configuration (optional), converted to an RRULE
 	RepeatType     string `json:"repeat_type"`     // "none", "daily", "weekly", "monthly", "custom"
 	RepeatUntil    string `json:"repeat_until"`    // optional: when to stop repeating (YYYY-MM-DD)
 	RepeatInterval int    `json:"repeat_interval"` // repeat every N units (default 1)
-	RepeatDays     []int  `json:"repeat_days"`     // for weekly: [0=Sun,1=Mon,...,6=Sat], for custom dates
+	RepeatDays     []int  `json:"repeat_days"`     // for weekly: weekdays [0=Sun,1=Mon,...,6=Sat], for custom: day offsets from date
 }
 
 type UpdateDefaultDayColorTimeRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
+
+type MoveBlockRequest struct {
+	StartTime string `json:"start_time" binding:"required"` // new start of the block, "HH:MM"
+}
+
+type ReorderBlockSlotsRequest struct {
+	SlotIDs []string `json:"slot_ids" binding:"required"` // every slot of the block in the new order
+}
+
+type SplitBlockRequest struct {
+	SlotID string `json:"slot_id" binding:"required"` // first slot of the new block
+}
+
+type MergeBlocksRequest struct {
+	BlockID string `json:"block_id" binding:"required"` // block merged into the block of the path and removed
+}
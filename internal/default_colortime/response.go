@@ -1,6 +1,7 @@
 package default_colortime
 
 import (
+	"colortime-service/internal/closure"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,19 +16,24 @@ type DefaultColorTimeResponse struct {
 }
 
 type DefaultDayColorTimeResponse struct {
-	ID             primitive.ObjectID   `bson:"_id" json:"id"`
-	OrganizationID string               `bson:"organization_id" json:"organization_id"`
-	Date           time.Time            `bson:"date" json:"date"`
-	TimeSlots      []*DefaultColorBlock `bson:"time_slots" json:"time_slots"`
-	IsBaseTemplate bool                 `bson:"is_base_template" json:"is_base_template"`
-	RepeatType     string               `bson:"repeat_type" json:"repeat_type"`
-	RepeatUntil    *time.Time           `bson:"repeat_until" json:"repeat_until"`
-	RepeatInterval int                  `bson:"repeat_interval" json:"repeat_interval"`
-	RepeatDays     []int                `bson:"repeat_days" json:"repeat_days"`
-	CreatedBlockID *primitive.ObjectID  `bson:"created_block_id" json:"created_block_id"`
-	CreatedBy      string               `bson:"created_by" json:"created_by"`
-	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+	ID              primitive.ObjectID   `bson:"_id" json:"id"`
+	OrganizationID  string               `bson:"organization_id" json:"organization_id"`
+	Date            time.Time            `bson:"date" json:"date"`
+	TimeSlots       []*DefaultColorBlock `bson:"time_slots" json:"time_slots"`
+	IsBaseTemplate  bool                 `bson:"is_base_template" json:"is_base_template"`
+	RepeatType      string               `bson:"repeat_type" json:"repeat_type"`
+	RepeatUntil     *time.Time           `bson:"repeat_until" json:"repeat_until"`
+	RepeatInterval  int                  `bson:"repeat_interval" json:"repeat_interval"`
+	RepeatDays      []int                `bson:"repeat_days" json:"repeat_days"`
+	CreatedBlockID  *primitive.ObjectID  `bson:"created_block_id" json:"created_block_id"`
+	CreatedSeriesID *primitive.ObjectID  `bson:"created_series_id,omitempty" json:"created_series_id,omitempty"`
+	Closure         *closure.DayClosure  `bson:"closure,omitempty" json:"closure,omitempty"`
+	Source          *TemplateSource      `bson:"source,omitempty" json:"source,omitempty"`
+	CustomizedAt    *time.Time           `bson:"customized_at,omitempty" json:"customized_at,omitempty"`
+	CreatedBy       string               `bson:"created_by" json:"created_by"`
+	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time            `bson:"updated_at" json:"updated_at"`
+	Version         int64                `bson:"version" json:"version"`
 }
 
 type TopicToDefaultColorTimeWeekResponse struct {
@@ -19,5 +19,15 @@ func RegisterRoutes(r *gin.Engine, defaultColorTimeHandler *DefaultColorTimeHand
 		defaultColorTime.PUT("/day/:id/slot/edit/:slot_id", defaultColorTimeHandler.UpdateDefaultColorSlot)
 		defaultColorTime.DELETE("/day/:id/delete-slot/:slot_id", defaultColorTimeHandler.DeleteDefaultDayColorTimeSlot)
 		defaultColorTime.DELETE("/day/:id/delete-block/:block_id", defaultColorTimeHandler.DeleteDefaultDayColorTimeBlock)
+		defaultColorTime.POST("/day/:id/blocks", defaultColorTimeHandler.CreateDefaultBlock)
+		defaultColorTime.PUT("/day/:id/blocks/:block_id/move", defaultColorTimeHandler.MoveDefaultBlock)
+		defaultColorTime.PUT("/day/:id/blocks/:block_id/reorder", defaultColorTimeHandler.ReorderDefaultBlockSlots)
+		defaultColorTime.POST("/day/:id/blocks/:block_id/split", defaultColorTimeHandler.SplitDefaultBlock)
+		defaultColorTime.POST("/day/:id/blocks/:block_id/merge", defaultColorTimeHandler.MergeDefaultBlocks)
+
+		defaultColorTime.GET("/series", defaultColorTimeHandler.GetSlotSeriesList)
+		defaultColorTime.GET("/series/:id", defaultColorTimeHandler.GetSlotSeries)
+		defaultColorTime.DELETE("/series/:id", defaultColorTimeHandler.DeleteSlotSeries)
+		defaultColorTime.POST("/series/:id/exdate", defaultColorTimeHandler.ExcludeSeriesOccurrence)
 	}
 }
@@ -0,0 +1,984 @@
+package default_colortime
+
+import (
+	"colortime-service/helper"
+	"colortime-service/internal/closure"
+	"colortime-service/internal/organization_setting"
+	"colortime-service/pkg/rrule"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// seriesHorizonDays bounds how far ahead an open ended series is expanded
+// when checking conflicts and notifying synced weeks. Weeks further out pick
+// the series up when they are read.
+const seriesHorizonDays = 366
+
+// occurrences returns the days of the series between from and to.
+func (s *DefaultSlotSeries) occurrences(from, to time.Time) ([]time.Time, error) {
+	from, to = rrule.Day(from), rrule.Day(to)
+
+	excluded := make(map[time.Time]bool, len(s.ExDates))
+	for _, exdate := range s.ExDates {
+		excluded[rrule.Day(exdate)] = true
+	}
+
+	seen := make(map[time.Time]bool)
+	var days []time.Time
+	add := func(day time.Time) {
+		day = rrule.Day(day)
+		if seen[day] || excluded[day] || day.Before(from) || day.After(to) {
+			return
+		}
+		seen[day] = true
+		days = append(days, day)
+	}
+
+	if s.RRule == "" {
+		add(s.DTStart)
+	} else {
+		rule, err := rrule.Parse(s.RRule)
+		if err != nil {
+			return nil, err
+		}
+		for _, day := range rule.Between(s.DTStart, from, to, s.ExDates) {
+			add(day)
+		}
+	}
+
+	for _, rdate := range s.RDates {
+		add(rdate)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// origin identifies the recurring slot the series belongs to. Series split
+// off another one keep the origin of the first.
+func (s *DefaultSlotSeries) origin() primitive.ObjectID {
+	if s.OriginID.IsZero() {
+		return s.ID
+	}
+	return s.OriginID
+}
+
+// occurrenceSlotID is the slot id of the occurrence on date. It depends on the
+// origin only, so splitting a series keeps the ids of its occurrences.
+func (s *DefaultSlotSeries) occurrenceSlotID(date time.Time) primitive.ObjectID {
+	return derivedObjectID("slot", s.origin().Hex(), date.Format("2006-01-02"))
+}
+
+// horizon returns the last day worth expanding the series to.
+func (s *DefaultSlotSeries) horizon() time.Time {
+	if s.LastDate != nil {
+		return *s.LastDate
+	}
+	return rrule.Day(time.Now()).AddDate(0, 0, seriesHorizonDays)
+}
+
+// lastDate returns the final occurrence of the series, or nil when its rule
+// never ends.
+func lastDate(rule *rrule.Rule, dtstart time.Time, rdates []time.Time) *time.Time {
+	last := rrule.Day(dtstart)
+
+	if rule != nil {
+		ruleLast, ok := rule.Last(dtstart)
+		if !ok {
+			return nil
+		}
+		if ruleLast.After(last) {
+			last = ruleLast
+		}
+	}
+
+	for _, rdate := range rdates {
+		if rdate.After(last) {
+			last = rrule.Day(rdate)
+		}
+	}
+
+	return &last
+}
+
+// derivedObjectID gives expanded occurrences stable ids, so the weeks synced
+// from them keep matching the same slot on every read.
+func derivedObjectID(parts ...string) primitive.ObjectID {
+	hash := sha1.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	var id primitive.ObjectID
+	copy(id[:], hash.Sum(nil))
+	return id
+}
+
+// seriesDayID is the id of a day that only exists through a series. A stored
+// day created for an override on that date reuses it.
+func seriesDayID(organizationID string, date time.Time) primitive.ObjectID {
+	return derivedObjectID("day", organizationID, date.Format("2006-01-02"))
+}
+
+// expandSlotSeries adds the occurrences of series between from and to to the
+// stored days, creating days that only exist through a series, and applies
+// the closures of the calendar: occurrences skip closed days, make-up days
+// get the occurrences of the day they stand in for, and every closed or
+// make-up day in the range carries its closure. Closed days lose their slots.
+func expandSlotSeries(organizationID string, days []*DefaultDayColorTime, series []*DefaultSlotSeries, closures *closure.Calendar, from, to time.Time) []*DefaultDayColorTime {
+
+	dayMap := make(map[string]*DefaultDayColorTime, len(days))
+	for _, day := range days {
+		dayMap[day.Date.Format("2006-01-02")] = day
+	}
+
+	virtualDay := func(date time.Time) *DefaultDayColorTime {
+		dateStr := date.Format("2006-01-02")
+		day, exists := dayMap[dateStr]
+		if !exists {
+			day = &DefaultDayColorTime{
+				ID:             seriesDayID(organizationID, date),
+				OrganizationID: organizationID,
+				Date:           date,
+				TimeSlots:      []*DefaultColorBlock{},
+				RepeatType:     "none",
+			}
+			dayMap[dateStr] = day
+			days = append(days, day)
+		}
+		return day
+	}
+
+	for _, item := range series {
+		occurrences, err := item.occurrencesAround(closures, from, to)
+		if err != nil || item.Slot == nil {
+			continue
+		}
+
+		for _, date := range occurrences {
+			day := virtualDay(date)
+			if day.CreatedBy == "" {
+				day.CreatedBy = item.CreatedBy
+				day.CreatedAt = item.CreatedAt
+			}
+
+			if item.UpdatedAt.After(day.UpdatedAt) {
+				day.UpdatedAt = item.UpdatedAt
+			}
+
+			var block *DefaultColorBlock
+			for _, b := range day.TimeSlots {
+				if b.BlockID == item.BlockID {
+					block = b
+					break
+				}
+			}
+			if block == nil {
+				block = &DefaultColorBlock{
+					BlockID: item.BlockID,
+					Slots:   []*DefaultColortimeSlot{},
+				}
+				day.TimeSlots = append(day.TimeSlots, block)
+			}
+
+			origin := item.origin()
+			slot := *item.Slot
+			slot.SlotID = item.occurrenceSlotID(date)
+			slot.SeriesID = &origin
+			slot.Sessions = len(block.Slots) + 1
+			slot.UpdatedAt = item.UpdatedAt
+			block.Slots = append(block.Slots, &slot)
+		}
+	}
+
+	for date := rrule.Day(from); !date.After(to); date = date.AddDate(0, 0, 1) {
+		dayClosure := closures.Day(date)
+		if dayClosure == nil {
+			continue
+		}
+
+		day := virtualDay(date)
+		day.Closure = dayClosure
+		if dayClosure.Kind == closure.KindClosed {
+			day.TimeSlots = []*DefaultColorBlock{}
+		}
+		if dayClosure.UpdatedAt.After(day.UpdatedAt) {
+			day.UpdatedAt = dayClosure.UpdatedAt
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+	return days
+}
+
+// occurrencesAround returns the occurrences of the series between from and to
+// as the closures shape them: none on closed days, and one on each make-up
+// day whose reference day has an occurrence.
+func (s *DefaultSlotSeries) occurrencesAround(closures *closure.Calendar, from, to time.Time) ([]time.Time, error) {
+	occurrences, err := s.occurrences(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[time.Time]bool, len(occurrences))
+	days := occurrences[:0]
+	for _, date := range occurrences {
+		if closures.IsClosed(date) {
+			continue
+		}
+		seen[date] = true
+		days = append(days, date)
+	}
+
+	for date := rrule.Day(from); !date.After(to); date = date.AddDate(0, 0, 1) {
+		reference, ok := closures.MakeUpReference(date)
+		if !ok || seen[date] {
+			continue
+		}
+
+		if referenced, err := s.occurrences(reference, reference); err == nil && len(referenced) > 0 {
+			days = append(days, date)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// seriesRecurrence resolves the recurrence of a create request into an RRULE
+// and extra dates. Legacy repeat settings are converted and need repeat_until;
+// an empty rule with no rdates means the slot does not repeat.
+func seriesRecurrence(req *CreateDefaultDayColorTimeRequest, calendar *organization_setting.Calendar, date time.Time) (*rrule.Rule, []time.Time, error) {
+
+	if req.RRule != "" {
+		rule, err := rrule.Parse(req.RRule)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid rrule: %w", err)
+		}
+		return rule, nil, nil
+	}
+
+	repeatType := req.RepeatType
+	if repeatType == "" || repeatType == "none" {
+		return nil, nil, nil
+	}
+
+	if req.RepeatUntil == "" {
+		return nil, nil, fmt.Errorf("repeat_until is required for repeat type %s, use rrule for an open-ended series", repeatType)
+	}
+
+	until, err := calendar.ParseDate(req.RepeatUntil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid repeat_until: %w", err)
+	}
+
+	interval := req.RepeatInterval
+	if interval == 0 {
+		interval = 1
+	}
+
+	rule := &rrule.Rule{Interval: interval, Until: &until, WeekStart: calendar.WeekStart}
+
+	switch repeatType {
+	case "daily":
+		rule.Freq = rrule.Daily
+	case "weekly":
+		rule.Freq = rrule.Weekly
+		for _, weekday := range req.RepeatDays {
+			if weekday < 0 || weekday > 6 {
+				return nil, nil, fmt.Errorf("invalid repeat day %d, use 0=Sun..6=Sat", weekday)
+			}
+			rule.ByDay = append(rule.ByDay, rrule.WeekdayNum{Weekday: time.Weekday(weekday)})
+		}
+	case "monthly":
+		rule.Freq = rrule.Monthly
+	case "custom":
+		var rdates []time.Time
+		for _, offset := range req.RepeatDays {
+			d := date.AddDate(0, 0, offset)
+			if offset != 0 && !d.After(until) {
+				rdates = append(rdates, d)
+			}
+		}
+		return nil, rdates, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported repeat type %s", repeatType)
+	}
+
+	return rule, nil, nil
+}
+
+// createSlotSeries stores a recurring slot once instead of copying it into
+// every day it repeats on.
+func (s *defaultColorTimeService) createSlotSeries(ctx context.Context, req *CreateDefaultDayColorTimeRequest, calendar *organization_setting.Calendar, date time.Time, rule *rrule.Rule, rdates []time.Time, slot *DefaultColortimeSlot, userID string) (*DefaultDayColorTimeResponse, error) {
+
+	var exdates []time.Time
+	for _, value := range req.ExDates {
+		exdate, err := calendar.ParseDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exdate %s: %w", value, err)
+		}
+		exdates = append(exdates, exdate)
+	}
+
+	blockID := primitive.NewObjectID()
+	if req.BlockID != "" {
+		if id, err := primitive.ObjectIDFromHex(req.BlockID); err == nil {
+			blockID = id
+		}
+	}
+
+	seriesID := primitive.NewObjectID()
+	series := &DefaultSlotSeries{
+		ID:             seriesID,
+		OriginID:       seriesID,
+		OrganizationID: req.OrganizationID,
+		DTStart:        date,
+		LastDate:       lastDate(rule, date, rdates),
+		ExDates:        exdates,
+		RDates:         rdates,
+		BlockID:        blockID,
+		Slot:           slot,
+		CreatedBy:      userID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if rule != nil {
+		series.RRule = rule.String()
+	}
+
+	occurrences, err := series.occurrences(series.DTStart, series.horizon())
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule: %w", err)
+	}
+
+	if len(occurrences) == 0 {
+		return nil, errors.New("recurrence has no occurrences")
+	}
+
+	if err := s.checkSeriesConflicts(ctx, series, occurrences); err != nil {
+		return nil, err
+	}
+
+	if err := s.DefaultColorTimeRepository.CreateSlotSeries(ctx, series); err != nil {
+		return nil, fmt.Errorf("failed to create slot series: %w", err)
+	}
+
+	s.notifyChanged(ctx, series.OrganizationID, occurrences...)
+
+	days, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, occurrences[0], occurrences[0], req.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(days) == 0 {
+		return nil, fmt.Errorf("failed to retrieve base day after creation")
+	}
+
+	baseDay := days[0]
+	return &DefaultDayColorTimeResponse{
+		ID:              baseDay.ID,
+		OrganizationID:  baseDay.OrganizationID,
+		Date:            baseDay.Date,
+		TimeSlots:       baseDay.TimeSlots,
+		IsBaseTemplate:  true,
+		RepeatType:      baseDay.RepeatType,
+		CreatedBlockID:  &series.BlockID,
+		CreatedSeriesID: &series.ID,
+		CreatedBy:       baseDay.CreatedBy,
+		CreatedAt:       baseDay.CreatedAt,
+		UpdatedAt:       baseDay.UpdatedAt,
+		Version:         baseDay.Version,
+	}, nil
+}
+
+// checkSeriesConflicts rejects a series whose slot overlaps an existing slot
+// on one of its occurrences.
+func (s *defaultColorTimeService) checkSeriesConflicts(ctx context.Context, series *DefaultSlotSeries, occurrences []time.Time) error {
+
+	days, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, occurrences[0], occurrences[len(occurrences)-1], series.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	dayMap := make(map[string]*DefaultDayColorTime, len(days))
+	for _, day := range days {
+		dayMap[day.Date.Format("2006-01-02")] = day
+	}
+
+	for _, date := range occurrences {
+		day, exists := dayMap[date.Format("2006-01-02")]
+		if !exists {
+			continue
+		}
+
+		var allSlots []*DefaultColortimeSlot
+		for _, block := range day.TimeSlots {
+			for _, slot := range block.Slots {
+				if slot.SeriesID == nil || *slot.SeriesID != series.origin() {
+					allSlots = append(allSlots, slot)
+				}
+			}
+		}
+
+		if isTimeSlotConflict(series.Slot.StartTime, series.Slot.EndTime, allSlots, nil) {
+			return fmt.Errorf("time slot conflicts with existing slots on %s", date.Format("2006-01-02"))
+		}
+	}
+
+	return nil
+}
+
+func (s *defaultColorTimeService) GetSlotSeries(ctx context.Context, id string) (*DefaultSlotSeries, error) {
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid series id format")
+	}
+
+	series, err := s.DefaultColorTimeRepository.GetSlotSeriesByID(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot series: %w", err)
+	}
+
+	if series == nil {
+		return nil, errors.New("slot series not found")
+	}
+
+	return series, nil
+}
+
+func (s *defaultColorTimeService) GetSlotSeriesList(ctx context.Context, orgID string) ([]*DefaultSlotSeries, error) {
+
+	if orgID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	series, err := s.DefaultColorTimeRepository.GetSlotSeriesByOrganization(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot series: %w", err)
+	}
+
+	if series == nil {
+		series = make([]*DefaultSlotSeries, 0)
+	}
+
+	return series, nil
+}
+
+// DeleteSlotSeries removes the series with all of its occurrences.
+func (s *defaultColorTimeService) DeleteSlotSeries(ctx context.Context, id string) error {
+
+	series, err := s.GetSlotSeries(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := helper.CheckIfMatch(ctx, series.Version); err != nil {
+		return err
+	}
+
+	return s.deleteSeries(ctx, series)
+}
+
+// ExcludeSeriesOccurrence skips one occurrence of the series by adding its
+// date to the EXDATE list.
+func (s *defaultColorTimeService) ExcludeSeriesOccurrence(ctx context.Context, id string, req *ExcludeSeriesOccurrenceRequest) error {
+
+	series, err := s.GetSlotSeries(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := helper.CheckIfMatch(ctx, series.Version); err != nil {
+		return err
+	}
+
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, series.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	date, err := calendar.ParseDate(req.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date format: %w", err)
+	}
+
+	occurrences, err := series.occurrences(date, date)
+	if err != nil {
+		return fmt.Errorf("invalid rrule: %w", err)
+	}
+
+	if len(occurrences) == 0 {
+		return fmt.Errorf("series has no occurrence on %s", req.Date)
+	}
+
+	return s.excludeOccurrence(ctx, series, date)
+}
+
+func (s *defaultColorTimeService) deleteSeries(ctx context.Context, series *DefaultSlotSeries) error {
+
+	occurrences, err := series.occurrences(series.DTStart, series.horizon())
+	if err != nil {
+		return fmt.Errorf("invalid rrule: %w", err)
+	}
+
+	if err := s.DefaultColorTimeRepository.DeleteSlotSeries(ctx, series.ID); err != nil {
+		return fmt.Errorf("failed to delete slot series: %w", err)
+	}
+
+	if err := s.DefaultColorTimeRepository.RemoveSeriesOverrides(ctx, series.OrganizationID, series.origin(), series.DTStart, series.LastDate); err != nil {
+		return fmt.Errorf("failed to remove series overrides: %w", err)
+	}
+
+	if err := s.DefaultColorTimeRepository.TouchDefaultDays(ctx, series.OrganizationID, series.DTStart, series.LastDate); err != nil {
+		return fmt.Errorf("failed to touch default days: %w", err)
+	}
+
+	s.notifyChanged(ctx, series.OrganizationID, occurrences...)
+
+	return nil
+}
+
+func (s *defaultColorTimeService) excludeOccurrence(ctx context.Context, series *DefaultSlotSeries, date time.Time) error {
+
+	series.ExDates = append(series.ExDates, date)
+	series.UpdatedAt = time.Now()
+
+	if err := s.DefaultColorTimeRepository.UpdateSlotSeries(ctx, series); err != nil {
+		return err
+	}
+
+	if err := s.DefaultColorTimeRepository.TouchDefaultDays(ctx, series.OrganizationID, date, &date); err != nil {
+		return fmt.Errorf("failed to touch default days: %w", err)
+	}
+
+	s.notifyChanged(ctx, series.OrganizationID, date)
+
+	return nil
+}
+
+// seriesOccurrence is one day of a series an edit scope is applied from.
+type seriesOccurrence struct {
+	Series *DefaultSlotSeries
+	Date   time.Time
+}
+
+// resolveEditScope validates the scope of an update or delete, defaulting to
+// the single occurrence.
+func resolveEditScope(scope *EditScopeRequest) (string, error) {
+	if scope == nil || scope.Scope == "" {
+		return EditScopeThis, nil
+	}
+
+	switch scope.Scope {
+	case EditScopeThis, EditScopeFollowing, EditScopeAll:
+		return scope.Scope, nil
+	}
+
+	return "", fmt.Errorf("invalid scope %s (use this, following or all)", scope.Scope)
+}
+
+// findSeriesOccurrence resolves a slot id shown on a day to the series
+// occurrence it was expanded from. day is the stored day, nil when the day
+// only exists through a series; the series id of the slot is needed then.
+func (s *defaultColorTimeService) findSeriesOccurrence(ctx context.Context, dayID, slotID primitive.ObjectID, day *DefaultDayColorTime, seriesID string) (*seriesOccurrence, error) {
+
+	var candidates []*DefaultSlotSeries
+	if day != nil {
+		series, err := s.DefaultColorTimeRepository.GetSlotSeriesInRange(ctx, day.Date, day.Date, day.OrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get slot series: %w", err)
+		}
+		candidates = series
+	} else {
+		if seriesID == "" {
+			return nil, nil
+		}
+
+		originID, err := primitive.ObjectIDFromHex(seriesID)
+		if err != nil {
+			return nil, errors.New("invalid series id format")
+		}
+
+		series, err := s.DefaultColorTimeRepository.GetSlotSeriesByOrigin(ctx, originID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get slot series: %w", err)
+		}
+		candidates = series
+	}
+
+	for _, series := range candidates {
+		from, to := series.DTStart, series.horizon()
+		if day != nil {
+			from, to = day.Date, day.Date
+		}
+
+		occurrences, err := series.occurrences(from, to)
+		if err != nil {
+			continue
+		}
+
+		for _, date := range occurrences {
+			if series.occurrenceSlotID(date) != slotID {
+				continue
+			}
+			if day == nil && seriesDayID(series.OrganizationID, date) != dayID {
+				continue
+			}
+			return &seriesOccurrence{Series: series, Date: date}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findOverrideSeries returns the series of the recurring slot that covers the
+// day of a stored override.
+func (s *defaultColorTimeService) findOverrideSeries(ctx context.Context, day *DefaultDayColorTime, originID primitive.ObjectID) (*seriesOccurrence, error) {
+
+	family, err := s.DefaultColorTimeRepository.GetSlotSeriesByOrigin(ctx, originID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot series: %w", err)
+	}
+
+	date := rrule.Day(day.Date)
+	for _, series := range family {
+		if series.DTStart.After(date) || (series.LastDate != nil && series.LastDate.Before(date)) {
+			continue
+		}
+		return &seriesOccurrence{Series: series, Date: date}, nil
+	}
+
+	return nil, errors.New("slot series not found")
+}
+
+// cloneSlot copies slot including its translations, so updating the copy
+// leaves the series untouched.
+func cloneSlot(slot *DefaultColortimeSlot) *DefaultColortimeSlot {
+	clone := *slot
+	clone.ColorTimeSlotLanguage = make([]*DefaultColorTimeSlotLanguage, 0, len(slot.ColorTimeSlotLanguage))
+	for _, lang := range slot.ColorTimeSlotLanguage {
+		langCopy := *lang
+		clone.ColorTimeSlotLanguage = append(clone.ColorTimeSlotLanguage, &langCopy)
+	}
+	return &clone
+}
+
+// splitSeries ends series the day before date and returns a new series, not
+// yet stored, with the occurrences from date on. Both keep the origin, so the
+// occurrences keep their slot ids.
+func splitSeries(series *DefaultSlotSeries, date time.Time) (*DefaultSlotSeries, error) {
+	date = rrule.Day(date)
+	dayBefore := date.AddDate(0, 0, -1)
+
+	var headRule, tailRule *rrule.Rule
+	if series.RRule != "" {
+		rule, err := rrule.Parse(series.RRule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rrule: %w", err)
+		}
+
+		head, tail := *rule, *rule
+		head.Count = 0
+		head.Until = &dayBefore
+		if rule.Count > 0 {
+			tail.Count = rule.Count - len(rule.Between(series.DTStart, series.DTStart, dayBefore, nil))
+		}
+		headRule, tailRule = &head, &tail
+	}
+
+	var headExDates, tailExDates []time.Time
+	for _, exdate := range series.ExDates {
+		if exdate.Before(date) {
+			headExDates = append(headExDates, exdate)
+		} else {
+			tailExDates = append(tailExDates, exdate)
+		}
+	}
+
+	var headRDates, tailRDates []time.Time
+	for _, rdate := range series.RDates {
+		if rdate.Before(date) {
+			headRDates = append(headRDates, rdate)
+		} else if rdate.After(date) {
+			tailRDates = append(tailRDates, rdate)
+		}
+	}
+
+	tail := &DefaultSlotSeries{
+		ID:             primitive.NewObjectID(),
+		OriginID:       series.origin(),
+		OrganizationID: series.OrganizationID,
+		DTStart:        date,
+		LastDate:       lastDate(tailRule, date, tailRDates),
+		ExDates:        tailExDates,
+		RDates:         tailRDates,
+		BlockID:        series.BlockID,
+		Slot:           cloneSlot(series.Slot),
+		CreatedBy:      series.CreatedBy,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if tailRule != nil {
+		tail.RRule = tailRule.String()
+	}
+
+	series.OriginID = series.origin()
+	if headRule != nil {
+		series.RRule = headRule.String()
+	}
+	series.ExDates = headExDates
+	series.RDates = headRDates
+	series.LastDate = lastDate(headRule, series.DTStart, headRDates)
+	series.UpdatedAt = time.Now()
+
+	return tail, nil
+}
+
+// overrideOccurrence replaces one occurrence by a stored slot on its day and
+// excludes the date from the series. The override keeps the occurrence's slot
+// id, so synced weeks update the slot in place.
+func (s *defaultColorTimeService) overrideOccurrence(ctx context.Context, occurrence *seriesOccurrence, req *UpdateDefaultColorSlotRequest) error {
+	series, date := occurrence.Series, occurrence.Date
+
+	origin := series.origin()
+	slot := cloneSlot(series.Slot)
+	slot.SlotID = series.occurrenceSlotID(date)
+	slot.SeriesID = &origin
+	slot.CreatedAt = time.Now()
+	if err := applySlotUpdate(slot, req); err != nil {
+		return err
+	}
+
+	if slotTimeChanged(req) {
+		if err := s.checkDayConflict(ctx, series.OrganizationID, date, slot); err != nil {
+			return err
+		}
+	}
+
+	series.ExDates = append(series.ExDates, date)
+	series.UpdatedAt = time.Now()
+	if err := s.DefaultColorTimeRepository.UpdateSlotSeries(ctx, series); err != nil {
+		return err
+	}
+
+	existingDay, err := s.DefaultColorTimeRepository.GetDefaultDayColorTime(ctx, date, series.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	day := existingDay
+	if day == nil {
+		day = &DefaultDayColorTime{
+			ID:             seriesDayID(series.OrganizationID, date),
+			OrganizationID: series.OrganizationID,
+			Date:           date,
+			TimeSlots:      []*DefaultColorBlock{},
+			CreatedBy:      series.CreatedBy,
+			CreatedAt:      time.Now(),
+			IsBaseTemplate: true,
+			RepeatType:     "none",
+			RepeatInterval: 1,
+		}
+	}
+
+	var block *DefaultColorBlock
+	for _, b := range day.TimeSlots {
+		if b.BlockID == series.BlockID {
+			block = b
+			break
+		}
+	}
+	if block == nil {
+		block = &DefaultColorBlock{
+			BlockID: series.BlockID,
+			Slots:   []*DefaultColortimeSlot{},
+		}
+		day.TimeSlots = append(day.TimeSlots, block)
+	}
+
+	slot.Sessions = len(block.Slots) + 1
+	block.Slots = append(block.Slots, slot)
+	now := time.Now()
+	day.UpdatedAt = now
+	day.CustomizedAt = &now
+
+	if existingDay == nil {
+		err = s.DefaultColorTimeRepository.CreateDefaultDayColorTime(ctx, day)
+	} else {
+		err = s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, day.ID, day)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save day %s: %w", date.Format("2006-01-02"), err)
+	}
+
+	s.notifyChanged(ctx, series.OrganizationID, date)
+
+	return nil
+}
+
+// updateSeriesOccurrences applies req to every occurrence of the recurring
+// slot, or to the occurrence and the ones after it. Single-day overrides are
+// left as they are. Nothing is written when the moved slot would overlap
+// another slot on one of the occurrences.
+func (s *defaultColorTimeService) updateSeriesOccurrences(ctx context.Context, occurrence *seriesOccurrence, req *UpdateDefaultColorSlotRequest, scope string) error {
+
+	family, err := s.DefaultColorTimeRepository.GetSlotSeriesByOrigin(ctx, occurrence.Series.origin())
+	if err != nil {
+		return fmt.Errorf("failed to get slot series: %w", err)
+	}
+
+	if slotTimeChanged(req) {
+		if err := s.checkSeriesUpdateConflicts(ctx, family, occurrence, req, scope); err != nil {
+			return err
+		}
+	}
+
+	var changed []time.Time
+	for _, series := range family {
+		if series.ID == occurrence.Series.ID {
+			series = occurrence.Series
+		}
+
+		if scope == EditScopeFollowing && series.LastDate != nil && series.LastDate.Before(occurrence.Date) {
+			continue
+		}
+
+		if scope == EditScopeFollowing && series.DTStart.Before(occurrence.Date) {
+			tail, err := splitSeries(series, occurrence.Date)
+			if err != nil {
+				return err
+			}
+			if err := applySlotUpdate(tail.Slot, req); err != nil {
+				return err
+			}
+			if err := s.DefaultColorTimeRepository.UpdateSlotSeries(ctx, series); err != nil {
+				return err
+			}
+			if err := s.DefaultColorTimeRepository.CreateSlotSeries(ctx, tail); err != nil {
+				return fmt.Errorf("failed to create slot series: %w", err)
+			}
+			series = tail
+		} else {
+			if err := applySlotUpdate(series.Slot, req); err != nil {
+				return err
+			}
+			series.UpdatedAt = time.Now()
+			if err := s.DefaultColorTimeRepository.UpdateSlotSeries(ctx, series); err != nil {
+				return err
+			}
+		}
+
+		occurrences, err := series.occurrences(series.DTStart, series.horizon())
+		if err != nil {
+			return fmt.Errorf("invalid rrule: %w", err)
+		}
+		changed = append(changed, occurrences...)
+	}
+
+	s.notifyChanged(ctx, occurrence.Series.OrganizationID, changed...)
+
+	return nil
+}
+
+// checkSeriesUpdateConflicts runs the conflict check of series creation on
+// the occurrences updateSeriesOccurrences would move, with the updated slot.
+func (s *defaultColorTimeService) checkSeriesUpdateConflicts(ctx context.Context, family []*DefaultSlotSeries, occurrence *seriesOccurrence, req *UpdateDefaultColorSlotRequest, scope string) error {
+
+	for _, series := range family {
+		if series.ID == occurrence.Series.ID {
+			series = occurrence.Series
+		}
+
+		if scope == EditScopeFollowing && series.LastDate != nil && series.LastDate.Before(occurrence.Date) {
+			continue
+		}
+
+		from := series.DTStart
+		if scope == EditScopeFollowing && from.Before(occurrence.Date) {
+			from = occurrence.Date
+		}
+
+		updated := *series
+		updated.Slot = cloneSlot(series.Slot)
+		if err := applySlotUpdate(updated.Slot, req); err != nil {
+			return err
+		}
+
+		occurrences, err := updated.occurrences(from, updated.horizon())
+		if err != nil {
+			return fmt.Errorf("invalid rrule: %w", err)
+		}
+
+		if len(occurrences) == 0 {
+			continue
+		}
+
+		if err := s.checkSeriesConflicts(ctx, &updated, occurrences); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteSeriesOccurrences removes every occurrence of the recurring slot, or
+// the occurrence and the ones after it, together with their overrides.
+func (s *defaultColorTimeService) deleteSeriesOccurrences(ctx context.Context, occurrence *seriesOccurrence, scope string) error {
+
+	family, err := s.DefaultColorTimeRepository.GetSlotSeriesByOrigin(ctx, occurrence.Series.origin())
+	if err != nil {
+		return fmt.Errorf("failed to get slot series: %w", err)
+	}
+
+	for _, series := range family {
+		if series.ID == occurrence.Series.ID {
+			series = occurrence.Series
+		}
+
+		if scope == EditScopeFollowing && series.LastDate != nil && series.LastDate.Before(occurrence.Date) {
+			continue
+		}
+
+		if scope != EditScopeFollowing || !series.DTStart.Before(occurrence.Date) {
+			if err := s.deleteSeries(ctx, series); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tail, err := splitSeries(series, occurrence.Date)
+		if err != nil {
+			return err
+		}
+
+		occurrences, err := tail.occurrences(tail.DTStart, tail.horizon())
+		if err != nil {
+			return fmt.Errorf("invalid rrule: %w", err)
+		}
+
+		if err := s.DefaultColorTimeRepository.UpdateSlotSeries(ctx, series); err != nil {
+			return err
+		}
+
+		if err := s.DefaultColorTimeRepository.RemoveSeriesOverrides(ctx, series.OrganizationID, series.origin(), tail.DTStart, tail.LastDate); err != nil {
+			return fmt.Errorf("failed to remove series overrides: %w", err)
+		}
+
+		if err := s.DefaultColorTimeRepository.TouchDefaultDays(ctx, series.OrganizationID, tail.DTStart, tail.LastDate); err != nil {
+			return fmt.Errorf("failed to touch default days: %w", err)
+		}
+
+		s.notifyChanged(ctx, series.OrganizationID, occurrences...)
+	}
+
+	return nil
+}
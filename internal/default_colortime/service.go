@@ -1,6 +1,9 @@
 package default_colortime
 
 import (
+	"colortime-service/helper"
+	"colortime-service/internal/closure"
+	"colortime-service/internal/organization_setting"
 	"colortime-service/internal/product"
 	"colortime-service/internal/topic"
 	"context"
@@ -8,7 +11,9 @@ import (
 	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type DefaultColorTimeService interface {
@@ -17,30 +22,60 @@ type DefaultColorTimeService interface {
 	GetDefaultDayColorTimesInRange(ctx context.Context, orgID, startDate, endDate, userID string, languageID *int) ([]*DefaultDayColorTimeResponse, error)
 	GetAllDefaultDayColorTimes(ctx context.Context, orgID string) ([]*DefaultDayColorTimeResponse, error)
 	GetBlockBySlotID(ctx context.Context, dayID, slotID string) (*BlockWithSlotResponse, error)
-	UpdateDefaultColorSlot(ctx context.Context, dayID, slotID string, req *UpdateDefaultColorSlotRequest) error
+	UpdateDefaultColorSlot(ctx context.Context, dayID, slotID string, req *UpdateDefaultColorSlotRequest, scope *EditScopeRequest) error
 	DeleteDefaultDayColorTime(ctx context.Context, id string) error
-	DeleteDefaultDayColorTimeSlot(ctx context.Context, dayID, slotID string, userID string) error
+	DeleteDefaultDayColorTimeSlot(ctx context.Context, dayID, slotID string, userID string, scope *EditScopeRequest) error
 	DeleteDefaultDayColorTimeBlock(ctx context.Context, dayID, blockID string, userID string) error
+
+	CreateDefaultBlock(ctx context.Context, dayID string, userID string) (*DefaultColorBlock, error)
+	MoveDefaultBlock(ctx context.Context, dayID, blockID string, req *MoveBlockRequest, userID string) error
+	ReorderDefaultBlockSlots(ctx context.Context, dayID, blockID string, req *ReorderBlockSlotsRequest, userID string) error
+	SplitDefaultBlock(ctx context.Context, dayID, blockID string, req *SplitBlockRequest, userID string) (*DefaultColorBlock, error)
+	MergeDefaultBlocks(ctx context.Context, dayID, blockID string, req *MergeBlocksRequest, userID string) error
+
+	GetSlotSeries(ctx context.Context, id string) (*DefaultSlotSeries, error)
+	GetSlotSeriesList(ctx context.Context, orgID string) ([]*DefaultSlotSeries, error)
+	DeleteSlotSeries(ctx context.Context, id string) error
+	ExcludeSeriesOccurrence(ctx context.Context, id string, req *ExcludeSeriesOccurrenceRequest) error
+}
+
+// DefaultDayChangeNotifier is told which default days of an organization
+// changed so the weeks materialized from them can be refreshed.
+type DefaultDayChangeNotifier interface {
+	NotifyDefaultDayChanged(ctx context.Context, organizationID string, dates []time.Time)
 }
 
 type defaultColorTimeService struct {
 	DefaultColorTimeRepository DefaultColorTimeRepository
 	ProductService             product.ProductService
 	TopicService               topic.TopicService
+	OrganizationSettingService organization_setting.OrganizationSettingService
+	ChangeNotifier             DefaultDayChangeNotifier
 }
 
 func NewDefaultColorTimeService(
 	defaultColorTimeRepository DefaultColorTimeRepository,
 	productService product.ProductService,
 	topicService topic.TopicService,
+	organizationSettingService organization_setting.OrganizationSettingService,
+	changeNotifier DefaultDayChangeNotifier,
 ) DefaultColorTimeService {
 	return &defaultColorTimeService{
 		DefaultColorTimeRepository: defaultColorTimeRepository,
 		ProductService:             productService,
 		TopicService:               topicService,
+		OrganizationSettingService: organizationSettingService,
+		ChangeNotifier:             changeNotifier,
 	}
 }
 
+func (s *defaultColorTimeService) notifyChanged(ctx context.Context, organizationID string, dates ...time.Time) {
+	if s.ChangeNotifier == nil {
+		return
+	}
+	s.ChangeNotifier.NotifyDefaultDayChanged(ctx, organizationID, dates)
+}
+
 func isTimeSlotConflict(newStart, newEnd time.Time, existingSlots []*DefaultColortimeSlot, excludeSlotID *primitive.ObjectID) bool {
 	for _, slot := range existingSlots {
 		if excludeSlotID != nil && slot.SlotID == *excludeSlotID {
@@ -93,7 +128,12 @@ func (s *defaultColorTimeService) CreateDefaultDayColorTime(ctx context.Context,
 		}
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, req.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := calendar.ParseDate(req.Date)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date format: %w", err)
 	}
@@ -105,57 +145,6 @@ func (s *defaultColorTimeService) CreateDefaultDayColorTime(ctx context.Context,
 
 	endTime := startTime.Add(time.Duration(req.Duration) * time.Second)
 
-	repeatType := req.RepeatType
-	if repeatType == "" {
-		repeatType = "none"
-	}
-
-	repeatInterval := req.RepeatInterval
-	if repeatInterval == 0 {
-		repeatInterval = 1
-	}
-
-	var repeatUntil *time.Time
-	if req.RepeatUntil != "" {
-		if parsedUntil, err := time.Parse("2006-01-02", req.RepeatUntil); err == nil {
-			repeatUntil = &parsedUntil
-		}
-	}
-
-	dates := []time.Time{date}
-	maxEnd := time.Time{}
-	if repeatUntil != nil {
-		maxEnd = *repeatUntil
-	}
-
-	if repeatType != "none" && !maxEnd.IsZero() {
-		switch repeatType {
-		case "daily":
-			for d := date.AddDate(0, 0, repeatInterval); !d.After(maxEnd); d = d.AddDate(0, 0, repeatInterval) {
-				dates = append(dates, d)
-			}
-		case "weekly":
-			for d := date.AddDate(0, 0, 7*repeatInterval); !d.After(maxEnd); d = d.AddDate(0, 0, 7*repeatInterval) {
-				dates = append(dates, d)
-			}
-		case "monthly":
-			for d := date.AddDate(0, repeatInterval, 0); !d.After(maxEnd); d = d.AddDate(0, repeatInterval, 0) {
-				dates = append(dates, d)
-			}
-		case "custom":
-			for _, off := range req.RepeatDays {
-				d := date.AddDate(0, 0, off)
-				if !d.After(maxEnd) {
-					if !sameDay(d, date) {
-						dates = append(dates, d)
-					}
-				}
-			}
-		default:
-
-		}
-	}
-
 	baseSlot := &DefaultColortimeSlot{
 		SlotID:                primitive.NewObjectID(),
 		Sessions:              1,
@@ -170,164 +159,123 @@ func (s *defaultColorTimeService) CreateDefaultDayColorTime(ctx context.Context,
 		UpdatedAt:             time.Now(),
 	}
 
-	var baseDayResult *DefaultDayColorTime
-	var baseBlockID *primitive.ObjectID
+	// Recurring slots are stored once as a series and expanded on read
+	rule, rdates, err := seriesRecurrence(req, calendar, date)
+	if err != nil {
+		return nil, err
+	}
 
-	for idx, d := range dates {
-		isBase := sameDay(d, date)
-		existingDay, err := s.DefaultColorTimeRepository.GetDefaultDayColorTime(ctx, d, req.OrganizationID)
-		if err != nil {
-			return nil, err
-		}
-
-		var dayColorTime *DefaultDayColorTime
-		if existingDay != nil {
-			dayColorTime = existingDay
-		} else {
-			dayColorTime = &DefaultDayColorTime{
-				ID:             primitive.NewObjectID(),
-				OrganizationID: req.OrganizationID,
-				Date:           d,
-				TimeSlots:      []*DefaultColorBlock{},
-				CreatedBy:      userID,
-				CreatedAt:      time.Now(),
-				UpdatedAt:      time.Now(),
-
-				IsBaseTemplate: isBase,
-				RepeatType:     repeatType,
-				RepeatUntil:    repeatUntil,
-				RepeatInterval: repeatInterval,
-				RepeatDays:     req.RepeatDays,
-			}
-		}
+	if rule != nil || len(rdates) > 0 {
+		return s.createSlotSeries(ctx, req, calendar, date, rule, rdates, baseSlot, userID)
+	}
 
-		var targetBlock *DefaultColorBlock
-		if idx == 0 {
-			if req.BlockID != "" {
-				id, err := primitive.ObjectIDFromHex(req.BlockID)
-				if err == nil {
-					baseBlockID = &id
-				} else {
-					newBlock := &DefaultColorBlock{
-						BlockID: primitive.NewObjectID(),
-						Slots:   []*DefaultColortimeSlot{},
-					}
-					dayColorTime.TimeSlots = append(dayColorTime.TimeSlots, newBlock)
-					baseBlockID = &newBlock.BlockID
-				}
-			} else {
-				newBlock := &DefaultColorBlock{
-					BlockID: primitive.NewObjectID(),
-					Slots:   []*DefaultColortimeSlot{},
-				}
-				dayColorTime.TimeSlots = append(dayColorTime.TimeSlots, newBlock)
-				baseBlockID = &newBlock.BlockID
-			}
+	existingDay, err := s.DefaultColorTimeRepository.GetDefaultDayColorTime(ctx, date, req.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
 
+	dayColorTime := existingDay
+	if dayColorTime == nil {
+		dayColorTime = &DefaultDayColorTime{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: req.OrganizationID,
+			Date:           date,
+			TimeSlots:      []*DefaultColorBlock{},
+			CreatedBy:      userID,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+
+			IsBaseTemplate: true,
+			RepeatType:     "none",
+			RepeatInterval: 1,
+		}
+	}
+
+	var targetBlock *DefaultColorBlock
+	if req.BlockID != "" {
+		if id, err := primitive.ObjectIDFromHex(req.BlockID); err == nil {
 			for _, b := range dayColorTime.TimeSlots {
-				if baseBlockID != nil && b.BlockID == *baseBlockID {
-					targetBlock = b
-					break
-				}
-			}
-		} else {
-			for _, b := range dayColorTime.TimeSlots {
-				if baseBlockID != nil && b.BlockID == *baseBlockID {
+				if b.BlockID == id {
 					targetBlock = b
 					break
 				}
 			}
-
 			if targetBlock == nil {
-				if baseBlockID == nil {
-					targetBlock = &DefaultColorBlock{
-						BlockID: primitive.NewObjectID(),
-						Slots:   []*DefaultColortimeSlot{},
-					}
-				} else {
-					targetBlock = &DefaultColorBlock{
-						BlockID: *baseBlockID,
-						Slots:   []*DefaultColortimeSlot{},
-					}
+				targetBlock = &DefaultColorBlock{
+					BlockID: id,
+					Slots:   []*DefaultColortimeSlot{},
 				}
 				dayColorTime.TimeSlots = append(dayColorTime.TimeSlots, targetBlock)
 			}
 		}
+	}
 
-		var slotToAdd *DefaultColortimeSlot
-		if isBase {
-			slotToAdd = baseSlot
-			slotToAdd.Sessions = len(targetBlock.Slots) + 1
-		} else {
-			slotCopy := &DefaultColortimeSlot{
-				SlotID:                primitive.NewObjectID(),
-				Sessions:              len(targetBlock.Slots) + 1,
-				Title:                 baseSlot.Title,
-				ColorTimeSlotLanguage: baseSlot.ColorTimeSlotLanguage,
-				StartTime:             baseSlot.StartTime,
-				EndTime:               baseSlot.EndTime,
-				Duration:              baseSlot.Duration,
-				Color:                 baseSlot.Color,
-				Note:                  baseSlot.Note,
-				CreatedAt:             time.Now(),
-				UpdatedAt:             time.Now(),
-			}
-			slotToAdd = slotCopy
-		}
-
-		// Check for conflicts with existing slots across entire day
-		var allSlots []*DefaultColortimeSlot
-		for _, b := range dayColorTime.TimeSlots {
-			allSlots = append(allSlots, b.Slots...)
+	if targetBlock == nil {
+		targetBlock = &DefaultColorBlock{
+			BlockID: primitive.NewObjectID(),
+			Slots:   []*DefaultColortimeSlot{},
 		}
+		dayColorTime.TimeSlots = append(dayColorTime.TimeSlots, targetBlock)
+	}
 
-		// Validate time slot conflict across entire day
-		if isTimeSlotConflict(slotToAdd.StartTime, slotToAdd.EndTime, allSlots, nil) {
-			return nil, errors.New("time slot conflicts with existing slots in the day")
-		}
+	baseSlot.Sessions = len(targetBlock.Slots) + 1
 
-		targetBlock.Slots = append(targetBlock.Slots, slotToAdd)
+	// Check for conflicts with existing slots across entire day, including
+	// occurrences of slot series
+	expandedDays, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, date, date, req.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
 
-		if existingDay == nil {
-			if err := s.DefaultColorTimeRepository.CreateDefaultDayColorTime(ctx, dayColorTime); err != nil {
-				return nil, fmt.Errorf("failed to create day %s: %w", d.Format("2006-01-02"), err)
-			}
-		} else {
-			dayColorTime.UpdatedAt = time.Now()
-			if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, dayColorTime.ID, dayColorTime); err != nil {
-				return nil, fmt.Errorf("failed to update day %s: %w", d.Format("2006-01-02"), err)
-			}
+	var allSlots []*DefaultColortimeSlot
+	for _, day := range expandedDays {
+		if day.Closure != nil && day.Closure.Kind == closure.KindClosed {
+			return nil, fmt.Errorf("%s is a closed day (%s)", date.Format("2006-01-02"), day.Closure.Name)
 		}
-
-		if isBase || idx == 0 {
-			baseDayResult = dayColorTime
+		for _, b := range day.TimeSlots {
+			allSlots = append(allSlots, b.Slots...)
 		}
 	}
 
-	if baseDayResult == nil {
-		baseDayResult, err = s.DefaultColorTimeRepository.GetDefaultDayColorTime(ctx, date, req.OrganizationID)
-		if err != nil {
-			return nil, err
+	// Validate time slot conflict across entire day
+	if isTimeSlotConflict(baseSlot.StartTime, baseSlot.EndTime, allSlots, nil) {
+		return nil, errors.New("time slot conflicts with existing slots in the day")
+	}
+
+	targetBlock.Slots = append(targetBlock.Slots, baseSlot)
+
+	if existingDay == nil {
+		if err := s.DefaultColorTimeRepository.CreateDefaultDayColorTime(ctx, dayColorTime); err != nil {
+			return nil, fmt.Errorf("failed to create day %s: %w", date.Format("2006-01-02"), err)
 		}
-		if baseDayResult == nil {
-			return nil, fmt.Errorf("failed to retrieve base day after creation")
+	} else {
+		now := time.Now()
+		dayColorTime.UpdatedAt = now
+		dayColorTime.CustomizedAt = &now
+		if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, dayColorTime.ID, dayColorTime); err != nil {
+			return nil, fmt.Errorf("failed to update day %s: %w", date.Format("2006-01-02"), err)
 		}
 	}
 
+	s.notifyChanged(ctx, req.OrganizationID, date)
+
 	result := &DefaultDayColorTimeResponse{
-		ID:             baseDayResult.ID,
-		OrganizationID: baseDayResult.OrganizationID,
-		Date:           baseDayResult.Date,
-		TimeSlots:      baseDayResult.TimeSlots,
-		IsBaseTemplate: baseDayResult.IsBaseTemplate,
-		RepeatType:     baseDayResult.RepeatType,
-		RepeatUntil:    baseDayResult.RepeatUntil,
-		RepeatInterval: baseDayResult.RepeatInterval,
-		RepeatDays:     baseDayResult.RepeatDays,
-		CreatedBlockID: baseBlockID,
-		CreatedBy:      baseDayResult.CreatedBy,
-		CreatedAt:      baseDayResult.CreatedAt,
-		UpdatedAt:      baseDayResult.UpdatedAt,
+		ID:             dayColorTime.ID,
+		OrganizationID: dayColorTime.OrganizationID,
+		Date:           dayColorTime.Date,
+		TimeSlots:      dayColorTime.TimeSlots,
+		IsBaseTemplate: dayColorTime.IsBaseTemplate,
+		RepeatType:     dayColorTime.RepeatType,
+		RepeatUntil:    dayColorTime.RepeatUntil,
+		RepeatInterval: dayColorTime.RepeatInterval,
+		RepeatDays:     dayColorTime.RepeatDays,
+		Source:         dayColorTime.Source,
+		CustomizedAt:   dayColorTime.CustomizedAt,
+		CreatedBlockID: &targetBlock.BlockID,
+		CreatedBy:      dayColorTime.CreatedBy,
+		CreatedAt:      dayColorTime.CreatedAt,
+		UpdatedAt:      dayColorTime.UpdatedAt,
+		Version:        dayColorTime.Version,
 	}
 
 	return result, nil
@@ -342,20 +290,27 @@ func (s *defaultColorTimeService) GetDefaultDayColorTime(ctx context.Context, or
 		return nil, errors.New("date is required")
 	}
 
-	parsedDate, err := time.Parse("2006-01-02", date)
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedDate, err := calendar.ParseDate(date)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date format: %w", err)
 	}
 
-	dayColorTime, err := s.DefaultColorTimeRepository.GetDefaultDayColorTime(ctx, parsedDate, orgID)
+	dayColorTimes, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, parsedDate, parsedDate, orgID)
 	if err != nil {
 		return nil, err
 	}
 
-	if dayColorTime == nil {
+	if len(dayColorTimes) == 0 {
 		return nil, fmt.Errorf("default day color time not found for date: %s", date)
 	}
 
+	dayColorTime := dayColorTimes[0]
+
 	if languageID != nil {
 		for _, block := range dayColorTime.TimeSlots {
 			for _, slot := range block.Slots {
@@ -380,9 +335,13 @@ func (s *defaultColorTimeService) GetDefaultDayColorTime(ctx context.Context, or
 		RepeatUntil:    dayColorTime.RepeatUntil,
 		RepeatInterval: dayColorTime.RepeatInterval,
 		RepeatDays:     dayColorTime.RepeatDays,
+		Source:         dayColorTime.Source,
+		CustomizedAt:   dayColorTime.CustomizedAt,
+		Closure:        dayColorTime.Closure,
 		CreatedBy:      dayColorTime.CreatedBy,
 		CreatedAt:      dayColorTime.CreatedAt,
 		UpdatedAt:      dayColorTime.UpdatedAt,
+		Version:        dayColorTime.Version,
 	}
 
 	return response, nil
@@ -397,12 +356,17 @@ func (s *defaultColorTimeService) GetDefaultDayColorTimesInRange(ctx context.Con
 		return nil, errors.New("start date and end date are required")
 	}
 
-	startParsed, err := time.Parse("2006-01-02", startDate)
+	calendar, err := s.OrganizationSettingService.GetCalendar(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	startParsed, err := calendar.ParseDate(startDate)
 	if err != nil {
 		return nil, fmt.Errorf("invalid start date format: %w", err)
 	}
 
-	endParsed, err := time.Parse("2006-01-02", endDate)
+	endParsed, err := calendar.ParseDate(endDate)
 	if err != nil {
 		return nil, fmt.Errorf("invalid end date format: %w", err)
 	}
@@ -437,9 +401,13 @@ func (s *defaultColorTimeService) GetDefaultDayColorTimesInRange(ctx context.Con
 			RepeatUntil:    day.RepeatUntil,
 			RepeatInterval: day.RepeatInterval,
 			RepeatDays:     day.RepeatDays,
+			Source:         day.Source,
+			CustomizedAt:   day.CustomizedAt,
+			Closure:        day.Closure,
 			CreatedBy:      day.CreatedBy,
 			CreatedAt:      day.CreatedAt,
 			UpdatedAt:      day.UpdatedAt,
+			Version:        day.Version,
 		}
 		responses = append(responses, response)
 	}
@@ -469,9 +437,12 @@ func (s *defaultColorTimeService) GetAllDefaultDayColorTimes(ctx context.Context
 			RepeatUntil:    day.RepeatUntil,
 			RepeatInterval: day.RepeatInterval,
 			RepeatDays:     day.RepeatDays,
+			Source:         day.Source,
+			CustomizedAt:   day.CustomizedAt,
 			CreatedBy:      day.CreatedBy,
 			CreatedAt:      day.CreatedAt,
 			UpdatedAt:      day.UpdatedAt,
+			Version:        day.Version,
 		}
 		responses = append(responses, response)
 	}
@@ -485,7 +456,20 @@ func (s *defaultColorTimeService) DeleteDefaultDayColorTime(ctx context.Context,
 		return errors.New("invalid id format")
 	}
 
-	return s.DefaultColorTimeRepository.DeleteDefaultDayColorTime(ctx, objID)
+	day, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimeByID(ctx, objID)
+	if err != nil {
+		return fmt.Errorf("failed to get day: %w", err)
+	}
+
+	if err := s.DefaultColorTimeRepository.DeleteDefaultDayColorTime(ctx, objID); err != nil {
+		return err
+	}
+
+	if day != nil {
+		s.notifyChanged(ctx, day.OrganizationID, day.Date)
+	}
+
+	return nil
 }
 
 func (s *defaultColorTimeService) GetBlockBySlotID(ctx context.Context, dayID, slotID string) (*BlockWithSlotResponse, error) {
@@ -535,7 +519,7 @@ func (s *defaultColorTimeService) GetBlockBySlotID(ctx context.Context, dayID, s
 	return nil, fmt.Errorf("slot not found in day")
 }
 
-func (s *defaultColorTimeService) UpdateDefaultColorSlot(ctx context.Context, dayID, slotID string, req *UpdateDefaultColorSlotRequest) error {
+func (s *defaultColorTimeService) UpdateDefaultColorSlot(ctx context.Context, dayID, slotID string, req *UpdateDefaultColorSlotRequest, scope *EditScopeRequest) error {
 
 	dayObjectID, err := primitive.ObjectIDFromHex(dayID)
 	if err != nil {
@@ -547,13 +531,9 @@ func (s *defaultColorTimeService) UpdateDefaultColorSlot(ctx context.Context, da
 		return errors.New("invalid slot_id format")
 	}
 
-	day, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimeByID(ctx, dayObjectID)
+	editScope, err := resolveEditScope(scope)
 	if err != nil {
-		return fmt.Errorf("failed to get day: %w", err)
-	}
-
-	if day == nil {
-		return fmt.Errorf("day not found")
+		return err
 	}
 
 	if req.ColorTimeSlotLanguage != nil {
@@ -565,74 +545,187 @@ func (s *defaultColorTimeService) UpdateDefaultColorSlot(ctx context.Context, da
 		}
 	}
 
-	var slotFound bool
+	day, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimeByID(ctx, dayObjectID)
+	if err != nil {
+		return fmt.Errorf("failed to get day: %w", err)
+	}
+
+	storedSlot := findDefaultSlot(day, slotObjectID)
+
+	// Slots stored on the day, including single-day overrides of a series
+	if storedSlot != nil {
+		if err := helper.CheckIfMatch(ctx, day.Version); err != nil {
+			return err
+		}
+
+		if editScope != EditScopeThis && storedSlot.SeriesID == nil {
+			return errors.New("slot is not part of a series")
+		}
+
+		if err := applySlotUpdate(storedSlot, req); err != nil {
+			return err
+		}
+
+		if slotTimeChanged(req) {
+			if err := s.checkDayConflict(ctx, day.OrganizationID, day.Date, storedSlot); err != nil {
+				return err
+			}
+		}
+
+		// The rest of the series goes first: it is checked for conflicts
+		// before anything is written
+		if editScope != EditScopeThis {
+			occurrence, err := s.findOverrideSeries(ctx, day, *storedSlot.SeriesID)
+			if err != nil {
+				return err
+			}
+
+			if err := s.updateSeriesOccurrences(ctx, occurrence, req, editScope); err != nil {
+				return err
+			}
+		}
+
+		err = s.DefaultColorTimeRepository.UpdateDefaultSlotFields(ctx, dayObjectID, slotObjectID, day.Version, bson.M{
+			"title":                    storedSlot.Title,
+			"color":                    storedSlot.Color,
+			"note":                     storedSlot.Note,
+			"start_time":               storedSlot.StartTime,
+			"end_time":                 storedSlot.EndTime,
+			"duration":                 storedSlot.Duration,
+			"color_time_slot_language": storedSlot.ColorTimeSlotLanguage,
+			"updated_at":               storedSlot.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		s.notifyChanged(ctx, day.OrganizationID, day.Date)
+
+		return nil
+	}
+
+	occurrence, err := s.findSeriesOccurrence(ctx, dayObjectID, slotObjectID, day, scope.SeriesID)
+	if err != nil {
+		return err
+	}
+
+	if occurrence == nil {
+		if day == nil {
+			return fmt.Errorf("day not found")
+		}
+		return fmt.Errorf("slot not found in day")
+	}
+
+	if err := helper.CheckIfMatch(ctx, occurrence.Series.Version); err != nil {
+		return err
+	}
+
+	if editScope == EditScopeThis {
+		return s.overrideOccurrence(ctx, occurrence, req)
+	}
+
+	return s.updateSeriesOccurrences(ctx, occurrence, req, editScope)
+}
+
+// findDefaultSlot returns the slot stored on day, nil when day is nil or does
+// not hold it.
+func findDefaultSlot(day *DefaultDayColorTime, slotID primitive.ObjectID) *DefaultColortimeSlot {
+	if day == nil {
+		return nil
+	}
+
 	for _, block := range day.TimeSlots {
 		for _, slot := range block.Slots {
-			if slot.SlotID == slotObjectID {
-				if req.Title != "" {
-					slot.Title = req.Title
-				}
+			if slot.SlotID == slotID {
+				return slot
+			}
+		}
+	}
 
-				if req.Color != "" {
-					slot.Color = req.Color
-				}
+	return nil
+}
 
-				if req.Note != "" {
-					slot.Note = req.Note
-				}
+// slotTimeChanged reports whether req moves or resizes the slot.
+func slotTimeChanged(req *UpdateDefaultColorSlotRequest) bool {
+	return req.StartTime != "" || req.Duration > 0
+}
 
-				var newStartTime *time.Time
-				if req.StartTime != "" {
-					parsedTime, err := time.Parse("15:04", req.StartTime)
-					if err != nil {
-						return fmt.Errorf("invalid start_time format (use HH:MM): %w", err)
-					}
-					newStartTime = &parsedTime
-					slot.StartTime = time.Date(slot.StartTime.Year(), slot.StartTime.Month(), slot.StartTime.Day(),
-						parsedTime.Hour(), parsedTime.Minute(), 0, 0, slot.StartTime.Location())
-				}
+// checkDayConflict rejects slot when it overlaps another slot of the day on
+// date, occurrences of slot series included.
+func (s *defaultColorTimeService) checkDayConflict(ctx context.Context, organizationID string, date time.Time, slot *DefaultColortimeSlot) error {
 
-				if req.Duration > 0 {
-					slot.Duration = req.Duration
-				}
+	days, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesInRange(ctx, date, date, organizationID)
+	if err != nil {
+		return err
+	}
 
-				if newStartTime != nil || req.Duration > 0 {
-					slot.EndTime = slot.StartTime.Add(time.Duration(slot.Duration) * time.Second)
-				}
+	var allSlots []*DefaultColortimeSlot
+	for _, day := range days {
+		for _, block := range day.TimeSlots {
+			allSlots = append(allSlots, block.Slots...)
+		}
+	}
 
-				if req.ColorTimeSlotLanguage != nil {
-					languageExists := false
-					for i, lang := range slot.ColorTimeSlotLanguage {
-						if lang.LanguageID == req.ColorTimeSlotLanguage.LanguageID {
-							slot.ColorTimeSlotLanguage[i].Title = req.ColorTimeSlotLanguage.Title
-							languageExists = true
-							break
-						}
-					}
-					if !languageExists {
-						slot.ColorTimeSlotLanguage = append(slot.ColorTimeSlotLanguage, req.ColorTimeSlotLanguage)
-					}
-				}
+	if isTimeSlotConflict(slot.StartTime, slot.EndTime, allSlots, &slot.SlotID) {
+		return errors.New("time slot conflicts with existing slots in the day")
+	}
 
-				slot.UpdatedAt = time.Now()
-				slotFound = true
+	return nil
+}
+
+// applySlotUpdate copies the fields set in req onto slot.
+func applySlotUpdate(slot *DefaultColortimeSlot, req *UpdateDefaultColorSlotRequest) error {
+	if req.Title != "" {
+		slot.Title = req.Title
+	}
+
+	if req.Color != "" {
+		slot.Color = req.Color
+	}
+
+	if req.Note != "" {
+		slot.Note = req.Note
+	}
+
+	var newStartTime *time.Time
+	if req.StartTime != "" {
+		parsedTime, err := time.Parse("15:04", req.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid start_time format (use HH:MM): %w", err)
+		}
+		newStartTime = &parsedTime
+		slot.StartTime = time.Date(slot.StartTime.Year(), slot.StartTime.Month(), slot.StartTime.Day(),
+			parsedTime.Hour(), parsedTime.Minute(), 0, 0, slot.StartTime.Location())
+	}
+
+	if req.Duration > 0 {
+		slot.Duration = req.Duration
+	}
+
+	if newStartTime != nil || req.Duration > 0 {
+		slot.EndTime = slot.StartTime.Add(time.Duration(slot.Duration) * time.Second)
+	}
+
+	if req.ColorTimeSlotLanguage != nil {
+		languageExists := false
+		for i, lang := range slot.ColorTimeSlotLanguage {
+			if lang.LanguageID == req.ColorTimeSlotLanguage.LanguageID {
+				slot.ColorTimeSlotLanguage[i].Title = req.ColorTimeSlotLanguage.Title
+				languageExists = true
 				break
 			}
 		}
-		if slotFound {
-			break
+		if !languageExists {
+			slot.ColorTimeSlotLanguage = append(slot.ColorTimeSlotLanguage, req.ColorTimeSlotLanguage)
 		}
 	}
 
-	if !slotFound {
-		return fmt.Errorf("slot not found in day")
-	}
+	slot.UpdatedAt = time.Now()
 
-	day.UpdatedAt = time.Now()
-	return s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, dayObjectID, day)
+	return nil
 }
 
-func (s *defaultColorTimeService) DeleteDefaultDayColorTimeSlot(ctx context.Context, dayID, slotID string, userID string) error {
+func (s *defaultColorTimeService) DeleteDefaultDayColorTimeSlot(ctx context.Context, dayID, slotID string, userID string, scope *EditScopeRequest) error {
 	if dayID == "" {
 		return errors.New("day id is required")
 	}
@@ -653,29 +746,69 @@ func (s *defaultColorTimeService) DeleteDefaultDayColorTimeSlot(ctx context.Cont
 		return errors.New("invalid slot id format")
 	}
 
+	editScope, err := resolveEditScope(scope)
+	if err != nil {
+		return err
+	}
+
 	day, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimeByID(ctx, dayObjectID)
 	if err != nil {
 		return fmt.Errorf("failed to get day: %w", err)
 	}
 
-	if day == nil {
-		return fmt.Errorf("day not found")
-	}
+	storedSlot := findDefaultSlot(day, slotObjectID)
 
-	for _, block := range day.TimeSlots {
-		for i, slot := range block.Slots {
-			if slot.SlotID == slotObjectID {
-				block.Slots = append(block.Slots[:i], block.Slots[i+1:]...)
-				break
+	if storedSlot != nil {
+		if err := helper.CheckIfMatch(ctx, day.Version); err != nil {
+			return err
+		}
+
+		if editScope != EditScopeThis && storedSlot.SeriesID == nil {
+			return errors.New("slot is not part of a series")
+		}
+
+		if err := s.DefaultColorTimeRepository.RemoveDefaultSlot(ctx, dayObjectID, slotObjectID, day.Version); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return errors.New("slot not found")
 			}
+			return fmt.Errorf("failed to update day: %w", err)
 		}
+
+		s.notifyChanged(ctx, day.OrganizationID, day.Date)
+
+		if editScope == EditScopeThis {
+			return nil
+		}
+
+		occurrence, err := s.findOverrideSeries(ctx, day, *storedSlot.SeriesID)
+		if err != nil {
+			return err
+		}
+
+		return s.deleteSeriesOccurrences(ctx, occurrence, editScope)
 	}
 
-	if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, dayObjectID, day); err != nil {
-		return fmt.Errorf("failed to update day: %w", err)
+	occurrence, err := s.findSeriesOccurrence(ctx, dayObjectID, slotObjectID, day, scope.SeriesID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	if occurrence == nil {
+		if day == nil {
+			return fmt.Errorf("day not found")
+		}
+		return errors.New("slot not found")
+	}
+
+	if err := helper.CheckIfMatch(ctx, occurrence.Series.Version); err != nil {
+		return err
+	}
+
+	if editScope == EditScopeThis {
+		return s.excludeOccurrence(ctx, occurrence.Series, occurrence.Date)
+	}
+
+	return s.deleteSeriesOccurrences(ctx, occurrence, editScope)
 }
 
 func (s *defaultColorTimeService) DeleteDefaultDayColorTimeBlock(ctx context.Context, dayID, blockID string, userID string) error {
@@ -710,29 +843,18 @@ func (s *defaultColorTimeService) DeleteDefaultDayColorTimeBlock(ctx context.Con
 		return fmt.Errorf("day not found")
 	}
 
-	var targetBlockIndex = -1
-	for i, block := range day.TimeSlots {
-		if block.BlockID == blockObjectID {
-			targetBlockIndex = i
-			break
-		}
-	}
-
-	if targetBlockIndex == -1 {
-		return errors.New("block not found")
+	if err := helper.CheckIfMatch(ctx, day.Version); err != nil {
+		return err
 	}
 
-	day.TimeSlots = append(day.TimeSlots[:targetBlockIndex], day.TimeSlots[targetBlockIndex+1:]...)
-
-	if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, dayObjectID, day); err != nil {
+	if err := s.DefaultColorTimeRepository.RemoveDefaultBlock(ctx, dayObjectID, blockObjectID, day.Version); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errors.New("block not found")
+		}
 		return fmt.Errorf("failed to update day: %w", err)
 	}
 
-	return nil
-}
+	s.notifyChanged(ctx, day.OrganizationID, day.Date)
 
-func sameDay(a, b time.Time) bool {
-	y1, m1, d1 := a.Date()
-	y2, m2, d2 := b.Date()
-	return y1 == y2 && m1 == m2 && d1 == d2
+	return nil
 }
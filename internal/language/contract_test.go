@@ -0,0 +1,22 @@
+package language
+
+import (
+	"colortime-service/pkg/consul"
+	"colortime-service/pkg/consul/consultest"
+	"testing"
+)
+
+func TestDecodeMessageLanguagesContract(t *testing.T) {
+	consultest.RunDecodeCases(t, decodeMessageLanguages, []consultest.DecodeCase[[]MessageLanguageResponse]{
+		{
+			Fixture: "messages.json",
+			Want: []MessageLanguageResponse{
+				{LangID: 1, Contents: map[string]string{"title": "Circle time", "note": "Sit in a circle"}},
+				{LangID: 2, Contents: map[string]string{"title": "Giờ vòng tròn", "note": "Ngồi thành vòng tròn"}},
+			},
+		},
+		{Fixture: "messages_empty.json", Want: []MessageLanguageResponse{}},
+		{Fixture: "messages_contents_as_list.json", WantErr: consul.ErrMalformed},
+		{Fixture: "messages_renamed_language.json", WantErr: consul.ErrMalformed},
+	})
+}
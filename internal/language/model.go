@@ -1,16 +1,23 @@
 package language
 
-type APIGateWayResponse[T any] struct {
-	StatusCode int    `json:"status_code"`
-	Message    string `json:"message"`
-	Data       T      `json:"data"`
-}
+import "fmt"
 
 type MessageLanguageResponse struct {
 	LangID   uint              `json:"language_id"`
 	Contents map[string]string `json:"contents"`
 }
 
+type messageLanguageList []MessageLanguageResponse
+
+func (l messageLanguageList) Validate() error {
+	for i, message := range l {
+		if message.LangID == 0 {
+			return fmt.Errorf("message %d: language_id is missing", i)
+		}
+	}
+	return nil
+}
+
 type UploadMessageRequest struct {
 	TypeID     string `json:"type_id" binding:"required"`
 	Type       string `json:"type" binding:"required"`
@@ -5,9 +5,9 @@ import (
 	"colortime-service/pkg/consul"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/hashicorp/consul/api"
 )
@@ -20,51 +20,21 @@ type MessageLanguageGateway interface {
 }
 
 type messageLanguageGateway struct {
-	client *callAPI
-}
-
-type callAPI struct {
-	client       consul.ServiceDiscovery
-	clientServer *api.CatalogService
+	client *consul.GatewayClient
 }
 
 var (
 	mainService = "go-main-service"
 )
 
-func NewLanguageService(client *api.Client) MessageLanguageGateway {
-	mainServiceAPI := NewServiceAPI(client, mainService)
-	return &messageLanguageGateway{
-		client: mainServiceAPI,
-	}
-}
-
-func NewServiceAPI(client *api.Client, serviceName string) *callAPI {
-
-	sd, err := consul.NewServiceDiscovery(client, serviceName)
+func NewLanguageService(client *api.Client, gatewayOptions consul.GatewayOptions) MessageLanguageGateway {
+	gateway, err := consul.NewGatewayClient(client, mainService, gatewayOptions)
 	if err != nil {
-		fmt.Printf("Error creating service discovery: %v\n", err)
-		return nil
+		fmt.Printf("Error creating gateway client for %s: %v\n", mainService, err)
 	}
 
-	var service *api.CatalogService
-
-	for i := 0; i < 10; i++ {
-		service, err = sd.DiscoverService()
-		if err == nil && service != nil {
-			break
-		}
-		fmt.Printf("Waiting for service %s... retry %d/10\n", serviceName, i+1)
-		time.Sleep(3 * time.Second)
-	}
-
-	if service == nil {
-		fmt.Printf("Service %s not found after retries, continuing anyway...\n", serviceName)
-	}
-
-	return &callAPI{
-		client:       sd,
-		clientServer: service,
+	return &messageLanguageGateway{
+		client: gateway,
 	}
 }
 
@@ -76,7 +46,7 @@ func (g *messageLanguageGateway) UploadMessage(ctx context.Context, req UploadMe
 		return fmt.Errorf("token not found in context")
 	}
 
-	err := g.client.uploadMessage(token, req)
+	err := g.uploadMessage(ctx, token, req)
 	if err != nil {
 		return err
 	}
@@ -93,7 +63,7 @@ func (g *messageLanguageGateway) UploadMessages(ctx context.Context, req UploadM
 		return fmt.Errorf("token not found in context")
 	}
 
-	err := g.client.uploadMessages(token, req)
+	err := g.uploadMessages(ctx, token, req)
 	if err != nil {
 		return err
 	}
@@ -110,7 +80,7 @@ func (g *messageLanguageGateway) GetMessageLanguages(ctx context.Context, typeID
 		return nil, fmt.Errorf("token not found in context")
 	}
 
-	resp, err := g.client.getMessageLanguages(token, typeID)
+	resp, err := g.getMessageLanguages(ctx, token, typeID)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +88,7 @@ func (g *messageLanguageGateway) GetMessageLanguages(ctx context.Context, typeID
 	return resp, nil
 
 }
-func (c *callAPI) uploadMessage(token string, req UploadMessageRequest) error {
+func (g *messageLanguageGateway) uploadMessage(ctx context.Context, token string, req UploadMessageRequest) error {
 
 	endpoint := "/v1/gateway/messages"
 
@@ -133,7 +103,7 @@ func (c *callAPI) uploadMessage(token string, req UploadMessageRequest) error {
 		return err
 	}
 
-	_, err = c.client.CallAPI(c.clientServer, endpoint, http.MethodPost, jsonReq, header)
+	_, err = g.client.Call(ctx, http.MethodPost, endpoint, jsonReq, header)
 	if err != nil {
 		fmt.Printf("Error calling API: %v\n", err)
 		return err
@@ -143,7 +113,7 @@ func (c *callAPI) uploadMessage(token string, req UploadMessageRequest) error {
 
 }
 
-func (c *callAPI) uploadMessages(token string, req UploadMessageLanguagesRequest) error {
+func (g *messageLanguageGateway) uploadMessages(ctx context.Context, token string, req UploadMessageLanguagesRequest) error {
 
 	endpoint := "/v1/gateway/messages"
 
@@ -158,7 +128,7 @@ func (c *callAPI) uploadMessages(token string, req UploadMessageLanguagesRequest
 		return err
 	}
 
-	_, err = c.client.CallAPI(c.clientServer, endpoint, http.MethodPost, jsonReq, header)
+	_, err = g.client.Call(ctx, http.MethodPost, endpoint, jsonReq, header)
 	if err != nil {
 		fmt.Printf("Error calling API: %v\n", err)
 		return err
@@ -168,7 +138,7 @@ func (c *callAPI) uploadMessages(token string, req UploadMessageLanguagesRequest
 
 }
 
-func (c *callAPI) getMessageLanguages(token string, typeID string) ([]MessageLanguageResponse, error) {
+func (g *messageLanguageGateway) getMessageLanguages(ctx context.Context, token string, typeID string) ([]MessageLanguageResponse, error) {
 
 	endpoint := fmt.Sprintf("/v1/gateway/messages?type=%s&type_id=%s", "colortime", typeID)
 
@@ -177,19 +147,30 @@ func (c *callAPI) getMessageLanguages(token string, typeID string) ([]MessageLan
 		"Authorization": "Bearer " + token,
 	}
 
-	res, err := c.client.CallAPI(c.clientServer, endpoint, http.MethodGet, nil, header)
+	res, err := g.client.Call(ctx, http.MethodGet, endpoint, nil, header)
 	if err != nil {
 		fmt.Printf("Error calling API: %v\n", err)
 		return nil, err
 	}
-	fmt.Printf("res: %v\n", res)
-	var data APIGateWayResponse[[]MessageLanguageResponse]
-	err = json.Unmarshal([]byte(res), &data)
+	messages, err := decodeMessageLanguages(res)
 	if err != nil {
-		fmt.Printf("Error unmarshalling response: %v\n", err)
-		return nil, err
+		return nil, fmt.Errorf("messages of %s: %w", typeID, err)
 	}
 
-	return data.Data, nil
+	return messages, nil
+
+}
+
+// decodeMessageLanguages returns the messages of a response, empty when it has
+// none.
+func decodeMessageLanguages(res []byte) ([]MessageLanguageResponse, error) {
+	data, err := consul.DecodeData[messageLanguageList](res)
+	if errors.Is(err, consul.ErrNotFound) {
+		return []MessageLanguageResponse{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	return data, nil
 }
@@ -0,0 +1,87 @@
+package organization_setting
+
+import (
+	"time"
+	_ "time/tzdata"
+)
+
+// Calendar maps between calendar days and instants for one organization.
+//
+// Days are stored as midnight UTC of the calendar date (what
+// time.Parse("2006-01-02") yields) whatever the organization's timezone. The
+// timezone only decides which day an instant falls on and turns a slot's
+// clock time on a given day into an instant.
+type Calendar struct {
+	Location  *time.Location
+	WeekStart time.Weekday
+}
+
+func DefaultCalendar() *Calendar {
+	return &Calendar{
+		Location:  time.UTC,
+		WeekStart: time.Weekday(DefaultWeekStartDay),
+	}
+}
+
+// ParseDate accepts a plain "2006-01-02" date or an RFC 3339 timestamp, which
+// is reduced to the day it falls on in the organization's timezone.
+func (c *Calendar) ParseDate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return date, nil
+	}
+
+	instant, rfcErr := time.Parse(time.RFC3339, value)
+	if rfcErr != nil {
+		return time.Time{}, err
+	}
+
+	return c.Date(instant), nil
+}
+
+// Date returns the calendar day an instant falls on in the organization's
+// timezone. Stored days go through StoredDate instead: midnight UTC is a real
+// instant too, and for an organization west of UTC it is the day before.
+func (c *Calendar) Date(t time.Time) time.Time {
+	local := t.In(c.Location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// StoredDate returns the day a stored date stands for, its date at UTC.
+func (c *Calendar) StoredDate(t time.Time) time.Time {
+	utc := t.UTC()
+	return time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (c *Calendar) Today() time.Time {
+	return c.Date(time.Now())
+}
+
+// SameDay reports whether two stored days are the same day.
+func (c *Calendar) SameDay(a, b time.Time) bool {
+	return c.StoredDate(a).Equal(c.StoredDate(b))
+}
+
+// DayRange returns the bounds [start, end) of the stored day containing date.
+func (c *Calendar) DayRange(date time.Time) (time.Time, time.Time) {
+	start := c.StoredDate(date)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// WeekRange returns the first and last day of the week containing the stored
+// day date.
+func (c *Calendar) WeekRange(date time.Time) (time.Time, time.Time) {
+	day := c.StoredDate(date)
+	offset := (int(day.Weekday()) - int(c.WeekStart) + 7) % 7
+
+	start := day.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 6)
+}
+
+// At places the clock time of a slot on the stored day date in the
+// organization's timezone.
+func (c *Calendar) At(date, clock time.Time) time.Time {
+	day := c.StoredDate(date)
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), c.Location)
+}
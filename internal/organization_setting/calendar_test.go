@@ -0,0 +1,61 @@
+package organization_setting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarDate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	calendar := &Calendar{Location: newYork, WeekStart: time.Monday}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain date", value: "2025-03-10", want: "2025-03-10"},
+		{name: "midnight UTC is the evening before", value: "2025-03-10T00:00:00Z", want: "2025-03-09"},
+		{name: "local midnight", value: "2025-03-10T00:00:00-04:00", want: "2025-03-10"},
+		{name: "late evening local time", value: "2025-03-10T23:30:00-04:00", want: "2025-03-10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calendar.ParseDate(tt.value)
+			if err != nil {
+				t.Fatalf("ParseDate(%q): %v", tt.value, err)
+			}
+			if got.Format("2006-01-02") != tt.want || !got.Equal(calendar.StoredDate(got)) {
+				t.Fatalf("ParseDate(%q) = %s, want %s at midnight UTC", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarStoredDays(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	calendar := &Calendar{Location: newYork, WeekStart: time.Monday}
+
+	// Stored days keep their date whatever the timezone
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	if start, end := calendar.WeekRange(day); !start.Equal(day) || !end.Equal(day.AddDate(0, 0, 6)) {
+		t.Fatalf("WeekRange(%s) = %s, %s, want the week starting on that Monday", day, start, end)
+	}
+
+	if !calendar.SameDay(day, day.Add(20*time.Hour)) {
+		t.Fatalf("SameDay should compare the stored date")
+	}
+
+	at := calendar.At(day, time.Date(0, 1, 1, 8, 30, 0, 0, time.UTC))
+	if want := time.Date(2025, 3, 10, 8, 30, 0, 0, newYork); !at.Equal(want) {
+		t.Fatalf("At = %s, want %s", at, want)
+	}
+}
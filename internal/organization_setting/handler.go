@@ -0,0 +1,76 @@
+package organization_setting
+
+import (
+	"colortime-service/helper"
+	"colortime-service/pkg/constants"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationSettingHandler struct {
+	OrganizationSettingService OrganizationSettingService
+}
+
+func NewOrganizationSettingHandler(organizationSettingService OrganizationSettingService) *OrganizationSettingHandler {
+	return &OrganizationSettingHandler{
+		OrganizationSettingService: organizationSettingService,
+	}
+}
+
+func (h *OrganizationSettingHandler) GetOrganizationSetting(c *gin.Context) {
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	setting, err := h.OrganizationSettingService.GetOrganizationSetting(ctx, orgID)
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "organization setting retrieved successfully", setting)
+}
+
+func (h *OrganizationSettingHandler) UpdateOrganizationSetting(c *gin.Context) {
+	var req UpdateOrganizationSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	setting, err := h.OrganizationSettingService.UpdateOrganizationSetting(ctx, &req, userID.(string))
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "organization setting updated successfully", setting)
+}
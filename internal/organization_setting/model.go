@@ -0,0 +1,22 @@
+package organization_setting
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultTimezone     = "UTC"
+	DefaultWeekStartDay = int(time.Monday)
+)
+
+type OrganizationSetting struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	Timezone       string             `bson:"timezone" json:"timezone"`             // IANA name, e.g. "Asia/Ho_Chi_Minh"
+	WeekStartDay   int                `bson:"week_start_day" json:"week_start_day"` // 0=Sun,1=Mon,...,6=Sat
+	UpdatedBy      string             `bson:"updated_by" json:"updated_by"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
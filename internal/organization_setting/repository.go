@@ -0,0 +1,60 @@
+package organization_setting
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrganizationSettingRepository interface {
+	GetOrganizationSetting(ctx context.Context, organizationID string) (*OrganizationSetting, error)
+	UpsertOrganizationSetting(ctx context.Context, setting *OrganizationSetting) error
+}
+
+type organizationSettingRepository struct {
+	OrganizationSettingCollection *mongo.Collection
+}
+
+func NewOrganizationSettingRepository(organizationSettingCollection *mongo.Collection) OrganizationSettingRepository {
+	return &organizationSettingRepository{
+		OrganizationSettingCollection: organizationSettingCollection,
+	}
+}
+
+func (r *organizationSettingRepository) GetOrganizationSetting(ctx context.Context, organizationID string) (*OrganizationSetting, error) {
+
+	var setting OrganizationSetting
+
+	if err := r.OrganizationSettingCollection.FindOne(ctx, bson.M{"organization_id": organizationID}).Decode(&setting); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &setting, nil
+}
+
+func (r *organizationSettingRepository) UpsertOrganizationSetting(ctx context.Context, setting *OrganizationSetting) error {
+
+	update := bson.M{
+		"$set": bson.M{
+			"timezone":       setting.Timezone,
+			"week_start_day": setting.WeekStartDay,
+			"updated_by":     setting.UpdatedBy,
+			"updated_at":     setting.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": setting.CreatedAt,
+		},
+	}
+
+	_, err := r.OrganizationSettingCollection.UpdateOne(ctx,
+		bson.M{"organization_id": setting.OrganizationID},
+		update,
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
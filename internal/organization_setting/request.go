@@ -0,0 +1,7 @@
+package organization_setting
+
+type UpdateOrganizationSettingRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+	Timezone       string `json:"timezone" binding:"required"`
+	WeekStartDay   *int   `json:"week_start_day" binding:"required"`
+}
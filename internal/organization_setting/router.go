@@ -0,0 +1,15 @@
+package organization_setting
+
+import (
+	"colortime-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, organizationSettingHandler *OrganizationSettingHandler) {
+	organizationSetting := r.Group("api/v1/organization-setting").Use(middleware.Secured())
+	{
+		organizationSetting.GET("", organizationSettingHandler.GetOrganizationSetting)
+		organizationSetting.PUT("", organizationSettingHandler.UpdateOrganizationSetting)
+	}
+}
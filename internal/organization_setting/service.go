@@ -0,0 +1,96 @@
+package organization_setting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type OrganizationSettingService interface {
+	GetOrganizationSetting(ctx context.Context, organizationID string) (*OrganizationSetting, error)
+	UpdateOrganizationSetting(ctx context.Context, req *UpdateOrganizationSettingRequest, userID string) (*OrganizationSetting, error)
+	GetCalendar(ctx context.Context, organizationID string) (*Calendar, error)
+}
+
+type organizationSettingService struct {
+	OrganizationSettingRepository OrganizationSettingRepository
+}
+
+func NewOrganizationSettingService(organizationSettingRepository OrganizationSettingRepository) OrganizationSettingService {
+	return &organizationSettingService{
+		OrganizationSettingRepository: organizationSettingRepository,
+	}
+}
+
+// GetOrganizationSetting returns the stored setting, or the defaults for an
+// organization that never saved one.
+func (s *organizationSettingService) GetOrganizationSetting(ctx context.Context, organizationID string) (*OrganizationSetting, error) {
+
+	if organizationID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	setting, err := s.OrganizationSettingRepository.GetOrganizationSetting(ctx, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization setting: %w", err)
+	}
+
+	if setting == nil {
+		setting = &OrganizationSetting{
+			OrganizationID: organizationID,
+			Timezone:       DefaultTimezone,
+			WeekStartDay:   DefaultWeekStartDay,
+		}
+	}
+
+	return setting, nil
+}
+
+func (s *organizationSettingService) UpdateOrganizationSetting(ctx context.Context, req *UpdateOrganizationSettingRequest, userID string) (*OrganizationSetting, error) {
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+
+	if req.WeekStartDay == nil || *req.WeekStartDay < 0 || *req.WeekStartDay > 6 {
+		return nil, errors.New("week start day must be between 0 (Sunday) and 6 (Saturday)")
+	}
+
+	setting := &OrganizationSetting{
+		OrganizationID: req.OrganizationID,
+		Timezone:       req.Timezone,
+		WeekStartDay:   *req.WeekStartDay,
+		UpdatedBy:      userID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := s.OrganizationSettingRepository.UpsertOrganizationSetting(ctx, setting); err != nil {
+		return nil, fmt.Errorf("failed to update organization setting: %w", err)
+	}
+
+	return s.GetOrganizationSetting(ctx, req.OrganizationID)
+}
+
+func (s *organizationSettingService) GetCalendar(ctx context.Context, organizationID string) (*Calendar, error) {
+
+	setting, err := s.GetOrganizationSetting(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := time.LoadLocation(setting.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone for organization %s: %w", organizationID, err)
+	}
+
+	return &Calendar{
+		Location:  location,
+		WeekStart: time.Weekday(setting.WeekStartDay),
+	}, nil
+}
@@ -0,0 +1,87 @@
+package product
+
+import (
+	"sync"
+	"time"
+)
+
+// productCache keeps products for a fixed time, shared by every request. When
+// it is full the expired entries are dropped first, then the oldest ones.
+type productCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*productCacheEntry
+	now        func() time.Time
+}
+
+type productCacheEntry struct {
+	product  *Product
+	storedAt time.Time
+}
+
+func newProductCache(ttl time.Duration, maxEntries int) *productCache {
+	return &productCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*productCacheEntry),
+		now:        time.Now,
+	}
+}
+
+func (c *productCache) get(productID string) (*Product, bool) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[productID]
+	if !ok {
+		return nil, false
+	}
+
+	if c.now().Sub(entry.storedAt) > c.ttl {
+		delete(c.entries, productID)
+		return nil, false
+	}
+
+	return entry.product, true
+}
+
+func (c *productCache) set(productID string, product *Product) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[productID]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+
+	c.entries[productID] = &productCacheEntry{product: product, storedAt: c.now()}
+}
+
+// evict makes room for one entry. Called with the lock held.
+func (c *productCache) evict() {
+	now := c.now()
+	for id, entry := range c.entries {
+		if now.Sub(entry.storedAt) > c.ttl {
+			delete(c.entries, id)
+		}
+	}
+
+	for len(c.entries) >= c.maxEntries {
+		var oldestID string
+		var oldest time.Time
+		for id, entry := range c.entries {
+			if oldestID == "" || entry.storedAt.Before(oldest) {
+				oldestID, oldest = id, entry.storedAt
+			}
+		}
+		delete(c.entries, oldestID)
+	}
+}
@@ -0,0 +1,120 @@
+package product
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is the time of a productCache, moved by hand.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestCache(ttl time.Duration, maxEntries int) (*productCache, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)}
+	cache := newProductCache(ttl, maxEntries)
+	cache.now = clock.Now
+	return cache, clock
+}
+
+func TestProductCacheExpiry(t *testing.T) {
+	cache, clock := newTestCache(time.Minute, 10)
+	cache.set("p1", &Product{ProductName: "Crayons"})
+
+	clock.now = clock.now.Add(time.Minute)
+	if _, ok := cache.get("p1"); !ok {
+		t.Fatal("product expired at its TTL, want it kept until the TTL has passed")
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if _, ok := cache.get("p1"); ok {
+		t.Fatal("product still cached after its TTL")
+	}
+	if len(cache.entries) != 0 {
+		t.Fatalf("expired product left in the map: %d entries", len(cache.entries))
+	}
+}
+
+func TestProductCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		// age of each entry already stored when p4 is added
+		stored map[string]time.Duration
+		want   []string
+		gone   []string
+	}{
+		{
+			name:   "oldest goes when nothing expired",
+			stored: map[string]time.Duration{"p1": 30 * time.Second, "p2": 20 * time.Second, "p3": 10 * time.Second},
+			want:   []string{"p2", "p3", "p4"},
+			gone:   []string{"p1"},
+		},
+		{
+			name:   "expired entries go first",
+			stored: map[string]time.Duration{"p1": 50 * time.Second, "p2": 2 * time.Minute, "p3": 10 * time.Second},
+			want:   []string{"p1", "p3", "p4"},
+			gone:   []string{"p2"},
+		},
+		{
+			name:   "every expired entry goes",
+			stored: map[string]time.Duration{"p1": 3 * time.Minute, "p2": 2 * time.Minute, "p3": 10 * time.Second},
+			want:   []string{"p3", "p4"},
+			gone:   []string{"p1", "p2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, clock := newTestCache(time.Minute, 3)
+			start := clock.now
+			for id, age := range tt.stored {
+				clock.now = start.Add(-age)
+				cache.set(id, &Product{})
+			}
+
+			clock.now = start
+			cache.set("p4", &Product{})
+
+			for _, id := range tt.want {
+				if _, ok := cache.entries[id]; !ok {
+					t.Errorf("%s was evicted", id)
+				}
+			}
+			for _, id := range tt.gone {
+				if _, ok := cache.entries[id]; ok {
+					t.Errorf("%s was kept", id)
+				}
+			}
+		})
+	}
+}
+
+func TestProductCacheFullOverwrite(t *testing.T) {
+	cache, _ := newTestCache(time.Minute, 2)
+	cache.set("p1", &Product{ProductName: "old"})
+	cache.set("p2", &Product{})
+
+	// Storing a cached product again makes no room
+	cache.set("p1", &Product{ProductName: "new"})
+
+	if product, ok := cache.get("p1"); !ok || product.ProductName != "new" {
+		t.Fatalf("p1 = %+v, %v, want the new product", product, ok)
+	}
+	if _, ok := cache.get("p2"); !ok {
+		t.Fatal("p2 was evicted by overwriting p1")
+	}
+}
+
+func TestProductCacheDisabled(t *testing.T) {
+	for name, cache := range map[string]*productCache{
+		"zero ttl":  newProductCache(0, 10),
+		"zero size": newProductCache(time.Minute, 0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache.set("p1", &Product{})
+			if _, ok := cache.get("p1"); ok {
+				t.Fatal("disabled cache returned a product")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package product
+
+import (
+	"colortime-service/pkg/consul"
+	"colortime-service/pkg/consul/consultest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mustObjectID(t *testing.T, hex string) primitive.ObjectID {
+	t.Helper()
+
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		t.Fatalf("invalid object id %s: %v", hex, err)
+	}
+	return id
+}
+
+func TestDecodeProductContract(t *testing.T) {
+	consultest.RunDecodeCases(t, decodeProduct, []consultest.DecodeCase[*Product]{
+		{
+			Fixture: "product.json",
+			Want: &Product{
+				ID:                   mustObjectID(t, "6652b1c2d3e4f5a6b7c8d9e0"),
+				ProductName:          "Wooden farm puzzle",
+				OriginalPriceStore:   120000,
+				OriginalPriceService: 95000.5,
+				ProductImage:         "https://cdn.example.com/products/puzzle.png",
+				TopicName:            "Animals on the farm",
+				CategoryName:         "Puzzles",
+			},
+		},
+		{
+			Fixture: "product_without_topic.json",
+			Want: &Product{
+				ID:                   mustObjectID(t, "6652b1c2d3e4f5a6b7c8d9e1"),
+				ProductName:          "Crayons",
+				OriginalPriceStore:   30000,
+				OriginalPriceService: 30000,
+			},
+		},
+		{Fixture: "product_not_found.json", WantErr: consul.ErrNotFound},
+		{Fixture: "product_price_as_string.json", WantErr: consul.ErrMalformed},
+		{Fixture: "product_invalid_id.json", WantErr: consul.ErrMalformed},
+	})
+}
@@ -1,6 +1,11 @@
 package product
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Product struct {
 	ID                   primitive.ObjectID `bson:"_id" json:"id"`
@@ -12,3 +17,33 @@ type Product struct {
 	TopicName            string             `bson:"topic_name" json:"topic_name"`
 	CategoryName         string             `bson:"category_name" json:"category_name"`
 }
+
+// productData is a product as returned by product-service.
+type productData struct {
+	ID                   string  `json:"id"`
+	ProductName          string  `json:"product_name"`
+	OriginalPriceStore   float64 `json:"original_price_store"`
+	OriginalPriceService float64 `json:"original_price_service"`
+	CoverImage           string  `json:"cover_image"`
+	Topic                *struct {
+		TopicName string `json:"topic_name"`
+	} `json:"topic"`
+	Category *struct {
+		CategoryName string `json:"category_name"`
+	} `json:"category"`
+
+	objectID primitive.ObjectID
+}
+
+func (d *productData) Validate() error {
+	objectID, err := primitive.ObjectIDFromHex(d.ID)
+	if err != nil {
+		return fmt.Errorf("invalid product id %q", d.ID)
+	}
+	d.objectID = objectID
+
+	if d.ProductName == "" {
+		return errors.New("product_name is missing")
+	}
+	return nil
+}
@@ -4,71 +4,49 @@ import (
 	"colortime-service/pkg/constants"
 	"colortime-service/pkg/consul"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/consul/api"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ProductService interface {
 	GetProductInfor(ctx context.Context, productID string) (*Product, error)
+	GetProductsInfor(ctx context.Context, productIDs []string) (map[string]*Product, error)
 }
 
 type productService struct {
-	client *callAPI
-}
-
-type callAPI struct {
-	client       consul.ServiceDiscovery
-	clientServer *api.CatalogService
+	client *consul.GatewayClient
+	cache  *productCache
+	fetch  func(ctx context.Context, productID, token string) (*Product, error)
 }
 
 var (
 	mainService = "product-service"
-)
 
-func NewUserService(client *api.Client) ProductService {
-	mainServiceAPI := NewServiceAPI(client, mainService)
-	return &productService{
-		client: mainServiceAPI,
-	}
-}
+	// Most product-service calls a batch runs at the same time. product-service
+	// has no endpoint taking several ids, so a batch is one GET per product.
+	productFetchWorkers = 8
+)
 
-func NewServiceAPI(client *api.Client, serviceName string) *callAPI {
-	sd, err := consul.NewServiceDiscovery(client, serviceName)
+// NewUserService creates the product service. Products are cached for
+// cacheTTL, keeping at most cacheSize of them; a zero value disables the
+// cache.
+func NewUserService(client *api.Client, gatewayOptions consul.GatewayOptions, cacheTTL time.Duration, cacheSize int) ProductService {
+	gateway, err := consul.NewGatewayClient(client, mainService, gatewayOptions)
 	if err != nil {
-		fmt.Printf("Error creating service discovery: %v\n", err)
-		return nil
-	}
-
-	var service *api.CatalogService
-
-	for i := 0; i < 10; i++ {
-		service, err = sd.DiscoverService()
-		if err == nil && service != nil {
-			break
-		}
-		fmt.Printf("Waiting for service %s... retry %d/10\n", serviceName, i+1)
-		time.Sleep(3 * time.Second)
-	}
-
-	if service == nil {
-		fmt.Printf("Service %s not found after retries, continuing anyway...\n", serviceName)
-	}
-
-	if os.Getenv("LOCAL_TEST") == "true" {
-		fmt.Println("Running in LOCAL_TEST mode — overriding service address to localhost")
-		service.ServiceAddress = "localhost"
+		fmt.Printf("Error creating gateway client for %s: %v\n", mainService, err)
 	}
 
-	return &callAPI{
-		client:       sd,
-		clientServer: service,
+	s := &productService{
+		client: gateway,
+		cache:  newProductCache(cacheTTL, cacheSize),
 	}
+	s.fetch = s.fetchProduct
+	return s
 }
 
 func (s *productService) GetProductInfor(ctx context.Context, productID string) (*Product, error) {
@@ -77,81 +55,129 @@ func (s *productService) GetProductInfor(ctx context.Context, productID string)
 		return nil, fmt.Errorf("token not found in context")
 	}
 
-	productRes, err := s.client.getProductInfor(productID, token)
+	if product, ok := s.cache.get(productID); ok {
+		return product, nil
+	}
+
+	product, err := s.fetch(ctx, productID, token)
 	if err != nil {
 		return nil, err
 	}
 
-	rawData, ok := productRes["data"]
-	if !ok || rawData == nil {
-		return nil, fmt.Errorf("product not found for id=%s", productID)
-	}
+	s.cache.set(productID, product)
+
+	return product, nil
+}
 
-	product, ok := rawData.(map[string]interface{})
+// GetProductsInfor looks up several products at once. Repeated ids are looked
+// up once and cached products are not requested again. The products found are
+// returned by id even when some lookups fail, the failures being joined into
+// the error. Products are requested one by one, at most productFetchWorkers
+// at a time.
+func (s *productService) GetProductsInfor(ctx context.Context, productIDs []string) (map[string]*Product, error) {
+	token, ok := ctx.Value(constants.TokenKey).(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid product format for id=%s", productID)
+		return nil, fmt.Errorf("token not found in context")
 	}
 
-	name, _ := product["product_name"].(string)
-	id, _ := product["id"].(string)
+	products := make(map[string]*Product, len(productIDs))
+	missing := make([]string, 0, len(productIDs))
+	seen := make(map[string]bool, len(productIDs))
 
-	objIDProduct, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, fmt.Errorf("invalid product ID format: %v", err)
+	for _, productID := range productIDs {
+		if productID == "" || seen[productID] {
+			continue
+		}
+		seen[productID] = true
+
+		if product, ok := s.cache.get(productID); ok {
+			products[productID] = product
+			continue
+		}
+		missing = append(missing, productID)
 	}
 
-	priceStore, _ := product["original_price_store"].(float64)
-	priceService, _ := product["original_price_service"].(float64)
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	slots := make(chan struct{}, productFetchWorkers)
 
-	var imageURL string
-	if v, ok := product["cover_image"].(string); ok {
-		imageURL = v
-	}
+	for _, productID := range missing {
+		if ctx.Err() != nil {
+			mu.Lock()
+			errs = append(errs, ctx.Err())
+			mu.Unlock()
+			break
+		}
 
-	var topic string
-	if rawTopic, ok := product["topic"].(map[string]interface{}); ok {
-		topic, _ = rawTopic["topic_name"].(string)
-	}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(productID string) {
+			defer wg.Done()
+			defer func() { <-slots }()
 
-	var category string
-	if rawCategory, ok := product["category"].(map[string]interface{}); ok {
-		category, _ = rawCategory["category_name"].(string)
+			product, err := s.fetch(ctx, productID, token)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			products[productID] = product
+			s.cache.set(productID, product)
+		}(productID)
 	}
 
-	return &Product{
-		ID:                   objIDProduct,
-		ProductName:          name,
-		OriginalPriceStore:   priceStore,
-		OriginalPriceService: priceService,
-		ProductImage:         imageURL,
-		TopicName:            topic,
-		CategoryName:         category,
-	}, nil
+	wg.Wait()
+
+	return products, errors.Join(errs...)
 }
 
-func (c *callAPI) getProductInfor(productID, token string) (map[string]interface{}, error) {
+func (s *productService) fetchProduct(ctx context.Context, productID, token string) (*Product, error) {
 
 	endpoint := fmt.Sprintf("/api/v1/products/%s", productID)
 	header := map[string]string{
 		"Content-Type":  "application/json",
 		"Authorization": "Bearer " + token,
 	}
-	res, err := c.client.CallAPI(c.clientServer, endpoint, http.MethodGet, nil, header)
+	res, err := s.client.Call(ctx, http.MethodGet, endpoint, nil, header)
 	if err != nil {
 		fmt.Printf("Error calling API: %v\n", err)
 		return nil, err
 	}
 
-	var productData interface{}
+	product, err := decodeProduct(res)
+	if err != nil {
+		return nil, fmt.Errorf("product %s: %w", productID, err)
+	}
+
+	return product, nil
+}
 
-	err = json.Unmarshal([]byte(res), &productData)
+func decodeProduct(res []byte) (*Product, error) {
+	data, err := consul.DecodeData[productData](res)
 	if err != nil {
-		fmt.Printf("Error unmarshalling response: %v\n", err)
 		return nil, err
 	}
 
-	myMap := productData.(map[string]interface{})
+	product := &Product{
+		ID:                   data.objectID,
+		ProductName:          data.ProductName,
+		OriginalPriceStore:   data.OriginalPriceStore,
+		OriginalPriceService: data.OriginalPriceService,
+		ProductImage:         data.CoverImage,
+	}
 
-	return myMap, nil
+	if data.Topic != nil {
+		product.TopicName = data.Topic.TopicName
+	}
+
+	if data.Category != nil {
+		product.CategoryName = data.Category.CategoryName
+	}
 
+	return product, nil
 }
@@ -0,0 +1,117 @@
+package product
+
+import (
+	"colortime-service/pkg/constants"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFetch answers product lookups, recording how many ran at once. The ids
+// in failing are not found.
+type fakeFetch struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	running  int
+	peak     int
+	failing  map[string]bool
+	duration time.Duration
+}
+
+func (f *fakeFetch) fetch(ctx context.Context, productID, token string) (*Product, error) {
+	f.mu.Lock()
+	f.calls[productID]++
+	f.running++
+	f.peak = max(f.peak, f.running)
+	f.mu.Unlock()
+
+	time.Sleep(f.duration)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running--
+	if f.failing[productID] {
+		return nil, errors.New("product " + productID + " not found")
+	}
+	return &Product{ProductName: productID}, nil
+}
+
+func newTestService(failing ...string) (*productService, *fakeFetch) {
+	fake := &fakeFetch{calls: make(map[string]int), failing: make(map[string]bool), duration: 5 * time.Millisecond}
+	for _, id := range failing {
+		fake.failing[id] = true
+	}
+	return &productService{cache: newProductCache(time.Minute, 100), fetch: fake.fetch}, fake
+}
+
+func tokenContext() context.Context {
+	return context.WithValue(context.Background(), constants.TokenKey, "token")
+}
+
+func TestGetProductsInforCapsConcurrency(t *testing.T) {
+	s, fake := newTestService()
+
+	productIDs := make([]string, 0, 3*productFetchWorkers)
+	for i := 0; i < cap(productIDs); i++ {
+		productIDs = append(productIDs, "p"+strconv.Itoa(i))
+	}
+
+	products, err := s.GetProductsInfor(tokenContext(), productIDs)
+	if err != nil {
+		t.Fatalf("GetProductsInfor: %v", err)
+	}
+	if len(products) != len(productIDs) {
+		t.Fatalf("got %d products, want %d", len(products), len(productIDs))
+	}
+	if fake.peak > productFetchWorkers {
+		t.Fatalf("%d lookups ran at once, want at most %d", fake.peak, productFetchWorkers)
+	}
+}
+
+func TestGetProductsInforLooksUpEachProductOnce(t *testing.T) {
+	s, fake := newTestService("missing")
+	s.cache.set("cached", &Product{ProductName: "cached"})
+
+	products, err := s.GetProductsInfor(tokenContext(), []string{"p1", "p1", "", "cached", "missing", "p2"})
+	if err == nil {
+		t.Fatal("GetProductsInfor succeeded with a product not found")
+	}
+
+	for _, id := range []string{"p1", "p2", "cached"} {
+		if products[id] == nil || products[id].ProductName != id {
+			t.Errorf("products[%s] = %+v", id, products[id])
+		}
+	}
+	if _, ok := products["missing"]; ok {
+		t.Error("product not found is in the result")
+	}
+
+	want := map[string]int{"p1": 1, "p2": 1, "missing": 1}
+	for id, n := range want {
+		if fake.calls[id] != n {
+			t.Errorf("%s looked up %d times, want %d", id, fake.calls[id], n)
+		}
+	}
+	if len(fake.calls) != len(want) {
+		t.Errorf("lookups = %v, want %v", fake.calls, want)
+	}
+
+	// Found products are cached for the next batch
+	if _, err := s.GetProductsInfor(tokenContext(), []string{"p1", "p2"}); err != nil {
+		t.Fatalf("second GetProductsInfor: %v", err)
+	}
+	if fake.calls["p1"] != 1 || fake.calls["p2"] != 1 {
+		t.Fatalf("cached products looked up again: %v", fake.calls)
+	}
+}
+
+func TestGetProductsInforWithoutToken(t *testing.T) {
+	s, _ := newTestService()
+
+	if _, err := s.GetProductsInfor(context.Background(), []string{"p1"}); err == nil {
+		t.Fatal("GetProductsInfor succeeded without a token")
+	}
+}
@@ -0,0 +1,350 @@
+package templatecolortime
+
+import (
+	"colortime-service/internal/default_colortime"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// templateBlocks converts the blocks of a template into default blocks. Slots
+// keep the template's slot ids, which is how a later merge recognizes them,
+// and record the template slot they came from.
+func templateBlocks(template *TemplateColorTime, appliedAt time.Time) []*default_colortime.DefaultColorBlock {
+	blocks := make([]*default_colortime.DefaultColorBlock, 0, len(template.ColorTimes))
+
+	for _, templateBlock := range template.ColorTimes {
+		colorBlock := &default_colortime.DefaultColorBlock{
+			BlockID: templateBlock.BlockID,
+			Slots:   []*default_colortime.DefaultColortimeSlot{},
+		}
+
+		for _, templateSlot := range templateBlock.Slots {
+			colorBlock.Slots = append(colorBlock.Slots, templateSlotToDefault(template, templateSlot, appliedAt))
+		}
+
+		blocks = append(blocks, colorBlock)
+	}
+
+	return blocks
+}
+
+// templateSource is the provenance of a day applied from template.
+func templateSource(template *TemplateColorTime, appliedAt time.Time) *default_colortime.TemplateSource {
+	source := &default_colortime.TemplateSource{
+		TemplateID: template.ID,
+		TermID:     template.TermID,
+		AppliedAt:  appliedAt,
+	}
+
+	if template.libraryVersion != nil {
+		source.LibraryTemplateID = &template.libraryVersion.TemplateID
+		source.LibraryVersion = template.libraryVersion.Version
+	}
+
+	return source
+}
+
+func templateSlotToDefault(template *TemplateColorTime, templateSlot *ColortimeSlot, appliedAt time.Time) *default_colortime.DefaultColortimeSlot {
+	var defaultLanguages []*default_colortime.DefaultColorTimeSlotLanguage
+	for _, lang := range templateSlot.ColorTimeSlotLanguage {
+		defaultLanguages = append(defaultLanguages, &default_colortime.DefaultColorTimeSlotLanguage{
+			LanguageID: lang.LanguageID,
+			Title:      lang.Title,
+		})
+	}
+
+	source := templateSource(template, appliedAt)
+	templateSlotID := templateSlot.SlotID
+	source.TemplateSlotID = &templateSlotID
+
+	return &default_colortime.DefaultColortimeSlot{
+		SlotID:                templateSlot.SlotID,
+		Sessions:              templateSlot.Sessions,
+		Title:                 templateSlot.Title,
+		ColorTimeSlotLanguage: defaultLanguages,
+		StartTime:             templateSlot.StartTime,
+		EndTime:               templateSlot.EndTime,
+		Duration:              templateSlot.Duration,
+		Color:                 templateSlot.Color,
+		Note:                  templateSlot.Note,
+		Source:                source,
+		CreatedAt:             time.Now(),
+		UpdatedAt:             time.Now(),
+	}
+}
+
+// reapplyBlocks brings the slots a day got from template up to date: slots
+// whose template slot changed after appliedAt are rewritten, slots whose
+// template slot is gone are dropped and template slots the day lacks are
+// added. Other slots are kept. It fails when a rewritten or added slot
+// overlaps another slot of the day.
+func reapplyBlocks(existing []*default_colortime.DefaultColorBlock, template *TemplateColorTime, appliedAt, now time.Time) ([]*default_colortime.DefaultColorBlock, error) {
+	templateSlots := make(map[primitive.ObjectID]*ColortimeSlot)
+	for _, block := range template.ColorTimes {
+		for _, slot := range block.Slots {
+			templateSlots[slot.SlotID] = slot
+		}
+	}
+
+	result := make([]*default_colortime.DefaultColorBlock, 0, len(existing))
+	blockIndex := make(map[primitive.ObjectID]*default_colortime.DefaultColorBlock)
+	present := make(map[primitive.ObjectID]bool)
+	var touched []*default_colortime.DefaultColortimeSlot
+
+	for _, block := range existing {
+		copied := &default_colortime.DefaultColorBlock{
+			BlockID: block.BlockID,
+			Slots:   []*default_colortime.DefaultColortimeSlot{},
+		}
+
+		for _, slot := range block.Slots {
+			if slot.Source == nil || slot.Source.TemplateID != template.ID || slot.Source.TemplateSlotID == nil {
+				slotCopy := *slot
+				copied.Slots = append(copied.Slots, &slotCopy)
+				continue
+			}
+
+			templateSlot, exists := templateSlots[*slot.Source.TemplateSlotID]
+			if !exists {
+				continue
+			}
+			present[templateSlot.SlotID] = true
+
+			if !templateSlot.UpdatedAt.After(appliedAt) {
+				slotCopy := *slot
+				copied.Slots = append(copied.Slots, &slotCopy)
+				continue
+			}
+
+			updated := templateSlotToDefault(template, templateSlot, now)
+			updated.SlotID = slot.SlotID
+			updated.Sessions = slot.Sessions
+			updated.CreatedAt = slot.CreatedAt
+			copied.Slots = append(copied.Slots, updated)
+			touched = append(touched, updated)
+		}
+
+		// Blocks emptied by removed template slots go as well
+		if len(block.Slots) > 0 && len(copied.Slots) == 0 {
+			continue
+		}
+
+		result = append(result, copied)
+		blockIndex[block.BlockID] = copied
+	}
+
+	for _, templateBlock := range template.ColorTimes {
+		for _, templateSlot := range templateBlock.Slots {
+			if present[templateSlot.SlotID] {
+				continue
+			}
+
+			target, exists := blockIndex[templateBlock.BlockID]
+			if !exists {
+				target = &default_colortime.DefaultColorBlock{
+					BlockID: templateBlock.BlockID,
+					Slots:   []*default_colortime.DefaultColortimeSlot{},
+				}
+				result = append(result, target)
+				blockIndex[templateBlock.BlockID] = target
+			}
+
+			added := templateSlotToDefault(template, templateSlot, now)
+			added.Sessions = len(target.Slots) + 1
+			target.Slots = append(target.Slots, added)
+			touched = append(touched, added)
+		}
+	}
+
+	for _, slot := range touched {
+		for _, block := range result {
+			for _, other := range block.Slots {
+				if other.SlotID != slot.SlotID && clockOverlap(slot, other) {
+					return nil, fmt.Errorf("template slot %q overlaps slot %q", slot.Title, other.Title)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// mergeBlocks lays the template over the existing blocks of a day: slots with
+// a template slot id take the template's fields, new template slots are added
+// to their block and slots added by hand are kept. It fails when a template
+// slot overlaps a kept slot.
+func mergeBlocks(existing, planned []*default_colortime.DefaultColorBlock) ([]*default_colortime.DefaultColorBlock, error) {
+	merged := make([]*default_colortime.DefaultColorBlock, 0, len(existing))
+	slotIndex := make(map[primitive.ObjectID]*default_colortime.DefaultColortimeSlot)
+	blockIndex := make(map[primitive.ObjectID]*default_colortime.DefaultColorBlock)
+
+	for _, block := range existing {
+		copied := &default_colortime.DefaultColorBlock{BlockID: block.BlockID}
+		for _, slot := range block.Slots {
+			slotCopy := *slot
+			copied.Slots = append(copied.Slots, &slotCopy)
+			slotIndex[slot.SlotID] = &slotCopy
+		}
+		merged = append(merged, copied)
+		blockIndex[block.BlockID] = copied
+	}
+
+	templateSlots := make(map[primitive.ObjectID]bool)
+	for _, block := range planned {
+		for _, slot := range block.Slots {
+			templateSlots[slot.SlotID] = true
+		}
+	}
+
+	for _, block := range planned {
+		for _, slot := range block.Slots {
+			for _, kept := range slotIndex {
+				if !templateSlots[kept.SlotID] && clockOverlap(slot, kept) {
+					return nil, fmt.Errorf("template slot %q overlaps slot %q", slot.Title, kept.Title)
+				}
+			}
+
+			if current, exists := slotIndex[slot.SlotID]; exists {
+				createdAt := current.CreatedAt
+				*current = *slot
+				current.CreatedAt = createdAt
+				continue
+			}
+
+			target, exists := blockIndex[block.BlockID]
+			if !exists {
+				target = &default_colortime.DefaultColorBlock{
+					BlockID: block.BlockID,
+					Slots:   []*default_colortime.DefaultColortimeSlot{},
+				}
+				merged = append(merged, target)
+				blockIndex[block.BlockID] = target
+			}
+
+			slotCopy := *slot
+			slotCopy.Sessions = len(target.Slots) + 1
+			target.Slots = append(target.Slots, &slotCopy)
+		}
+	}
+
+	return merged, nil
+}
+
+// clockOverlap compares slots by time of day, as template slots and stored
+// slots carry different dates.
+func clockOverlap(a, b *default_colortime.DefaultColortimeSlot) bool {
+	aStart, aEnd := clockMinutes(a.StartTime), clockMinutes(a.EndTime)
+	bStart, bEnd := clockMinutes(b.StartTime), clockMinutes(b.EndTime)
+	return aStart < bEnd && bStart < aEnd
+}
+
+func clockMinutes(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// locatedSlot is a slot with the block it sits in.
+type locatedSlot struct {
+	slot    *default_colortime.DefaultColortimeSlot
+	blockID primitive.ObjectID
+}
+
+// diffBlocks lists the slots added, removed and changed between the blocks of
+// a day before and after applying the template, matched by slot id.
+func diffBlocks(before, after []*default_colortime.DefaultColorBlock) (added, removed, changed []*SlotChange) {
+	index := func(blocks []*default_colortime.DefaultColorBlock) (map[primitive.ObjectID]locatedSlot, []primitive.ObjectID) {
+		slots := make(map[primitive.ObjectID]locatedSlot)
+		var order []primitive.ObjectID
+		for _, block := range blocks {
+			for _, slot := range block.Slots {
+				slots[slot.SlotID] = locatedSlot{slot: slot, blockID: block.BlockID}
+				order = append(order, slot.SlotID)
+			}
+		}
+		return slots, order
+	}
+
+	beforeSlots, beforeOrder := index(before)
+	afterSlots, afterOrder := index(after)
+
+	change := func(item locatedSlot, fields []string) *SlotChange {
+		return &SlotChange{
+			SlotID:    item.slot.SlotID,
+			BlockID:   item.blockID,
+			Title:     item.slot.Title,
+			StartTime: item.slot.StartTime,
+			EndTime:   item.slot.EndTime,
+			Fields:    fields,
+		}
+	}
+
+	added, removed, changed = []*SlotChange{}, []*SlotChange{}, []*SlotChange{}
+
+	for _, id := range afterOrder {
+		item := afterSlots[id]
+		previous, exists := beforeSlots[id]
+		if !exists {
+			added = append(added, change(item, nil))
+			continue
+		}
+		if fields := changedFields(previous, item); len(fields) > 0 {
+			changed = append(changed, change(item, fields))
+		}
+	}
+
+	for _, id := range beforeOrder {
+		if _, exists := afterSlots[id]; !exists {
+			removed = append(removed, change(beforeSlots[id], nil))
+		}
+	}
+
+	return added, removed, changed
+}
+
+func changedFields(before, after locatedSlot) []string {
+	a, b := before.slot, after.slot
+
+	var fields []string
+	if before.blockID != after.blockID {
+		fields = append(fields, "block_id")
+	}
+	if a.Title != b.Title {
+		fields = append(fields, "title")
+	}
+	if clockMinutes(a.StartTime) != clockMinutes(b.StartTime) {
+		fields = append(fields, "start_time")
+	}
+	if clockMinutes(a.EndTime) != clockMinutes(b.EndTime) {
+		fields = append(fields, "end_time")
+	}
+	if a.Duration != b.Duration {
+		fields = append(fields, "duration")
+	}
+	if a.Color != b.Color {
+		fields = append(fields, "color")
+	}
+	if a.Note != b.Note {
+		fields = append(fields, "note")
+	}
+	if !sameLanguages(a.ColorTimeSlotLanguage, b.ColorTimeSlotLanguage) {
+		fields = append(fields, "color_time_slot_language")
+	}
+	return fields
+}
+
+func sameLanguages(a, b []*default_colortime.DefaultColorTimeSlotLanguage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	titles := make(map[int]string, len(a))
+	for _, lang := range a {
+		titles[lang.LanguageID] = lang.Title
+	}
+	for _, lang := range b {
+		if title, exists := titles[lang.LanguageID]; !exists || title != lang.Title {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,375 @@
+package templatecolortime
+
+import (
+	"colortime-service/helper"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	applyBatchSize    = 7
+	applyJobQueueSize = 64
+	applyJobListLimit = 50
+
+	// How often jobs left pending by a full queue are picked up
+	applyJobPollInterval = time.Minute
+
+	// How long a worker holds a job without renewing its claim. Renewed
+	// before every batch and every day rolled back.
+	applyJobLease = 5 * time.Minute
+)
+
+func (s *templateColorTimeService) enqueueApplyJob(jobID primitive.ObjectID) {
+	select {
+	case s.applyJobs <- jobID:
+	default:
+		// Left pending, the worker picks it up on its next poll
+		log.Printf("[WARN] template apply queue is full, apply job %s stays pending", jobID.Hex())
+	}
+}
+
+// RunApplyWorker runs queued apply jobs and rollbacks until ctx is cancelled.
+// Unfinished jobs, left by a previous run or not queued because the queue was
+// full, are resumed at start and then polled. Every instance runs a worker;
+// runApplyJob claims a job first, so a job is run by one worker at a time and
+// one finished while it waited in the queue is skipped.
+func (s *templateColorTimeService) RunApplyWorker(ctx context.Context) {
+	s.resumeApplyJobs(ctx)
+
+	ticker := time.NewTicker(applyJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.resumeApplyJobs(ctx)
+		case jobID := <-s.applyJobs:
+			s.runApplyJob(ctx, jobID)
+		}
+	}
+}
+
+func (s *templateColorTimeService) resumeApplyJobs(ctx context.Context) {
+	jobs, err := s.ApplyJobRepository.GetUnfinishedApplyJobs(ctx)
+	if err != nil {
+		log.Printf("[ERROR] failed to load unfinished template apply jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		s.runApplyJob(ctx, job.ID)
+	}
+}
+
+func (s *templateColorTimeService) GetApplyJob(ctx context.Context, id string) (*ApplyJob, error) {
+
+	if id == "" {
+		return nil, errors.New("apply job id is required")
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apply job id: %w", err)
+	}
+
+	return s.ApplyJobRepository.GetApplyJobByID(ctx, objectID)
+}
+
+func (s *templateColorTimeService) GetApplyJobs(ctx context.Context, organizationID string) ([]*ApplyJob, error) {
+
+	if organizationID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	return s.ApplyJobRepository.GetApplyJobs(ctx, organizationID, applyJobListLimit)
+}
+
+// RollbackApplyJob queues the undo of a completed or failed job: days it
+// created are deleted and days it changed get their previous slots back.
+func (s *templateColorTimeService) RollbackApplyJob(ctx context.Context, id string) (*ApplyJob, error) {
+
+	if id == "" {
+		return nil, errors.New("apply job id is required")
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apply job id: %w", err)
+	}
+
+	if err := s.ApplyJobRepository.StartApplyRollback(ctx, objectID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("apply job not found or not finished")
+		}
+		return nil, err
+	}
+
+	s.enqueueApplyJob(objectID)
+
+	return s.ApplyJobRepository.GetApplyJobByID(ctx, objectID)
+}
+
+// runApplyJob applies the job's dates in batches of applyBatchSize days. Each
+// batch is written in one transaction together with its results and progress,
+// so after a crash the job continues with the first unrecorded date. When a
+// batch fails its days are retried one by one, so only the failing day is
+// reported as failed.
+func (s *templateColorTimeService) runApplyJob(ctx context.Context, jobID primitive.ObjectID) {
+
+	job, err := s.ApplyJobRepository.ClaimApplyJob(ctx, jobID, s.workerID, applyJobLease)
+	if err != nil {
+		log.Printf("[ERROR] failed to claim template apply job %s: %v", jobID.Hex(), err)
+		return
+	}
+	if job == nil {
+		// Finished, or run by another worker
+		return
+	}
+
+	if job.Status == ApplyJobStatusRollingBack {
+		s.rollbackApplyJob(ctx, job)
+		return
+	}
+
+	if job.ProcessedDays == 0 {
+		// Requests are checked before their job exists, so two of them for
+		// different terms can both pass. Check again against the jobs that
+		// applied days before this one started.
+		overlapping, err := s.ApplyJobRepository.GetOverlappingApplyJobs(ctx, job.OrganizationID, job.TermID, job.StartDate, job.EndDate)
+		if err != nil {
+			// Stays running, claimed again by the next poll
+			log.Printf("[ERROR] failed to check overlapping apply jobs for template apply job %s: %v", jobID.Hex(), err)
+			return
+		}
+		if len(overlapping) > 0 {
+			if err := s.ApplyJobRepository.FinishApplyJob(ctx, jobID, ApplyJobStatusFailed, overlapMessage(overlapping[0])); err != nil {
+				log.Printf("[ERROR] failed to finish template apply job %s: %v", jobID.Hex(), err)
+			}
+			return
+		}
+	}
+
+	var library *LibraryTemplateVersion
+	if job.LibraryTemplateID != nil {
+		library, err = s.LibraryTemplateRepository.GetLibraryTemplateVersion(ctx, *job.LibraryTemplateID, job.LibraryVersion)
+		if err == nil && library == nil {
+			err = fmt.Errorf("version %d of library template %s not found", job.LibraryVersion, job.LibraryTemplateID.Hex())
+		}
+	}
+
+	var ac *applyContext
+	if err == nil {
+		ac, err = s.loadApplyContext(ctx, job.OrganizationID, job.TermID, job.Strategy, job.StartDate, job.EndDate, library)
+	}
+	if err != nil {
+		log.Printf("[ERROR] template apply job %s failed: %v", jobID.Hex(), err)
+		if err := s.ApplyJobRepository.FinishApplyJob(ctx, jobID, ApplyJobStatusFailed, err.Error()); err != nil {
+			log.Printf("[ERROR] failed to finish template apply job %s: %v", jobID.Hex(), err)
+		}
+		return
+	}
+
+	failed := job.FailedDays
+	startDate := job.StartDate.AddDate(0, 0, job.ProcessedDays)
+
+	for batchStart := startDate; !batchStart.After(job.EndDate); batchStart = batchStart.AddDate(0, 0, applyBatchSize) {
+		if ctx.Err() != nil {
+			// Stays running, resumed on the next worker start
+			return
+		}
+		if err := s.ApplyJobRepository.RenewApplyJobLease(ctx, jobID, s.workerID, applyJobLease); err != nil {
+			log.Printf("[WARN] template apply job %s stopped, its claim could not be renewed: %v", jobID.Hex(), err)
+			return
+		}
+
+		var dates []time.Time
+		for date := batchStart; len(dates) < applyBatchSize && !date.After(job.EndDate); date = date.AddDate(0, 0, 1) {
+			dates = append(dates, date)
+		}
+
+		changed, batchFailed, err := s.applyBatch(ctx, job, ac, dates)
+		if errors.Is(err, helper.ErrTransactionsUnsupported) {
+			// Nothing was written, retrying day by day would fail the same way
+			log.Printf("[ERROR] template apply job %s failed: %v", jobID.Hex(), err)
+			if err := s.ApplyJobRepository.FinishApplyJob(ctx, jobID, ApplyJobStatusFailed, err.Error()); err != nil {
+				log.Printf("[ERROR] failed to finish template apply job %s: %v", jobID.Hex(), err)
+			}
+			return
+		}
+		if err != nil {
+			changed, batchFailed = nil, 0
+			for _, date := range dates {
+				dayChanged, dayFailed, err := s.applyBatch(ctx, job, ac, []time.Time{date})
+				if err != nil {
+					dayFailed = 1
+					err = s.recordFailedDay(ctx, job, ac, date, err)
+				}
+				if err != nil {
+					log.Printf("[ERROR] failed to record progress of template apply job %s: %v", jobID.Hex(), err)
+					return
+				}
+				changed = append(changed, dayChanged...)
+				batchFailed += dayFailed
+			}
+		}
+
+		failed += batchFailed
+		s.notifyChanged(ctx, job.OrganizationID, changed)
+	}
+
+	status := ApplyJobStatusCompleted
+	if failed > 0 {
+		status = ApplyJobStatusFailed
+	}
+
+	if err := s.ApplyJobRepository.FinishApplyJob(ctx, jobID, status, ""); err != nil {
+		log.Printf("[ERROR] failed to finish template apply job %s: %v", jobID.Hex(), err)
+	}
+}
+
+// applyBatch plans and writes the dates in one transaction and records their
+// results on the job. It returns the dates that were written and the number
+// of days that failed without aborting the batch, such as merge conflicts.
+func (s *templateColorTimeService) applyBatch(ctx context.Context, job *ApplyJob, ac *applyContext, dates []time.Time) ([]time.Time, int, error) {
+
+	var changed []time.Time
+	var summary *ApplySummary
+
+	err := s.ApplyJobRepository.WithTransaction(ctx, func(txCtx context.Context) error {
+		// Reset, the transaction may be retried
+		changed = nil
+		summary = &ApplySummary{}
+		results := []*ApplyDayResult{}
+
+		for _, date := range dates {
+			plan := s.planDay(txCtx, ac, date)
+			if plan == nil {
+				continue
+			}
+
+			if plan.needsWrite() {
+				if err := s.writeDay(txCtx, ac, plan, job.CreatedBy); err != nil {
+					return fmt.Errorf("%s: %w", plan.result.Date, err)
+				}
+				changed = append(changed, date)
+			}
+
+			if plan.result.Status == ApplyStatusFailed {
+				summary.Failed++
+			} else {
+				summary.count(plan.result.Action)
+			}
+			results = append(results, plan.result)
+		}
+
+		return s.ApplyJobRepository.RecordApplyBatch(txCtx, job.ID, len(dates), results, summary)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return changed, summary.Failed, nil
+}
+
+// recordFailedDay records a day whose write failed on its own.
+func (s *templateColorTimeService) recordFailedDay(ctx context.Context, job *ApplyJob, ac *applyContext, date time.Time, cause error) error {
+
+	plan := s.planDay(ctx, ac, date)
+	if plan == nil {
+		return s.ApplyJobRepository.RecordApplyBatch(ctx, job.ID, 1, []*ApplyDayResult{}, &ApplySummary{})
+	}
+
+	plan.fail(cause)
+
+	return s.ApplyJobRepository.RecordApplyBatch(ctx, job.ID, 1, []*ApplyDayResult{plan.result}, &ApplySummary{Failed: 1})
+}
+
+// rollbackApplyJob restores every day the job applied, each in its own
+// transaction with its rollback status. Days already rolled back by an
+// interrupted run are skipped. A day changed after the job wrote it is left
+// as it is and reported as failed.
+func (s *templateColorTimeService) rollbackApplyJob(ctx context.Context, job *ApplyJob) {
+
+	var changed []time.Time
+
+	for _, day := range job.Days {
+		if day.Status != ApplyStatusApplied || day.DayID == nil || day.RollbackStatus != "" {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			// Stays rolling back, resumed on the next worker start
+			s.notifyChanged(ctx, job.OrganizationID, changed)
+			return
+		}
+		if err := s.ApplyJobRepository.RenewApplyJobLease(ctx, job.ID, s.workerID, applyJobLease); err != nil {
+			log.Printf("[WARN] rollback of template apply job %s stopped, its claim could not be renewed: %v", job.ID.Hex(), err)
+			s.notifyChanged(ctx, job.OrganizationID, changed)
+			return
+		}
+
+		err := s.ApplyJobRepository.WithTransaction(ctx, func(txCtx context.Context) error {
+			if err := s.restoreDay(txCtx, day); err != nil {
+				return err
+			}
+			return s.ApplyJobRepository.RecordDayRollback(txCtx, job.ID, day.Date, RollbackStatusRestored, "")
+		})
+		if err != nil {
+			if err := s.ApplyJobRepository.RecordDayRollback(ctx, job.ID, day.Date, RollbackStatusFailed, err.Error()); err != nil {
+				log.Printf("[ERROR] failed to record rollback of template apply job %s: %v", job.ID.Hex(), err)
+				return
+			}
+			continue
+		}
+
+		if date, err := time.Parse("2006-01-02", day.Date); err == nil {
+			changed = append(changed, date)
+		}
+	}
+
+	s.notifyChanged(ctx, job.OrganizationID, changed)
+
+	if err := s.ApplyJobRepository.FinishApplyRollback(ctx, job.ID); err != nil {
+		log.Printf("[ERROR] failed to finish rollback of template apply job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+func (s *templateColorTimeService) restoreDay(ctx context.Context, day *ApplyDayResult) error {
+
+	current, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimeByID(ctx, *day.DayID)
+	if err != nil {
+		return fmt.Errorf("failed to get default color time: %w", err)
+	}
+
+	if current == nil {
+		return errors.New("default color time no longer exists")
+	}
+
+	if current.Version != day.AppliedVersion {
+		return fmt.Errorf("default color time was changed after the template was applied: %w", &helper.VersionConflictError{CurrentVersion: current.Version})
+	}
+
+	if day.Before == nil {
+		return s.DefaultColorTimeRepository.DeleteDefaultDayColorTime(ctx, current.ID)
+	}
+
+	restored := *day.Before
+	restored.Version = current.Version
+	restored.UpdatedAt = time.Now()
+
+	return s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, restored.ID, &restored)
+}
+
+func (s *templateColorTimeService) notifyChanged(ctx context.Context, organizationID string, dates []time.Time) {
+	if s.ChangeNotifier != nil && len(dates) > 0 {
+		s.ChangeNotifier.NotifyDefaultDayChanged(ctx, organizationID, dates)
+	}
+}
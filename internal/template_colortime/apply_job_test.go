@@ -0,0 +1,160 @@
+package templatecolortime
+
+import (
+	"colortime-service/internal/closure"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeApplyJobRepository hands out claimed, the job as claimed by the worker,
+// and records what the run did with it.
+type fakeApplyJobRepository struct {
+	ApplyJobRepository
+	claimed     *ApplyJob
+	claimErr    error
+	overlapping []*ApplyJob
+	leaseLost   bool
+
+	claims, renewals, overlapChecks int
+	finished                        []string // status of each FinishApplyJob
+	finishErrors                    []string
+	rolledBack                      bool
+}
+
+func (r *fakeApplyJobRepository) ClaimApplyJob(ctx context.Context, id primitive.ObjectID, workerID string, lease time.Duration) (*ApplyJob, error) {
+	r.claims++
+	return r.claimed, r.claimErr
+}
+
+func (r *fakeApplyJobRepository) RenewApplyJobLease(ctx context.Context, id primitive.ObjectID, workerID string, lease time.Duration) error {
+	r.renewals++
+	if r.leaseLost {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *fakeApplyJobRepository) GetOverlappingApplyJobs(ctx context.Context, organizationID, termID string, startDate, endDate time.Time) ([]*ApplyJob, error) {
+	r.overlapChecks++
+	return r.overlapping, nil
+}
+
+func (r *fakeApplyJobRepository) FinishApplyJob(ctx context.Context, id primitive.ObjectID, status, errMsg string) error {
+	r.finished = append(r.finished, status)
+	r.finishErrors = append(r.finishErrors, errMsg)
+	return nil
+}
+
+func (r *fakeApplyJobRepository) FinishApplyRollback(ctx context.Context, id primitive.ObjectID) error {
+	r.rolledBack = true
+	return nil
+}
+
+type fakeClosureService struct {
+	closure.ClosureService
+}
+
+func (f *fakeClosureService) GetCalendar(ctx context.Context, organizationID string, startDate, endDate time.Time) (*closure.Calendar, error) {
+	return &closure.Calendar{}, nil
+}
+
+func newApplyJobService(jobs *fakeApplyJobRepository) *templateColorTimeService {
+	return &templateColorTimeService{
+		TemplateColorTimeRepository: newFakeTemplateRepository(),
+		ClosureService:              &fakeClosureService{},
+		ApplyJobRepository:          jobs,
+		workerID:                    "worker-1",
+	}
+}
+
+func testApplyJob(status string, processedDays int) *ApplyJob {
+	start := time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)
+	return &ApplyJob{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: "org-1",
+		TermID:         "term-2",
+		StartDate:      start,
+		EndDate:        start.AddDate(0, 0, 13),
+		Status:         status,
+		ProcessedDays:  processedDays,
+		Summary:        &ApplySummary{},
+	}
+}
+
+func TestRunApplyJobClaim(t *testing.T) {
+	tests := []struct {
+		name string
+		jobs *fakeApplyJobRepository
+	}{
+		{name: "finished or held by another worker", jobs: &fakeApplyJobRepository{}},
+		{name: "claim failed", jobs: &fakeApplyJobRepository{claimErr: errors.New("connection reset")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newApplyJobService(tt.jobs).runApplyJob(context.Background(), primitive.NewObjectID())
+
+			if tt.jobs.claims != 1 {
+				t.Fatalf("%d claims, want 1", tt.jobs.claims)
+			}
+			if tt.jobs.overlapChecks+tt.jobs.renewals+len(tt.jobs.finished) != 0 || tt.jobs.rolledBack {
+				t.Fatalf("job not claimed was run: %+v", tt.jobs)
+			}
+		})
+	}
+}
+
+func TestRunApplyJobOverlapCheckedAfterClaim(t *testing.T) {
+	earlier := testApplyJob(ApplyJobStatusCompleted, 14)
+	earlier.TermID = "term-1"
+
+	jobs := &fakeApplyJobRepository{claimed: testApplyJob(ApplyJobStatusRunning, 0), overlapping: []*ApplyJob{earlier}}
+	newApplyJobService(jobs).runApplyJob(context.Background(), jobs.claimed.ID)
+
+	if jobs.overlapChecks != 1 {
+		t.Fatalf("%d overlap checks, want 1", jobs.overlapChecks)
+	}
+	if len(jobs.finished) != 1 || jobs.finished[0] != ApplyJobStatusFailed || jobs.finishErrors[0] != overlapMessage(earlier) {
+		t.Fatalf("job finished %v with %q, want failed on the overlap", jobs.finished, jobs.finishErrors)
+	}
+	if jobs.renewals != 0 {
+		t.Fatal("overlapping job went on to its batches")
+	}
+}
+
+func TestRunApplyJobStopsWithoutLease(t *testing.T) {
+	// Resumed after some batches, so not checked for overlaps again
+	jobs := &fakeApplyJobRepository{claimed: testApplyJob(ApplyJobStatusRunning, 7), leaseLost: true}
+	newApplyJobService(jobs).runApplyJob(context.Background(), jobs.claimed.ID)
+
+	if jobs.overlapChecks != 0 {
+		t.Fatalf("%d overlap checks for a job already writing days", jobs.overlapChecks)
+	}
+	if jobs.renewals != 1 {
+		t.Fatalf("%d lease renewals, want 1 before the first batch", jobs.renewals)
+	}
+	if len(jobs.finished) != 0 {
+		t.Fatalf("job finished %v by a worker that lost it", jobs.finished)
+	}
+}
+
+func TestRollbackStopsWithoutLease(t *testing.T) {
+	dayID := primitive.NewObjectID()
+	job := testApplyJob(ApplyJobStatusRollingBack, 14)
+	job.Days = []*ApplyDayResult{{Date: "2026-09-07", Status: ApplyStatusApplied, DayID: &dayID}}
+
+	jobs := &fakeApplyJobRepository{claimed: job, leaseLost: true}
+	newApplyJobService(jobs).runApplyJob(context.Background(), job.ID)
+
+	if jobs.renewals != 1 {
+		t.Fatalf("%d lease renewals, want 1 before the first day", jobs.renewals)
+	}
+	if jobs.rolledBack {
+		t.Fatal("rollback finished by a worker that lost the job")
+	}
+}
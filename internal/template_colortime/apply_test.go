@@ -0,0 +1,362 @@
+package templatecolortime
+
+import (
+	"colortime-service/internal/default_colortime"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func clock(value string) time.Time {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// defaultSlot builds a slot from a "HH:MM-HH:MM" span.
+func defaultSlot(id primitive.ObjectID, title, span string) *default_colortime.DefaultColortimeSlot {
+	start, end := clock(span[:5]), clock(span[6:])
+	return &default_colortime.DefaultColortimeSlot{
+		SlotID:    id,
+		Title:     title,
+		StartTime: start,
+		EndTime:   end,
+		Duration:  int(end.Sub(start).Seconds()),
+	}
+}
+
+func defaultBlock(id primitive.ObjectID, slots ...*default_colortime.DefaultColortimeSlot) *default_colortime.DefaultColorBlock {
+	return &default_colortime.DefaultColorBlock{BlockID: id, Slots: slots}
+}
+
+// layout lists the slots of each block as "title HH:MM-HH:MM".
+func layout(blocks []*default_colortime.DefaultColorBlock) [][]string {
+	result := make([][]string, 0, len(blocks))
+	for _, block := range blocks {
+		slots := []string{}
+		for _, slot := range block.Slots {
+			slots = append(slots, slot.Title+" "+slot.StartTime.Format("15:04")+"-"+slot.EndTime.Format("15:04"))
+		}
+		result = append(result, slots)
+	}
+	return result
+}
+
+func TestMergeBlocks(t *testing.T) {
+	blockA, blockB := primitive.NewObjectID(), primitive.NewObjectID()
+	circle, snack, garden, story := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name     string
+		existing []*default_colortime.DefaultColorBlock
+		planned  []*default_colortime.DefaultColorBlock
+		want     [][]string
+		wantErr  bool
+	}{
+		{
+			name:     "template slot takes the template's fields",
+			existing: []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-08:30"))},
+			planned:  []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle time", "08:15-08:45"))},
+			want:     [][]string{{"Circle time 08:15-08:45"}},
+		},
+		{
+			name:     "slots added by hand are kept",
+			existing: []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-08:30"), defaultSlot(garden, "Garden", "10:00-10:30"))},
+			planned:  []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-08:30"))},
+			want:     [][]string{{"Circle 08:00-08:30", "Garden 10:00-10:30"}},
+		},
+		{
+			name:     "new template slots join their block",
+			existing: []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-08:30"))},
+			planned: []*default_colortime.DefaultColorBlock{
+				defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-08:30"), defaultSlot(snack, "Snack", "09:00-09:15")),
+				defaultBlock(blockB, defaultSlot(story, "Story", "15:00-15:30")),
+			},
+			want: [][]string{{"Circle 08:00-08:30", "Snack 09:00-09:15"}, {"Story 15:00-15:30"}},
+		},
+		{
+			name:     "template slot overlapping a kept slot",
+			existing: []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(garden, "Garden", "09:00-10:00"))},
+			planned:  []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(snack, "Snack", "09:30-09:45"))},
+			wantErr:  true,
+		},
+		{
+			name:     "template slot moved over its own old time",
+			existing: []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-09:00"))},
+			planned:  []*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle", "08:30-09:30"))},
+			want:     [][]string{{"Circle 08:30-09:30"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := layout(tt.existing)
+
+			merged, err := mergeBlocks(tt.existing, tt.planned)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("mergeBlocks = %v, want an error", layout(merged))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeBlocks: %v", err)
+			}
+
+			if got := layout(merged); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			if got := layout(tt.existing); !reflect.DeepEqual(got, before) {
+				t.Fatalf("existing blocks changed to %v", got)
+			}
+		})
+	}
+}
+
+func TestMergeBlocksKeepsCreatedAt(t *testing.T) {
+	block, circle := primitive.NewObjectID(), primitive.NewObjectID()
+	created := time.Date(2026, 9, 1, 8, 0, 0, 0, time.UTC)
+
+	existing := defaultSlot(circle, "Circle", "08:00-08:30")
+	existing.CreatedAt = created
+	planned := defaultSlot(circle, "Circle time", "08:00-08:30")
+	planned.CreatedAt = created.AddDate(0, 1, 0)
+
+	merged, err := mergeBlocks([]*default_colortime.DefaultColorBlock{defaultBlock(block, existing)}, []*default_colortime.DefaultColorBlock{defaultBlock(block, planned)})
+	if err != nil {
+		t.Fatalf("mergeBlocks: %v", err)
+	}
+
+	if slot := merged[0].Slots[0]; !slot.CreatedAt.Equal(created) {
+		t.Fatalf("created_at = %s, want %s", slot.CreatedAt, created)
+	}
+}
+
+// templateSlot builds a template slot from a "HH:MM-HH:MM" span, last changed
+// at updatedAt.
+func templateSlot(title, span string, updatedAt time.Time) *ColortimeSlot {
+	start, end := clock(span[:5]), clock(span[6:])
+	return &ColortimeSlot{
+		SlotID:    primitive.NewObjectID(),
+		Title:     title,
+		StartTime: start,
+		EndTime:   end,
+		Duration:  int(end.Sub(start).Seconds()),
+		UpdatedAt: updatedAt,
+	}
+}
+
+func TestReapplyBlocks(t *testing.T) {
+	appliedAt := time.Date(2026, 9, 1, 8, 0, 0, 0, time.UTC)
+	before, after := appliedAt.Add(-time.Hour), appliedAt.Add(time.Hour)
+	now := appliedAt.Add(24 * time.Hour)
+	blockA, blockB := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name    string
+		build   func(template *TemplateColorTime) []*default_colortime.DefaultColorBlock
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name: "unchanged template slot is kept as applied",
+			build: func(template *TemplateColorTime) []*default_colortime.DefaultColorBlock {
+				circle := templateSlot("Circle", "08:00-08:30", before)
+				template.ColorTimes = []*ColorTimeTemplate{{BlockID: blockA, Slots: []*ColortimeSlot{circle}}}
+
+				day := templateSlotToDefault(template, circle, appliedAt)
+				day.Note = "edited by hand"
+				return []*default_colortime.DefaultColorBlock{defaultBlock(blockA, day)}
+			},
+			want: [][]string{{"Circle 08:00-08:30"}},
+		},
+		{
+			name: "template slot changed after the apply is rewritten",
+			build: func(template *TemplateColorTime) []*default_colortime.DefaultColorBlock {
+				circle := templateSlot("Circle", "08:00-08:30", before)
+				template.ColorTimes = []*ColorTimeTemplate{{BlockID: blockA, Slots: []*ColortimeSlot{circle}}}
+				day := templateSlotToDefault(template, circle, appliedAt)
+
+				circle.Title, circle.StartTime, circle.EndTime, circle.UpdatedAt = "Circle time", clock("08:15"), clock("08:45"), after
+				return []*default_colortime.DefaultColorBlock{defaultBlock(blockA, day)}
+			},
+			want: [][]string{{"Circle time 08:15-08:45"}},
+		},
+		{
+			name: "removed template slot goes with its emptied block",
+			build: func(template *TemplateColorTime) []*default_colortime.DefaultColorBlock {
+				circle := templateSlot("Circle", "08:00-08:30", before)
+				story := templateSlot("Story", "15:00-15:30", before)
+				template.ColorTimes = []*ColorTimeTemplate{{BlockID: blockA, Slots: []*ColortimeSlot{circle}}}
+
+				return []*default_colortime.DefaultColorBlock{
+					defaultBlock(blockA, templateSlotToDefault(template, circle, appliedAt)),
+					defaultBlock(blockB, templateSlotToDefault(template, story, appliedAt)),
+				}
+			},
+			want: [][]string{{"Circle 08:00-08:30"}},
+		},
+		{
+			name: "new template slot is added, slots by hand are kept",
+			build: func(template *TemplateColorTime) []*default_colortime.DefaultColorBlock {
+				circle := templateSlot("Circle", "08:00-08:30", before)
+				snack := templateSlot("Snack", "09:00-09:15", after)
+				template.ColorTimes = []*ColorTimeTemplate{
+					{BlockID: blockA, Slots: []*ColortimeSlot{circle}},
+					{BlockID: blockB, Slots: []*ColortimeSlot{snack}},
+				}
+
+				return []*default_colortime.DefaultColorBlock{
+					defaultBlock(blockA, templateSlotToDefault(template, circle, appliedAt), defaultSlot(primitive.NewObjectID(), "Garden", "10:00-10:30")),
+				}
+			},
+			want: [][]string{{"Circle 08:00-08:30", "Garden 10:00-10:30"}, {"Snack 09:00-09:15"}},
+		},
+		{
+			name: "rewritten slot overlapping a slot by hand",
+			build: func(template *TemplateColorTime) []*default_colortime.DefaultColorBlock {
+				circle := templateSlot("Circle", "08:00-08:30", before)
+				template.ColorTimes = []*ColorTimeTemplate{{BlockID: blockA, Slots: []*ColortimeSlot{circle}}}
+				day := templateSlotToDefault(template, circle, appliedAt)
+
+				circle.StartTime, circle.EndTime, circle.UpdatedAt = clock("09:45"), clock("10:15"), after
+				return []*default_colortime.DefaultColorBlock{
+					defaultBlock(blockA, day, defaultSlot(primitive.NewObjectID(), "Garden", "10:00-10:30")),
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "slots of another template are kept",
+			build: func(template *TemplateColorTime) []*default_colortime.DefaultColorBlock {
+				other := &TemplateColorTime{ID: primitive.NewObjectID()}
+				circle := templateSlot("Circle", "08:00-08:30", after)
+				other.ColorTimes = []*ColorTimeTemplate{{BlockID: blockA, Slots: []*ColortimeSlot{circle}}}
+
+				template.ColorTimes = []*ColorTimeTemplate{}
+				return []*default_colortime.DefaultColorBlock{defaultBlock(blockA, templateSlotToDefault(other, circle, appliedAt))}
+			},
+			want: [][]string{{"Circle 08:00-08:30"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &TemplateColorTime{ID: primitive.NewObjectID(), TermID: "term-1"}
+			existing := tt.build(template)
+
+			result, err := reapplyBlocks(existing, template, appliedAt, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("reapplyBlocks = %v, want an error", layout(result))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reapplyBlocks: %v", err)
+			}
+
+			if got := layout(result); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReapplyBlocksSource(t *testing.T) {
+	appliedAt := time.Date(2026, 9, 1, 8, 0, 0, 0, time.UTC)
+	now := appliedAt.Add(24 * time.Hour)
+
+	template := &TemplateColorTime{ID: primitive.NewObjectID(), TermID: "term-1"}
+	circle := templateSlot("Circle", "08:00-08:30", appliedAt.Add(-time.Hour))
+	template.ColorTimes = []*ColorTimeTemplate{{BlockID: primitive.NewObjectID(), Slots: []*ColortimeSlot{circle}}}
+
+	day := templateSlotToDefault(template, circle, appliedAt)
+	day.SlotID = primitive.NewObjectID() // the day's own id, kept on rewrite
+	day.Sessions = 3
+	circle.UpdatedAt = appliedAt.Add(time.Hour)
+
+	result, err := reapplyBlocks([]*default_colortime.DefaultColorBlock{defaultBlock(template.ColorTimes[0].BlockID, day)}, template, appliedAt, now)
+	if err != nil {
+		t.Fatalf("reapplyBlocks: %v", err)
+	}
+
+	slot := result[0].Slots[0]
+	if slot.SlotID != day.SlotID || slot.Sessions != 3 {
+		t.Fatalf("rewritten slot has id %s and session %d, want the day's %s and 3", slot.SlotID.Hex(), slot.Sessions, day.SlotID.Hex())
+	}
+	if slot.Source == nil || !slot.Source.AppliedAt.Equal(now) || *slot.Source.TemplateSlotID != circle.SlotID {
+		t.Fatalf("rewritten slot source = %+v, want applied now from the template slot", slot.Source)
+	}
+}
+
+func TestDiffBlocks(t *testing.T) {
+	blockA, blockB := primitive.NewObjectID(), primitive.NewObjectID()
+	circle, snack, garden := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	before := []*default_colortime.DefaultColorBlock{
+		defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-08:30"), defaultSlot(garden, "Garden", "10:00-10:30")),
+	}
+
+	moved := defaultSlot(circle, "Circle time", "08:15-08:45")
+	moved.ColorTimeSlotLanguage = []*default_colortime.DefaultColorTimeSlotLanguage{{LanguageID: 1, Title: "Circle time"}}
+	after := []*default_colortime.DefaultColorBlock{
+		defaultBlock(blockA, moved),
+		defaultBlock(blockB, defaultSlot(snack, "Snack", "09:00-09:15")),
+	}
+
+	added, removed, changed := diffBlocks(before, after)
+
+	if len(added) != 1 || added[0].SlotID != snack || added[0].BlockID != blockB {
+		t.Fatalf("added = %+v, want the snack in block B", added)
+	}
+	if len(removed) != 1 || removed[0].SlotID != garden {
+		t.Fatalf("removed = %+v, want the garden", removed)
+	}
+	if len(changed) != 1 || changed[0].SlotID != circle {
+		t.Fatalf("changed = %+v, want the circle", changed)
+	}
+	wantFields := []string{"title", "start_time", "end_time", "color_time_slot_language"}
+	if !reflect.DeepEqual(changed[0].Fields, wantFields) {
+		t.Fatalf("changed fields = %v, want %v", changed[0].Fields, wantFields)
+	}
+}
+
+func TestDiffBlocksUnchanged(t *testing.T) {
+	block, circle := primitive.NewObjectID(), primitive.NewObjectID()
+
+	// Same time of day on another date is no change
+	before := defaultSlot(circle, "Circle", "08:00-08:30")
+	after := *before
+	after.StartTime = before.StartTime.AddDate(2026, 0, 0)
+	after.EndTime = before.EndTime.AddDate(2026, 0, 0)
+
+	added, removed, changed := diffBlocks(
+		[]*default_colortime.DefaultColorBlock{defaultBlock(block, before)},
+		[]*default_colortime.DefaultColorBlock{defaultBlock(block, &after)},
+	)
+
+	if len(added)+len(removed)+len(changed) != 0 {
+		t.Fatalf("diff of the same day: added %v, removed %v, changed %v", added, removed, changed)
+	}
+	if added == nil || removed == nil || changed == nil {
+		t.Fatal("empty diff lists are nil, want empty lists in the JSON")
+	}
+}
+
+func TestDiffBlocksMovedToAnotherBlock(t *testing.T) {
+	blockA, blockB, circle := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	_, _, changed := diffBlocks(
+		[]*default_colortime.DefaultColorBlock{defaultBlock(blockA, defaultSlot(circle, "Circle", "08:00-08:30"))},
+		[]*default_colortime.DefaultColorBlock{defaultBlock(blockB, defaultSlot(circle, "Circle", "08:00-08:30"))},
+	)
+
+	if len(changed) != 1 || !reflect.DeepEqual(changed[0].Fields, []string{"block_id"}) {
+		t.Fatalf("changed = %+v, want the block_id of the circle", changed)
+	}
+}
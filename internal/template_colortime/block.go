@@ -0,0 +1,238 @@
+package templatecolortime
+
+import (
+	"colortime-service/helper"
+	"colortime-service/pkg/slotblock"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateTemplateBlock adds an empty block at the end of the template.
+func (s *templateColorTimeService) CreateTemplateBlock(ctx context.Context, templateColorTimeID string, userID string) (*ColorTimeTemplate, error) {
+
+	template, err := s.templateForBlockEdit(ctx, templateColorTimeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &ColorTimeTemplate{
+		BlockID: primitive.NewObjectID(),
+		Slots:   []*ColortimeSlot{},
+	}
+	template.ColorTimes = append(template.ColorTimes, block)
+
+	if err := s.saveTemplateBlocks(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// MoveTemplateBlock shifts every slot of the block so the block starts at
+// req.StartTime, keeping their durations and the gaps between them.
+func (s *templateColorTimeService) MoveTemplateBlock(ctx context.Context, templateColorTimeID, blockID string, req *MoveBlockRequest, userID string) error {
+
+	template, err := s.templateForBlockEdit(ctx, templateColorTimeID, userID)
+	if err != nil {
+		return err
+	}
+
+	block, _, err := findTemplateBlock(template, blockID)
+	if err != nil {
+		return err
+	}
+
+	if err := slotblock.Move(block.Slots, req.StartTime); err != nil {
+		return err
+	}
+
+	if err := checkTemplateBlockSlots(template, block.Slots); err != nil {
+		return err
+	}
+
+	return s.saveTemplateBlocks(ctx, template)
+}
+
+// ReorderTemplateBlockSlots puts the slots of the block in the order of
+// req.SlotIDs, see slotblock.Reorder for how their times change.
+func (s *templateColorTimeService) ReorderTemplateBlockSlots(ctx context.Context, templateColorTimeID, blockID string, req *ReorderBlockSlotsRequest, userID string) error {
+
+	template, err := s.templateForBlockEdit(ctx, templateColorTimeID, userID)
+	if err != nil {
+		return err
+	}
+
+	block, _, err := findTemplateBlock(template, blockID)
+	if err != nil {
+		return err
+	}
+
+	ordered, err := slotblock.Reorder(block.Slots, req.SlotIDs)
+	if err != nil {
+		return err
+	}
+	block.Slots = ordered
+
+	if err := checkTemplateBlockSlots(template, block.Slots); err != nil {
+		return err
+	}
+
+	return s.saveTemplateBlocks(ctx, template)
+}
+
+// SplitTemplateBlock moves req.SlotID and the slots after it into a new block
+// right after the block and returns the new block.
+func (s *templateColorTimeService) SplitTemplateBlock(ctx context.Context, templateColorTimeID, blockID string, req *SplitBlockRequest, userID string) (*ColorTimeTemplate, error) {
+
+	template, err := s.templateForBlockEdit(ctx, templateColorTimeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	block, index, err := findTemplateBlock(template, blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	at, err := slotblock.SplitIndex(block.Slots, req.SlotID)
+	if err != nil {
+		return nil, err
+	}
+
+	newBlock := &ColorTimeTemplate{
+		BlockID: primitive.NewObjectID(),
+		Slots:   append([]*ColortimeSlot(nil), block.Slots[at:]...),
+	}
+	block.Slots = block.Slots[:at]
+
+	for _, slot := range newBlock.Slots {
+		slot.UpdatedAt = time.Now()
+	}
+	slotblock.Renumber(newBlock.Slots)
+
+	template.ColorTimes = append(template.ColorTimes[:index+1], append([]*ColorTimeTemplate{newBlock}, template.ColorTimes[index+1:]...)...)
+
+	if err := s.saveTemplateBlocks(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return newBlock, nil
+}
+
+// MergeTemplateBlocks moves the slots of req.BlockID into the block and
+// removes req.BlockID.
+func (s *templateColorTimeService) MergeTemplateBlocks(ctx context.Context, templateColorTimeID, blockID string, req *MergeBlocksRequest, userID string) error {
+
+	template, err := s.templateForBlockEdit(ctx, templateColorTimeID, userID)
+	if err != nil {
+		return err
+	}
+
+	block, _, err := findTemplateBlock(template, blockID)
+	if err != nil {
+		return err
+	}
+
+	other, otherIndex, err := findTemplateBlock(template, req.BlockID)
+	if err != nil {
+		return err
+	}
+
+	if other == block {
+		return errors.New("cannot merge a block into itself")
+	}
+
+	for _, slot := range other.Slots {
+		slot.UpdatedAt = time.Now()
+	}
+
+	block.Slots = append(block.Slots, other.Slots...)
+	template.ColorTimes = append(template.ColorTimes[:otherIndex], template.ColorTimes[otherIndex+1:]...)
+
+	if err := checkTemplateBlockSlots(template, block.Slots); err != nil {
+		return err
+	}
+
+	slotblock.Renumber(block.Slots)
+
+	return s.saveTemplateBlocks(ctx, template)
+}
+
+func (s *templateColorTimeService) templateForBlockEdit(ctx context.Context, templateColorTimeID, userID string) (*TemplateColorTime, error) {
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	templateObjectID, err := primitive.ObjectIDFromHex(templateColorTimeID)
+	if err != nil {
+		return nil, errors.New("invalid template color time id format")
+	}
+
+	template, err := s.TemplateColorTimeRepository.GetTemplateColorTimeByID(ctx, templateObjectID)
+	if err != nil {
+		return nil, errors.New("failed to get template color time")
+	}
+
+	if template == nil {
+		return nil, errors.New("template color time not found")
+	}
+
+	if err := helper.CheckIfMatch(ctx, template.Version); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (s *templateColorTimeService) saveTemplateBlocks(ctx context.Context, template *TemplateColorTime) error {
+	template.UpdatedAt = time.Now()
+
+	if err := s.TemplateColorTimeRepository.UpdateTemplateColorTime(ctx, template.ID, template); err != nil {
+		return fmt.Errorf("failed to update template color time: %w", err)
+	}
+
+	return nil
+}
+
+func findTemplateBlock(template *TemplateColorTime, blockID string) (*ColorTimeTemplate, int, error) {
+
+	blockObjectID, err := primitive.ObjectIDFromHex(blockID)
+	if err != nil {
+		return nil, 0, errors.New("invalid block id format")
+	}
+
+	for i, block := range template.ColorTimes {
+		if block.BlockID == blockObjectID {
+			return block, i, nil
+		}
+	}
+
+	return nil, 0, errors.New("block not found")
+}
+
+// checkTemplateBlockSlots rejects changed slots that leave their day or overlap
+// any other slot of the template.
+func checkTemplateBlockSlots(template *TemplateColorTime, changed []*ColortimeSlot) error {
+
+	var allSlots []*ColortimeSlot
+	for _, block := range template.ColorTimes {
+		allSlots = append(allSlots, block.Slots...)
+	}
+
+	for _, slot := range changed {
+		if !slotblock.FitsInDay(slot.StartTime, slot.EndTime) {
+			return fmt.Errorf("slot %q would not fit in the day", slot.Title)
+		}
+
+		if isTimeSlotConflict(slot.StartTime, slot.EndTime, allSlots, &slot.SlotID) {
+			return fmt.Errorf("slot %q conflicts with another slot in the template", slot.Title)
+		}
+	}
+
+	return nil
+}
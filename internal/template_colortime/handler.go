@@ -50,7 +50,7 @@ func (h *TemplateColorTimeHandler) CreateTemplateColorTime(c *gin.Context) {
 
 	templateColorTime, err := h.TemplateColorTimeService.CreateTemplateColorTime(ctx, request, userID.(string))
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -109,6 +109,11 @@ func (h *TemplateColorTimeHandler) GetTemplateColorTime(c *gin.Context) {
 		return
 	}
 
+	// A single template is addressable by the /:id routes, so it gets an ETag
+	if len(templateColorTime) == 1 && templateColorTime[0] != nil {
+		helper.SetETag(c, templateColorTime[0].Version)
+	}
+
 	helper.SendSuccess(c, http.StatusOK, "template color time retrieved successfully", templateColorTime)
 }
 
@@ -140,13 +145,53 @@ func (h *TemplateColorTimeHandler) DuplicateTemplateColorTime(c *gin.Context) {
 
 	err := h.TemplateColorTimeService.DuplicateTemplateColorTime(ctx, request, userID.(string))
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	helper.SendSuccess(c, http.StatusOK, "template color time duplicated successfully", nil)
 }
 
+func (h *TemplateColorTimeHandler) RolloverTemplateColorTime(c *gin.Context) {
+	var request RolloverTemplateColorTimeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists || userID == "" {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	data, err := h.TemplateColorTimeService.RolloverTemplateColorTime(ctx, &request, userID.(string))
+	if err != nil && data != nil {
+		// Templates were copied, only applying them failed
+		c.JSON(http.StatusOK, helper.APIResponse{
+			StatusCode: http.StatusOK,
+			Message:    "template color time rolled over, applying it failed",
+			Data:       data,
+			Error:      err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "template color time rolled over successfully", data)
+}
+
 func (h *TemplateColorTimeHandler) ApplyTemplateColorTime(c *gin.Context) {
 	var request ApplyTemplateColorTimeRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -173,13 +218,115 @@ func (h *TemplateColorTimeHandler) ApplyTemplateColorTime(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.TemplateColorTimeService.ApplyTemplateColorTime(ctx, request, userID.(string))
+	if request.DryRun {
+		report, err := h.TemplateColorTimeService.PreviewApplyTemplateColorTime(ctx, request)
+		if err != nil {
+			helper.SendServiceError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		helper.SendSuccess(c, http.StatusOK, "template color time apply previewed successfully", report)
+		return
+	}
+
+	job, err := h.TemplateColorTimeService.ApplyTemplateColorTime(ctx, request, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusAccepted, "template color time apply queued successfully", job)
+}
+
+func (h *TemplateColorTimeHandler) GetApplyJobs(c *gin.Context) {
+
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.GetApplyJobs(c, orgID)
 	if err != nil {
 		helper.SendError(c, http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	helper.SendSuccess(c, http.StatusOK, "template color time applied successfully", nil)
+	helper.SendSuccess(c, http.StatusOK, "get apply jobs successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) GetApplyJob(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.GetApplyJob(c, id)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if data == nil {
+		helper.SendError(c, http.StatusNotFound, errors.New("apply job not found"), nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get apply job successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) RollbackApplyJob(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.RollbackApplyJob(c, id)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusAccepted, "apply job rollback queued successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) ReapplyTemplateColorTime(c *gin.Context) {
+	templateColorTimeID := c.Param("id")
+	if templateColorTimeID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("template color time id is required"), nil)
+		return
+	}
+
+	var request ReapplyTemplateColorTimeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return
+	}
+
+	ctx := context.WithValue(c, constants.TokenKey, token)
+
+	report, err := h.TemplateColorTimeService.ReapplyTemplateColorTime(ctx, templateColorTimeID, &request)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	message := "template color time reapplied successfully"
+	if report.DryRun {
+		message = "template color time reapply previewed successfully"
+	}
+
+	helper.SendSuccess(c, http.StatusOK, message, report)
 }
 
 func (h *TemplateColorTimeHandler) UpdateTemplateColorTimeSlot(c *gin.Context) {
@@ -220,9 +367,15 @@ func (h *TemplateColorTimeHandler) UpdateTemplateColorTimeSlot(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.TemplateColorTimeService.UpdateTemplateColorTimeSlot(ctx, templateColorTimeID, slotID, &request, userID.(string))
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	err = h.TemplateColorTimeService.UpdateTemplateColorTimeSlot(ctx, templateColorTimeID, slotID, &request, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -256,9 +409,15 @@ func (h *TemplateColorTimeHandler) DeleteTemplateColorTimeBlock(c *gin.Context)
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.TemplateColorTimeService.DeleteTemplateColorTimeBlock(ctx, templateColorTimeID, blockID, userID.(string))
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	err = h.TemplateColorTimeService.DeleteTemplateColorTimeBlock(ctx, templateColorTimeID, blockID, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -292,9 +451,15 @@ func (h *TemplateColorTimeHandler) DeleteTemplateColorTimeSlot(c *gin.Context) {
 
 	ctx := context.WithValue(c, constants.TokenKey, token)
 
-	err := h.TemplateColorTimeService.DeleteTemplateColorTimeSlot(ctx, templateColorTimeID, slotID, userID.(string))
+	ctx, err := helper.WithIfMatch(ctx, c)
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	err = h.TemplateColorTimeService.DeleteTemplateColorTimeSlot(ctx, templateColorTimeID, slotID, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -330,9 +495,277 @@ func (h *TemplateColorTimeHandler) CopySlotToTemplateColorTime(c *gin.Context) {
 
 	err := h.TemplateColorTimeService.CopySlotToTemplateColorTime(ctx, blockID, &request, userID.(string))
 	if err != nil {
-		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		helper.SendServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	helper.SendSuccess(c, http.StatusOK, "block copied to template color time successfully", nil)
 }
+
+func (h *TemplateColorTimeHandler) CreateLibraryTemplate(c *gin.Context) {
+	var request CreateLibraryTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists || userID == "" {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.CreateLibraryTemplate(c, &request, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "library template created successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) GetLibraryTemplates(c *gin.Context) {
+
+	orgID := c.Query("org_id")
+	if orgID == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("org_id is required"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.GetLibraryTemplates(c, orgID)
+	if err != nil {
+		helper.SendError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get library templates successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) GetLibraryTemplate(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.GetLibraryTemplate(c, id)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get library template successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) UpdateLibraryTemplate(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	var request UpdateLibraryTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.TemplateColorTimeService.UpdateLibraryTemplate(c, id, &request); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "library template updated successfully", nil)
+}
+
+func (h *TemplateColorTimeHandler) GetLibraryTemplateVersions(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.GetLibraryTemplateVersions(c, id)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get library template versions successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) GetLibraryTemplateVersion(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		helper.SendError(c, http.StatusBadRequest, errors.New("invalid version"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.GetLibraryTemplateVersion(c, id, version)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "get library template version successfully", data)
+}
+
+func (h *TemplateColorTimeHandler) PublishLibraryTemplateVersion(c *gin.Context) {
+
+	id := c.Param("id")
+	if id == "" {
+		helper.SendError(c, http.StatusBadRequest, errors.New("id is required"), nil)
+		return
+	}
+
+	var request PublishLibraryTemplateVersionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists || userID == "" {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return
+	}
+
+	data, err := h.TemplateColorTimeService.PublishLibraryTemplateVersion(c, id, &request, userID.(string))
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "library template version published successfully", data)
+}
+
+// blockEditContext reads the user, the token and If-Match for the block
+// handlers, answering the request itself when one is missing.
+func blockEditContext(c *gin.Context) (context.Context, string, bool) {
+
+	userID, exists := c.Get(constants.UserID)
+	if !exists || userID == "" {
+		helper.SendError(c, http.StatusUnauthorized, errors.New("user ID not found in context"), nil)
+		return nil, "", false
+	}
+
+	token, exists := c.Get(constants.Token)
+	if !exists {
+		helper.SendError(c, 400, fmt.Errorf("token not found"), nil)
+		return nil, "", false
+	}
+
+	ctx, err := helper.WithIfMatch(context.WithValue(c, constants.TokenKey, token), c)
+	if err != nil {
+		helper.SendError(c, http.StatusBadRequest, err, nil)
+		return nil, "", false
+	}
+
+	return ctx, userID.(string), true
+}
+
+func (h *TemplateColorTimeHandler) CreateTemplateBlock(c *gin.Context) {
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	block, err := h.TemplateColorTimeService.CreateTemplateBlock(ctx, c.Param("id"), userID)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "template color time block created successfully", block)
+}
+
+func (h *TemplateColorTimeHandler) MoveTemplateBlock(c *gin.Context) {
+	var request MoveBlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.TemplateColorTimeService.MoveTemplateBlock(ctx, c.Param("id"), c.Param("block_id"), &request, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "template color time block moved successfully", nil)
+}
+
+func (h *TemplateColorTimeHandler) ReorderTemplateBlockSlots(c *gin.Context) {
+	var request ReorderBlockSlotsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.TemplateColorTimeService.ReorderTemplateBlockSlots(ctx, c.Param("id"), c.Param("block_id"), &request, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "template color time block slots reordered successfully", nil)
+}
+
+func (h *TemplateColorTimeHandler) SplitTemplateBlock(c *gin.Context) {
+	var request SplitBlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	block, err := h.TemplateColorTimeService.SplitTemplateBlock(ctx, c.Param("id"), c.Param("block_id"), &request, userID)
+	if err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "template color time block split successfully", block)
+}
+
+func (h *TemplateColorTimeHandler) MergeTemplateBlocks(c *gin.Context) {
+	var request MergeBlocksRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, userID, ok := blockEditContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.TemplateColorTimeService.MergeTemplateBlocks(ctx, c.Param("id"), c.Param("block_id"), &request, userID); err != nil {
+		helper.SendServiceError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	helper.SendSuccess(c, http.StatusOK, "template color time blocks merged successfully", nil)
+}
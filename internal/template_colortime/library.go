@@ -0,0 +1,469 @@
+package templatecolortime
+
+import (
+	"colortime-service/helper"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var templateWeekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// CreateLibraryTemplate creates a named template with its first version, built
+// from the days of the request or copied from the weekday templates of a term.
+func (s *templateColorTimeService) CreateLibraryTemplate(ctx context.Context, req *CreateLibraryTemplateRequest, userID string) (*LibraryTemplateResponse, error) {
+
+	if req.OrganizationID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	existing, err := s.LibraryTemplateRepository.GetLibraryTemplateByName(ctx, req.OrganizationID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check library template name: %w", err)
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("library template %q already exists", name)
+	}
+
+	days, err := s.libraryDays(ctx, req.OrganizationID, req.FromTermID, req.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &LibraryTemplate{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: req.OrganizationID,
+		Name:           name,
+		Description:    req.Description,
+		LatestVersion:  1,
+		CreatedBy:      userID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	version := &LibraryTemplateVersion{
+		ID:         primitive.NewObjectID(),
+		TemplateID: template.ID,
+		Version:    1,
+		Note:       req.Note,
+		Days:       days,
+		CreatedBy:  userID,
+		CreatedAt:  time.Now(),
+	}
+
+	err = s.LibraryTemplateRepository.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.LibraryTemplateRepository.CreateLibraryTemplate(txCtx, template); err != nil {
+			return err
+		}
+		return s.LibraryTemplateRepository.CreateLibraryTemplateVersion(txCtx, version)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create library template: %w", err)
+	}
+
+	return &LibraryTemplateResponse{LibraryTemplate: template, Latest: version}, nil
+}
+
+func (s *templateColorTimeService) GetLibraryTemplates(ctx context.Context, organizationID string) ([]*LibraryTemplate, error) {
+
+	if organizationID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	return s.LibraryTemplateRepository.GetLibraryTemplates(ctx, organizationID)
+}
+
+func (s *templateColorTimeService) GetLibraryTemplate(ctx context.Context, id string) (*LibraryTemplateResponse, error) {
+
+	template, err := s.getLibraryTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.LibraryTemplateRepository.GetLibraryTemplateVersion(ctx, template.ID, template.LatestVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library template version: %w", err)
+	}
+
+	return &LibraryTemplateResponse{LibraryTemplate: template, Latest: latest}, nil
+}
+
+// UpdateLibraryTemplate renames the template or changes its description. The
+// timetable only changes by publishing a version.
+func (s *templateColorTimeService) UpdateLibraryTemplate(ctx context.Context, id string, req *UpdateLibraryTemplateRequest) error {
+
+	template, err := s.getLibraryTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = template.Name
+	}
+
+	description := req.Description
+	if description == "" {
+		description = template.Description
+	}
+
+	if name != template.Name {
+		existing, err := s.LibraryTemplateRepository.GetLibraryTemplateByName(ctx, template.OrganizationID, name)
+		if err != nil {
+			return fmt.Errorf("failed to check library template name: %w", err)
+		}
+		if existing != nil {
+			return fmt.Errorf("library template %q already exists", name)
+		}
+	}
+
+	return s.LibraryTemplateRepository.UpdateLibraryTemplateInfo(ctx, template.ID, name, description)
+}
+
+func (s *templateColorTimeService) GetLibraryTemplateVersions(ctx context.Context, id string) ([]*LibraryTemplateVersion, error) {
+
+	template, err := s.getLibraryTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.LibraryTemplateRepository.GetLibraryTemplateVersions(ctx, template.ID)
+}
+
+func (s *templateColorTimeService) GetLibraryTemplateVersion(ctx context.Context, id string, version int) (*LibraryTemplateVersion, error) {
+
+	template, err := s.getLibraryTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolveLibraryVersion(ctx, template, version)
+}
+
+// PublishLibraryTemplateVersion adds a version after BaseVersion. It fails with
+// a version conflict when another version was published since.
+func (s *templateColorTimeService) PublishLibraryTemplateVersion(ctx context.Context, id string, req *PublishLibraryTemplateVersionRequest, userID string) (*LibraryTemplateVersion, error) {
+
+	template, err := s.getLibraryTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.libraryDays(ctx, template.OrganizationID, req.FromTermID, req.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &LibraryTemplateVersion{
+		ID:         primitive.NewObjectID(),
+		TemplateID: template.ID,
+		Version:    req.BaseVersion + 1,
+		Note:       req.Note,
+		Days:       days,
+		CreatedBy:  userID,
+		CreatedAt:  time.Now(),
+	}
+
+	err = s.LibraryTemplateRepository.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.LibraryTemplateRepository.ClaimLibraryVersion(txCtx, template.ID, req.BaseVersion); err != nil {
+			return err
+		}
+		return s.LibraryTemplateRepository.CreateLibraryTemplateVersion(txCtx, version)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, getErr := s.LibraryTemplateRepository.GetLibraryTemplateByID(ctx, template.ID)
+		if getErr != nil || current == nil {
+			return nil, errors.New("library template not found")
+		}
+		return nil, &helper.VersionConflictError{CurrentVersion: int64(current.LatestVersion)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish library template version: %w", err)
+	}
+
+	return version, nil
+}
+
+func (s *templateColorTimeService) getLibraryTemplate(ctx context.Context, id string) (*LibraryTemplate, error) {
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid library template id format")
+	}
+
+	template, err := s.LibraryTemplateRepository.GetLibraryTemplateByID(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library template: %w", err)
+	}
+
+	if template == nil {
+		return nil, errors.New("library template not found")
+	}
+
+	return template, nil
+}
+
+// resolveLibraryVersion returns the version of template, its latest one when
+// version is 0.
+func (s *templateColorTimeService) resolveLibraryVersion(ctx context.Context, template *LibraryTemplate, version int) (*LibraryTemplateVersion, error) {
+
+	if version == 0 {
+		version = template.LatestVersion
+	}
+
+	templateVersion, err := s.LibraryTemplateRepository.GetLibraryTemplateVersion(ctx, template.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library template version: %w", err)
+	}
+
+	if templateVersion == nil {
+		return nil, fmt.Errorf("version %d of library template %q not found", version, template.Name)
+	}
+
+	return templateVersion, nil
+}
+
+// libraryDays builds the days of a version from the request, or copies the
+// weekday templates of fromTermID keeping their block and slot ids so days
+// already applied from them merge cleanly.
+func (s *templateColorTimeService) libraryDays(ctx context.Context, organizationID, fromTermID string, requests []*LibraryTemplateDayRequest) ([]*LibraryTemplateDay, error) {
+
+	if fromTermID != "" {
+		var days []*LibraryTemplateDay
+		for _, weekday := range templateWeekdays {
+			template, err := s.TemplateColorTimeRepository.GetTemplateColorTime(ctx, organizationID, fromTermID, weekday)
+			if err != nil {
+				return nil, errors.New("failed to get template color time for " + weekday)
+			}
+			if template == nil {
+				continue
+			}
+			days = append(days, &LibraryTemplateDay{
+				Weekday:    weekday,
+				ColorTimes: cloneTemplateBlocks(template.ColorTimes, false),
+			})
+		}
+
+		if len(days) == 0 {
+			return nil, errors.New("term has no weekday templates")
+		}
+		return days, nil
+	}
+
+	if len(requests) == 0 {
+		return nil, errors.New("days or from_term_id is required")
+	}
+
+	seen := make(map[string]bool)
+	days := make([]*LibraryTemplateDay, 0, len(requests))
+
+	for _, req := range requests {
+		weekday := strings.ToLower(strings.TrimSpace(req.Weekday))
+		if weekday != "" && !isTemplateWeekday(weekday) {
+			return nil, fmt.Errorf("invalid weekday %q", req.Weekday)
+		}
+		if seen[weekday] {
+			return nil, fmt.Errorf("weekday %q is given twice", req.Weekday)
+		}
+		seen[weekday] = true
+
+		day, err := buildLibraryDay(weekday, req)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+func buildLibraryDay(weekday string, req *LibraryTemplateDayRequest) (*LibraryTemplateDay, error) {
+
+	label := weekday
+	if label == "" {
+		label = "every day"
+	}
+
+	day := &LibraryTemplateDay{
+		Weekday:    weekday,
+		ColorTimes: []*ColorTimeTemplate{},
+	}
+
+	var allSlots []*ColortimeSlot
+
+	for _, blockReq := range req.Blocks {
+		block := &ColorTimeTemplate{
+			BlockID: primitive.NewObjectID(),
+			Slots:   []*ColortimeSlot{},
+		}
+		if blockReq.BlockID != "" {
+			blockID, err := primitive.ObjectIDFromHex(blockReq.BlockID)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid block id format", label)
+			}
+			block.BlockID = blockID
+		}
+
+		for _, slotReq := range blockReq.Slots {
+			slot, err := buildLibrarySlot(slotReq)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", label, err)
+			}
+
+			if isTimeSlotConflict(slot.StartTime, slot.EndTime, allSlots, nil) {
+				return nil, fmt.Errorf("%s: slot %q conflicts with another slot", label, slot.Title)
+			}
+
+			slot.Sessions = len(block.Slots) + 1
+			block.Slots = append(block.Slots, slot)
+			allSlots = append(allSlots, slot)
+		}
+
+		day.ColorTimes = append(day.ColorTimes, block)
+	}
+
+	return day, nil
+}
+
+func buildLibrarySlot(req *LibraryTemplateSlotRequest) (*ColortimeSlot, error) {
+
+	if req.StartTime == "" {
+		return nil, errors.New("start time is required")
+	}
+
+	if req.Duration <= 0 {
+		return nil, errors.New("duration must be greater than 0")
+	}
+
+	if req.Color == "" {
+		return nil, errors.New("color is required")
+	}
+
+	if len(req.ColorTimeSlotLanguage) == 0 {
+		return nil, errors.New("color time slot language is required")
+	}
+
+	for _, lang := range req.ColorTimeSlotLanguage {
+		if lang.LanguageID == 0 {
+			return nil, errors.New("language id is required")
+		}
+		if lang.Title == "" {
+			return nil, errors.New("title is required")
+		}
+	}
+
+	startTime, err := time.Parse("15:04", req.StartTime)
+	if err != nil {
+		return nil, errors.New("invalid start time format (use HH:MM)")
+	}
+
+	endTime := startTime.Add(time.Duration(req.Duration) * time.Second)
+	if endTime.Day() != startTime.Day() {
+		return nil, errors.New("slot must end on the day it starts")
+	}
+
+	slot := &ColortimeSlot{
+		SlotID:                primitive.NewObjectID(),
+		Title:                 req.Title,
+		ColorTimeSlotLanguage: req.ColorTimeSlotLanguage,
+		StartTime:             startTime,
+		EndTime:               endTime,
+		Duration:              req.Duration,
+		Color:                 req.Color,
+		Note:                  req.Note,
+		CreatedAt:             time.Now(),
+		UpdatedAt:             time.Now(),
+	}
+
+	if req.SlotID != "" {
+		slotID, err := primitive.ObjectIDFromHex(req.SlotID)
+		if err != nil {
+			return nil, errors.New("invalid slot id format")
+		}
+		slot.SlotID = slotID
+	}
+
+	return slot, nil
+}
+
+func isTemplateWeekday(weekday string) bool {
+	for _, day := range templateWeekdays {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneTemplateBlocks deep-copies blocks. With newIDs the copies get fresh
+// block and slot ids, otherwise they keep the originals.
+func cloneTemplateBlocks(blocks []*ColorTimeTemplate, newIDs bool) []*ColorTimeTemplate {
+	cloned := make([]*ColorTimeTemplate, 0, len(blocks))
+
+	for _, block := range blocks {
+		blockCopy := &ColorTimeTemplate{
+			BlockID: block.BlockID,
+			Slots:   make([]*ColortimeSlot, 0, len(block.Slots)),
+		}
+		if newIDs {
+			blockCopy.BlockID = primitive.NewObjectID()
+		}
+
+		for _, slot := range block.Slots {
+			slotCopy := *slot
+			slotCopy.ColorTimeSlotLanguage = make([]*ColorTimeSlotLanguage, 0, len(slot.ColorTimeSlotLanguage))
+			for _, lang := range slot.ColorTimeSlotLanguage {
+				langCopy := *lang
+				slotCopy.ColorTimeSlotLanguage = append(slotCopy.ColorTimeSlotLanguage, &langCopy)
+			}
+			if newIDs {
+				slotCopy.SlotID = primitive.NewObjectID()
+				slotCopy.CreatedAt = time.Now()
+				slotCopy.UpdatedAt = time.Now()
+			}
+			blockCopy.Slots = append(blockCopy.Slots, &slotCopy)
+		}
+
+		cloned = append(cloned, blockCopy)
+	}
+
+	return cloned
+}
+
+// libraryWeekdayTemplates builds, for every weekday the version has a day
+// for, a weekday template that applies it.
+func libraryWeekdayTemplates(organizationID, termID string, version *LibraryTemplateVersion) map[string]*TemplateColorTime {
+	templates := make(map[string]*TemplateColorTime)
+
+	for _, weekday := range templateWeekdays {
+		day := version.day(weekday)
+		if day == nil {
+			continue
+		}
+
+		templates[weekday] = &TemplateColorTime{
+			ID:             version.ID,
+			Date:           weekday,
+			OrganizationID: organizationID,
+			TermID:         termID,
+			ColorTimes:     day.ColorTimes,
+			CreatedBy:      version.CreatedBy,
+			CreatedAt:      version.CreatedAt,
+			UpdatedAt:      version.CreatedAt,
+			libraryVersion: version,
+		}
+	}
+
+	return templates
+}
@@ -0,0 +1,245 @@
+package templatecolortime
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeTemplateRepository keeps weekday templates by term and weekday.
+type fakeTemplateRepository struct {
+	TemplateColorTimeRepository
+	templates map[string]*TemplateColorTime
+	created   []*TemplateColorTime
+	deleted   []primitive.ObjectID
+}
+
+func newFakeTemplateRepository(templates ...*TemplateColorTime) *fakeTemplateRepository {
+	r := &fakeTemplateRepository{templates: make(map[string]*TemplateColorTime)}
+	for _, template := range templates {
+		r.templates[template.TermID+"/"+template.Date] = template
+	}
+	return r
+}
+
+func (r *fakeTemplateRepository) GetTemplateColorTime(ctx context.Context, organizationID, termID, date string) (*TemplateColorTime, error) {
+	return r.templates[termID+"/"+date], nil
+}
+
+func (r *fakeTemplateRepository) CreateTemplateColorTime(ctx context.Context, template *TemplateColorTime) error {
+	r.created = append(r.created, template)
+	return nil
+}
+
+func (r *fakeTemplateRepository) DeleteTemplateColorTime(ctx context.Context, id primitive.ObjectID) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+func (r *fakeTemplateRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// weekdayTemplate builds the template of a term for weekday with one slot.
+func weekdayTemplate(termID, weekday, title, span string) *TemplateColorTime {
+	slot := templateSlot(title, span, clock("00:00"))
+	slot.ColorTimeSlotLanguage = []*ColorTimeSlotLanguage{{LanguageID: 1, Title: title}}
+	return &TemplateColorTime{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: "org-1",
+		TermID:         termID,
+		Date:           weekday,
+		ColorTimes:     []*ColorTimeTemplate{{BlockID: primitive.NewObjectID(), Slots: []*ColortimeSlot{slot}}},
+	}
+}
+
+func librarySlotRequest(start string, duration int) *LibraryTemplateSlotRequest {
+	return &LibraryTemplateSlotRequest{
+		Title:                 "Circle",
+		StartTime:             start,
+		Duration:              duration,
+		Color:                 "#ffcc00",
+		ColorTimeSlotLanguage: []*ColorTimeSlotLanguage{{LanguageID: 1, Title: "Circle"}},
+	}
+}
+
+func libraryDayRequest(weekday string, slots ...*LibraryTemplateSlotRequest) *LibraryTemplateDayRequest {
+	return &LibraryTemplateDayRequest{
+		Weekday: weekday,
+		Blocks:  []*LibraryTemplateBlockRequest{{Slots: slots}},
+	}
+}
+
+func TestLibraryDaysFromTerm(t *testing.T) {
+	monday := weekdayTemplate("term-1", "monday", "Circle", "08:00-08:30")
+	friday := weekdayTemplate("term-1", "friday", "Music", "14:00-14:45")
+	s := &templateColorTimeService{TemplateColorTimeRepository: newFakeTemplateRepository(monday, friday)}
+
+	days, err := s.libraryDays(context.Background(), "org-1", "term-1", nil)
+	if err != nil {
+		t.Fatalf("libraryDays: %v", err)
+	}
+
+	if len(days) != 2 || days[0].Weekday != "monday" || days[1].Weekday != "friday" {
+		t.Fatalf("days = %+v, want monday and friday", days)
+	}
+
+	// Ids are kept so days applied from the term merge cleanly
+	got := days[0].ColorTimes[0]
+	if got.BlockID != monday.ColorTimes[0].BlockID || got.Slots[0].SlotID != monday.ColorTimes[0].Slots[0].SlotID {
+		t.Fatal("copied day has new block or slot ids")
+	}
+
+	// but the version never shares slots with the term
+	got.Slots[0].Title = "changed"
+	got.Slots[0].ColorTimeSlotLanguage[0].Title = "changed"
+	if monday.ColorTimes[0].Slots[0].Title != "Circle" || monday.ColorTimes[0].Slots[0].ColorTimeSlotLanguage[0].Title != "Circle" {
+		t.Fatal("editing the version changed the term's template")
+	}
+
+	if _, err := s.libraryDays(context.Background(), "org-1", "term-2", nil); err == nil {
+		t.Fatal("libraryDays succeeded from a term without templates")
+	}
+}
+
+func TestLibraryDaysFromRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []*LibraryTemplateDayRequest
+		weekdays []string
+		wantErr  bool
+	}{
+		{
+			name:     "weekday and every-day fallback",
+			requests: []*LibraryTemplateDayRequest{libraryDayRequest(" Monday "), libraryDayRequest("", librarySlotRequest("08:00", 1800))},
+			weekdays: []string{"monday", ""},
+		},
+		{name: "no days", wantErr: true},
+		{name: "unknown weekday", requests: []*LibraryTemplateDayRequest{libraryDayRequest("funday")}, wantErr: true},
+		{name: "weekday given twice", requests: []*LibraryTemplateDayRequest{libraryDayRequest("monday"), libraryDayRequest("MONDAY")}, wantErr: true},
+		{
+			name:     "overlapping slots",
+			requests: []*LibraryTemplateDayRequest{libraryDayRequest("monday", librarySlotRequest("08:00", 3600), librarySlotRequest("08:30", 1800))},
+			wantErr:  true,
+		},
+		{
+			name:     "slot past midnight",
+			requests: []*LibraryTemplateDayRequest{libraryDayRequest("monday", librarySlotRequest("23:30", 3600))},
+			wantErr:  true,
+		},
+	}
+
+	s := &templateColorTimeService{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, err := s.libraryDays(context.Background(), "org-1", "", tt.requests)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("libraryDays = %d days, want an error", len(days))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("libraryDays: %v", err)
+			}
+
+			var weekdays []string
+			for _, day := range days {
+				weekdays = append(weekdays, day.Weekday)
+			}
+			if !reflect.DeepEqual(weekdays, tt.weekdays) {
+				t.Fatalf("weekdays = %q, want %q", weekdays, tt.weekdays)
+			}
+		})
+	}
+}
+
+func TestBuildLibrarySlot(t *testing.T) {
+	slot, err := buildLibrarySlot(librarySlotRequest("08:15", 2700))
+	if err != nil {
+		t.Fatalf("buildLibrarySlot: %v", err)
+	}
+	if slot.StartTime != clock("08:15") || slot.EndTime != clock("09:00") {
+		t.Fatalf("slot runs %s-%s, want 08:15-09:00", slot.StartTime.Format("15:04"), slot.EndTime.Format("15:04"))
+	}
+
+	slotID := primitive.NewObjectID()
+	req := librarySlotRequest("08:15", 2700)
+	req.SlotID = slotID.Hex()
+	if slot, err := buildLibrarySlot(req); err != nil || slot.SlotID != slotID {
+		t.Fatalf("slot with a given id = %v, %v", slot, err)
+	}
+
+	invalid := map[string]func(req *LibraryTemplateSlotRequest){
+		"no start":    func(req *LibraryTemplateSlotRequest) { req.StartTime = "" },
+		"bad start":   func(req *LibraryTemplateSlotRequest) { req.StartTime = "8am" },
+		"no duration": func(req *LibraryTemplateSlotRequest) { req.Duration = 0 },
+		"no color":    func(req *LibraryTemplateSlotRequest) { req.Color = "" },
+		"no language": func(req *LibraryTemplateSlotRequest) { req.ColorTimeSlotLanguage = nil },
+		"language without id": func(req *LibraryTemplateSlotRequest) {
+			req.ColorTimeSlotLanguage = []*ColorTimeSlotLanguage{{Title: "Circle"}}
+		},
+		"bad slot id": func(req *LibraryTemplateSlotRequest) { req.SlotID = "slot-1" },
+	}
+
+	for name, change := range invalid {
+		t.Run(name, func(t *testing.T) {
+			req := librarySlotRequest("08:15", 2700)
+			change(req)
+			if _, err := buildLibrarySlot(req); err == nil {
+				t.Fatal("buildLibrarySlot succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestLibraryWeekdayTemplates(t *testing.T) {
+	monday := &LibraryTemplateDay{Weekday: "monday", ColorTimes: weekdayTemplate("", "", "Circle", "08:00-08:30").ColorTimes}
+	everyDay := &LibraryTemplateDay{ColorTimes: weekdayTemplate("", "", "Garden", "10:00-10:30").ColorTimes}
+
+	version := &LibraryTemplateVersion{
+		ID:         primitive.NewObjectID(),
+		TemplateID: primitive.NewObjectID(),
+		Version:    3,
+		Days:       []*LibraryTemplateDay{everyDay, monday},
+	}
+
+	templates := libraryWeekdayTemplates("org-1", "term-1", version)
+	if len(templates) != len(templateWeekdays) {
+		t.Fatalf("got templates for %d weekdays, want every weekday from the fallback", len(templates))
+	}
+	if templates["monday"].ColorTimes[0].Slots[0].Title != "Circle" || templates["tuesday"].ColorTimes[0].Slots[0].Title != "Garden" {
+		t.Fatal("weekday templates do not prefer the weekday's own day over the fallback")
+	}
+
+	// Days applied from it point back to the version
+	source := templateSource(templates["monday"], clock("00:00"))
+	if source.TemplateID != version.ID || *source.LibraryTemplateID != version.TemplateID || source.LibraryVersion != 3 || source.TermID != "term-1" {
+		t.Fatalf("source = %+v, want version 3 of the library template", source)
+	}
+
+	withoutFallback := &LibraryTemplateVersion{Days: []*LibraryTemplateDay{monday}}
+	if templates := libraryWeekdayTemplates("org-1", "term-1", withoutFallback); len(templates) != 1 || templates["monday"] == nil {
+		t.Fatalf("got templates for %d weekdays, want monday only", len(templates))
+	}
+}
+
+func TestCloneTemplateBlocks(t *testing.T) {
+	blocks := weekdayTemplate("term-1", "monday", "Circle", "08:00-08:30").ColorTimes
+	original := blocks[0].Slots[0]
+
+	for _, newIDs := range []bool{false, true} {
+		cloned := cloneTemplateBlocks(blocks, newIDs)
+		slot := cloned[0].Slots[0]
+
+		if sameIDs := cloned[0].BlockID == blocks[0].BlockID && slot.SlotID == original.SlotID; sameIDs == newIDs {
+			t.Fatalf("newIDs %v: block and slot ids kept = %v", newIDs, sameIDs)
+		}
+		if slot == original || slot.ColorTimeSlotLanguage[0] == original.ColorTimeSlotLanguage[0] {
+			t.Fatalf("newIDs %v: clone shares slots or languages with the original", newIDs)
+		}
+	}
+}
@@ -15,6 +15,11 @@ type TemplateColorTime struct {
 	CreatedBy      string               `bson:"created_by" json:"created_by"`
 	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+	Version        int64                `bson:"version" json:"version"`
+
+	// Set on the weekday templates built from a library template version to
+	// apply it, never stored.
+	libraryVersion *LibraryTemplateVersion
 }
 
 type ColorTimeTemplate struct {
@@ -36,8 +41,111 @@ type ColortimeSlot struct {
 	UpdatedAt             time.Time                `json:"updated_at" bson:"updated_at"`
 }
 
+func (s *ColortimeSlot) ID() primitive.ObjectID { return s.SlotID }
+
+func (s *ColortimeSlot) Span() (time.Time, time.Time) { return s.StartTime, s.EndTime }
+
+func (s *ColortimeSlot) Reschedule(start, end time.Time) {
+	s.StartTime, s.EndTime = start, end
+	s.UpdatedAt = time.Now()
+}
+
+func (s *ColortimeSlot) SetSessions(sessions int) { s.Sessions = sessions }
+
 type ColorTimeSlotLanguage struct {
 	LanguageID int    `json:"language_id" bson:"language_id"`
 	Title      string `json:"title" bson:"title"`
 }
 
+const (
+	ApplyJobStatusPending     = "pending"
+	ApplyJobStatusRunning     = "running"
+	ApplyJobStatusCompleted   = "completed"
+	ApplyJobStatusFailed      = "failed"
+	ApplyJobStatusRollingBack = "rolling_back"
+	ApplyJobStatusRolledBack  = "rolled_back"
+)
+
+// ApplyJob tracks the application of a term's templates to a range of default
+// days. Days are written in batches, each batch together with its results, so
+// an interrupted job resumes after the last batch it recorded.
+type ApplyJob struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	TermID         string             `bson:"term_id" json:"term_id"`
+	StartDate      time.Time          `bson:"start_date" json:"start_date"`
+	EndDate        time.Time          `bson:"end_date" json:"end_date"`
+	Strategy       string             `bson:"strategy" json:"strategy"`
+	Status         string             `bson:"status" json:"status"`
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"` // why the whole job stopped
+	TotalDays      int                `bson:"total_days" json:"total_days"`
+	ProcessedDays  int                `bson:"processed_days" json:"processed_days"`
+	FailedDays     int                `bson:"failed_days" json:"failed_days"`
+	Summary        *ApplySummary      `bson:"summary" json:"summary"`
+	Days           []*ApplyDayResult  `bson:"days" json:"days"`
+	CreatedBy      string             `bson:"created_by" json:"created_by"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	StartedAt      *time.Time         `bson:"started_at" json:"started_at"`
+	FinishedAt     *time.Time         `bson:"finished_at" json:"finished_at"`
+	RolledBackAt   *time.Time         `bson:"rolled_back_at" json:"rolled_back_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Worker running the job, until its lease runs out
+	ClaimedBy  string     `bson:"claimed_by,omitempty" json:"-"`
+	LeaseUntil *time.Time `bson:"lease_until,omitempty" json:"-"`
+
+	// Dates asked for when they were clamped to the term
+	RequestedRange *ApplyDateRange `bson:"requested_range,omitempty" json:"requested_range,omitempty"`
+
+	// Library template version applied instead of the term's weekday templates
+	LibraryTemplateID *primitive.ObjectID `bson:"library_template_id,omitempty" json:"library_template_id,omitempty"`
+	LibraryVersion    int                 `bson:"library_version,omitempty" json:"library_version,omitempty"`
+}
+
+// LibraryTemplate is a named template of an organization, such as "Normal
+// week", "Exam week" or "Half day". Its timetable lives in immutable versions:
+// editing it publishes a new version.
+type LibraryTemplate struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	Name           string             `bson:"name" json:"name"`
+	Description    string             `bson:"description" json:"description"`
+	LatestVersion  int                `bson:"latest_version" json:"latest_version"`
+	CreatedBy      string             `bson:"created_by" json:"created_by"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// LibraryTemplateVersion is one version of a library template. Versions are
+// never changed once published.
+type LibraryTemplateVersion struct {
+	ID         primitive.ObjectID    `bson:"_id" json:"id"`
+	TemplateID primitive.ObjectID    `bson:"template_id" json:"template_id"`
+	Version    int                   `bson:"version" json:"version"`
+	Note       string                `bson:"note" json:"note"`
+	Days       []*LibraryTemplateDay `bson:"days" json:"days,omitempty"`
+	CreatedBy  string                `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time             `bson:"created_at" json:"created_at"`
+}
+
+// LibraryTemplateDay is the timetable of one weekday in a version. A day with
+// an empty Weekday is used for every weekday that has no day of its own.
+type LibraryTemplateDay struct {
+	Weekday    string               `bson:"weekday" json:"weekday"`
+	ColorTimes []*ColorTimeTemplate `bson:"color_times" json:"color_times"`
+}
+
+// day returns the timetable of weekday (lowercase), nil when the version has
+// none.
+func (v *LibraryTemplateVersion) day(weekday string) *LibraryTemplateDay {
+	var fallback *LibraryTemplateDay
+	for _, day := range v.Days {
+		if day.Weekday == weekday {
+			return day
+		}
+		if day.Weekday == "" {
+			fallback = day
+		}
+	}
+	return fallback
+}
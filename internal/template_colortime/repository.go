@@ -1,11 +1,15 @@
 package templatecolortime
 
 import (
+	"colortime-service/helper"
 	"context"
+	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TemplateColorTimeRepository interface {
@@ -14,6 +18,12 @@ type TemplateColorTimeRepository interface {
 	GetTemplateColorTimeByID(ctx context.Context, id primitive.ObjectID) (*TemplateColorTime, error)
 	UpdateTemplateColorTime(ctx context.Context, id primitive.ObjectID, colortimeTemplate *TemplateColorTime) error
 	DeleteTemplateColorTime(ctx context.Context, id primitive.ObjectID) error
+
+	UpdateTemplateSlotFields(ctx context.Context, templateID, slotID primitive.ObjectID, version int64, fields bson.M) error
+	RemoveTemplateSlot(ctx context.Context, templateID, slotID primitive.ObjectID, version int64) error
+	RemoveTemplateBlock(ctx context.Context, templateID, blockID primitive.ObjectID, version int64) error
+
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type templateColorTimeRepository struct {
@@ -63,12 +73,478 @@ func (r *templateColorTimeRepository) GetTemplateColorTimeByID(ctx context.Conte
 	return &colortimeTemplate, nil
 }
 
+// UpdateTemplateColorTime only writes when the stored document is still at the version
+// that was read, and bumps the version on success.
 func (r *templateColorTimeRepository) UpdateTemplateColorTime(ctx context.Context, id primitive.ObjectID, colortimeTemplate *TemplateColorTime) error {
-	_, err := r.TemplateColorTimeCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": colortimeTemplate})
-	return err
+	readVersion := colortimeTemplate.Version
+	colortimeTemplate.Version = readVersion + 1
+
+	result, err := r.TemplateColorTimeCollection.UpdateOne(ctx, helper.VersionFilter(id, readVersion), bson.M{"$set": colortimeTemplate})
+	if err != nil {
+		colortimeTemplate.Version = readVersion
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		colortimeTemplate.Version = readVersion
+		return r.versionConflict(ctx, id)
+	}
+
+	return nil
+}
+
+func (r *templateColorTimeRepository) versionConflict(ctx context.Context, id primitive.ObjectID) error {
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+
+	if err := r.TemplateColorTimeCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&current); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("document not found")
+		}
+		return err
+	}
+
+	return &helper.VersionConflictError{CurrentVersion: current.Version}
+}
+
+// UpdateTemplateSlotFields sets the given slot fields (bson names) on one slot
+// of the template without rewriting the rest of the document, while the
+// template is still at version.
+func (r *templateColorTimeRepository) UpdateTemplateSlotFields(ctx context.Context, templateID, slotID primitive.ObjectID, version int64, fields bson.M) error {
+	filter := bson.M{"_id": templateID, "color_times.slots.slot_id": slotID}
+	update := bson.M{"$set": helper.PrefixFields("color_times.$[].slots.$[slot].", fields)}
+
+	return helper.PatchDocument(ctx, r.TemplateColorTimeCollection, filter, version, update, bson.M{"slot.slot_id": slotID})
+}
+
+func (r *templateColorTimeRepository) RemoveTemplateSlot(ctx context.Context, templateID, slotID primitive.ObjectID, version int64) error {
+	filter := bson.M{"_id": templateID, "color_times.slots.slot_id": slotID}
+	update := bson.M{"$pull": bson.M{"color_times.$[].slots": bson.M{"slot_id": slotID}}}
+
+	return helper.PatchDocument(ctx, r.TemplateColorTimeCollection, filter, version, update)
+}
+
+func (r *templateColorTimeRepository) RemoveTemplateBlock(ctx context.Context, templateID, blockID primitive.ObjectID, version int64) error {
+	filter := bson.M{"_id": templateID, "color_times.block_id": blockID}
+	update := bson.M{"$pull": bson.M{"color_times": bson.M{"block_id": blockID}}}
+
+	return helper.PatchDocument(ctx, r.TemplateColorTimeCollection, filter, version, update)
 }
 
 func (r *templateColorTimeRepository) DeleteTemplateColorTime(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.TemplateColorTimeCollection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+// WithTransaction runs fn in a transaction; see helper.RunInTransaction.
+func (r *templateColorTimeRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return helper.RunInTransaction(ctx, r.TemplateColorTimeCollection.Database().Client(), fn)
+}
+
+type ApplyJobRepository interface {
+	CreateApplyJob(ctx context.Context, job *ApplyJob) error
+	GetApplyJobByID(ctx context.Context, id primitive.ObjectID) (*ApplyJob, error)
+	GetApplyJobs(ctx context.Context, organizationID string, limit int64) ([]*ApplyJob, error)
+	GetUnfinishedApplyJobs(ctx context.Context) ([]*ApplyJob, error)
+	GetOverlappingApplyJobs(ctx context.Context, organizationID, termID string, startDate, endDate time.Time) ([]*ApplyJob, error)
+	ClaimApplyJob(ctx context.Context, id primitive.ObjectID, workerID string, lease time.Duration) (*ApplyJob, error)
+	RenewApplyJobLease(ctx context.Context, id primitive.ObjectID, workerID string, lease time.Duration) error
+	RecordApplyBatch(ctx context.Context, id primitive.ObjectID, processedDays int, days []*ApplyDayResult, summary *ApplySummary) error
+	FinishApplyJob(ctx context.Context, id primitive.ObjectID, status, errMsg string) error
+	StartApplyRollback(ctx context.Context, id primitive.ObjectID) error
+	RecordDayRollback(ctx context.Context, id primitive.ObjectID, date, status, errMsg string) error
+	FinishApplyRollback(ctx context.Context, id primitive.ObjectID) error
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type applyJobRepository struct {
+	ApplyJobCollection *mongo.Collection
+}
+
+func NewApplyJobRepository(applyJobCollection *mongo.Collection) ApplyJobRepository {
+	return &applyJobRepository{
+		ApplyJobCollection: applyJobCollection,
+	}
+}
+
+func (r *applyJobRepository) CreateApplyJob(ctx context.Context, job *ApplyJob) error {
+	_, err := r.ApplyJobCollection.InsertOne(ctx, job)
+	return err
+}
+
+func (r *applyJobRepository) GetApplyJobByID(ctx context.Context, id primitive.ObjectID) (*ApplyJob, error) {
+
+	var job ApplyJob
+
+	if err := r.ApplyJobCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (r *applyJobRepository) GetApplyJobs(ctx context.Context, organizationID string, limit int64) ([]*ApplyJob, error) {
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"days": 0})
+
+	return r.findApplyJobs(ctx, bson.M{"organization_id": organizationID}, opts)
+}
+
+func (r *applyJobRepository) GetUnfinishedApplyJobs(ctx context.Context) ([]*ApplyJob, error) {
+
+	filter := bson.M{
+		"status": bson.M{"$in": []string{ApplyJobStatusPending, ApplyJobStatusRunning, ApplyJobStatusRollingBack}},
+	}
+
+	return r.findApplyJobs(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+// GetOverlappingApplyJobs returns the jobs that applied another term than
+// termID to dates between startDate and endDate and were not rolled back.
+// Jobs that have not applied a day, pending or failed on every day, are left
+// out.
+func (r *applyJobRepository) GetOverlappingApplyJobs(ctx context.Context, organizationID, termID string, startDate, endDate time.Time) ([]*ApplyJob, error) {
+	filter := bson.M{
+		"organization_id": organizationID,
+		"term_id":         bson.M{"$ne": termID},
+		"status":          bson.M{"$ne": ApplyJobStatusRolledBack},
+		"days.status":     ApplyStatusApplied,
+		"start_date":      bson.M{"$lte": endDate},
+		"end_date":        bson.M{"$gte": startDate},
+	}
+	opts := options.Find().SetProjection(bson.M{"days": 0}).SetSort(bson.M{"start_date": 1})
+	return r.findApplyJobs(ctx, filter, opts)
+}
+
+func (r *applyJobRepository) findApplyJobs(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*ApplyJob, error) {
+
+	cursor, err := r.ApplyJobCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*ApplyJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ClaimApplyJob gives workerID the job for lease, moving a pending job to
+// running. A running or rolling back job can only be claimed once the lease of
+// its worker has run out. It returns nil when the job is finished or another
+// worker holds it.
+func (r *applyJobRepository) ClaimApplyJob(ctx context.Context, id primitive.ObjectID, workerID string, lease time.Duration) (*ApplyJob, error) {
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": id,
+		"$or": []bson.M{
+			{"status": ApplyJobStatusPending},
+			{
+				"status": bson.M{"$in": []string{ApplyJobStatusRunning, ApplyJobStatusRollingBack}},
+				"$or": []bson.M{
+					{"lease_until": bson.M{"$exists": false}},
+					{"lease_until": bson.M{"$lt": now}},
+					{"claimed_by": workerID},
+				},
+			},
+		},
+	}
+
+	// A pipeline, so only a pending job changes status
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"status": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$status", ApplyJobStatusPending}}, ApplyJobStatusRunning, "$status",
+		}},
+		"started_at":  bson.M{"$ifNull": bson.A{"$started_at", now}},
+		"claimed_by":  workerID,
+		"lease_until": now.Add(lease),
+		"updated_at":  now,
+	}}}}
+
+	var job ApplyJob
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := r.ApplyJobCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// RenewApplyJobLease extends the lease of workerID on the job. It returns
+// mongo.ErrNoDocuments when the worker no longer holds the job.
+func (r *applyJobRepository) RenewApplyJobLease(ctx context.Context, id primitive.ObjectID, workerID string, lease time.Duration) error {
+
+	now := time.Now()
+	result, err := r.ApplyJobCollection.UpdateOne(ctx, bson.M{"_id": id, "claimed_by": workerID}, bson.M{"$set": bson.M{
+		"lease_until": now.Add(lease),
+		"updated_at":  now,
+	}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RecordApplyBatch appends the results of a batch of processedDays dates to
+// the job. Days without a template have no result but count as processed.
+func (r *applyJobRepository) RecordApplyBatch(ctx context.Context, id primitive.ObjectID, processedDays int, days []*ApplyDayResult, summary *ApplySummary) error {
+
+	update := bson.M{
+		"$push": bson.M{"days": bson.M{"$each": days}},
+		"$inc": bson.M{
+			"processed_days":    processedDays,
+			"failed_days":       summary.Failed,
+			"summary.created":   summary.Created,
+			"summary.replaced":  summary.Replaced,
+			"summary.merged":    summary.Merged,
+			"summary.unchanged": summary.Unchanged,
+			"summary.closed":    summary.Closed,
+			"summary.failed":    summary.Failed,
+		},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	_, err := r.ApplyJobCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *applyJobRepository) FinishApplyJob(ctx context.Context, id primitive.ObjectID, status, errMsg string) error {
+	now := time.Now()
+	_, err := r.ApplyJobCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":      status,
+		"error":       errMsg,
+		"finished_at": now,
+		"updated_at":  now,
+	}, "$unset": bson.M{"claimed_by": "", "lease_until": ""}})
+	return err
+}
+
+// StartApplyRollback moves a finished job to rolling back. It returns
+// mongo.ErrNoDocuments when the job is missing or not finished.
+func (r *applyJobRepository) StartApplyRollback(ctx context.Context, id primitive.ObjectID) error {
+
+	filter := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": []string{ApplyJobStatusCompleted, ApplyJobStatusFailed}},
+	}
+
+	result, err := r.ApplyJobCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"status":     ApplyJobStatusRollingBack,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *applyJobRepository) RecordDayRollback(ctx context.Context, id primitive.ObjectID, date, status, errMsg string) error {
+
+	update := bson.M{"$set": bson.M{
+		"days.$[day].rollback_status": status,
+		"days.$[day].rollback_error":  errMsg,
+		"updated_at":                  time.Now(),
+	}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"day.date": date}}})
+
+	_, err := r.ApplyJobCollection.UpdateOne(ctx, bson.M{"_id": id}, update, opts)
+	return err
+}
+
+func (r *applyJobRepository) FinishApplyRollback(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	_, err := r.ApplyJobCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":         ApplyJobStatusRolledBack,
+		"rolled_back_at": now,
+		"updated_at":     now,
+	}, "$unset": bson.M{"claimed_by": "", "lease_until": ""}})
+	return err
+}
+
+// WithTransaction runs fn in a transaction. A day's write and the result that
+// rollback restores from must not be recorded apart, so there is no fallback
+// for standalone servers; see helper.RunInRequiredTransaction.
+func (r *applyJobRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return helper.RunInRequiredTransaction(ctx, r.ApplyJobCollection.Database().Client(), fn)
+}
+
+type LibraryTemplateRepository interface {
+	CreateLibraryTemplate(ctx context.Context, template *LibraryTemplate) error
+	GetLibraryTemplateByID(ctx context.Context, id primitive.ObjectID) (*LibraryTemplate, error)
+	GetLibraryTemplateByName(ctx context.Context, organizationID, name string) (*LibraryTemplate, error)
+	GetLibraryTemplates(ctx context.Context, organizationID string) ([]*LibraryTemplate, error)
+	UpdateLibraryTemplateInfo(ctx context.Context, id primitive.ObjectID, name, description string) error
+	ClaimLibraryVersion(ctx context.Context, id primitive.ObjectID, baseVersion int) error
+
+	CreateLibraryTemplateVersion(ctx context.Context, version *LibraryTemplateVersion) error
+	GetLibraryTemplateVersion(ctx context.Context, templateID primitive.ObjectID, version int) (*LibraryTemplateVersion, error)
+	GetLibraryTemplateVersions(ctx context.Context, templateID primitive.ObjectID) ([]*LibraryTemplateVersion, error)
+
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type libraryTemplateRepository struct {
+	LibraryTemplateCollection        *mongo.Collection
+	LibraryTemplateVersionCollection *mongo.Collection
+}
+
+func NewLibraryTemplateRepository(libraryTemplateCollection, libraryTemplateVersionCollection *mongo.Collection) LibraryTemplateRepository {
+	return &libraryTemplateRepository{
+		LibraryTemplateCollection:        libraryTemplateCollection,
+		LibraryTemplateVersionCollection: libraryTemplateVersionCollection,
+	}
+}
+
+func (r *libraryTemplateRepository) CreateLibraryTemplate(ctx context.Context, template *LibraryTemplate) error {
+	_, err := r.LibraryTemplateCollection.InsertOne(ctx, template)
+	return err
+}
+
+func (r *libraryTemplateRepository) GetLibraryTemplateByID(ctx context.Context, id primitive.ObjectID) (*LibraryTemplate, error) {
+	return r.findLibraryTemplate(ctx, bson.M{"_id": id})
+}
+
+func (r *libraryTemplateRepository) GetLibraryTemplateByName(ctx context.Context, organizationID, name string) (*LibraryTemplate, error) {
+	return r.findLibraryTemplate(ctx, bson.M{"organization_id": organizationID, "name": name})
+}
+
+func (r *libraryTemplateRepository) findLibraryTemplate(ctx context.Context, filter bson.M) (*LibraryTemplate, error) {
+
+	var template LibraryTemplate
+
+	if err := r.LibraryTemplateCollection.FindOne(ctx, filter).Decode(&template); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+func (r *libraryTemplateRepository) GetLibraryTemplates(ctx context.Context, organizationID string) ([]*LibraryTemplate, error) {
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.LibraryTemplateCollection.Find(ctx, bson.M{"organization_id": organizationID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var templates []*LibraryTemplate
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (r *libraryTemplateRepository) UpdateLibraryTemplateInfo(ctx context.Context, id primitive.ObjectID, name, description string) error {
+
+	result, err := r.LibraryTemplateCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"name":        name,
+		"description": description,
+		"updated_at":  time.Now(),
+	}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// ClaimLibraryVersion moves the latest version of the template from
+// baseVersion to the next one. It returns mongo.ErrNoDocuments when another
+// version was published since baseVersion.
+func (r *libraryTemplateRepository) ClaimLibraryVersion(ctx context.Context, id primitive.ObjectID, baseVersion int) error {
+
+	result, err := r.LibraryTemplateCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "latest_version": baseVersion},
+		bson.M{"$inc": bson.M{"latest_version": 1}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *libraryTemplateRepository) CreateLibraryTemplateVersion(ctx context.Context, version *LibraryTemplateVersion) error {
+	_, err := r.LibraryTemplateVersionCollection.InsertOne(ctx, version)
+	return err
+}
+
+func (r *libraryTemplateRepository) GetLibraryTemplateVersion(ctx context.Context, templateID primitive.ObjectID, version int) (*LibraryTemplateVersion, error) {
+
+	var templateVersion LibraryTemplateVersion
+
+	filter := bson.M{"template_id": templateID, "version": version}
+	if err := r.LibraryTemplateVersionCollection.FindOne(ctx, filter).Decode(&templateVersion); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &templateVersion, nil
+}
+
+// GetLibraryTemplateVersions lists the versions of the template, newest first
+// and without their days.
+func (r *libraryTemplateRepository) GetLibraryTemplateVersions(ctx context.Context, templateID primitive.ObjectID) ([]*LibraryTemplateVersion, error) {
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"days": 0})
+
+	cursor, err := r.LibraryTemplateVersionCollection.Find(ctx, bson.M{"template_id": templateID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var versions []*LibraryTemplateVersion
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// WithTransaction runs fn in a transaction; see helper.RunInTransaction.
+func (r *libraryTemplateRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return helper.RunInTransaction(ctx, r.LibraryTemplateCollection.Database().Client(), fn)
+}
@@ -20,11 +20,45 @@ type DuplicateTemplateColorTimeRequest struct {
 	TargetDate     string `json:"target_date"`
 }
 
+// Apply strategies for days that already have slots.
+const (
+	// ApplyStrategyReplace overwrites the slots of the day with the template.
+	ApplyStrategyReplace = "replace"
+	// ApplyStrategyMerge updates the slots that came from the template, adds
+	// the new ones and keeps the slots added to the day by hand.
+	ApplyStrategyMerge = "merge"
+)
+
 type ApplyTemplateColorTimeRequest struct {
 	OrganizationID string `json:"organization_id" binding:"required"`
 	TermID         string `json:"term_id" binding:"required"`
 	StartDate      string `json:"start_date" binding:"required"`
 	EndDate        string `json:"end_date" binding:"required"`
+	Strategy       string `json:"strategy"` // "replace" (default) or "merge"
+	DryRun         bool   `json:"dry_run"`  // only report what would change
+
+	// Library template to apply instead of the term's weekday templates, at
+	// LibraryVersion or its latest version when 0.
+	LibraryTemplateID string `json:"library_template_id"`
+	LibraryVersion    int    `json:"library_version"`
+}
+
+// RolloverTemplateColorTimeRequest copies the weekday templates of
+// SourceTermID into TargetTermID. Templates the target term already has are
+// only replaced with Overwrite. With Apply the copies are applied to the
+// whole target term.
+type RolloverTemplateColorTimeRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+	SourceTermID   string `json:"source_term_id" binding:"required"`
+	TargetTermID   string `json:"target_term_id" binding:"required"`
+	Overwrite      bool   `json:"overwrite"`
+	Apply          bool   `json:"apply"`
+	Strategy       string `json:"strategy"` // for Apply, "replace" (default) or "merge"
+}
+
+type ReapplyTemplateColorTimeRequest struct {
+	FromDate string `json:"from_date"` // only days from this date (YYYY-MM-DD), all when empty
+	DryRun   bool   `json:"dry_run"`
 }
 
 type UpdateTemplateColorTimeSlotRequest struct {
@@ -46,3 +80,60 @@ type CopySlotToTemplateColorTimeRequest struct {
 	TargetDate     string  `json:"target_date" binding:"required"`
 	BaseHour       *int    `json:"base_hour"`
 }
+
+type CreateLibraryTemplateRequest struct {
+	OrganizationID string                       `json:"organization_id" binding:"required"`
+	Name           string                       `json:"name" binding:"required"`
+	Description    string                       `json:"description"`
+	Note           string                       `json:"note"`
+	Days           []*LibraryTemplateDayRequest `json:"days"`
+	FromTermID     string                       `json:"from_term_id"` // copy the term's weekday templates instead of Days
+}
+
+type UpdateLibraryTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type PublishLibraryTemplateVersionRequest struct {
+	BaseVersion int                          `json:"base_version" binding:"required"` // latest version the edit started from
+	Note        string                       `json:"note"`
+	Days        []*LibraryTemplateDayRequest `json:"days"`
+	FromTermID  string                       `json:"from_term_id"` // copy the term's weekday templates instead of Days
+}
+
+type LibraryTemplateDayRequest struct {
+	Weekday string                         `json:"weekday"` // monday ... sunday, empty for every weekday
+	Blocks  []*LibraryTemplateBlockRequest `json:"blocks"`
+}
+
+type LibraryTemplateBlockRequest struct {
+	BlockID string                        `json:"block_id"` // kept from an earlier version, new when empty
+	Slots   []*LibraryTemplateSlotRequest `json:"slots"`
+}
+
+type LibraryTemplateSlotRequest struct {
+	SlotID                string                   `json:"slot_id"` // kept from an earlier version, new when empty
+	StartTime             string                   `json:"start_time"`
+	Duration              int                      `json:"duration"`
+	Title                 string                   `json:"title"`
+	ColorTimeSlotLanguage []*ColorTimeSlotLanguage `json:"color_time_slot_language"`
+	Color                 string                   `json:"color"`
+	Note                  string                   `json:"note"`
+}
+
+type MoveBlockRequest struct {
+	StartTime string `json:"start_time" binding:"required"` // new start of the block, "HH:MM"
+}
+
+type ReorderBlockSlotsRequest struct {
+	SlotIDs []string `json:"slot_ids" binding:"required"` // every slot of the block in the new order
+}
+
+type SplitBlockRequest struct {
+	SlotID string `json:"slot_id" binding:"required"` // first slot of the new block
+}
+
+type MergeBlocksRequest struct {
+	BlockID string `json:"block_id" binding:"required"` // block merged into the block of the path and removed
+}
@@ -1,6 +1,7 @@
 package templatecolortime
 
 import (
+	"colortime-service/internal/default_colortime"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,3 +18,125 @@ type TemplateColorTimeResponse struct {
 	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
 }
+
+// Apply actions per day.
+const (
+	ApplyActionCreate    = "create"
+	ApplyActionReplace   = "replace"
+	ApplyActionMerge     = "merge"
+	ApplyActionUnchanged = "unchanged"
+	ApplyActionClosed    = "skip_closed"
+
+	// Reapplying a template
+	ApplyActionReapply    = "reapply"
+	ApplyActionCustomized = "skip_customized"
+)
+
+// Apply statuses per day. Dry runs only plan.
+const (
+	ApplyStatusPlanned = "planned"
+	ApplyStatusApplied = "applied"
+	ApplyStatusFailed  = "failed"
+)
+
+// Rollback statuses per applied day.
+const (
+	RollbackStatusRestored = "restored"
+	RollbackStatusFailed   = "failed"
+)
+
+// ApplyTemplateColorTimeResponse is the report of a dry run. Real runs are
+// queued and answered with the ApplyJob.
+type ApplyTemplateColorTimeResponse struct {
+	DryRun         bool              `json:"dry_run"`
+	Strategy       string            `json:"strategy"`
+	StartDate      string            `json:"start_date"`
+	EndDate        string            `json:"end_date"`
+	RequestedRange *ApplyDateRange   `json:"requested_range,omitempty"` // set when the dates were clamped to the term
+	Summary        *ApplySummary     `json:"summary"`
+	Days           []*ApplyDayResult `json:"days"`
+}
+
+type ApplyDateRange struct {
+	StartDate string `bson:"start_date" json:"start_date"`
+	EndDate   string `bson:"end_date" json:"end_date"`
+}
+
+type RolloverTemplateColorTimeResponse struct {
+	SourceTermID string               `json:"source_term_id"`
+	TargetTermID string               `json:"target_term_id"`
+	Templates    []*TemplateColorTime `json:"templates"`
+	ApplyJob     *ApplyJob            `json:"apply_job,omitempty"` // set with Apply
+}
+
+type ApplySummary struct {
+	Created   int `bson:"created" json:"created"`
+	Replaced  int `bson:"replaced" json:"replaced"`
+	Merged    int `bson:"merged" json:"merged"`
+	Unchanged int `bson:"unchanged" json:"unchanged"`
+	Closed    int `bson:"closed" json:"closed"`
+	Failed    int `bson:"failed" json:"failed"`
+}
+
+func (s *ApplySummary) count(action string) {
+	switch action {
+	case ApplyActionCreate:
+		s.Created++
+	case ApplyActionReplace:
+		s.Replaced++
+	case ApplyActionMerge:
+		s.Merged++
+	case ApplyActionUnchanged:
+		s.Unchanged++
+	case ApplyActionClosed:
+		s.Closed++
+	}
+}
+
+type ReapplyTemplateColorTimeResponse struct {
+	TemplateID primitive.ObjectID `json:"template_id"`
+	DryRun     bool               `json:"dry_run"`
+	Summary    *ReapplySummary    `json:"summary"`
+	Days       []*ApplyDayResult  `json:"days"`
+}
+
+type ReapplySummary struct {
+	Reapplied  int `json:"reapplied"`
+	Unchanged  int `json:"unchanged"`
+	Customized int `json:"customized"`
+	Failed     int `json:"failed"`
+}
+
+// ApplyDayResult is what applying the template does, or would do, to one day.
+type ApplyDayResult struct {
+	Date    string        `bson:"date" json:"date"`
+	Weekday string        `bson:"weekday" json:"weekday"` // template used, differs from the date on make-up days
+	Action  string        `bson:"action" json:"action"`
+	Status  string        `bson:"status" json:"status"`
+	Error   string        `bson:"error,omitempty" json:"error,omitempty"`
+	Added   []*SlotChange `bson:"added" json:"added"`
+	Removed []*SlotChange `bson:"removed" json:"removed"`
+	Changed []*SlotChange `bson:"changed" json:"changed"`
+
+	// Kept on jobs to roll an applied day back: the day as it was before
+	// (nil when the job created it) and the version the job left it at.
+	DayID          *primitive.ObjectID                    `bson:"day_id,omitempty" json:"day_id,omitempty"`
+	Before         *default_colortime.DefaultDayColorTime `bson:"before,omitempty" json:"-"`
+	AppliedVersion int64                                  `bson:"applied_version,omitempty" json:"-"`
+	RollbackStatus string                                 `bson:"rollback_status,omitempty" json:"rollback_status,omitempty"`
+	RollbackError  string                                 `bson:"rollback_error,omitempty" json:"rollback_error,omitempty"`
+}
+
+type SlotChange struct {
+	SlotID    primitive.ObjectID `bson:"slot_id" json:"slot_id"`
+	BlockID   primitive.ObjectID `bson:"block_id" json:"block_id"`
+	Title     string             `bson:"title" json:"title"`
+	StartTime time.Time          `bson:"start_time" json:"start_time"`
+	EndTime   time.Time          `bson:"end_time" json:"end_time"`
+	Fields    []string           `bson:"fields,omitempty" json:"fields,omitempty"` // changed fields
+}
+
+type LibraryTemplateResponse struct {
+	*LibraryTemplate
+	Latest *LibraryTemplateVersion `json:"latest"`
+}
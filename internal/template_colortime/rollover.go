@@ -0,0 +1,145 @@
+package templatecolortime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RolloverTemplateColorTime starts a new term from the weekday templates of
+// the previous one. Every template is deep-copied with new block and slot ids,
+// so editing the new term never touches the old one. All copies are written in
+// one transaction. When applying them fails after the copy, the response is
+// returned together with the error.
+func (s *templateColorTimeService) RolloverTemplateColorTime(ctx context.Context, req *RolloverTemplateColorTimeRequest, userID string) (*RolloverTemplateColorTimeResponse, error) {
+
+	if req.OrganizationID == "" {
+		return nil, errors.New("organization id is required")
+	}
+
+	if req.SourceTermID == "" || req.TargetTermID == "" {
+		return nil, errors.New("source and target term id are required")
+	}
+
+	if req.SourceTermID == req.TargetTermID {
+		return nil, errors.New("source and target term must differ")
+	}
+
+	if _, err := s.TermService.GetTermByID(ctx, req.SourceTermID); err != nil {
+		return nil, fmt.Errorf("invalid source term: %w", err)
+	}
+
+	targetTerm, err := s.TermService.GetTermByID(ctx, req.TargetTermID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target term: %w", err)
+	}
+
+	var sources, existing []*TemplateColorTime
+	var taken []string
+
+	for _, weekday := range templateWeekdays {
+		source, err := s.TemplateColorTimeRepository.GetTemplateColorTime(ctx, req.OrganizationID, req.SourceTermID, weekday)
+		if err != nil {
+			return nil, errors.New("failed to get template color time for " + weekday)
+		}
+		if source == nil {
+			continue
+		}
+		sources = append(sources, source)
+
+		target, err := s.TemplateColorTimeRepository.GetTemplateColorTime(ctx, req.OrganizationID, req.TargetTermID, weekday)
+		if err != nil {
+			return nil, errors.New("failed to check existing template color time for " + weekday)
+		}
+		if target != nil {
+			existing = append(existing, target)
+			taken = append(taken, weekday)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("source term has no templates")
+	}
+
+	if len(existing) > 0 && !req.Overwrite {
+		return nil, fmt.Errorf("target term already has templates for %s, set overwrite to replace them", strings.Join(taken, ", "))
+	}
+
+	applyReq := ApplyTemplateColorTimeRequest{
+		OrganizationID: req.OrganizationID,
+		TermID:         req.TargetTermID,
+		StartDate:      termDate(targetTerm.StartDate),
+		EndDate:        termDate(targetTerm.EndDate),
+		Strategy:       req.Strategy,
+	}
+
+	// Refuse before copying, a refused apply must not leave the copies behind
+	if req.Apply {
+		if _, err := s.validateApplyRequest(ctx, applyReq); err != nil {
+			return nil, err
+		}
+	}
+
+	copies := make([]*TemplateColorTime, 0, len(sources))
+	for _, source := range sources {
+		copies = append(copies, &TemplateColorTime{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: source.OrganizationID,
+			TermID:         req.TargetTermID,
+			Date:           source.Date,
+			ColorTimes:     cloneTemplateBlocks(source.ColorTimes, true),
+			CreatedBy:      userID,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		})
+	}
+
+	err = s.TemplateColorTimeRepository.WithTransaction(ctx, func(txCtx context.Context) error {
+		for _, template := range existing {
+			if err := s.TemplateColorTimeRepository.DeleteTemplateColorTime(txCtx, template.ID); err != nil {
+				return err
+			}
+		}
+		for _, template := range copies {
+			if err := s.TemplateColorTimeRepository.CreateTemplateColorTime(txCtx, template); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy templates to target term: %w", err)
+	}
+
+	response := &RolloverTemplateColorTimeResponse{
+		SourceTermID: req.SourceTermID,
+		TargetTermID: req.TargetTermID,
+		Templates:    copies,
+	}
+
+	if !req.Apply {
+		return response, nil
+	}
+
+	job, err := s.ApplyTemplateColorTime(ctx, applyReq, userID)
+	if err != nil {
+		// The copies are committed, the caller needs them to apply again
+		return response, fmt.Errorf("templates copied but applying them failed: %w", err)
+	}
+	response.ApplyJob = job
+
+	return response, nil
+}
+
+// termDate returns the day part of a term date, which the term service may
+// send with a time.
+func termDate(date string) string {
+	if len(date) > len("2006-01-02") {
+		return date[:len("2006-01-02")]
+	}
+	return date
+}
@@ -0,0 +1,131 @@
+package templatecolortime
+
+import (
+	"colortime-service/internal/term"
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeTermService struct {
+	term.TermService
+	terms map[string]*term.TermInfor
+}
+
+func (f *fakeTermService) GetTermByID(ctx context.Context, id string) (*term.TermInfor, error) {
+	if t, ok := f.terms[id]; ok {
+		return t, nil
+	}
+	return nil, errors.New("term not found")
+}
+
+func newRolloverService(templates ...*TemplateColorTime) (*templateColorTimeService, *fakeTemplateRepository) {
+	repo := newFakeTemplateRepository(templates...)
+	terms := &fakeTermService{terms: map[string]*term.TermInfor{
+		"term-1": {ID: "term-1", StartDate: "2026-01-05", EndDate: "2026-05-29"},
+		"term-2": {ID: "term-2", StartDate: "2026-09-07T00:00:00Z", EndDate: "2027-01-22T00:00:00Z"},
+	}}
+	return &templateColorTimeService{TemplateColorTimeRepository: repo, TermService: terms}, repo
+}
+
+func TestRolloverCopiesTemplates(t *testing.T) {
+	monday := weekdayTemplate("term-1", "monday", "Circle", "08:00-08:30")
+	friday := weekdayTemplate("term-1", "friday", "Music", "14:00-14:45")
+	s, repo := newRolloverService(monday, friday)
+
+	req := &RolloverTemplateColorTimeRequest{OrganizationID: "org-1", SourceTermID: "term-1", TargetTermID: "term-2"}
+	response, err := s.RolloverTemplateColorTime(context.Background(), req, "user-1")
+	if err != nil {
+		t.Fatalf("RolloverTemplateColorTime: %v", err)
+	}
+
+	if len(response.Templates) != 2 || len(repo.created) != 2 || len(repo.deleted) != 0 {
+		t.Fatalf("%d templates in the response, %d created, %d deleted; want 2 created", len(response.Templates), len(repo.created), len(repo.deleted))
+	}
+
+	for i, source := range []*TemplateColorTime{monday, friday} {
+		copied := response.Templates[i]
+		if copied.TermID != "term-2" || copied.Date != source.Date || copied.CreatedBy != "user-1" || copied.ID == source.ID {
+			t.Fatalf("copy of %s = %+v", source.Date, copied)
+		}
+
+		block, slot := copied.ColorTimes[0], copied.ColorTimes[0].Slots[0]
+		if block.BlockID == source.ColorTimes[0].BlockID || slot.SlotID == source.ColorTimes[0].Slots[0].SlotID {
+			t.Fatalf("copy of %s keeps the block or slot ids of the source term", source.Date)
+		}
+		if slot.Title != source.ColorTimes[0].Slots[0].Title || slot.StartTime != source.ColorTimes[0].Slots[0].StartTime {
+			t.Fatalf("copy of %s has slot %+v", source.Date, slot)
+		}
+
+		slot.ColorTimeSlotLanguage[0].Title = "changed"
+		if source.ColorTimes[0].Slots[0].ColorTimeSlotLanguage[0].Title == "changed" {
+			t.Fatalf("copy of %s shares languages with the source term", source.Date)
+		}
+	}
+}
+
+func TestRolloverOverwrite(t *testing.T) {
+	source := weekdayTemplate("term-1", "monday", "Circle", "08:00-08:30")
+	target := weekdayTemplate("term-2", "monday", "Old circle", "09:00-09:30")
+
+	t.Run("refused without overwrite", func(t *testing.T) {
+		s, repo := newRolloverService(source, target)
+
+		req := &RolloverTemplateColorTimeRequest{OrganizationID: "org-1", SourceTermID: "term-1", TargetTermID: "term-2"}
+		if _, err := s.RolloverTemplateColorTime(context.Background(), req, "user-1"); err == nil {
+			t.Fatal("rollover over existing templates succeeded without overwrite")
+		}
+		if len(repo.created)+len(repo.deleted) != 0 {
+			t.Fatalf("refused rollover created %d and deleted %d templates", len(repo.created), len(repo.deleted))
+		}
+	})
+
+	t.Run("replaced with overwrite", func(t *testing.T) {
+		s, repo := newRolloverService(source, target)
+
+		req := &RolloverTemplateColorTimeRequest{OrganizationID: "org-1", SourceTermID: "term-1", TargetTermID: "term-2", Overwrite: true}
+		if _, err := s.RolloverTemplateColorTime(context.Background(), req, "user-1"); err != nil {
+			t.Fatalf("RolloverTemplateColorTime: %v", err)
+		}
+		if len(repo.deleted) != 1 || repo.deleted[0] != target.ID || len(repo.created) != 1 {
+			t.Fatalf("deleted %v and created %d templates, want the old monday replaced", repo.deleted, len(repo.created))
+		}
+	})
+}
+
+func TestRolloverRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		req  RolloverTemplateColorTimeRequest
+	}{
+		{name: "no organization", req: RolloverTemplateColorTimeRequest{SourceTermID: "term-1", TargetTermID: "term-2"}},
+		{name: "same term", req: RolloverTemplateColorTimeRequest{OrganizationID: "org-1", SourceTermID: "term-1", TargetTermID: "term-1"}},
+		{name: "unknown target term", req: RolloverTemplateColorTimeRequest{OrganizationID: "org-1", SourceTermID: "term-1", TargetTermID: "term-3"}},
+		{name: "source term without templates", req: RolloverTemplateColorTimeRequest{OrganizationID: "org-1", SourceTermID: "term-2", TargetTermID: "term-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newRolloverService(weekdayTemplate("term-1", "monday", "Circle", "08:00-08:30"))
+
+			if _, err := s.RolloverTemplateColorTime(context.Background(), &tt.req, "user-1"); err == nil {
+				t.Fatal("RolloverTemplateColorTime succeeded, want an error")
+			}
+			if len(repo.created) != 0 {
+				t.Fatalf("rejected rollover created %d templates", len(repo.created))
+			}
+		})
+	}
+}
+
+func TestTermDate(t *testing.T) {
+	for input, want := range map[string]string{
+		"2026-09-07":           "2026-09-07",
+		"2026-09-07T00:00:00Z": "2026-09-07",
+		"":                     "",
+	} {
+		if got := termDate(input); got != want {
+			t.Errorf("termDate(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -12,9 +12,26 @@ func RegisterRoutes(r *gin.Engine, templateColorTimeHandler *TemplateColorTimeHa
 		templateColorTime.GET("", templateColorTimeHandler.GetTemplateColorTime)
 		templateColorTime.POST("", templateColorTimeHandler.CreateTemplateColorTime)
 		templateColorTime.POST("/duplicate", templateColorTimeHandler.DuplicateTemplateColorTime)
+		templateColorTime.POST("/rollover", templateColorTimeHandler.RolloverTemplateColorTime)
 		templateColorTime.POST("/apply-template", templateColorTimeHandler.ApplyTemplateColorTime)
+		templateColorTime.GET("/apply-jobs", templateColorTimeHandler.GetApplyJobs)
+		templateColorTime.GET("/apply-jobs/:id", templateColorTimeHandler.GetApplyJob)
+		templateColorTime.POST("/apply-jobs/:id/rollback", templateColorTimeHandler.RollbackApplyJob)
+		templateColorTime.GET("/library", templateColorTimeHandler.GetLibraryTemplates)
+		templateColorTime.POST("/library", templateColorTimeHandler.CreateLibraryTemplate)
+		templateColorTime.GET("/library/:id", templateColorTimeHandler.GetLibraryTemplate)
+		templateColorTime.PUT("/library/:id", templateColorTimeHandler.UpdateLibraryTemplate)
+		templateColorTime.GET("/library/:id/versions", templateColorTimeHandler.GetLibraryTemplateVersions)
+		templateColorTime.POST("/library/:id/versions", templateColorTimeHandler.PublishLibraryTemplateVersion)
+		templateColorTime.GET("/library/:id/versions/:version", templateColorTimeHandler.GetLibraryTemplateVersion)
 		templateColorTime.PUT("/copy-slot/:block_id", templateColorTimeHandler.CopySlotToTemplateColorTime)
+		templateColorTime.POST("/:id/reapply", templateColorTimeHandler.ReapplyTemplateColorTime)
 		templateColorTime.PUT("/:id/update-slot/:slot_id", templateColorTimeHandler.UpdateTemplateColorTimeSlot)
+		templateColorTime.POST("/:id/blocks", templateColorTimeHandler.CreateTemplateBlock)
+		templateColorTime.PUT("/:id/blocks/:block_id/move", templateColorTimeHandler.MoveTemplateBlock)
+		templateColorTime.PUT("/:id/blocks/:block_id/reorder", templateColorTimeHandler.ReorderTemplateBlockSlots)
+		templateColorTime.POST("/:id/blocks/:block_id/split", templateColorTimeHandler.SplitTemplateBlock)
+		templateColorTime.POST("/:id/blocks/:block_id/merge", templateColorTimeHandler.MergeTemplateBlocks)
 		templateColorTime.DELETE("/:id/delete-block/:block_id", templateColorTimeHandler.DeleteTemplateColorTimeBlock)
 		templateColorTime.DELETE("/:id/delete-slot/:slot_id", templateColorTimeHandler.DeleteTemplateColorTimeSlot)
 	}
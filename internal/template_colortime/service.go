@@ -1,14 +1,19 @@
 package templatecolortime
 
 import (
+	"colortime-service/helper"
+	"colortime-service/internal/closure"
 	"colortime-service/internal/default_colortime"
 	"colortime-service/internal/term"
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type TemplateColorTimeService interface {
@@ -18,25 +23,67 @@ type TemplateColorTimeService interface {
 	DeleteTemplateColorTimeBlock(ctx context.Context, templateColorTimeID, blockID string, userID string) error
 	DeleteTemplateColorTimeSlot(ctx context.Context, templateColorTimeID, slotID string, userID string) error
 	DuplicateTemplateColorTime(ctx context.Context, req DuplicateTemplateColorTimeRequest, userID string) error
-	ApplyTemplateColorTime(ctx context.Context, req ApplyTemplateColorTimeRequest, userID string) error
+	RolloverTemplateColorTime(ctx context.Context, req *RolloverTemplateColorTimeRequest, userID string) (*RolloverTemplateColorTimeResponse, error)
+	PreviewApplyTemplateColorTime(ctx context.Context, req ApplyTemplateColorTimeRequest) (*ApplyTemplateColorTimeResponse, error)
+	ApplyTemplateColorTime(ctx context.Context, req ApplyTemplateColorTimeRequest, userID string) (*ApplyJob, error)
+	GetApplyJob(ctx context.Context, id string) (*ApplyJob, error)
+	GetApplyJobs(ctx context.Context, organizationID string) ([]*ApplyJob, error)
+	RollbackApplyJob(ctx context.Context, id string) (*ApplyJob, error)
+	RunApplyWorker(ctx context.Context)
+	ReapplyTemplateColorTime(ctx context.Context, templateColorTimeID string, req *ReapplyTemplateColorTimeRequest) (*ReapplyTemplateColorTimeResponse, error)
 	CopySlotToTemplateColorTime(ctx context.Context, blockID string, req *CopySlotToTemplateColorTimeRequest, userID string) error
+	CreateTemplateBlock(ctx context.Context, templateColorTimeID string, userID string) (*ColorTimeTemplate, error)
+	MoveTemplateBlock(ctx context.Context, templateColorTimeID, blockID string, req *MoveBlockRequest, userID string) error
+	ReorderTemplateBlockSlots(ctx context.Context, templateColorTimeID, blockID string, req *ReorderBlockSlotsRequest, userID string) error
+	SplitTemplateBlock(ctx context.Context, templateColorTimeID, blockID string, req *SplitBlockRequest, userID string) (*ColorTimeTemplate, error)
+	MergeTemplateBlocks(ctx context.Context, templateColorTimeID, blockID string, req *MergeBlocksRequest, userID string) error
+	CreateLibraryTemplate(ctx context.Context, req *CreateLibraryTemplateRequest, userID string) (*LibraryTemplateResponse, error)
+	GetLibraryTemplates(ctx context.Context, organizationID string) ([]*LibraryTemplate, error)
+	GetLibraryTemplate(ctx context.Context, id string) (*LibraryTemplateResponse, error)
+	UpdateLibraryTemplate(ctx context.Context, id string, req *UpdateLibraryTemplateRequest) error
+	GetLibraryTemplateVersions(ctx context.Context, id string) ([]*LibraryTemplateVersion, error)
+	GetLibraryTemplateVersion(ctx context.Context, id string, version int) (*LibraryTemplateVersion, error)
+	PublishLibraryTemplateVersion(ctx context.Context, id string, req *PublishLibraryTemplateVersionRequest, userID string) (*LibraryTemplateVersion, error)
 }
 
 type templateColorTimeService struct {
 	TemplateColorTimeRepository TemplateColorTimeRepository
 	TermService                 term.TermService
 	DefaultColorTimeRepository  default_colortime.DefaultColorTimeRepository
+	ClosureService              closure.ClosureService
+	ChangeNotifier              default_colortime.DefaultDayChangeNotifier
+	ApplyJobRepository          ApplyJobRepository
+	LibraryTemplateRepository   LibraryTemplateRepository
+	MaxApplyDays                int
+	applyJobs                   chan primitive.ObjectID
+	workerID                    string
 }
 
 func NewTemplateColorTimeService(
 	templateColorTimeRepository TemplateColorTimeRepository,
 	termService term.TermService,
 	defaultColorTimeRepository default_colortime.DefaultColorTimeRepository,
+	closureService closure.ClosureService,
+	changeNotifier default_colortime.DefaultDayChangeNotifier,
+	applyJobRepository ApplyJobRepository,
+	libraryTemplateRepository LibraryTemplateRepository,
+	maxApplyDays int,
 ) TemplateColorTimeService {
+	if maxApplyDays <= 0 {
+		maxApplyDays = DefaultMaxApplyDays
+	}
+
 	return &templateColorTimeService{
 		TemplateColorTimeRepository: templateColorTimeRepository,
 		TermService:                 termService,
 		DefaultColorTimeRepository:  defaultColorTimeRepository,
+		ClosureService:              closureService,
+		ChangeNotifier:              changeNotifier,
+		ApplyJobRepository:          applyJobRepository,
+		LibraryTemplateRepository:   libraryTemplateRepository,
+		MaxApplyDays:                maxApplyDays,
+		applyJobs:                   make(chan primitive.ObjectID, applyJobQueueSize),
+		workerID:                    primitive.NewObjectID().Hex(),
 	}
 }
 
@@ -361,6 +408,10 @@ func (s *templateColorTimeService) UpdateTemplateColorTimeSlot(ctx context.Conte
 		return errors.New("template color time not found")
 	}
 
+	if err := helper.CheckIfMatch(ctx, templateColorTime.Version); err != nil {
+		return err
+	}
+
 	slotObjectID, err := primitive.ObjectIDFromHex(slotID)
 	if err != nil {
 		return errors.New("invalid slot id format")
@@ -479,10 +530,27 @@ func (s *templateColorTimeService) UpdateTemplateColorTimeSlot(ctx context.Conte
 			}
 		}
 		targetSlot.UpdatedAt = time.Now()
+
+		err = s.TemplateColorTimeRepository.UpdateTemplateSlotFields(ctx, templateColorTimeObjectID, slotObjectID, templateColorTime.Version, bson.M{
+			"title":                    targetSlot.Title,
+			"color":                    targetSlot.Color,
+			"note":                     targetSlot.Note,
+			"start_time":               targetSlot.StartTime,
+			"end_time":                 targetSlot.EndTime,
+			"duration":                 targetSlot.Duration,
+			"color_time_slot_language": targetSlot.ColorTimeSlotLanguage,
+			"updated_at":               targetSlot.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update template color time: %w", err)
+		}
+
+		return nil
 	}
 
+	// Moving the slot to another block changes the structure, write it whole
 	if err := s.TemplateColorTimeRepository.UpdateTemplateColorTime(ctx, templateColorTimeObjectID, templateColorTime); err != nil {
-		return errors.New("failed to update template color time")
+		return fmt.Errorf("failed to update template color time: %w", err)
 	}
 
 	return nil
@@ -515,28 +583,20 @@ func (s *templateColorTimeService) DeleteTemplateColorTimeBlock(ctx context.Cont
 		return errors.New("template color time not found")
 	}
 
+	if err := helper.CheckIfMatch(ctx, templateColorTime.Version); err != nil {
+		return err
+	}
+
 	blockObjectID, err := primitive.ObjectIDFromHex(blockID)
 	if err != nil {
 		return errors.New("invalid block id format")
 	}
 
-	var targetBlockIndex = -1
-	for i, block := range templateColorTime.ColorTimes {
-		if block.BlockID == blockObjectID {
-			targetBlockIndex = i
-			break
+	if err := s.TemplateColorTimeRepository.RemoveTemplateBlock(ctx, templateColorTimeObjectID, blockObjectID, templateColorTime.Version); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errors.New("block not found")
 		}
-	}
-
-	if targetBlockIndex == -1 {
-		return errors.New("block not found")
-	}
-
-	// Remove the block from slice
-	templateColorTime.ColorTimes = append(templateColorTime.ColorTimes[:targetBlockIndex], templateColorTime.ColorTimes[targetBlockIndex+1:]...)
-
-	if err := s.TemplateColorTimeRepository.UpdateTemplateColorTime(ctx, templateColorTimeObjectID, templateColorTime); err != nil {
-		return errors.New("failed to update template color time")
+		return fmt.Errorf("failed to update template color time: %w", err)
 	}
 
 	return nil
@@ -570,32 +630,20 @@ func (s *templateColorTimeService) DeleteTemplateColorTimeSlot(ctx context.Conte
 		return errors.New("template color time not found")
 	}
 
+	if err := helper.CheckIfMatch(ctx, templateColorTime.Version); err != nil {
+		return err
+	}
+
 	slotObjectID, err := primitive.ObjectIDFromHex(slotID)
 	if err != nil {
 		return errors.New("invalid slot id format")
 	}
 
-	slotFound := false
-
-	for bIdx := range templateColorTime.ColorTimes {
-		newSlots := []*ColortimeSlot{} // tuỳ struct bạn đặt tên
-		for _, slot := range templateColorTime.ColorTimes[bIdx].Slots {
-			if slot.SlotID != slotObjectID {
-				newSlots = append(newSlots, slot)
-			} else {
-				slotFound = true
-			}
+	if err := s.TemplateColorTimeRepository.RemoveTemplateSlot(ctx, templateID, slotObjectID, templateColorTime.Version); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errors.New("slot not found")
 		}
-
-		templateColorTime.ColorTimes[bIdx].Slots = newSlots
-	}
-
-	if !slotFound {
-		return errors.New("slot not found")
-	}
-
-	if err := s.TemplateColorTimeRepository.UpdateTemplateColorTime(ctx, templateID, templateColorTime); err != nil {
-		return errors.New("failed to update template color time")
+		return fmt.Errorf("failed to update template color time: %w", err)
 	}
 
 	return nil
@@ -659,178 +707,524 @@ func (s *templateColorTimeService) DuplicateTemplateColorTime(ctx context.Contex
 	return nil
 }
 
-func (s *templateColorTimeService) ApplyTemplateColorTime(ctx context.Context, req ApplyTemplateColorTimeRequest, userID string) error {
+// PreviewApplyTemplateColorTime reports what ApplyTemplateColorTime would do
+// to every day of the clamped range without writing anything.
+func (s *templateColorTimeService) PreviewApplyTemplateColorTime(ctx context.Context, req ApplyTemplateColorTimeRequest) (*ApplyTemplateColorTimeResponse, error) {
 
-	if req.OrganizationID == "" {
-		return errors.New("organization id is required")
+	ar, err := s.validateApplyRequest(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.TermID == "" {
-		return errors.New("term id is required")
+	library, err := s.applyLibraryVersion(ctx, req.OrganizationID, req.LibraryTemplateID, req.LibraryVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	var result []*TemplateColorTime
+	ac, err := s.loadApplyContext(ctx, req.OrganizationID, req.TermID, ar.strategy, ar.startDate, ar.endDate, library)
+	if err != nil {
+		return nil, err
+	}
 
-	weekdays := []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+	report := &ApplyTemplateColorTimeResponse{
+		DryRun:         true,
+		Strategy:       ar.strategy,
+		StartDate:      ar.startDate.Format("2006-01-02"),
+		EndDate:        ar.endDate.Format("2006-01-02"),
+		RequestedRange: ar.requested,
+		Summary:        &ApplySummary{},
+		Days:           []*ApplyDayResult{},
+	}
 
-	for _, weekday := range weekdays {
-		template, err := s.TemplateColorTimeRepository.GetTemplateColorTime(ctx, req.OrganizationID, req.TermID, weekday)
-		if err != nil {
-			return errors.New("failed to get template color time for " + weekday)
-		}
-		if template == nil {
+	for currentDate := ar.startDate; !currentDate.After(ar.endDate); currentDate = currentDate.AddDate(0, 0, 1) {
+		plan := s.planDay(ctx, ac, currentDate)
+		if plan == nil {
 			continue
+		}
+
+		report.Days = append(report.Days, plan.result)
+		if plan.result.Status == ApplyStatusFailed {
+			report.Summary.Failed++
 		} else {
-			result = append(result, template)
+			report.Summary.count(plan.result.Action)
 		}
+	}
+
+	return report, nil
+}
 
+// ApplyTemplateColorTime queues a job that copies the weekday templates of the
+// term, or a version of a library template, onto the default days from
+// StartDate to EndDate, clamped to the term. Days that already exist are
+// replaced or merged depending on the strategy. The job is run by
+// RunApplyWorker and reports every day with the slots it gained, lost or
+// changed; a failing day is reported and the rest are still applied.
+func (s *templateColorTimeService) ApplyTemplateColorTime(ctx context.Context, req ApplyTemplateColorTimeRequest, userID string) (*ApplyJob, error) {
+
+	ar, err := s.validateApplyRequest(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	// term, err := s.TermService.GetTermByID(ctx, req.TermID)
-	// if err != nil {
-	// 	return errors.New("failed to get term")
-	// }
+	library, err := s.applyLibraryVersion(ctx, req.OrganizationID, req.LibraryTemplateID, req.LibraryVersion)
+	if err != nil {
+		return nil, err
+	}
 
-	// if term == nil {
-	// 	return errors.New("term not found")
-	// }
+	job := &ApplyJob{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: req.OrganizationID,
+		TermID:         req.TermID,
+		StartDate:      ar.startDate,
+		EndDate:        ar.endDate,
+		RequestedRange: ar.requested,
+		Strategy:       ar.strategy,
+		Status:         ApplyJobStatusPending,
+		TotalDays:      int(ar.endDate.Sub(ar.startDate).Hours()/24) + 1,
+		Summary:        &ApplySummary{},
+		Days:           []*ApplyDayResult{},
+		CreatedBy:      userID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	// Pin the version, publishing a newer one must not change a queued job
+	if library != nil {
+		job.LibraryTemplateID = &library.TemplateID
+		job.LibraryVersion = library.Version
+	}
+
+	if err := s.ApplyJobRepository.CreateApplyJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create apply job: %w", err)
+	}
+
+	s.enqueueApplyJob(job.ID)
+
+	return job, nil
+}
+
+// DefaultMaxApplyDays is the longest range applied at once unless configured
+// otherwise, a school year.
+const DefaultMaxApplyDays = 366
+
+// applyRange is a validated apply request: its dates are clamped to the term.
+type applyRange struct {
+	strategy  string
+	startDate time.Time
+	endDate   time.Time
+	requested *ApplyDateRange // set when the dates were clamped
+}
+
+// validateApplyRequest checks the request against its term. Dates outside the
+// term are clamped to it; a range that misses the term, is longer than
+// MaxApplyDays or overlaps an application of another term is refused. All
+// problems are returned together as a helper.ValidationError.
+func (s *templateColorTimeService) validateApplyRequest(ctx context.Context, req ApplyTemplateColorTimeRequest) (*applyRange, error) {
+
+	validation := &helper.ValidationError{}
+
+	if req.OrganizationID == "" {
+		validation.Add("organization_id", "required", "organization id is required")
+	}
+
+	if req.TermID == "" {
+		validation.Add("term_id", "required", "term id is required")
+	}
+
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = ApplyStrategyReplace
+	}
+	if strategy != ApplyStrategyReplace && strategy != ApplyStrategyMerge {
+		validation.Add("strategy", "invalid", "invalid strategy "+strategy+" (use replace or merge)")
+	}
 
 	startDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
-		return errors.New("failed to parse start date")
+		validation.Add("start_date", "invalid_format", "start date must be YYYY-MM-DD")
+	}
+
+	endDate, endErr := time.Parse("2006-01-02", req.EndDate)
+	if endErr != nil {
+		validation.Add("end_date", "invalid_format", "end date must be YYYY-MM-DD")
+	}
+
+	if err == nil && endErr == nil && endDate.Before(startDate) {
+		validation.Add("end_date", "before_start", "end date must not be before start date")
+	}
+
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
+
+	termInfo, err := s.TermService.GetTermByID(ctx, req.TermID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get term: %w", err)
+	}
+
+	termStart, err := time.Parse("2006-01-02", termDate(termInfo.StartDate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid term start date %q", termInfo.StartDate)
 	}
 
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	termEnd, err := time.Parse("2006-01-02", termDate(termInfo.EndDate))
 	if err != nil {
-		return errors.New("failed to parse end date")
+		return nil, fmt.Errorf("invalid term end date %q", termInfo.EndDate)
 	}
 
-	// Create a map of weekday to template for quick lookup
-	templateMap := make(map[string]*TemplateColorTime)
-	for _, template := range result {
-		templateMap[strings.ToLower(template.Date)] = template
+	if endDate.Before(termStart) || startDate.After(termEnd) {
+		validation.Add("start_date", "outside_term", fmt.Sprintf("date range is outside the term (%s to %s)", termStart.Format("2006-01-02"), termEnd.Format("2006-01-02")))
+		return nil, validation
 	}
 
-	// Loop through all dates from start to end
-	for currentDate := startDate; !currentDate.After(endDate); currentDate = currentDate.AddDate(0, 0, 1) {
-		// Get weekday name in lowercase (convert Go's weekday to our format)
-		weekdayName := strings.ToLower(currentDate.Weekday().String())
+	ar := &applyRange{strategy: strategy, startDate: startDate, endDate: endDate}
 
-		// Get template for this weekday
-		template, exists := templateMap[weekdayName]
-		if !exists || template == nil {
-			continue // Skip if no template for this weekday
+	if startDate.Before(termStart) || endDate.After(termEnd) {
+		ar.requested = &ApplyDateRange{StartDate: req.StartDate, EndDate: req.EndDate}
+		if startDate.Before(termStart) {
+			ar.startDate = termStart
 		}
-
-		// Check if default colortime already exists for this date
-		existingDefaultColorTime, err := s.DefaultColorTimeRepository.GetDefaultDayColorTime(ctx, currentDate, req.OrganizationID)
-		if err != nil {
-			return errors.New("failed to get existing default color time")
+		if endDate.After(termEnd) {
+			ar.endDate = termEnd
 		}
+	}
 
-		if existingDefaultColorTime != nil {
-			// Replace existing TimeSlots with template data (overwrite completely)
-			existingDefaultColorTime.TimeSlots = []*default_colortime.DefaultColorBlock{}
+	if days := int(ar.endDate.Sub(ar.startDate).Hours()/24) + 1; days > s.MaxApplyDays {
+		validation.Add("end_date", "max_span", fmt.Sprintf("date range covers %d days, at most %d can be applied at once", days, s.MaxApplyDays))
+	}
 
-			// Copy all blocks and slots from template to default
-			for _, templateBlock := range template.ColorTimes {
-				colorBlock := &default_colortime.DefaultColorBlock{
-					BlockID: templateBlock.BlockID,
-					Slots:   []*default_colortime.DefaultColortimeSlot{},
-				}
+	overlapping, err := s.ApplyJobRepository.GetOverlappingApplyJobs(ctx, req.OrganizationID, req.TermID, ar.startDate, ar.endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check overlapping apply jobs: %w", err)
+	}
 
-				for _, templateSlot := range templateBlock.Slots {
-					// Convert language data
-					var defaultLanguages []*default_colortime.DefaultColorTimeSlotLanguage
-					for _, lang := range templateSlot.ColorTimeSlotLanguage {
-						defaultLanguages = append(defaultLanguages, &default_colortime.DefaultColorTimeSlotLanguage{
-							LanguageID: lang.LanguageID,
-							Title:      lang.Title,
-						})
-					}
+	for _, job := range overlapping {
+		validation.Add("term_id", "overlapping_term", overlapMessage(job))
+	}
 
-					colorSlot := &default_colortime.DefaultColortimeSlot{
-						SlotID:                templateSlot.SlotID,
-						Sessions:              templateSlot.Sessions,
-						Title:                 templateSlot.Title,
-						ColorTimeSlotLanguage: defaultLanguages,
-						StartTime:             templateSlot.StartTime,
-						EndTime:               templateSlot.EndTime,
-						Duration:              templateSlot.Duration,
-						Color:                 templateSlot.Color,
-						Note:                  templateSlot.Note,
-						CreatedAt:             time.Now(),
-						UpdatedAt:             time.Now(),
-					}
-					colorBlock.Slots = append(colorBlock.Slots, colorSlot)
-				}
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
 
-				existingDefaultColorTime.TimeSlots = append(existingDefaultColorTime.TimeSlots, colorBlock)
-			}
+	return ar, nil
+}
+
+func overlapMessage(job *ApplyJob) string {
+	return fmt.Sprintf("term %s was already applied from %s to %s (apply job %s)",
+		job.TermID, job.StartDate.Format("2006-01-02"), job.EndDate.Format("2006-01-02"), job.ID.Hex())
+}
+
+// applyContext is what applying the templates of a term needs besides the
+// days themselves.
+type applyContext struct {
+	organizationID string
+	strategy       string
+	templates      map[string]*TemplateColorTime // by lowercase weekday
+	closures       *closure.Calendar
+}
 
-			existingDefaultColorTime.UpdatedAt = time.Now()
-			if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, existingDefaultColorTime.ID, existingDefaultColorTime); err != nil {
-				return errors.New("failed to update default color time")
+// applyLibraryVersion returns the library template version an apply request
+// asks for, or nil when it applies the term's weekday templates.
+func (s *templateColorTimeService) applyLibraryVersion(ctx context.Context, organizationID, libraryTemplateID string, version int) (*LibraryTemplateVersion, error) {
+
+	if libraryTemplateID == "" {
+		return nil, nil
+	}
+
+	template, err := s.getLibraryTemplate(ctx, libraryTemplateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.OrganizationID != organizationID {
+		return nil, errors.New("library template not found")
+	}
+
+	return s.resolveLibraryVersion(ctx, template, version)
+}
+
+// loadApplyContext loads the weekday templates of the term, or the days of
+// library when it is given.
+func (s *templateColorTimeService) loadApplyContext(ctx context.Context, organizationID, termID, strategy string, startDate, endDate time.Time, library *LibraryTemplateVersion) (*applyContext, error) {
+
+	ac := &applyContext{
+		organizationID: organizationID,
+		strategy:       strategy,
+		templates:      make(map[string]*TemplateColorTime),
+	}
+
+	if library != nil {
+		ac.templates = libraryWeekdayTemplates(organizationID, termID, library)
+	} else {
+		for _, weekday := range templateWeekdays {
+			template, err := s.TemplateColorTimeRepository.GetTemplateColorTime(ctx, organizationID, termID, weekday)
+			if err != nil {
+				return nil, errors.New("failed to get template color time for " + weekday)
 			}
-		} else {
-			// Create new default colortime by copying template structure
-			defaultColorTime := &default_colortime.DefaultDayColorTime{
-				ID:             primitive.NewObjectID(),
-				OrganizationID: req.OrganizationID,
-				Date:           currentDate,
-				TimeSlots:      []*default_colortime.DefaultColorBlock{},
-				CreatedBy:      userID,
-				CreatedAt:      time.Now(),
-				UpdatedAt:      time.Now(),
-				IsBaseTemplate: false,
-				RepeatType:     "none",
+			if template != nil {
+				ac.templates[strings.ToLower(template.Date)] = template
 			}
+		}
+	}
 
-			// Copy all blocks and slots from template to default
-			for _, templateBlock := range template.ColorTimes {
-				colorBlock := &default_colortime.DefaultColorBlock{
-					BlockID: templateBlock.BlockID, // Generate new block ID
-					Slots:   []*default_colortime.DefaultColortimeSlot{},
-				}
+	closures, err := s.ClosureService.GetCalendar(ctx, organizationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	ac.closures = closures
 
-				for _, templateSlot := range templateBlock.Slots {
-					// Convert language data
-					var defaultLanguages []*default_colortime.DefaultColorTimeSlotLanguage
-					for _, lang := range templateSlot.ColorTimeSlotLanguage {
-						defaultLanguages = append(defaultLanguages, &default_colortime.DefaultColorTimeSlotLanguage{
-							LanguageID: lang.LanguageID,
-							Title:      lang.Title,
-						})
-					}
-					colorSlot := &default_colortime.DefaultColortimeSlot{
-						SlotID:    templateSlot.SlotID, // Generate new slot ID
-						Sessions:  templateSlot.Sessions,
-						Title:     templateSlot.Title,
-						ColorTimeSlotLanguage: defaultLanguages,
-						StartTime: templateSlot.StartTime,
-						EndTime:   templateSlot.EndTime,
-						Duration:  templateSlot.Duration,
-						Color:     templateSlot.Color,
-						Note:      templateSlot.Note,
-						CreatedAt: time.Now(),
-						UpdatedAt: time.Now(),
-					}
-					colorBlock.Slots = append(colorBlock.Slots, colorSlot)
-				}
+	return ac, nil
+}
 
-				defaultColorTime.TimeSlots = append(defaultColorTime.TimeSlots, colorBlock)
-			}
+// dayPlan is the change applying the template makes to one day.
+type dayPlan struct {
+	date      time.Time
+	template  *TemplateColorTime
+	appliedAt time.Time
+	result    *ApplyDayResult
+	existing  *default_colortime.DefaultDayColorTime
+	blocks    []*default_colortime.DefaultColorBlock
+}
 
-			// Create the new default colortime document
-			if err := s.DefaultColorTimeRepository.CreateDefaultDayColorTime(ctx, defaultColorTime); err != nil {
-				continue // Continue to next date if creation fails
-			}
+// needsWrite reports whether the plan changes the stored day.
+func (p *dayPlan) needsWrite() bool {
+	if p.result.Status == ApplyStatusFailed {
+		return false
+	}
+	switch p.result.Action {
+	case ApplyActionCreate, ApplyActionReplace, ApplyActionMerge:
+		return true
+	}
+	return false
+}
+
+// planDay computes what applying the template does to the day, reading the
+// stored day with ctx. It returns nil for days without a template; a day that
+// cannot be read or merged is planned as failed.
+func (s *templateColorTimeService) planDay(ctx context.Context, ac *applyContext, date time.Time) *dayPlan {
+
+	// Make-up days follow the template of another weekday
+	weekdayName := strings.ToLower(ac.closures.Weekday(date).String())
+
+	plan := &dayPlan{
+		date: date,
+		result: &ApplyDayResult{
+			Date:    date.Format("2006-01-02"),
+			Weekday: weekdayName,
+			Status:  ApplyStatusPlanned,
+			Added:   []*SlotChange{},
+			Removed: []*SlotChange{},
+			Changed: []*SlotChange{},
+		},
+	}
+
+	// No colortime on closed days
+	if ac.closures.IsClosed(date) {
+		plan.result.Action = ApplyActionClosed
+		return plan
+	}
+
+	template, exists := ac.templates[weekdayName]
+	if !exists || template == nil {
+		return nil
+	}
+
+	existing, err := s.DefaultColorTimeRepository.GetDefaultDayColorTime(ctx, date, ac.organizationID)
+	if err != nil {
+		plan.fail(errors.New("failed to get existing default color time"))
+		return plan
+	}
+	plan.existing = existing
+
+	plan.template = template
+	plan.appliedAt = time.Now()
+	planned := templateBlocks(template, plan.appliedAt)
+
+	var before []*default_colortime.DefaultColorBlock
+	switch {
+	case existing == nil:
+		plan.result.Action = ApplyActionCreate
+	case ac.strategy == ApplyStrategyMerge:
+		plan.result.Action = ApplyActionMerge
+		before = existing.TimeSlots
+		merged, err := mergeBlocks(before, planned)
+		if err != nil {
+			plan.fail(err)
+			return plan
+		}
+		planned = merged
+	default:
+		plan.result.Action = ApplyActionReplace
+		before = existing.TimeSlots
+	}
+
+	plan.blocks = planned
+	plan.result.Added, plan.result.Removed, plan.result.Changed = diffBlocks(before, planned)
+
+	if existing != nil && len(plan.result.Added)+len(plan.result.Removed)+len(plan.result.Changed) == 0 {
+		plan.result.Action = ApplyActionUnchanged
+	}
+
+	return plan
+}
+
+func (p *dayPlan) fail(err error) {
+	p.result.Status = ApplyStatusFailed
+	p.result.Error = err.Error()
+}
+
+// writeDay stores the planned day and keeps on the result what rolling it
+// back needs.
+func (s *templateColorTimeService) writeDay(ctx context.Context, ac *applyContext, plan *dayPlan, userID string) error {
+
+	if plan.existing != nil {
+		before := *plan.existing
+		updated := *plan.existing
+		updated.TimeSlots = plan.blocks
+		updated.Source = templateSource(plan.template, plan.appliedAt)
+		updated.UpdatedAt = time.Now()
+		if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, updated.ID, &updated); err != nil {
+			return fmt.Errorf("failed to update default color time: %w", err)
+		}
+
+		plan.result.DayID = &updated.ID
+		plan.result.Before = &before
+		plan.result.AppliedVersion = updated.Version
+	} else {
+		// Create new default colortime by copying template structure
+		defaultColorTime := &default_colortime.DefaultDayColorTime{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: ac.organizationID,
+			Date:           plan.date,
+			TimeSlots:      plan.blocks,
+			Source:         templateSource(plan.template, plan.appliedAt),
+			CreatedBy:      userID,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+			IsBaseTemplate: false,
+			RepeatType:     "none",
+		}
+
+		if err := s.DefaultColorTimeRepository.CreateDefaultDayColorTime(ctx, defaultColorTime); err != nil {
+			return fmt.Errorf("failed to create default color time: %w", err)
 		}
+
+		plan.result.DayID = &defaultColorTime.ID
+		plan.result.AppliedVersion = defaultColorTime.Version
 	}
 
+	plan.result.Status = ApplyStatusApplied
 	return nil
 }
 
+// ReapplyTemplateColorTime pushes the changes made to a template since it was
+// applied to the default days it was applied to. Only the changed, added and
+// removed template slots are rewritten; days edited by hand since the template
+// was applied are skipped and reported.
+func (s *templateColorTimeService) ReapplyTemplateColorTime(ctx context.Context, templateColorTimeID string, req *ReapplyTemplateColorTimeRequest) (*ReapplyTemplateColorTimeResponse, error) {
+
+	templateObjectID, err := primitive.ObjectIDFromHex(templateColorTimeID)
+	if err != nil {
+		return nil, errors.New("invalid template color time id format")
+	}
+
+	var from *time.Time
+	if req.FromDate != "" {
+		fromDate, err := time.Parse("2006-01-02", req.FromDate)
+		if err != nil {
+			return nil, errors.New("failed to parse from date")
+		}
+		from = &fromDate
+	}
+
+	template, err := s.TemplateColorTimeRepository.GetTemplateColorTimeByID(ctx, templateObjectID)
+	if err != nil {
+		return nil, errors.New("failed to get template color time")
+	}
+
+	if template == nil {
+		return nil, errors.New("template color time not found")
+	}
+
+	days, err := s.DefaultColorTimeRepository.GetDefaultDayColorTimesByTemplate(ctx, template.ID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default days of template: %w", err)
+	}
+
+	report := &ReapplyTemplateColorTimeResponse{
+		TemplateID: template.ID,
+		DryRun:     req.DryRun,
+		Summary:    &ReapplySummary{},
+		Days:       []*ApplyDayResult{},
+	}
+
+	var changedDates []time.Time
+	defer func() {
+		if len(changedDates) > 0 {
+			s.notifyChanged(ctx, template.OrganizationID, changedDates)
+		}
+	}()
+
+	now := time.Now()
+
+	for _, day := range days {
+		dayID := day.ID
+		result := &ApplyDayResult{
+			Date:    day.Date.Format("2006-01-02"),
+			Weekday: strings.ToLower(template.Date),
+			Status:  ApplyStatusPlanned,
+			Added:   []*SlotChange{},
+			Removed: []*SlotChange{},
+			Changed: []*SlotChange{},
+			DayID:   &dayID,
+		}
+		report.Days = append(report.Days, result)
+
+		if day.CustomizedSinceApplied() {
+			result.Action = ApplyActionCustomized
+			report.Summary.Customized++
+			continue
+		}
+
+		blocks, err := reapplyBlocks(day.TimeSlots, template, day.Source.AppliedAt, now)
+		if err != nil {
+			result.Status = ApplyStatusFailed
+			result.Error = err.Error()
+			report.Summary.Failed++
+			continue
+		}
+
+		result.Added, result.Removed, result.Changed = diffBlocks(day.TimeSlots, blocks)
+		if len(result.Added)+len(result.Removed)+len(result.Changed) == 0 {
+			result.Action = ApplyActionUnchanged
+			report.Summary.Unchanged++
+			continue
+		}
+
+		result.Action = ApplyActionReapply
+		if req.DryRun {
+			report.Summary.Reapplied++
+			continue
+		}
+
+		day.TimeSlots = blocks
+		day.Source = templateSource(template, now)
+		day.UpdatedAt = now
+		if err := s.DefaultColorTimeRepository.UpdateDefaultDayColorTime(ctx, day.ID, day); err != nil {
+			result.Status = ApplyStatusFailed
+			result.Error = fmt.Sprintf("failed to update default color time: %v", err)
+			report.Summary.Failed++
+			continue
+		}
+
+		result.Status = ApplyStatusApplied
+		report.Summary.Reapplied++
+		changedDates = append(changedDates, day.Date)
+	}
+
+	return report, nil
+}
+
 func (s *templateColorTimeService) CopySlotToTemplateColorTime(ctx context.Context, blockID string, req *CopySlotToTemplateColorTimeRequest, userID string) error {
 
 	if req.OrganizationID == "" {
@@ -0,0 +1,23 @@
+package term
+
+import (
+	"colortime-service/pkg/consul"
+	"colortime-service/pkg/consul/consultest"
+	"testing"
+)
+
+func TestDecodeTermContract(t *testing.T) {
+	consultest.RunDecodeCases(t, decodeTerm, []consultest.DecodeCase[*TermInfor]{
+		{
+			Fixture: "term.json",
+			Want: &TermInfor{
+				ID:        "6651a0b1c2d3e4f5a6b7c8d9",
+				StartDate: "2024-09-02T00:00:00Z",
+				EndDate:   "2025-01-17T00:00:00Z",
+			},
+		},
+		{Fixture: "term_not_found.json", WantErr: consul.ErrNotFound},
+		{Fixture: "term_renamed_dates.json", WantErr: consul.ErrMalformed},
+		{Fixture: "term_not_json.json", WantErr: consul.ErrMalformed},
+	})
+}
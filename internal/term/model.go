@@ -1,7 +1,26 @@
 package term
 
+import "errors"
+
 type TermInfor struct {
 	ID   string `json:"id"`
 	StartDate string `json:"start_date"`
 	EndDate   string `json:"end_date"`
 }
+
+// termData is a term as returned by term-service.
+type termData struct {
+	ID        string `json:"id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+func (d *termData) Validate() error {
+	if d.ID == "" {
+		return errors.New("term id is missing")
+	}
+	if d.StartDate == "" || d.EndDate == "" {
+		return errors.New("term start_date or end_date is missing")
+	}
+	return nil
+}
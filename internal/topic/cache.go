@@ -0,0 +1,118 @@
+package topic
+
+import (
+	"sync"
+	"time"
+)
+
+// topicCache keeps topics and their vocabularies for a fixed time, shared by
+// every request. Entries are dropped when they expire or are invalidated, and
+// when a map is full its expired entries go first, then the oldest ones.
+type topicCache struct {
+	mu           sync.Mutex
+	ttl          time.Duration
+	maxEntries   int
+	now          func() time.Time
+	topics       map[string]*topicCacheEntry[*Topic]
+	vocabularies map[string]*topicCacheEntry[[]*Vocabulary]
+}
+
+type topicCacheEntry[T any] struct {
+	value    T
+	storedAt time.Time
+}
+
+func newTopicCache(ttl time.Duration, maxEntries int) *topicCache {
+	return &topicCache{
+		ttl:          ttl,
+		maxEntries:   maxEntries,
+		now:          time.Now,
+		topics:       make(map[string]*topicCacheEntry[*Topic]),
+		vocabularies: make(map[string]*topicCacheEntry[[]*Vocabulary]),
+	}
+}
+
+func (c *topicCache) getTopic(topicID string) (*Topic, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return cacheGet(c, c.topics, topicID)
+}
+
+func (c *topicCache) setTopic(topicID string, topic *Topic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cacheSet(c, c.topics, topicID, topic)
+}
+
+func (c *topicCache) getVocabularies(topicID string) ([]*Vocabulary, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return cacheGet(c, c.vocabularies, topicID)
+}
+
+func (c *topicCache) setVocabularies(topicID string, vocabularies []*Vocabulary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cacheSet(c, c.vocabularies, topicID, vocabularies)
+}
+
+// invalidate drops the topic and its vocabularies, or everything when topicID
+// is empty.
+func (c *topicCache) invalidate(topicID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if topicID == "" {
+		c.topics = make(map[string]*topicCacheEntry[*Topic])
+		c.vocabularies = make(map[string]*topicCacheEntry[[]*Vocabulary])
+		return
+	}
+
+	delete(c.topics, topicID)
+	delete(c.vocabularies, topicID)
+}
+
+// cacheGet and cacheSet work on one of the maps of c, with its lock held.
+func cacheGet[T any](c *topicCache, entries map[string]*topicCacheEntry[T], key string) (T, bool) {
+	var zero T
+
+	entry, ok := entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	if c.now().Sub(entry.storedAt) > c.ttl {
+		delete(entries, key)
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+func cacheSet[T any](c *topicCache, entries map[string]*topicCacheEntry[T], key string, value T) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	now := c.now()
+	if _, ok := entries[key]; !ok && len(entries) >= c.maxEntries {
+		for k, entry := range entries {
+			if now.Sub(entry.storedAt) > c.ttl {
+				delete(entries, k)
+			}
+		}
+
+		for len(entries) >= c.maxEntries {
+			var oldestKey string
+			var oldest time.Time
+			for k, entry := range entries {
+				if oldestKey == "" || entry.storedAt.Before(oldest) {
+					oldestKey, oldest = k, entry.storedAt
+				}
+			}
+			delete(entries, oldestKey)
+		}
+	}
+
+	entries[key] = &topicCacheEntry[T]{value: value, storedAt: now}
+}
@@ -0,0 +1,124 @@
+package topic
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is the time of a topicCache, moved by hand.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestCache(ttl time.Duration, maxEntries int) (*topicCache, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)}
+	cache := newTopicCache(ttl, maxEntries)
+	cache.now = clock.Now
+	return cache, clock
+}
+
+func TestTopicCacheExpiry(t *testing.T) {
+	cache, clock := newTestCache(time.Minute, 10)
+	cache.setTopic("t1", &Topic{ID: "t1"})
+	cache.setVocabularies("t1", []*Vocabulary{{ID: "v1"}})
+
+	clock.now = clock.now.Add(time.Minute)
+	if _, ok := cache.getTopic("t1"); !ok {
+		t.Fatal("topic expired at its TTL, want it kept until the TTL has passed")
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if _, ok := cache.getTopic("t1"); ok {
+		t.Fatal("topic still cached after its TTL")
+	}
+	if _, ok := cache.getVocabularies("t1"); ok {
+		t.Fatal("vocabularies still cached after their TTL")
+	}
+	if len(cache.topics) != 0 {
+		t.Fatalf("expired topic left in the map: %d entries", len(cache.topics))
+	}
+}
+
+func TestTopicCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		// age of each entry already stored when t4 is added
+		stored map[string]time.Duration
+		want   []string
+		gone   []string
+	}{
+		{
+			name:   "oldest goes when nothing expired",
+			stored: map[string]time.Duration{"t1": 30 * time.Second, "t2": 20 * time.Second, "t3": 10 * time.Second},
+			want:   []string{"t2", "t3", "t4"},
+			gone:   []string{"t1"},
+		},
+		{
+			name:   "expired entries go first",
+			stored: map[string]time.Duration{"t1": 50 * time.Second, "t2": 2 * time.Minute, "t3": 10 * time.Second},
+			want:   []string{"t1", "t3", "t4"},
+			gone:   []string{"t2"},
+		},
+		{
+			name:   "every expired entry goes",
+			stored: map[string]time.Duration{"t1": 3 * time.Minute, "t2": 2 * time.Minute, "t3": 10 * time.Second},
+			want:   []string{"t3", "t4"},
+			gone:   []string{"t1", "t2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, clock := newTestCache(time.Minute, 3)
+			start := clock.now
+			for id, age := range tt.stored {
+				clock.now = start.Add(-age)
+				cache.setTopic(id, &Topic{ID: id})
+			}
+
+			clock.now = start
+			cache.setTopic("t4", &Topic{ID: "t4"})
+
+			for _, id := range tt.want {
+				if _, ok := cache.topics[id]; !ok {
+					t.Errorf("%s was evicted", id)
+				}
+			}
+			for _, id := range tt.gone {
+				if _, ok := cache.topics[id]; ok {
+					t.Errorf("%s was kept", id)
+				}
+			}
+		})
+	}
+}
+
+func TestTopicCacheFullOverwrite(t *testing.T) {
+	cache, _ := newTestCache(time.Minute, 2)
+	cache.setTopic("t1", &Topic{ID: "t1", Name: "old"})
+	cache.setTopic("t2", &Topic{ID: "t2"})
+
+	// Storing a cached topic again makes no room
+	cache.setTopic("t1", &Topic{ID: "t1", Name: "new"})
+
+	if topic, ok := cache.getTopic("t1"); !ok || topic.Name != "new" {
+		t.Fatalf("t1 = %+v, %v, want the new topic", topic, ok)
+	}
+	if _, ok := cache.getTopic("t2"); !ok {
+		t.Fatal("t2 was evicted by overwriting t1")
+	}
+}
+
+func TestTopicCacheDisabled(t *testing.T) {
+	for name, cache := range map[string]*topicCache{
+		"zero ttl":  newTopicCache(0, 10),
+		"zero size": newTopicCache(time.Minute, 0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache.setTopic("t1", &Topic{ID: "t1"})
+			if _, ok := cache.getTopic("t1"); ok {
+				t.Fatal("disabled cache returned a topic")
+			}
+		})
+	}
+}
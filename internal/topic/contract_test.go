@@ -0,0 +1,38 @@
+package topic
+
+import (
+	"colortime-service/pkg/consul"
+	"colortime-service/pkg/consul/consultest"
+	"testing"
+)
+
+func TestDecodeTopicContract(t *testing.T) {
+	consultest.RunDecodeCases(t, decodeTopic, []consultest.DecodeCase[*Topic]{
+		{
+			Fixture: "topic.json",
+			Want: &Topic{
+				ID:           "6650f1c2a4e3b2d1c0f9e8a1",
+				Name:         "Animals on the farm",
+				MainImageUrl: "https://cdn.example.com/topics/animals.png",
+				VideoUrl:     "https://cdn.example.com/topics/animals.mp4",
+			},
+		},
+		{Fixture: "topic_not_found.json", WantErr: consul.ErrNotFound},
+		{Fixture: "topic_renamed_title.json", WantErr: consul.ErrMalformed},
+		{Fixture: "topic_wrong_type.json", WantErr: consul.ErrMalformed},
+	})
+}
+
+func TestDecodeVocabulariesContract(t *testing.T) {
+	consultest.RunDecodeCases(t, decodeVocabularies, []consultest.DecodeCase[[]*Vocabulary]{
+		{
+			Fixture: "vocabularies.json",
+			Want: []*Vocabulary{
+				{ID: "6650f2d0a4e3b2d1c0f9e8b1", Title: "Cow", MainImageUrl: "https://cdn.example.com/vocabularies/cow.png"},
+				{ID: "6650f2d0a4e3b2d1c0f9e8b2", Title: "Duck", MainImageUrl: "https://cdn.example.com/vocabularies/duck.png"},
+			},
+		},
+		{Fixture: "vocabularies_empty.json", Want: []*Vocabulary{}},
+		{Fixture: "vocabularies_not_a_list.json", WantErr: consul.ErrMalformed},
+	})
+}
@@ -0,0 +1,32 @@
+package topic
+
+import (
+	"colortime-service/helper"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TopicHandler struct {
+	TopicService TopicService
+}
+
+func NewTopicHandler(topicService TopicService) *TopicHandler {
+	return &TopicHandler{
+		TopicService: topicService,
+	}
+}
+
+// InvalidateTopic drops one topic from the cache. Called by media-service, or
+// by hand, when a topic or its vocabularies change.
+func (h *TopicHandler) InvalidateTopic(c *gin.Context) {
+	h.TopicService.InvalidateTopicCache(c.Param("topic_id"))
+
+	helper.SendSuccess(c, http.StatusOK, "topic cache invalidated successfully", nil)
+}
+
+func (h *TopicHandler) InvalidateAllTopics(c *gin.Context) {
+	h.TopicService.InvalidateTopicCache("")
+
+	helper.SendSuccess(c, http.StatusOK, "topic cache invalidated successfully", nil)
+}
@@ -1,5 +1,10 @@
 package topic
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Topic struct {
 	ID           string `json:"id" bson:"_id"`
 	Name         string `json:"name" bson:"name"`
@@ -12,3 +17,38 @@ type Vocabulary struct {
 	Title        string `json:"title" bson:"title"`
 	MainImageUrl string `json:"main_image_url" bson:"main_image_url"`
 }
+
+// topicData is a topic as returned by media-service.
+type topicData struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	MainImageUrl string `json:"main_image_url"`
+	VideoUrl     string `json:"video_url"`
+}
+
+func (d *topicData) Validate() error {
+	if d.ID == "" {
+		return errors.New("topic id is missing")
+	}
+	if d.Title == "" {
+		return errors.New("topic title is missing")
+	}
+	return nil
+}
+
+type vocabularyData struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	MainImageUrl string `json:"main_image_url"`
+}
+
+type vocabularyList []vocabularyData
+
+func (l vocabularyList) Validate() error {
+	for i, vocabulary := range l {
+		if vocabulary.ID == "" {
+			return fmt.Errorf("vocabulary %d: id is missing", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package topic
+
+import (
+	"colortime-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, topicHandler *TopicHandler) {
+	topic := r.Group("api/v1/topic-cache").Use(middleware.Secured())
+	{
+		topic.DELETE("", topicHandler.InvalidateAllTopics)
+		topic.DELETE("/:topic_id", topicHandler.InvalidateTopic)
+	}
+}
@@ -4,10 +4,9 @@ import (
 	"colortime-service/pkg/constants"
 	"colortime-service/pkg/consul"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/hashicorp/consul/api"
@@ -16,61 +15,35 @@ import (
 type TopicService interface {
 	GetTopicInfor(ctx context.Context, topicID string) (*Topic, error)
 	GetVocabularyInforByTopicID(ctx context.Context, topicID string) ([]*Vocabulary, error)
+	InvalidateTopicCache(topicID string)
 }
 
 type topicService struct {
-	client *callAPI
-}
-
-type callAPI struct {
-	client       consul.ServiceDiscovery
-	clientServer *api.CatalogService
+	client *consul.GatewayClient
+	cache  *topicCache
 }
 
 var (
 	mainService = "media-service"
 )
 
-func NewTopicService(client *api.Client) TopicService {
-	mainServiceAPI := NewServiceAPI(client, mainService)
-	return &topicService{
-		client: mainServiceAPI,
-	}
-}
-
-func NewServiceAPI(client *api.Client, serviceName string) *callAPI {
-	sd, err := consul.NewServiceDiscovery(client, serviceName)
+// NewTopicService creates the topic service. Topics and vocabularies are
+// cached for cacheTTL, at most cacheSize of each; zero disables the cache.
+func NewTopicService(client *api.Client, gatewayOptions consul.GatewayOptions, cacheTTL time.Duration, cacheSize int) TopicService {
+	gateway, err := consul.NewGatewayClient(client, mainService, gatewayOptions)
 	if err != nil {
-		fmt.Printf("Error creating service discovery: %v\n", err)
-		return nil
-	}
-
-	var service *api.CatalogService
-
-	for i := 0; i < 10; i++ {
-		service, err = sd.DiscoverService()
-		if err == nil && service != nil {
-			break
-		}
-		fmt.Printf("Waiting for service %s... retry %d/10\n", serviceName, i+1)
-		time.Sleep(3 * time.Second)
-	}
-
-	if service == nil {
-		fmt.Printf("Service %s not found after retries, continuing anyway...\n", serviceName)
+		fmt.Printf("Error creating gateway client for %s: %v\n", mainService, err)
 	}
 
-	if os.Getenv("LOCAL_TEST") == "true" {
-		fmt.Println("Running in LOCAL_TEST mode — overriding service address to localhost")
-		service.ServiceAddress = "localhost"
-	}
-
-	return &callAPI{
-		client:       sd,
-		clientServer: service,
+	return &topicService{
+		client: gateway,
+		cache:  newTopicCache(cacheTTL, cacheSize),
 	}
 }
 
+// GetTopicInfor returns the topic, or an error matching consul.ErrNotFound when
+// media-service has no such topic and consul.ErrMalformed when its response
+// does not match the expected schema.
 func (s *topicService) GetTopicInfor(ctx context.Context, topicID string) (*Topic, error) {
 
 	token, ok := ctx.Value(constants.TokenKey).(string)
@@ -78,131 +51,104 @@ func (s *topicService) GetTopicInfor(ctx context.Context, topicID string) (*Topi
 		return nil, fmt.Errorf("token not found in context")
 	}
 
-	topictRes, err := s.client.getTopicInfor(topicID, token)
-	if err != nil {
-		return nil, err
+	if topic, ok := s.cache.getTopic(topicID); ok {
+		return topic, nil
 	}
 
-	rawData, ok := topictRes["data"]
-	if !ok || rawData == nil {
-		return nil, nil
+	res, err := s.call(ctx, fmt.Sprintf("/api/v2/gateway/topics/%s", topicID), token)
+	if err != nil {
+		return nil, err
 	}
 
-	topic, ok := rawData.(map[string]interface{})
-	if !ok {
-		return nil, nil
+	topic, err := decodeTopic(res)
+	if err != nil {
+		return nil, fmt.Errorf("topic %s: %w", topicID, err)
 	}
 
-	name, _ := topic["title"].(string)
-	id, _ := topic["id"].(string)
-	mainImageUrl, _ := topic["main_image_url"].(string)
-	videoUrl, _ := topic["video_url"].(string)
+	s.cache.setTopic(topicID, topic)
 
-	return &Topic{
-		ID:           id,
-		Name:         name,
-		MainImageUrl: mainImageUrl,
-		VideoUrl:     videoUrl,
-	}, nil
+	return topic, nil
 }
 
+// GetVocabularyInforByTopicID returns the vocabularies of the topic, empty when
+// the topic has none.
 func (s *topicService) GetVocabularyInforByTopicID(ctx context.Context, topicID string) ([]*Vocabulary, error) {
 	token, ok := ctx.Value(constants.TokenKey).(string)
 	if !ok {
 		return nil, fmt.Errorf("token not found in context")
 	}
 
-	vocabularyRes, err := s.client.getVocabularyInforByTopicID(topicID, token)
+	if vocabularies, ok := s.cache.getVocabularies(topicID); ok {
+		return vocabularies, nil
+	}
+
+	res, err := s.call(ctx, fmt.Sprintf("/api/v2/gateway/topics/%s/vocabularies", topicID), token)
 	if err != nil {
 		return nil, err
 	}
 
-	var vocabularies []*Vocabulary
-	for _, item := range vocabularyRes {
-		vocabMap, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		vocab := &Vocabulary{}
-
-		if id, ok := vocabMap["id"].(string); ok {
-			vocab.ID = id
-		}
-
-		if title, ok := vocabMap["title"].(string); ok {
-			vocab.Title = title
-		}
-
-		if mainImageUrl, ok := vocabMap["main_image_url"].(string); ok {
-			vocab.MainImageUrl = mainImageUrl
-		}
-
-		vocabularies = append(vocabularies, vocab)
+	vocabularies, err := decodeVocabularies(res)
+	if err != nil {
+		return nil, fmt.Errorf("vocabularies of topic %s: %w", topicID, err)
 	}
 
+	s.cache.setVocabularies(topicID, vocabularies)
+
 	return vocabularies, nil
 }
 
-func (c *callAPI) getVocabularyInforByTopicID(topicID, token string) ([]interface{}, error) {
-	endpoint := fmt.Sprintf("/api/v2/gateway/topics/%s/vocabularies", topicID)
+// InvalidateTopicCache drops the cached topic and vocabularies, or the whole
+// cache when topicID is empty, so the next lookup reads media-service again.
+func (s *topicService) InvalidateTopicCache(topicID string) {
+	s.cache.invalidate(topicID)
+}
 
+func (s *topicService) call(ctx context.Context, endpoint, token string) ([]byte, error) {
 	header := map[string]string{
 		"Content-Type":  "application/json",
 		"Authorization": "Bearer " + token,
 	}
 
-	res, err := c.client.CallAPI(c.clientServer, endpoint, http.MethodGet, nil, header)
+	res, err := s.client.Call(ctx, http.MethodGet, endpoint, nil, header)
 	if err != nil {
 		fmt.Printf("Error calling API: %v\n", err)
 		return nil, err
 	}
 
-	var apiResponse map[string]interface{}
-	err = json.Unmarshal([]byte(res), &apiResponse)
+	return res, nil
+}
+
+func decodeTopic(res []byte) (*Topic, error) {
+	data, err := consul.DecodeData[topicData](res)
 	if err != nil {
-		fmt.Printf("Error unmarshalling response: %v\n", err)
 		return nil, err
 	}
 
-	rawData, ok := apiResponse["data"]
-	if !ok || rawData == nil {
-		return nil, nil
-	}
-
-	vocabularyData, ok := rawData.([]interface{})
-	if !ok {
-		return nil, nil
-	}
-	fmt.Println("vocabularyData", vocabularyData)
-	return vocabularyData, nil
+	return &Topic{
+		ID:           data.ID,
+		Name:         data.Title,
+		MainImageUrl: data.MainImageUrl,
+		VideoUrl:     data.VideoUrl,
+	}, nil
 }
 
-func (c *callAPI) getTopicInfor(topicID, token string) (map[string]interface{}, error) {
-
-	endpoint := fmt.Sprintf("/api/v2/gateway/topics/%s", topicID)
-
-	header := map[string]string{
-		"Content-Type":  "application/json",
-		"Authorization": "Bearer " + token,
+func decodeVocabularies(res []byte) ([]*Vocabulary, error) {
+	data, err := consul.DecodeData[vocabularyList](res)
+	if errors.Is(err, consul.ErrNotFound) {
+		return []*Vocabulary{}, nil
 	}
-
-	res, err := c.client.CallAPI(c.clientServer, endpoint, http.MethodGet, nil, header)
 	if err != nil {
-		fmt.Printf("Error calling API: %v\n", err)
 		return nil, err
 	}
 
-	var topicData interface{}
-
-	err = json.Unmarshal([]byte(res), &topicData)
-	if err != nil {
-		fmt.Printf("Error unmarshalling response: %v\n", err)
-		return nil, err
+	vocabularies := make([]*Vocabulary, 0, len(data))
+	for _, item := range data {
+		vocabularies = append(vocabularies, &Vocabulary{
+			ID:           item.ID,
+			Title:        item.Title,
+			MainImageUrl: item.MainImageUrl,
+		})
 	}
 
-	myMap := topicData.(map[string]interface{})
-
-	return myMap, nil
-
+	return vocabularies, nil
 }
@@ -0,0 +1,86 @@
+package user
+
+import (
+	"colortime-service/pkg/consul"
+	"colortime-service/pkg/consul/consultest"
+	"errors"
+	"testing"
+)
+
+func TestDecodeUserContract(t *testing.T) {
+	consultest.RunDecodeCases(t, decodeUser, []consultest.DecodeCase[*UserInfor]{
+		{
+			Fixture: "student.json",
+			Want: &UserInfor{
+				UserID:         "6653c1d2e3f4a5b6c7d8e9f0",
+				UserName:       "Nguyen Minh An",
+				OrganizationID: "6650f0aab1c2d3e4f5a6b7c8",
+				Avartar: Avatar{
+					ImageID:  5012,
+					ImageKey: "avatars/an.png",
+					ImageUrl: "https://cdn.example.com/avatars/an.png",
+					IsMain:   true,
+				},
+			},
+		},
+		{
+			Fixture: "user_without_avatar.json",
+			Want:    &UserInfor{UserID: "6653c1d2e3f4a5b6c7d8e9f1", UserName: "Tran Thi Binh"},
+		},
+		{Fixture: "user_not_found.json", WantErr: consul.ErrNotFound},
+		{Fixture: "user_renamed_name.json", WantErr: consul.ErrMalformed},
+	})
+}
+
+func TestDecodeUsersContract(t *testing.T) {
+	consultest.RunDecodeCases(t, decodeUsers, []consultest.DecodeCase[[]*UserInfor]{
+		{
+			Fixture: "teachers.json",
+			Want:    []*UserInfor{{UserID: "6653c1d2e3f4a5b6c7d8ea01", UserName: "Le Van Cuong", OrganizationID: "6650f0aab1c2d3e4f5a6b7c8"}},
+		},
+	})
+}
+
+func TestDecodeGroupContract(t *testing.T) {
+	got, err := decodeGroup(consultest.LoadFixture(t, "group.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.GroupID != "6654d1e2f3a4b5c6d7e8f901" || got.GroupName != "Sunflower class" || len(got.Members) != 2 {
+		t.Fatalf("unexpected group %+v", got)
+	}
+
+	for _, member := range got.Members {
+		if member.OrganizationID != "6650f0aab1c2d3e4f5a6b7c8" {
+			t.Fatalf("member %s should carry the group organization, got %q", member.UserID, member.OrganizationID)
+		}
+	}
+
+	if !got.HasMember("6653c1d2e3f4a5b6c7d8e9f1") {
+		t.Fatalf("group should have member 6653c1d2e3f4a5b6c7d8e9f1")
+	}
+
+	if _, err := decodeGroup(consultest.LoadFixture(t, "group_member_without_id.json")); !errors.Is(err, consul.ErrMalformed) {
+		t.Fatalf("error = %v, want %v", err, consul.ErrMalformed)
+	}
+
+	if _, err := decodeGroup(consultest.LoadFixture(t, "user_not_found.json")); !errors.Is(err, consul.ErrNotFound) {
+		t.Fatalf("error = %v, want %v", err, consul.ErrNotFound)
+	}
+}
+
+func TestDecodeCurrentUserContract(t *testing.T) {
+	got, err := consul.DecodeData[CurrentUser](consultest.LoadFixture(t, "current_user.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.ID != "6653c1d2e3f4a5b6c7d8ea01" || got.OrganizationIdActive != "6650f0aab1c2d3e4f5a6b7c8" {
+		t.Fatalf("unexpected current user %+v", got)
+	}
+
+	if got.Roles == nil || len(*got.Roles) != 1 || (*got.Roles)[0].RoleName != "teacher" {
+		t.Fatalf("unexpected roles %+v", got.Roles)
+	}
+}
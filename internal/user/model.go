@@ -1,12 +1,10 @@
 package user
 
-import "time"
-
-type APIGateWayResponse[T any] struct {
-	StatusCode int    `json:"status_code"`
-	Message    string `json:"message"`
-	Data       T      `json:"data"`
-}
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 type UserInfor struct {
 	UserID         string          `json:"user_id"`
@@ -63,3 +61,90 @@ type OrganizationAdmin struct {
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
+
+type GroupInfor struct {
+	GroupID        string       `json:"group_id"`
+	GroupName      string       `json:"group_name"`
+	OrganizationID string       `json:"organization_id"`
+	Members        []*UserInfor `json:"members"`
+}
+
+func (g *GroupInfor) HasMember(userID string) bool {
+	for _, member := range g.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *CurrentUser) Validate() error {
+	if u.ID == "" {
+		return errors.New("user id is missing")
+	}
+	return nil
+}
+
+// userData is a user, student, teacher or staff as returned by the main
+// service.
+type userData struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	OrganizationID string `json:"organization_id"`
+	Avatar         Avatar `json:"avatar"`
+}
+
+func (d *userData) Validate() error {
+	if d.ID == "" {
+		return errors.New("user id is missing")
+	}
+	if d.Name == "" {
+		return errors.New("user name is missing")
+	}
+	return nil
+}
+
+func (d *userData) userInfor() *UserInfor {
+	return &UserInfor{
+		UserID:         d.ID,
+		UserName:       d.Name,
+		OrganizationID: d.OrganizationID,
+		Avartar:        d.Avatar,
+	}
+}
+
+type userList []userData
+
+func (l userList) Validate() error {
+	for i := range l {
+		if err := l[i].Validate(); err != nil {
+			return fmt.Errorf("user %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+type groupData struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	OrganizationID string            `json:"organization_id"`
+	Members        []groupMemberData `json:"members"`
+}
+
+type groupMemberData struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Avatar Avatar `json:"avatar"`
+}
+
+func (d *groupData) Validate() error {
+	if d.ID == "" {
+		return errors.New("group id is missing")
+	}
+	for i, member := range d.Members {
+		if member.ID == "" {
+			return fmt.Errorf("member %d: id is missing", i)
+		}
+	}
+	return nil
+}
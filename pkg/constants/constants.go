@@ -117,5 +117,6 @@ func (c contextKey) String() string {
 }
 
 var (
-	TokenKey = contextKey("token")
+	TokenKey          = contextKey("token")
+	IfMatchVersionKey = contextKey("if_match_version")
 )
@@ -0,0 +1,76 @@
+package consul
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+)
+
+// Balancer picks the instance a call goes to. Pick is called with the
+// discovery lock held and at least one instance.
+type Balancer interface {
+	Pick(instances []*Instance) *Instance
+}
+
+// NewBalancer returns the balancer named round_robin, random or
+// least_outstanding.
+func NewBalancer(name string) (Balancer, error) {
+	switch name {
+	case "", "round_robin":
+		return RoundRobin(), nil
+	case "random":
+		return Random(), nil
+	case "least_outstanding":
+		return LeastOutstanding(), nil
+	}
+	return nil, fmt.Errorf("unknown balancer %q", name)
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+// RoundRobin picks the instances in turn.
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+func (b *roundRobin) Pick(instances []*Instance) *Instance {
+	return instances[(b.next.Add(1)-1)%uint64(len(instances))]
+}
+
+type random struct{}
+
+// Random picks an instance at random.
+func Random() Balancer {
+	return random{}
+}
+
+func (random) Pick(instances []*Instance) *Instance {
+	return instances[rand.Intn(len(instances))]
+}
+
+type leastOutstanding struct{}
+
+// LeastOutstanding picks the instance with the fewest calls in flight, at
+// random among ties.
+func LeastOutstanding() Balancer {
+	return leastOutstanding{}
+}
+
+func (leastOutstanding) Pick(instances []*Instance) *Instance {
+	var best *Instance
+	ties := 0
+	for _, instance := range instances {
+		switch {
+		case best == nil || instance.outstanding < best.outstanding:
+			best, ties = instance, 1
+		case instance.outstanding == best.outstanding:
+			ties++
+			if rand.Intn(ties) == 0 {
+				best = instance
+			}
+		}
+	}
+	return best
+}
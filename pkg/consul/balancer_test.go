@@ -0,0 +1,97 @@
+package consul
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testInstances(ids ...string) []*Instance {
+	instances := make([]*Instance, 0, len(ids))
+	for _, id := range ids {
+		instances = append(instances, &Instance{ID: id})
+	}
+	return instances
+}
+
+func pickIDs(balancer Balancer, instances []*Instance, n int) []string {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ids = append(ids, balancer.Pick(instances).ID)
+	}
+	return ids
+}
+
+func TestNewBalancer(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Balancer
+		wantErr bool
+	}{
+		{name: "", want: &roundRobin{}},
+		{name: "round_robin", want: &roundRobin{}},
+		{name: "random", want: random{}},
+		{name: "least_outstanding", want: leastOutstanding{}},
+		{name: "weighted", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBalancer(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewBalancer(%q) = %T, want an error", tt.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewBalancer(%q): %v", tt.name, err)
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Fatalf("NewBalancer(%q) = %T, want %T", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	got := pickIDs(RoundRobin(), testInstances("a", "b", "c"), 7)
+	want := []string{"a", "b", "c", "a", "b", "c", "a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("picks = %v, want %v", got, want)
+	}
+}
+
+func TestRandomPicksEveryInstance(t *testing.T) {
+	instances := testInstances("a", "b", "c")
+
+	seen := make(map[string]bool)
+	for _, id := range pickIDs(Random(), instances, 300) {
+		seen[id] = true
+	}
+	if len(seen) != len(instances) {
+		t.Fatalf("picked %v out of 300 calls, want every instance", seen)
+	}
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	instances := testInstances("a", "b", "c")
+	instances[0].outstanding = 2
+	instances[1].outstanding = 1
+	instances[2].outstanding = 3
+
+	for _, id := range pickIDs(LeastOutstanding(), instances, 10) {
+		if id != "b" {
+			t.Fatalf("picked %s, want b, the instance with the fewest calls in flight", id)
+		}
+	}
+
+	// Ties are spread at random
+	instances[0].outstanding = 1
+	seen := make(map[string]bool)
+	for _, id := range pickIDs(LeastOutstanding(), instances, 200) {
+		seen[id] = true
+	}
+	if !reflect.DeepEqual(seen, map[string]bool{"a": true, "b": true}) {
+		t.Fatalf("picked %v among tied a and b", seen)
+	}
+}
@@ -0,0 +1,54 @@
+// Package consultest holds what the contract tests of the upstream service
+// clients share: reading recorded responses and checking how they decode.
+package consultest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// LoadFixture reads testdata/name of the package under test.
+func LoadFixture(t testing.TB, name string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+	return body
+}
+
+// DecodeCase is a fixture and either what it decodes to or the error decoding
+// it must wrap, such as consul.ErrNotFound or consul.ErrMalformed.
+type DecodeCase[T any] struct {
+	Fixture string
+	Want    T
+	WantErr error
+}
+
+// RunDecodeCases decodes the fixture of every case with decode, each in its
+// own subtest.
+func RunDecodeCases[T any](t *testing.T, decode func(body []byte) (T, error), cases []DecodeCase[T]) {
+	t.Helper()
+
+	for _, tt := range cases {
+		t.Run(tt.Fixture, func(t *testing.T) {
+			got, err := decode(LoadFixture(t, tt.Fixture))
+			if tt.WantErr != nil {
+				if !errors.Is(err, tt.WantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.WantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.Want) {
+				t.Fatalf("got %+v, want %+v", got, tt.Want)
+			}
+		})
+	}
+}
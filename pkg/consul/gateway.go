@@ -0,0 +1,364 @@
+package consul
+
+import (
+	"bytes"
+	"colortime-service/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+var (
+	// ErrCircuitOpen is returned without calling the service while its circuit
+	// breaker is open.
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+
+	// ErrNoInstance is returned when Consul has no instance of the service.
+	ErrNoInstance = errors.New("no service instance available")
+)
+
+// StatusError is returned when a service answers with a non-2xx status.
+type StatusError struct {
+	Service    string
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s %s: status %d: %s", e.Service, e.Method, e.Endpoint, e.StatusCode, bytes.TrimSpace(e.Body))
+}
+
+// NotFound reports whether the service answered 404.
+func (e *StatusError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// Temporary reports whether the same request may succeed later.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// GatewayOptions configures how a GatewayClient calls its service.
+type GatewayOptions struct {
+	Timeout          time.Duration // per attempt
+	MaxAttempts      int           // attempts of an idempotent request, 1 for other requests
+	BaseBackoff      time.Duration // wait before the first retry, doubled on every retry
+	MaxBackoff       time.Duration
+	FailureThreshold int           // consecutive failures that open the circuit breaker
+	OpenTimeout      time.Duration // how long the breaker stays open before letting a trial call through
+	Discovery        DiscoveryOptions
+}
+
+// NewGatewayOptions reads the gateway options from the configuration.
+func NewGatewayOptions(cfg *config.Config) GatewayOptions {
+	return GatewayOptions{
+		Timeout:          time.Duration(cfg.GatewayTimeoutMs) * time.Millisecond,
+		MaxAttempts:      cfg.GatewayMaxAttempts,
+		BaseBackoff:      time.Duration(cfg.GatewayBackoffMs) * time.Millisecond,
+		MaxBackoff:       time.Duration(cfg.GatewayMaxBackoffMs) * time.Millisecond,
+		FailureThreshold: cfg.GatewayFailureThreshold,
+		OpenTimeout:      time.Duration(cfg.GatewayOpenSeconds) * time.Second,
+		Discovery: DiscoveryOptions{
+			Balancer:      cfg.DiscoveryBalancer,
+			EjectFailures: cfg.DiscoveryEjectFailures,
+			EjectDuration: time.Duration(cfg.DiscoveryEjectSeconds) * time.Second,
+		},
+	}
+}
+
+// GatewayClient calls one Consul-discovered service. Every attempt goes to an
+// instance picked by the service discovery.
+type GatewayClient struct {
+	discovery   ServiceDiscovery
+	serviceName string
+	options     GatewayOptions
+	httpClient  *http.Client
+	breaker     *circuitBreaker
+	after       func(time.Duration) <-chan time.Time // waits between retries
+}
+
+// NewGatewayClient creates the client of serviceName. It does not wait for the
+// service to be registered.
+func NewGatewayClient(client *api.Client, serviceName string, options GatewayOptions) (*GatewayClient, error) {
+	sd, err := NewServiceDiscovery(client, serviceName, options.Discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = 1
+	}
+
+	return &GatewayClient{
+		discovery:   sd,
+		serviceName: serviceName,
+		options:     options,
+		httpClient:  &http.Client{},
+		breaker:     breakerFor(serviceName, options),
+		after:       time.After,
+	}, nil
+}
+
+// Call sends a request to the service and returns the body of a 2xx response.
+// Idempotent requests are retried with backoff on network errors, 5xx and 429.
+// A non-2xx response is returned as a *StatusError.
+func (g *GatewayClient) Call(ctx context.Context, method, endpoint string, body []byte, headers map[string]string) ([]byte, error) {
+	if g == nil {
+		return nil, fmt.Errorf("gateway client is not initialized: %w", ErrNoInstance)
+	}
+
+	attempts := 1
+	if isIdempotent(method) {
+		attempts = g.options.MaxAttempts
+	}
+
+	for attempt := 0; ; attempt++ {
+		if !g.breaker.allow() {
+			return nil, fmt.Errorf("%s: %w", g.serviceName, ErrCircuitOpen)
+		}
+
+		res, err := g.do(ctx, method, endpoint, body, headers)
+
+		switch {
+		case err == nil:
+			g.breaker.success()
+			return res, nil
+		case ctx.Err() != nil:
+			g.breaker.release()
+			return nil, err
+		case !isRetryable(err):
+			// The service answered, so it is up
+			g.breaker.success()
+			return nil, err
+		}
+
+		g.breaker.failure()
+
+		if attempt+1 >= attempts {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-g.after(g.backoff(attempt)):
+		}
+	}
+}
+
+func (g *GatewayClient) do(ctx context.Context, method, endpoint string, body []byte, headers map[string]string) (res []byte, err error) {
+	instance, err := g.discovery.DiscoverService()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", g.serviceName, ErrNoInstance, err)
+	}
+
+	// Network errors and 5xx count against the instance
+	defer func() {
+		var statusErr *StatusError
+		failed := err != nil && ctx.Err() == nil && (!errors.As(err, &statusErr) || statusErr.StatusCode >= http.StatusInternalServerError)
+		g.discovery.Release(instance, failed)
+	}()
+
+	attemptCtx := ctx
+	if g.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, g.options.Timeout)
+		defer cancel()
+	}
+
+	address := instance.Address
+	if os.Getenv("LOCAL_TEST") == "true" {
+		address = "localhost"
+	}
+
+	url := fmt.Sprintf("http://%s:%d%s", address, instance.Port, endpoint)
+	req, err := http.NewRequestWithContext(attemptCtx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s %s: %w", g.serviceName, method, endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s %s: failed to read response: %w", g.serviceName, method, endpoint, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{
+			Service:    g.serviceName,
+			Method:     method,
+			Endpoint:   endpoint,
+			StatusCode: resp.StatusCode,
+			Body:       bodyBytes,
+		}
+	}
+
+	return bodyBytes, nil
+}
+
+func (g *GatewayClient) backoff(attempt int) time.Duration {
+	wait := g.options.BaseBackoff << attempt
+	if wait <= 0 || (g.options.MaxBackoff > 0 && wait > g.options.MaxBackoff) {
+		wait = g.options.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	// Up to half of the wait is random so clients do not retry in step
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+
+	if errors.Is(err, ErrNoInstance) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops calls to a service after FailureThreshold consecutive
+// failures. After OpenTimeout one trial call is let through: its success
+// closes the breaker, its failure opens it again.
+type circuitBreaker struct {
+	mu          sync.Mutex
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+	state       int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+// breakerKey is a service and the breaker options its clients were created
+// with: clients of a service share a breaker only when they agree on when it
+// opens and for how long.
+type breakerKey struct {
+	serviceName string
+	threshold   int
+	openTimeout time.Duration
+}
+
+var (
+	breakers     = make(map[breakerKey]*circuitBreaker)
+	breakerMutex sync.Mutex
+)
+
+func breakerFor(serviceName string, options GatewayOptions) *circuitBreaker {
+	breakerMutex.Lock()
+	defer breakerMutex.Unlock()
+
+	key := breakerKey{serviceName: serviceName, threshold: options.FailureThreshold, openTimeout: options.OpenTimeout}
+	if b, exists := breakers[key]; exists {
+		return b
+	}
+
+	b := newCircuitBreaker(options.FailureThreshold, options.OpenTimeout)
+	breakers[key] = b
+	return b
+}
+
+func newCircuitBreaker(threshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, openTimeout: openTimeout, now: time.Now}
+}
+
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// release ends a call that was cancelled by the caller without counting it.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
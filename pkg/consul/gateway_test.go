@@ -0,0 +1,255 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a time moved by hand, for breakers and ejection windows.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	clock := newFakeClock()
+	b := newCircuitBreaker(3, 30*time.Second)
+	b.now = clock.Now
+
+	step := func(name string, want bool) {
+		t.Helper()
+		if got := b.allow(); got != want {
+			t.Fatalf("%s: allow() = %v, want %v", name, got, want)
+		}
+	}
+
+	// Closed: failures below the threshold keep it closed, a success resets them
+	b.failure()
+	b.failure()
+	step("closed below threshold", true)
+	b.success()
+	b.failure()
+	b.failure()
+	step("count reset by a success", true)
+
+	// Open
+	b.failure()
+	step("open", false)
+	clock.Advance(29 * time.Second)
+	step("open until the timeout", false)
+
+	// Half-open: one trial call at a time, a failed trial opens it again
+	clock.Advance(time.Second)
+	step("trial call", true)
+	step("second call during the trial", false)
+	b.failure()
+	step("reopened by the failed trial", false)
+	clock.Advance(29 * time.Second)
+	step("reopened for a full timeout", false)
+
+	// A cancelled trial lets the next call try
+	clock.Advance(time.Second)
+	step("trial call", true)
+	b.release()
+	step("trial after a cancelled one", true)
+
+	// A successful trial closes it
+	b.success()
+	step("closed", true)
+	b.failure()
+	b.failure()
+	step("closed with fresh failure count", true)
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.failure()
+	}
+	if !b.allow() {
+		t.Fatal("breaker without threshold opened")
+	}
+}
+
+func TestBreakerForKeysOnOptions(t *testing.T) {
+	options := GatewayOptions{FailureThreshold: 5, OpenTimeout: 30 * time.Second}
+
+	first := breakerFor("breaker-key-test", options)
+	if breakerFor("breaker-key-test", options) != first {
+		t.Fatal("clients with the same options got different breakers")
+	}
+
+	other := options
+	other.FailureThreshold = 2
+	if breakerFor("breaker-key-test", other) == first {
+		t.Fatal("client with another threshold shares the breaker")
+	}
+	if breakerFor("breaker-key-test-2", options) == first {
+		t.Fatal("another service shares the breaker")
+	}
+}
+
+// staticDiscovery always picks the same instance.
+type staticDiscovery struct {
+	instance *Instance
+}
+
+func (d *staticDiscovery) DiscoverService() (*Instance, error) { return d.instance, nil }
+
+func (d *staticDiscovery) Release(instance *Instance, failed bool) {}
+
+// testGateway points a client at a server answering the statuses in turn,
+// the last one for every call after them. Retry waits are recorded instead
+// of waited.
+func testGateway(t *testing.T, options GatewayOptions, statuses ...int) (*GatewayClient, *int, *[]time.Duration) {
+	t.Helper()
+	t.Setenv("LOCAL_TEST", "")
+
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[min(calls, len(statuses)-1)]
+		calls++
+		mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+
+	var waits []time.Duration
+	client := &GatewayClient{
+		discovery:   &staticDiscovery{instance: &Instance{ID: "test", Address: host, Port: portNumber}},
+		serviceName: "test-service",
+		options:     options,
+		httpClient:  server.Client(),
+		breaker:     newCircuitBreaker(options.FailureThreshold, options.OpenTimeout),
+		after: func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+			fired := make(chan time.Time, 1)
+			fired <- time.Time{}
+			return fired
+		},
+	}
+
+	return client, &calls, &waits
+}
+
+func TestGatewayRetries(t *testing.T) {
+	options := GatewayOptions{MaxAttempts: 4, BaseBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		method    string
+		statuses  []int
+		wantCalls int
+		wantErr   bool
+		notFound  bool
+	}{
+		{name: "succeeds after 5xx", method: http.MethodGet, statuses: []int{503, 500, 200}, wantCalls: 3},
+		{name: "429 is retried", method: http.MethodGet, statuses: []int{429, 200}, wantCalls: 2},
+		{name: "gives up after max attempts", method: http.MethodGet, statuses: []int{503}, wantCalls: 4, wantErr: true},
+		{name: "4xx is not retried", method: http.MethodGet, statuses: []int{404}, wantCalls: 1, wantErr: true, notFound: true},
+		{name: "POST is not retried", method: http.MethodPost, statuses: []int{503, 200}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, calls, waits := testGateway(t, options, tt.statuses...)
+
+			_, err := client.Call(context.Background(), tt.method, "/items", nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Call error = %v, want error %v", err, tt.wantErr)
+			}
+			var statusErr *StatusError
+			if tt.notFound && (!errors.As(err, &statusErr) || !statusErr.NotFound()) {
+				t.Fatalf("Call error = %v, want a 404 StatusError", err)
+			}
+			if *calls != tt.wantCalls {
+				t.Fatalf("%d calls, want %d", *calls, tt.wantCalls)
+			}
+			if len(*waits) != tt.wantCalls-1 {
+				t.Fatalf("%d waits for %d calls", len(*waits), *calls)
+			}
+		})
+	}
+}
+
+func TestGatewayBackoff(t *testing.T) {
+	options := GatewayOptions{MaxAttempts: 5, BaseBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	client, _, waits := testGateway(t, options, 503)
+
+	if _, err := client.Call(context.Background(), http.MethodGet, "/items", nil, nil); err == nil {
+		t.Fatal("Call succeeded against a failing service")
+	}
+
+	// Doubled from the base, capped, each with up to half of it random
+	full := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	if len(*waits) != len(full) {
+		t.Fatalf("waits = %v, want %d of them", *waits, len(full))
+	}
+	for i, wait := range *waits {
+		if wait < full[i]/2 || wait > full[i] {
+			t.Errorf("wait %d = %s, want between %s and %s", i, wait, full[i]/2, full[i])
+		}
+	}
+}
+
+func TestGatewayOpensCircuit(t *testing.T) {
+	options := GatewayOptions{MaxAttempts: 1, FailureThreshold: 2, OpenTimeout: 30 * time.Second}
+	client, calls, _ := testGateway(t, options, 503, 503, 200)
+
+	clock := newFakeClock()
+	client.breaker.now = clock.Now
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Call(context.Background(), http.MethodGet, "/items", nil, nil); err == nil {
+			t.Fatalf("call %d succeeded, want the 503", i+1)
+		}
+	}
+
+	_, err := client.Call(context.Background(), http.MethodGet, "/items", nil, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call with the circuit open = %v, want ErrCircuitOpen", err)
+	}
+	if *calls != 2 {
+		t.Fatalf("%d calls reached the service, want 2", *calls)
+	}
+
+	clock.Advance(30 * time.Second)
+	if _, err := client.Call(context.Background(), http.MethodGet, "/items", nil, nil); err != nil {
+		t.Fatalf("trial call after the open timeout: %v", err)
+	}
+	if !client.breaker.allow() {
+		t.Fatal("breaker still open after a successful trial")
+	}
+}
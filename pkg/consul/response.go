@@ -0,0 +1,77 @@
+package consul
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when the service has no data for the request:
+	// it answered 404 or its response has no data.
+	ErrNotFound = errors.New("not found")
+
+	// ErrMalformed is returned when a response does not match the expected
+	// schema: invalid JSON, a field of the wrong type or a missing required
+	// field.
+	ErrMalformed = errors.New("malformed response")
+)
+
+// APIGateWayResponse is the envelope of every gateway response.
+type APIGateWayResponse[T any] struct {
+	StatusCode int    `json:"status_code"`
+	Message    string `json:"message"`
+	Data       T      `json:"data"`
+}
+
+// Validator is implemented by response DTOs that check their required fields,
+// so a renamed upstream field is reported instead of read as empty.
+type Validator interface {
+	Validate() error
+}
+
+// Is makes a 404 StatusError match ErrNotFound.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotFound && e.NotFound()
+}
+
+// DecodeData decodes the data of a gateway response into T and validates it.
+// Fields unknown to T are ignored so upstream additions do not break decoding.
+func DecodeData[T any](body []byte) (T, error) {
+	var zero T
+
+	var envelope APIGateWayResponse[json.RawMessage]
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return zero, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	raw := bytes.TrimSpace(envelope.Data)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		if envelope.Message != "" {
+			return zero, fmt.Errorf("%w: %s", ErrNotFound, envelope.Message)
+		}
+		return zero, ErrNotFound
+	}
+
+	var data T
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return zero, fmt.Errorf("%w: data: %v", ErrMalformed, err)
+	}
+
+	if err := validate(&data); err != nil {
+		return zero, fmt.Errorf("%w: data: %v", ErrMalformed, err)
+	}
+
+	return data, nil
+}
+
+func validate[T any](data *T) error {
+	if v, ok := any(*data).(Validator); ok {
+		return v.Validate()
+	}
+	if v, ok := any(data).(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type testItem struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+func (i *testItem) Validate() error {
+	if i.ID == "" {
+		return errors.New("id is missing")
+	}
+	return nil
+}
+
+func TestDecodeData(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    testItem
+		wantErr error
+	}{
+		{name: "valid", body: `{"status_code":200,"message":"ok","data":{"id":"a","count":2}}`, want: testItem{ID: "a", Count: 2}},
+		{name: "unknown fields are ignored", body: `{"status_code":200,"data":{"id":"a","count":2,"extra":true}}`, want: testItem{ID: "a", Count: 2}},
+		{name: "null data", body: `{"status_code":404,"message":"not found","data":null}`, wantErr: ErrNotFound},
+		{name: "missing data", body: `{"status_code":200,"message":"ok"}`, wantErr: ErrNotFound},
+		{name: "invalid json", body: `<html>bad gateway</html>`, wantErr: ErrMalformed},
+		{name: "wrong field type", body: `{"data":{"id":"a","count":"2"}}`, wantErr: ErrMalformed},
+		{name: "data is not an object", body: `{"data":["a"]}`, wantErr: ErrMalformed},
+		{name: "required field renamed", body: `{"data":{"item_id":"a","count":2}}`, wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeData[testItem]([]byte(tt.body))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusErrorIsNotFound(t *testing.T) {
+	notFound := fmt.Errorf("call failed: %w", &StatusError{StatusCode: http.StatusNotFound})
+	if !errors.Is(notFound, ErrNotFound) {
+		t.Fatalf("404 status error should match ErrNotFound")
+	}
+
+	serverError := &StatusError{StatusCode: http.StatusInternalServerError}
+	if errors.Is(serverError, ErrNotFound) {
+		t.Fatalf("500 status error should not match ErrNotFound")
+	}
+}
@@ -1,26 +1,54 @@
 package consul
 
 import (
-	"bytes"
 	"fmt"
-	"io"
-	"math/rand"
-	"net/http"
 	"sync"
 	"time"
+
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/api/watch"
 )
 
 type ServiceDiscovery interface {
-	DiscoverService() (*api.CatalogService, error)
-	CallAPI(service *api.CatalogService, endpoint, method string, body []byte, headers map[string]string) (string, error)
+	// DiscoverService picks a passing, non-ejected instance of the service.
+	DiscoverService() (*Instance, error)
+	// Release reports the end of a call to an instance returned by
+	// DiscoverService; failed calls count towards ejecting the instance.
+	Release(instance *Instance, failed bool)
+}
+
+// Instance is one passing instance of a service.
+type Instance struct {
+	ID      string
+	Address string
+	Port    int
+
+	// Guarded by the serviceDiscovery lock
+	outstanding  int
+	failures     int
+	ejections    int
+	ejectedUntil time.Time
+}
+
+// DiscoveryOptions configures instance selection and outlier ejection.
+type DiscoveryOptions struct {
+	Balancer      string        // see NewBalancer
+	EjectFailures int           // consecutive failed calls that eject an instance, 0 disables ejection
+	EjectDuration time.Duration // first ejection, doubled for every ejection in a row
 }
 
 // serviceDiscovery - Struct to hold the Consul client and service name.
 type serviceDiscovery struct {
 	consulClient *api.Client
 	serviceName  string
+	options      DiscoveryOptions
+	balancer     Balancer
+	now          func() time.Time
 	once         sync.Once
+
+	mu        sync.Mutex
+	instances []*Instance
+	loaded    bool
 }
 
 // serviceDiscoveryMap - A map to store serviceDiscovery instances for each service name.
@@ -28,7 +56,8 @@ var serviceDiscoveryMap = make(map[string]*serviceDiscovery)
 var mapMutex sync.Mutex
 
 // NewServiceDiscovery - Constructor to initialize the serviceDiscovery with Consul client and service name.
-func NewServiceDiscovery(client *api.Client, serviceName string) (*serviceDiscovery, error) {
+// The first call for a service decides its options.
+func NewServiceDiscovery(client *api.Client, serviceName string, options DiscoveryOptions) (*serviceDiscovery, error) {
 	// Lock the map to avoid race condition while checking or inserting
 	mapMutex.Lock()
 	defer mapMutex.Unlock()
@@ -42,63 +71,139 @@ func NewServiceDiscovery(client *api.Client, serviceName string) (*serviceDiscov
 		return nil, fmt.Errorf("error while creating Consul client")
 	}
 
-	sd := &serviceDiscovery{consulClient: client, serviceName: serviceName}
+	balancer, err := NewBalancer(options.Balancer)
+	if err != nil {
+		return nil, err
+	}
 
-	// Use sync.Once to ensure that the serviceDiscovery setup runs only once
-	sd.once.Do(func() {
-		// Store the instance in the map
-		serviceDiscoveryMap[serviceName] = sd
-	})
+	sd := &serviceDiscovery{consulClient: client, serviceName: serviceName, options: options, balancer: balancer, now: time.Now}
+	serviceDiscoveryMap[serviceName] = sd
 
 	return sd, nil
 }
 
-// DiscoverService - Function to discover a service from Consul.
-func (sd *serviceDiscovery) DiscoverService() (*api.CatalogService, error) {
-	// Query the service in Consul
-	services, _, err := sd.consulClient.Catalog().Service(sd.serviceName, "", nil)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching service: %v", err)
+// DiscoverService - Function to pick an instance of the service. The passing
+// instances are loaded from Consul's health endpoint on first use, then kept
+// up to date by a blocking-query watch.
+func (sd *serviceDiscovery) DiscoverService() (*Instance, error) {
+	sd.once.Do(sd.watch)
+
+	sd.mu.Lock()
+	loaded := sd.loaded
+	sd.mu.Unlock()
+
+	if !loaded {
+		entries, _, err := sd.consulClient.Health().Service(sd.serviceName, "", true, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching service: %v", err)
+		}
+		sd.setInstances(entries)
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if len(sd.instances) == 0 {
+		return nil, fmt.Errorf("service %s has no passing instance in Consul", sd.serviceName)
+	}
+
+	now := sd.now()
+	candidates := make([]*Instance, 0, len(sd.instances))
+	for _, instance := range sd.instances {
+		if now.After(instance.ejectedUntil) {
+			candidates = append(candidates, instance)
+		}
+	}
+
+	// Every instance is ejected: better to try them than to fail every call
+	if len(candidates) == 0 {
+		candidates = sd.instances
+	}
+
+	instance := sd.balancer.Pick(candidates)
+	instance.outstanding++
+
+	return instance, nil
+}
+
+// Release - Function to record the outcome of a call to an instance.
+func (sd *serviceDiscovery) Release(instance *Instance, failed bool) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if instance.outstanding > 0 {
+		instance.outstanding--
 	}
 
-	if len(services) == 0 {
-		return nil, fmt.Errorf("service %s not found in Consul", sd.serviceName)
+	if !failed {
+		instance.failures = 0
+		if sd.now().After(instance.ejectedUntil) {
+			instance.ejections = 0
+		}
+		return
 	}
 
-	// Randomly select one service instance (can be enhanced for load balancing)
-	rand.New(rand.NewSource(time.Now().Unix()))
-	service := services[rand.Intn(len(services))]
-	return service, nil
+	instance.failures++
+	if sd.options.EjectFailures > 0 && instance.failures >= sd.options.EjectFailures {
+		instance.ejectedUntil = sd.now().Add(sd.options.EjectDuration << instance.ejections)
+		instance.ejections++
+		instance.failures = 0
+		fmt.Printf("Ejecting %s instance %s (%s:%d) until %s\n", sd.serviceName, instance.ID, instance.Address, instance.Port, instance.ejectedUntil.Format(time.RFC3339))
+	}
 }
 
-// CallAPI - Function to send an HTTP request to the discovered service (supports GET, PUT, PATCH, DELETE, POST, etc.).
-func (sd *serviceDiscovery) CallAPI(service *api.CatalogService, endpoint, method string, body []byte, headers map[string]string) (string, error) {
-	// Build the API URL using service address and port
-	url := fmt.Sprintf("http://%s:%d%s", service.ServiceAddress, service.ServicePort, endpoint)
-	// Create a new HTTP request
-	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+// watch starts a blocking-query watch on the passing instances of the service.
+func (sd *serviceDiscovery) watch() {
+	plan, err := watch.Parse(map[string]any{
+		"type":        "service",
+		"service":     sd.serviceName,
+		"passingonly": true,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		fmt.Printf("Error watching service %s: %v\n", sd.serviceName, err)
+		return
 	}
 
-	// Set headers if any
-	for key, value := range headers {
-		req.Header.Set(key, value)
+	plan.HybridHandler = func(index watch.BlockingParamVal, result interface{}) {
+		if entries, ok := result.([]*api.ServiceEntry); ok {
+			sd.setInstances(entries)
+		}
 	}
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+	go func() {
+		if err := plan.RunWithClientAndHclog(sd.consulClient, nil); err != nil {
+			fmt.Printf("Watch of service %s stopped: %v\n", sd.serviceName, err)
+		}
+	}()
+}
+
+// setInstances replaces the instance list, keeping the call statistics of
+// instances that are still passing.
+func (sd *serviceDiscovery) setInstances(entries []*api.ServiceEntry) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	previous := make(map[string]*Instance, len(sd.instances))
+	for _, instance := range sd.instances {
+		previous[instance.ID] = instance
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
+	instances := make([]*Instance, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+
+		id := entry.Node.Node + "/" + entry.Service.ID
+		if instance, exists := previous[id]; exists && instance.Address == address && instance.Port == entry.Service.Port {
+			instances = append(instances, instance)
+			continue
+		}
+
+		instances = append(instances, &Instance{ID: id, Address: address, Port: entry.Service.Port})
 	}
 
-	return string(bodyBytes), nil
+	sd.instances = instances
+	sd.loaded = true
 }
@@ -0,0 +1,169 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestDiscovery returns a discovery already holding the instances, with
+// the Consul watch skipped and time read from the returned clock.
+func newTestDiscovery(options DiscoveryOptions, ids ...string) (*serviceDiscovery, *fakeClock) {
+	clock := newFakeClock()
+	sd := &serviceDiscovery{
+		serviceName: "test-service",
+		options:     options,
+		balancer:    RoundRobin(),
+		now:         clock.Now,
+		instances:   testInstances(ids...),
+		loaded:      true,
+	}
+	sd.once.Do(func() {})
+	return sd, clock
+}
+
+// call picks an instance and ends the call to it, failed or not.
+func call(t *testing.T, sd *serviceDiscovery, failed func(id string) bool) string {
+	t.Helper()
+
+	instance, err := sd.DiscoverService()
+	if err != nil {
+		t.Fatalf("DiscoverService: %v", err)
+	}
+	sd.Release(instance, failed(instance.ID))
+	return instance.ID
+}
+
+func counts(t *testing.T, sd *serviceDiscovery, n int, failed func(id string) bool) map[string]int {
+	t.Helper()
+
+	picked := make(map[string]int)
+	for i := 0; i < n; i++ {
+		picked[call(t, sd, failed)]++
+	}
+	return picked
+}
+
+func never(string) bool { return false }
+
+func TestDiscoveryRoundRobinOverHealthyInstances(t *testing.T) {
+	sd, _ := newTestDiscovery(DiscoveryOptions{EjectFailures: 2, EjectDuration: 10 * time.Second}, "a", "b", "c")
+
+	picked := counts(t, sd, 9, never)
+	for _, id := range []string{"a", "b", "c"} {
+		if picked[id] != 3 {
+			t.Fatalf("picks = %v, want each instance 3 times", picked)
+		}
+	}
+}
+
+func TestDiscoveryEjection(t *testing.T) {
+	sd, clock := newTestDiscovery(DiscoveryOptions{EjectFailures: 2, EjectDuration: 10 * time.Second}, "a", "b", "c")
+	failB := func(id string) bool { return id == "b" }
+
+	// Two failures of b in a row eject it
+	counts(t, sd, 6, failB)
+
+	picked := counts(t, sd, 6, never)
+	if picked["b"] != 0 || picked["a"] != 3 || picked["c"] != 3 {
+		t.Fatalf("picks with b ejected = %v, want only a and c, in turn", picked)
+	}
+
+	// Restored at the end of the window
+	clock.Advance(10*time.Second + time.Nanosecond)
+	if picked := counts(t, sd, 3, never); picked["b"] != 1 {
+		t.Fatalf("picks after the ejection window = %v, want b back", picked)
+	}
+}
+
+func TestDiscoveryEjectionBackoff(t *testing.T) {
+	sd, clock := newTestDiscovery(DiscoveryOptions{EjectFailures: 1, EjectDuration: 10 * time.Second}, "a", "b")
+	failB := func(id string) bool { return id == "b" }
+	b := sd.instances[1]
+
+	tests := []struct {
+		name   string
+		ejects time.Duration // how long the next failure of b ejects it for
+	}{
+		{name: "first ejection", ejects: 10 * time.Second},
+		{name: "ejected again as soon as it is back", ejects: 20 * time.Second},
+		{name: "and again", ejects: 40 * time.Second},
+	}
+
+	for _, tt := range tests {
+		// Fails b on its next pick
+		for call(t, sd, failB) != "b" {
+		}
+
+		if got := b.ejectedUntil.Sub(clock.Now()); got != tt.ejects {
+			t.Fatalf("%s: ejected for %s, want %s", tt.name, got, tt.ejects)
+		}
+		clock.Advance(tt.ejects + time.Nanosecond)
+	}
+
+	// A success once back ends the run of ejections
+	for call(t, sd, never) != "b" {
+	}
+	for call(t, sd, failB) != "b" {
+	}
+	if got := b.ejectedUntil.Sub(clock.Now()); got != 10*time.Second {
+		t.Fatalf("ejected for %s after a success, want the first window again", got)
+	}
+}
+
+func TestDiscoveryFailuresMustBeConsecutive(t *testing.T) {
+	sd, _ := newTestDiscovery(DiscoveryOptions{EjectFailures: 2, EjectDuration: 10 * time.Second}, "a")
+	instance := sd.instances[0]
+
+	sd.Release(instance, true)
+	sd.Release(instance, false)
+	sd.Release(instance, true)
+
+	if instance.ejectedUntil.After(sd.now()) {
+		t.Fatal("instance ejected after failures separated by a success")
+	}
+}
+
+func TestDiscoveryEjectionDisabled(t *testing.T) {
+	sd, _ := newTestDiscovery(DiscoveryOptions{}, "a", "b")
+
+	picked := counts(t, sd, 10, func(id string) bool { return id == "b" })
+	if picked["b"] != 5 {
+		t.Fatalf("picks = %v, want b kept in turn without ejection", picked)
+	}
+}
+
+func TestDiscoveryAllEjected(t *testing.T) {
+	sd, _ := newTestDiscovery(DiscoveryOptions{EjectFailures: 1, EjectDuration: time.Minute}, "a", "b")
+
+	counts(t, sd, 2, func(string) bool { return true })
+
+	picked := counts(t, sd, 4, never)
+	if picked["a"] != 2 || picked["b"] != 2 {
+		t.Fatalf("picks with every instance ejected = %v, want all of them tried", picked)
+	}
+}
+
+func TestDiscoveryOutstanding(t *testing.T) {
+	sd, _ := newTestDiscovery(DiscoveryOptions{}, "a")
+
+	first, _ := sd.DiscoverService()
+	second, _ := sd.DiscoverService()
+	if first.outstanding != 2 {
+		t.Fatalf("outstanding = %d with two calls in flight", first.outstanding)
+	}
+
+	sd.Release(first, false)
+	sd.Release(second, false)
+	sd.Release(second, false)
+	if first.outstanding != 0 {
+		t.Fatalf("outstanding = %d after the calls ended", first.outstanding)
+	}
+}
+
+func TestDiscoveryNoInstance(t *testing.T) {
+	sd, _ := newTestDiscovery(DiscoveryOptions{})
+
+	if _, err := sd.DiscoverService(); err == nil {
+		t.Fatal("DiscoverService succeeded without instances")
+	}
+}
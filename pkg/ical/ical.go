@@ -0,0 +1,194 @@
+// Package ical reads the events of an iCalendar (RFC 5545) file, enough to
+// import holiday and school break calendars: VEVENT with UID, SUMMARY,
+// DTSTART, DTEND, RRULE and CATEGORIES. Other components are skipped.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Event struct {
+	UID        string
+	Summary    string
+	Categories []string
+	Start      time.Time
+	End        *time.Time // exclusive, nil when the event has no DTEND
+	AllDay     bool       // DTSTART is a DATE; Start and End are midnight UTC
+	RRule      string
+}
+
+// Parse returns the events of the calendar in r in file order.
+func Parse(r io.Reader) ([]*Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	var current *Event
+	depth := 0
+	sawCalendar := false
+
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		name, params, value, err := splitLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch name {
+		case "BEGIN":
+			depth++
+			if strings.EqualFold(value, "VCALENDAR") {
+				sawCalendar = true
+			}
+			if strings.EqualFold(value, "VEVENT") {
+				current = &Event{}
+			}
+			continue
+		case "END":
+			depth--
+			if strings.EqualFold(value, "VEVENT") && current != nil {
+				if current.Start.IsZero() {
+					return nil, fmt.Errorf("event %q has no DTSTART", current.UID)
+				}
+				events = append(events, current)
+				current = nil
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			current.UID = value
+		case "SUMMARY":
+			current.Summary = unescape(value)
+		case "CATEGORIES":
+			for _, category := range splitList(value) {
+				if category = strings.TrimSpace(unescape(category)); category != "" {
+					current.Categories = append(current.Categories, category)
+				}
+			}
+		case "RRULE":
+			current.RRule = value
+		case "DTSTART":
+			start, allDay, err := parseTime(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid DTSTART: %w", n+1, err)
+			}
+			current.Start, current.AllDay = start, allDay
+		case "DTEND":
+			end, _, err := parseTime(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid DTEND: %w", n+1, err)
+			}
+			current.End = &end
+		}
+	}
+
+	if !sawCalendar {
+		return nil, errors.New("not an iCalendar file: BEGIN:VCALENDAR missing")
+	}
+
+	if depth != 0 {
+		return nil, errors.New("unbalanced BEGIN/END")
+	}
+
+	return events, nil
+}
+
+// unfold joins continuation lines (starting with a space or tab) to the line
+// before them.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// splitLine splits "NAME;PARAM=VALUE:value" into its parts. Parameter names
+// are upper cased.
+func splitLine(line string) (string, map[string]string, string, error) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", nil, "", fmt.Errorf("missing ':' in %q", line)
+	}
+
+	parts := strings.Split(head, ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		key, val, _ := strings.Cut(part, "=")
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+
+	return strings.ToUpper(parts[0]), params, value, nil
+}
+
+// parseTime reads a DATE or DATE-TIME value. Floating times without TZID are
+// read as UTC.
+func parseTime(value string, params map[string]string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		date, err := time.Parse("20060102", value)
+		return date, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		instant, err := time.Parse("20060102T150405Z", value)
+		return instant, false, err
+	}
+
+	location := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		loaded, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown TZID %s", tzid)
+		}
+		location = loaded
+	}
+
+	instant, err := time.ParseInLocation("20060102T150405", value, location)
+	return instant, false, err
+}
+
+// splitList splits a comma separated list of text values, leaving escaped
+// commas in the values.
+func splitList(value string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, value[start:])
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
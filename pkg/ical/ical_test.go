@@ -0,0 +1,181 @@
+package ical
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// calendar wraps event lines in a VCALENDAR, with CRLF line endings.
+func calendar(lines ...string) string {
+	all := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...)
+	all = append(all, "END:VCALENDAR")
+	return strings.Join(all, "\r\n") + "\r\n"
+}
+
+func event(lines ...string) []string {
+	return append(append([]string{"BEGIN:VEVENT"}, lines...), "END:VEVENT")
+}
+
+func instant(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []*Event
+	}{
+		{
+			name:  "timed in UTC",
+			input: calendar(event("UID:1", "DTSTART:20261012T080000Z", "DTEND:20261012T093000Z")...),
+			want: []*Event{{
+				UID:   "1",
+				Start: instant("2026-10-12T08:00:00Z"),
+				End:   ptr(instant("2026-10-12T09:30:00Z")),
+			}},
+		},
+		{
+			name:  "all day with exclusive DTEND",
+			input: calendar(event("UID:1", "DTSTART;VALUE=DATE:20261020", "DTEND;VALUE=DATE:20261022")...),
+			want: []*Event{{
+				UID:    "1",
+				Start:  instant("2026-10-20T00:00:00Z"),
+				End:    ptr(instant("2026-10-22T00:00:00Z")),
+				AllDay: true,
+			}},
+		},
+		{
+			name:  "all day without VALUE=DATE",
+			input: calendar(event("UID:1", "DTSTART:20261020")...),
+			want:  []*Event{{UID: "1", Start: instant("2026-10-20T00:00:00Z"), AllDay: true}},
+		},
+		{
+			name:  "TZID",
+			input: calendar(event("UID:1", "DTSTART;TZID=Asia/Ho_Chi_Minh:20261012T080000", `DTEND;TZID="Asia/Ho_Chi_Minh":20261012T090000`)...),
+			want: []*Event{{
+				UID:   "1",
+				Start: instant("2026-10-12T01:00:00Z"),
+				End:   ptr(instant("2026-10-12T02:00:00Z")),
+			}},
+		},
+		{
+			name:  "floating time is UTC",
+			input: calendar(event("UID:1", "DTSTART:20261012T080000")...),
+			want:  []*Event{{UID: "1", Start: instant("2026-10-12T08:00:00Z")}},
+		},
+		{
+			name: "folded lines",
+			input: calendar(event(
+				"UID:1",
+				"SUMMARY:Mid-autumn",
+				" festival",
+				"\tholiday",
+				"CATEGORIES:holi",
+				" day,closed",
+				"DTSTART;VALUE=DATE:2026",
+				" 1006",
+			)...),
+			want: []*Event{{
+				UID:        "1",
+				Summary:    "Mid-autumnfestivalholiday",
+				Categories: []string{"holiday", "closed"},
+				Start:      instant("2026-10-06T00:00:00Z"),
+				AllDay:     true,
+			}},
+		},
+		{
+			name:  "escaped text",
+			input: calendar(event("UID:1", `SUMMARY:Break\, term 1\; week 8`, `CATEGORIES:a\,b, ,c`, "DTSTART:20261012")...),
+			want: []*Event{{
+				UID:        "1",
+				Summary:    "Break, term 1; week 8",
+				Categories: []string{"a,b", "c"},
+				Start:      instant("2026-10-12T00:00:00Z"),
+				AllDay:     true,
+			}},
+		},
+		{
+			name: "other components are skipped",
+			input: calendar(append(
+				[]string{"BEGIN:VTIMEZONE", "TZID:Asia/Ho_Chi_Minh", "END:VTIMEZONE", "BEGIN:VTODO", "UID:todo", "END:VTODO"},
+				event("UID:1", "DTSTART:20261012", "RRULE:FREQ=YEARLY")...,
+			)...),
+			want: []*Event{{UID: "1", Start: instant("2026-10-12T00:00:00Z"), AllDay: true, RRule: "FREQ=YEARLY"}},
+		},
+		{
+			name: "events in file order",
+			input: calendar(append(
+				event("UID:b", "DTSTART:20261013"),
+				event("UID:a", "DTSTART:20261012")...,
+			)...),
+			want: []*Event{
+				{UID: "b", Start: instant("2026-10-13T00:00:00Z"), AllDay: true},
+				{UID: "a", Start: instant("2026-10-12T00:00:00Z"), AllDay: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !sameEvent(got[i], tt.want[i]) {
+					t.Fatalf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// sameEvent compares events by instant, whatever location their times are in.
+func sameEvent(a, b *Event) bool {
+	if !a.Start.Equal(b.Start) || (a.End == nil) != (b.End == nil) || (a.End != nil && !a.End.Equal(*b.End)) {
+		return false
+	}
+
+	a2, b2 := *a, *b
+	a2.Start, b2.Start, a2.End, b2.End = time.Time{}, time.Time{}, nil, nil
+	return reflect.DeepEqual(a2, b2)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "not a calendar", input: strings.Join(event("UID:1", "DTSTART:20261012"), "\r\n")},
+		{name: "unclosed calendar", input: strings.TrimSuffix(calendar(), "END:VCALENDAR\r\n")},
+		{name: "unclosed event", input: calendar("BEGIN:VEVENT", "UID:1", "DTSTART:20261012")},
+		{name: "line without colon", input: calendar(event("UID:1", "DTSTART:20261012", "SUMMARY")...)},
+		{name: "event without DTSTART", input: calendar(event("UID:1", "SUMMARY:Holiday")...)},
+		{name: "invalid date", input: calendar(event("UID:1", "DTSTART;VALUE=DATE:20261340")...)},
+		{name: "invalid date-time", input: calendar(event("UID:1", "DTSTART:20261012T250000Z")...)},
+		{name: "unknown TZID", input: calendar(event("UID:1", "DTSTART;TZID=Mars/Olympus:20261012T080000")...)},
+		{name: "invalid DTEND", input: calendar(event("UID:1", "DTSTART:20261012", "DTEND:tomorrow")...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if events, err := Parse(strings.NewReader(tt.input)); err == nil {
+				t.Fatalf("Parse succeeded with %d events, want an error", len(events))
+			}
+		})
+	}
+}
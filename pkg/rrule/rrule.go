@@ -0,0 +1,410 @@
+// Package rrule parses and expands the subset of RFC 5545 recurrence rules
+// used for timetables: FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, COUNT, UNTIL,
+// BYDAY, BYMONTHDAY and WKST. Occurrences are whole days; times of day are
+// ignored and every date is handled as midnight UTC.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// maxEmptyPeriods stops the expansion of rules that can never match again,
+// e.g. BYMONTHDAY=31 on every other February.
+const maxEmptyPeriods = 1000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month for MONTHLY
+// rules (1 = first, -1 = last) and 0 for every such weekday.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	WeekStart  time.Weekday
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20250601". The
+// "RRULE:" prefix is optional.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("rrule is empty")
+	}
+
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid rrule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(val))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return nil, fmt.Errorf("unsupported FREQ %s", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %s", val)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %s", val)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseDate(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %s", val)
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, err := parseWeekdayNum(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, err := strconv.Atoi(code)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %s", code)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "WKST":
+			weekday, ok := weekdayCodes[strings.ToUpper(val)]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %s", val)
+			}
+			rule.WeekStart = weekday
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, errors.New("COUNT and UNTIL cannot be combined")
+	}
+
+	if rule.Freq != Monthly {
+		for _, day := range rule.ByDay {
+			if day.N != 0 {
+				return nil, errors.New("BYDAY ordinals are only allowed with FREQ=MONTHLY")
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+func parseWeekdayNum(code string) (WeekdayNum, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %s", code)
+	}
+
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %s", code)
+	}
+
+	n := 0
+	if prefix := code[:len(code)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %s", code)
+		}
+	}
+
+	return WeekdayNum{Weekday: weekday, N: n}, nil
+}
+
+// parseDate accepts the DATE and DATE-TIME forms of UNTIL as well as
+// "2006-01-02".
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return Day(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %s", value)
+}
+
+// Day truncates t to midnight UTC of its date.
+func Day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			code := strings.ToUpper(day.Weekday.String()[:2])
+			if day.N != 0 {
+				code = strconv.Itoa(day.N) + code
+			}
+			codes = append(codes, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		codes := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			codes = append(codes, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(codes, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+strings.ToUpper(r.WeekStart.String()[:2]))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Between returns the occurrences of the rule starting at dtstart that fall
+// within [from, to], skipping exdates. As in RFC 5545 dtstart is always the
+// first occurrence and COUNT is applied before EXDATE.
+func (r *Rule) Between(dtstart, from, to time.Time, exdates []time.Time) []time.Time {
+	dtstart, from, to = Day(dtstart), Day(from), Day(to)
+
+	excluded := make(map[time.Time]bool, len(exdates))
+	for _, exdate := range exdates {
+		excluded[Day(exdate)] = true
+	}
+
+	var result []time.Time
+	r.iterate(dtstart, to, func(day time.Time) {
+		if !day.Before(from) && !excluded[day] {
+			result = append(result, day)
+		}
+	})
+
+	return result
+}
+
+// Last returns the final occurrence of a bounded rule, before exdates are
+// applied. ok is false for rules without COUNT or UNTIL.
+func (r *Rule) Last(dtstart time.Time) (last time.Time, ok bool) {
+	if r.Count == 0 && r.Until == nil {
+		return time.Time{}, false
+	}
+
+	end := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	if r.Until != nil {
+		end = *r.Until
+	}
+
+	r.iterate(Day(dtstart), end, func(day time.Time) {
+		last = day
+	})
+
+	return last, !last.IsZero()
+}
+
+// iterate calls yield for each occurrence up to to, in order.
+func (r *Rule) iterate(dtstart, to time.Time, yield func(time.Time)) {
+	if r.Until != nil && r.Until.Before(to) {
+		to = *r.Until
+	}
+
+	if dtstart.After(to) {
+		return
+	}
+
+	count := 1
+	yield(dtstart)
+
+	emptyPeriods := 0
+	for period := 0; emptyPeriods < maxEmptyPeriods; period++ {
+		periodStart, candidates := r.period(dtstart, period)
+		if periodStart.After(to) {
+			return
+		}
+
+		matched := false
+		for _, day := range candidates {
+			if !day.After(dtstart) {
+				continue
+			}
+			if day.After(to) {
+				return
+			}
+			if r.Count > 0 && count >= r.Count {
+				return
+			}
+
+			matched = true
+			count++
+			yield(day)
+		}
+
+		if matched {
+			emptyPeriods = 0
+		} else {
+			emptyPeriods++
+		}
+	}
+}
+
+// period returns the first day of the n-th period of the rule and the sorted
+// candidate days inside it.
+func (r *Rule) period(dtstart time.Time, n int) (time.Time, []time.Time) {
+	switch r.Freq {
+	case Daily:
+		day := dtstart.AddDate(0, 0, n*r.Interval)
+		if r.matchesWeekday(day) && r.matchesMonthDay(day) {
+			return day, []time.Time{day}
+		}
+		return day, nil
+
+	case Weekly:
+		offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := dtstart.AddDate(0, 0, -offset+7*n*r.Interval)
+
+		var days []time.Time
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != dtstart.Weekday() {
+				continue
+			}
+			if r.matchesWeekday(day) && r.matchesMonthDay(day) {
+				days = append(days, day)
+			}
+		}
+		return weekStart, days
+
+	default:
+		monthStart := time.Date(dtstart.Year(), dtstart.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		return monthStart, r.monthDays(monthStart, dtstart)
+	}
+}
+
+func (r *Rule) monthDays(monthStart, dtstart time.Time) []time.Time {
+	daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+	for d := 1; d <= daysInMonth; d++ {
+		day := monthStart.AddDate(0, 0, d-1)
+
+		switch {
+		case len(r.ByDay) == 0 && len(r.ByMonthDay) == 0:
+			if d != dtstart.Day() {
+				continue
+			}
+		case len(r.ByDay) == 0:
+			if !r.matchesMonthDay(day) {
+				continue
+			}
+		default:
+			if !r.matchesMonthlyWeekday(day, daysInMonth) || !r.matchesMonthDay(day) {
+				continue
+			}
+		}
+
+		days = append(days, day)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+func (r *Rule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, byDay := range r.ByDay {
+		if byDay.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthlyWeekday(day time.Time, daysInMonth int) bool {
+	for _, byDay := range r.ByDay {
+		if byDay.Weekday != day.Weekday() {
+			continue
+		}
+
+		switch {
+		case byDay.N == 0:
+			return true
+		case byDay.N > 0 && (day.Day()-1)/7+1 == byDay.N:
+			return true
+		case byDay.N < 0 && (daysInMonth-day.Day())/7+1 == -byDay.N:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay > 0 && day.Day() == monthDay {
+			return true
+		}
+		if monthDay < 0 && day.Day() == daysInMonth+monthDay+1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package rrule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(values ...string) []time.Time {
+	result := make([]time.Time, 0, len(values))
+	for _, value := range values {
+		result = append(result, date(value))
+	}
+	return result
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		to      string
+		exdates []time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "BYDAY with COUNT",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			dtstart: "2025-01-06",
+			to:      "2025-12-31",
+			want:    dates("2025-01-06", "2025-01-08", "2025-01-13", "2025-01-15"),
+		},
+		{
+			name:    "COUNT is applied before EXDATE",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			dtstart: "2025-01-06",
+			to:      "2025-12-31",
+			exdates: dates("2025-01-08"),
+			want:    dates("2025-01-06", "2025-01-13", "2025-01-15"),
+		},
+		{
+			name:    "daily INTERVAL",
+			rule:    "FREQ=DAILY;INTERVAL=3",
+			dtstart: "2025-01-01",
+			to:      "2025-01-10",
+			want:    dates("2025-01-01", "2025-01-04", "2025-01-07", "2025-01-10"),
+		},
+		{
+			name:    "weekly INTERVAL",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			dtstart: "2025-01-07",
+			to:      "2025-02-10",
+			want:    dates("2025-01-07", "2025-01-21", "2025-02-04"),
+		},
+		{
+			name:    "last Friday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: "2025-01-31",
+			to:      "2025-04-30",
+			want:    dates("2025-01-31", "2025-02-28", "2025-03-28", "2025-04-25"),
+		},
+		{
+			name:    "BYMONTHDAY=31 skips shorter months",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31",
+			dtstart: "2025-01-31",
+			to:      "2025-08-31",
+			want:    dates("2025-01-31", "2025-03-31", "2025-05-31", "2025-07-31", "2025-08-31"),
+		},
+		{
+			name:    "UNTIL is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20250105",
+			dtstart: "2025-01-01",
+			to:      "2025-12-31",
+			want:    dates("2025-01-01", "2025-01-02", "2025-01-03", "2025-01-04", "2025-01-05"),
+		},
+		{
+			name:    "UNTIL as date-time",
+			rule:    "FREQ=WEEKLY;UNTIL=20250120T235959Z",
+			dtstart: "2025-01-06",
+			to:      "2025-12-31",
+			want:    dates("2025-01-06", "2025-01-13", "2025-01-20"),
+		},
+		// RFC 5545 3.8.5.3: WKST changes which days share a week with INTERVAL
+		{
+			name:    "WKST=MO",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			dtstart: "1997-08-05",
+			to:      "1997-12-31",
+			want:    dates("1997-08-05", "1997-08-10", "1997-08-19", "1997-08-24"),
+		},
+		{
+			name:    "WKST=SU",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			dtstart: "1997-08-05",
+			to:      "1997-12-31",
+			want:    dates("1997-08-05", "1997-08-17", "1997-08-19", "1997-08-31"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+
+			got := rule.Between(date(tt.dtstart), date(tt.dtstart), date(tt.to), tt.exdates)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLast(t *testing.T) {
+	tests := []struct {
+		rule   string
+		want   time.Time
+		wantOK bool
+	}{
+		{rule: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", want: date("2025-01-15"), wantOK: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31;UNTIL=20250630", want: date("2025-05-31"), wantOK: true},
+		{rule: "FREQ=DAILY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+
+			got, ok := rule.Last(date("2025-01-06"))
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Fatalf("got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20250101",
+		"FREQ=WEEKLY;BYDAY=-1FR",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;WKST=XX",
+	}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			if _, err := Parse(value); err == nil {
+				t.Fatalf("Parse(%q) succeeded, want an error", value)
+			}
+		})
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	value := "FREQ=MONTHLY;INTERVAL=2;COUNT=6;BYDAY=-1FR;WKST=SU"
+
+	rule, err := Parse(value)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", value, err)
+	}
+
+	if got := rule.String(); got != value {
+		t.Fatalf("String() = %q, want %q", got, value)
+	}
+}
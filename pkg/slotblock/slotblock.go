@@ -0,0 +1,162 @@
+// Package slotblock lays out the slots of a block, the edits weeks, default
+// days and templates share: moving a block, reordering and splitting its
+// slots and numbering their sessions. Slot times only carry a time of day,
+// on the zero date.
+package slotblock
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Slot is a slot of a block as the edits see it.
+type Slot interface {
+	ID() primitive.ObjectID
+	Span() (start, end time.Time)
+	// Reschedule sets the times of a slot moved by an edit.
+	Reschedule(start, end time.Time)
+	SetSessions(sessions int)
+}
+
+// Move shifts the slots so the earliest one starts at start, "HH:MM",
+// keeping their durations and the gaps between them.
+func Move[S Slot](slots []S, start string) error {
+
+	if len(slots) == 0 {
+		return errors.New("block has no slots to move")
+	}
+
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return errors.New("invalid start_time format (use HH:MM)")
+	}
+
+	blockStart, _ := slots[0].Span()
+	for _, slot := range slots {
+		if slotStart, _ := slot.Span(); slotStart.Before(blockStart) {
+			blockStart = slotStart
+		}
+	}
+	shift := startTime.Sub(blockStart)
+
+	for _, slot := range slots {
+		slotStart, slotEnd := slot.Span()
+		slot.Reschedule(slotStart.Add(shift), slotEnd.Add(shift))
+	}
+
+	return nil
+}
+
+// Reorder returns the slots in the order of slotIDs, which must list each of
+// them once. They are laid out again from the start of the block, the gap
+// after the n-th slot in time staying after the n-th slot in the new order.
+// Sessions are renumbered.
+func Reorder[S Slot](slots []S, slotIDs []string) ([]S, error) {
+
+	if len(slotIDs) != len(slots) {
+		return nil, errors.New("slot_ids must list every slot of the block once")
+	}
+
+	slotsByID := make(map[string]S, len(slots))
+	for _, slot := range slots {
+		slotsByID[slot.ID().Hex()] = slot
+	}
+
+	ordered := make([]S, 0, len(slotIDs))
+	for _, slotID := range slotIDs {
+		slot, exists := slotsByID[slotID]
+		if !exists {
+			return nil, fmt.Errorf("slot %s is not in the block or is listed twice", slotID)
+		}
+		delete(slotsByID, slotID)
+		ordered = append(ordered, slot)
+	}
+
+	current := append([]S(nil), slots...)
+	sortByStart(current)
+
+	gaps := make([]time.Duration, len(current))
+	for i := 0; i < len(current)-1; i++ {
+		_, end := current[i].Span()
+		nextStart, _ := current[i+1].Span()
+		if gap := nextStart.Sub(end); gap > 0 {
+			gaps[i] = gap
+		}
+	}
+
+	if len(current) > 0 {
+		next, _ := current[0].Span()
+		for i, slot := range ordered {
+			start, end := slot.Span()
+			slot.Reschedule(next, next.Add(end.Sub(start)))
+			next = next.Add(end.Sub(start)).Add(gaps[i])
+		}
+	}
+
+	Renumber(ordered)
+
+	return ordered, nil
+}
+
+// SplitIndex numbers the sessions of the block and returns where it splits
+// at slotID: the slots from that index on move to the new block.
+func SplitIndex[S Slot](slots []S, slotID string) (int, error) {
+
+	Renumber(slots)
+
+	for i, slot := range slots {
+		if slot.ID().Hex() != slotID {
+			continue
+		}
+		if i == 0 {
+			return 0, errors.New("cannot split a block at its first slot")
+		}
+		return i, nil
+	}
+
+	return 0, errors.New("slot not found in block")
+}
+
+// Renumber sorts the slots by start time and numbers their sessions from 1.
+func Renumber[S Slot](slots []S) {
+	sortByStart(slots)
+	for i, slot := range slots {
+		slot.SetSessions(i + 1)
+	}
+}
+
+// FitsInDay reports whether a slot from start to end stays within its day.
+func FitsInDay(start, end time.Time) bool {
+	dayStart := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return !start.Before(dayStart) && !end.After(dayStart.AddDate(0, 0, 1))
+}
+
+// Overlapping returns the first slot of others, other than slot itself, whose
+// time overlaps slot. Slots that only touch do not overlap.
+func Overlapping[S Slot](slot S, others []S) (S, bool) {
+	start, end := slot.Span()
+	for _, other := range others {
+		if other.ID() == slot.ID() {
+			continue
+		}
+		otherStart, otherEnd := other.Span()
+		if start.Before(otherEnd) && end.After(otherStart) {
+			return other, true
+		}
+	}
+
+	var none S
+	return none, false
+}
+
+func sortByStart[S Slot](slots []S) {
+	sort.SliceStable(slots, func(i, j int) bool {
+		a, _ := slots[i].Span()
+		b, _ := slots[j].Span()
+		return a.Before(b)
+	})
+}
@@ -0,0 +1,257 @@
+package slotblock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testSlot struct {
+	id         primitive.ObjectID
+	start, end time.Time
+	sessions   int
+}
+
+func (s *testSlot) ID() primitive.ObjectID { return s.id }
+
+func (s *testSlot) Span() (time.Time, time.Time) { return s.start, s.end }
+
+func (s *testSlot) Reschedule(start, end time.Time) { s.start, s.end = start, end }
+
+func (s *testSlot) SetSessions(sessions int) { s.sessions = sessions }
+
+func clock(value string) time.Time {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// block builds slots from "HH:MM-HH:MM" spans.
+func block(spans ...string) []*testSlot {
+	slots := make([]*testSlot, 0, len(spans))
+	for _, span := range spans {
+		slots = append(slots, &testSlot{id: primitive.NewObjectID(), start: clock(span[:5]), end: clock(span[6:])})
+	}
+	return slots
+}
+
+func spans(slots []*testSlot) []string {
+	result := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		result = append(result, slot.start.Format("15:04")+"-"+slot.end.Format("15:04"))
+	}
+	return result
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name    string
+		slots   []string
+		start   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "later, keeping the gap",
+			slots: []string{"08:00-08:30", "08:45-09:00"},
+			start: "10:00",
+			want:  []string{"10:00-10:30", "10:45-11:00"},
+		},
+		{
+			name:  "earlier, from the earliest slot whatever the order",
+			slots: []string{"09:00-09:30", "08:00-08:45"},
+			start: "07:30",
+			want:  []string{"08:30-09:00", "07:30-08:15"},
+		},
+		{name: "empty block", start: "10:00", wantErr: true},
+		{name: "bad start", slots: []string{"08:00-08:30"}, start: "8am", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := block(tt.slots...)
+
+			err := Move(slots, tt.start)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Move succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Move: %v", err)
+			}
+
+			if got := spans(slots); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReorder(t *testing.T) {
+	tests := []struct {
+		name  string
+		slots []string
+		order []int
+		want  []string
+	}{
+		{
+			name:  "gaps stay at their position",
+			slots: []string{"08:00-08:30", "08:40-09:00", "09:30-10:30"},
+			order: []int{2, 0, 1},
+			want:  []string{"08:00-09:00", "09:10-09:40", "10:10-10:30"},
+		},
+		{
+			name:  "overlapping slots are laid out back to back",
+			slots: []string{"08:00-09:00", "08:30-09:30"},
+			order: []int{1, 0},
+			want:  []string{"08:00-09:00", "09:00-10:00"},
+		},
+		{
+			name:  "same order",
+			slots: []string{"08:00-08:30", "09:00-09:15"},
+			order: []int{0, 1},
+			want:  []string{"08:00-08:30", "09:00-09:15"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := block(tt.slots...)
+
+			slotIDs := make([]string, 0, len(tt.order))
+			for _, i := range tt.order {
+				slotIDs = append(slotIDs, slots[i].id.Hex())
+			}
+
+			ordered, err := Reorder(slots, slotIDs)
+			if err != nil {
+				t.Fatalf("Reorder: %v", err)
+			}
+
+			if got := spans(ordered); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i, slot := range ordered {
+				if slot.sessions != i+1 {
+					t.Fatalf("slot %d has session %d", i, slot.sessions)
+				}
+			}
+		})
+	}
+}
+
+func TestReorderRejectsSlotIDs(t *testing.T) {
+	slots := block("08:00-08:30", "09:00-09:30")
+	first, second := slots[0].id.Hex(), slots[1].id.Hex()
+
+	tests := map[string][]string{
+		"missing":  {first},
+		"repeated": {first, first},
+		"unknown":  {first, primitive.NewObjectID().Hex()},
+		"extra":    {first, second, second},
+	}
+
+	for name, slotIDs := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Reorder(slots, slotIDs); err == nil {
+				t.Fatal("Reorder succeeded, want an error")
+			}
+			if got := spans(slots); !reflect.DeepEqual(got, []string{"08:00-08:30", "09:00-09:30"}) {
+				t.Fatalf("rejected reorder moved the slots to %v", got)
+			}
+		})
+	}
+}
+
+func TestSplitIndex(t *testing.T) {
+	// Stored out of time order, as after a merge
+	slots := block("09:00-09:30", "08:00-08:30", "10:00-10:30")
+
+	tests := []struct {
+		name    string
+		slotID  string
+		want    int
+		wantErr bool
+	}{
+		{name: "second in time", slotID: slots[0].id.Hex(), want: 1},
+		{name: "last", slotID: slots[2].id.Hex(), want: 2},
+		{name: "first in time", slotID: slots[1].id.Hex(), wantErr: true},
+		{name: "not in block", slotID: primitive.NewObjectID().Hex(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitIndex(append([]*testSlot(nil), slots...), tt.slotID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SplitIndex = %d, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitIndex: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("SplitIndex = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverlapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		slot   string
+		others []string
+		want   bool
+	}{
+		{name: "touching end", slot: "08:00-09:00", others: []string{"09:00-10:00"}, want: false},
+		{name: "touching start", slot: "09:00-10:00", others: []string{"08:00-09:00"}, want: false},
+		{name: "overlapping", slot: "08:00-09:00", others: []string{"08:59-10:00"}, want: true},
+		{name: "inside", slot: "08:00-10:00", others: []string{"08:30-09:00"}, want: true},
+		{name: "apart", slot: "08:00-08:30", others: []string{"07:00-07:30", "09:00-09:30"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot := block(tt.slot)[0]
+			// The slot itself is part of the day and must not count
+			others := append(block(tt.others...), slot)
+
+			if _, got := Overlapping(slot, others); got != tt.want {
+				t.Fatalf("Overlapping = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitsInDay(t *testing.T) {
+	start := clock("22:00")
+
+	tests := []struct {
+		name string
+		end  time.Time
+		want bool
+	}{
+		{name: "ends at midnight", end: start.Add(2 * time.Hour), want: true},
+		{name: "runs past midnight", end: start.Add(2*time.Hour + time.Minute), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FitsInDay(start, tt.end); got != tt.want {
+				t.Fatalf("FitsInDay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if FitsInDay(clock("00:30").Add(-time.Hour), clock("00:30")) {
+		t.Fatal("a slot moved before midnight fits in the day")
+	}
+}